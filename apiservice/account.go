@@ -0,0 +1,216 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"strconv"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/jsh-api"
+	"github.com/sbosnick1/openacct/domain"
+	"golang.org/x/net/context"
+)
+
+const (
+	accountResourceType = "account"
+
+	accountFundRelationship = "fund"
+)
+
+// newAccountResource creates the resource for accounts. Accounts can't be
+// deleted so there is no DELETE route.
+func newAccountResource(store *accountStore) *jshapi.Resource {
+	resource := jshapi.NewResource(accountResourceType)
+	resource.Post(store.Save)
+	resource.Get(store.Get)
+	resource.List(store.List)
+	resource.Patch(store.Update)
+	resource.ToOne(accountFundRelationship, store.GetFund)
+	return resource
+}
+
+type accountAttributes struct {
+	Name string `json:"name,omitempty" valid:"required"`
+	Type string `json:"type,omitempty" valid:"required,accounttype"`
+}
+
+// accountPatchAttributes are the attributes of an account that may be
+// changed by an update. The type of an account can't be changed.
+type accountPatchAttributes struct {
+	Name string `json:"name,omitempty" valid:"required"`
+}
+
+// An accountStore is a store for the account resource type. It adapts a
+// domain.AccountRepository to a json api spec. resource.
+type accountStore struct {
+	repository domain.AccountRepository
+	funds      *fundStore
+}
+
+func (a *accountStore) Save(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if a.repository == nil {
+		return nil, jsh.ISE("accountStore requires an AccountRepository")
+	}
+
+	var attributes accountAttributes
+	jsherrs := object.Unmarshal(accountResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	fundID, jsherr := parseToOneRelationship(object, accountFundRelationship, fundResourceType)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	accountType, err := domain.ParseAccountType(attributes.Type)
+	if err != nil {
+		// the validation on accountAttributes should have ensured
+		// this does not happen
+		return nil, jsh.InputError(err.Error(), "type")
+	}
+
+	account, err := a.repository.Create(ctx, fundID, attributes.Name, accountType)
+	if notfound, ok := err.(*domain.NotFoundError); ok && notfound.Entity == fundResourceType {
+		return nil, newRelationshipError(StatusUnprocessableEntity, "Invalid Relationship",
+			err.Error(), accountFundRelationship)
+	}
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createAccountObjectWithETag(ctx, account)
+}
+
+func (a *accountStore) Get(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if a.repository == nil {
+		return nil, jsh.ISE("accountStore requires an AccountRepository")
+	}
+
+	accountID, jsherr := parseID(accountResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	account, err := a.repository.Get(ctx, accountID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createAccountObjectWithETag(ctx, account)
+}
+
+func (a *accountStore) List(ctx context.Context) (jsh.List, jsh.ErrorType) {
+	if a.repository == nil {
+		return nil, jsh.ISE("accountStore requires an AccountRepository")
+	}
+
+	accounts, err := a.repository.GetAll(ctx)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createAccountList(accounts)
+}
+
+func (a *accountStore) Update(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if a.repository == nil {
+		return nil, jsh.ISE("accountStore requires an AccountRepository")
+	}
+
+	var attributes accountPatchAttributes
+	jsherrs := object.Unmarshal(accountResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	accountID, jsherr := parseID(accountResourceType, object.ID)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	version, anyversion, jsherr := ifMatchVersion(ctx)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	account, err := a.repository.Get(ctx, accountID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	if !anyversion && account.Version() != version {
+		return nil, newJshError(ctx, &domain.ConcurrencyError{Entity: accountResourceType, Id: object.ID})
+	}
+
+	updated, err := a.repository.Update(ctx, accountID, account.Version(), attributes.Name)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createAccountObjectWithETag(ctx, updated)
+}
+
+// GetFund gets the fund that the account with the given id belongs to.
+func (a *accountStore) GetFund(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if a.repository == nil || a.funds == nil {
+		return nil, jsh.ISE("accountStore requires an AccountRepository and a fundStore")
+	}
+
+	accountID, jsherr := parseID(accountResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	account, err := a.repository.Get(ctx, accountID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return a.funds.getFundObject(ctx, account.FundId())
+}
+
+func createAccountList(accounts []domain.Account) (jsh.List, jsh.ErrorType) {
+	list := make(jsh.List, 0)
+	for _, account := range accounts {
+		obj, err := createAccountObject(account)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, obj)
+	}
+
+	return list, nil
+}
+
+// createAccountObjectWithETag creates the object for an account and sets
+// the ETag for the account's version on the response.
+func createAccountObjectWithETag(ctx context.Context, account domain.Account) (*jsh.Object, jsh.ErrorType) {
+	obj, jsherr := createAccountObject(account)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	setETag(ctx, account.Version())
+	return obj, nil
+}
+
+func createAccountObject(account domain.Account) (*jsh.Object, *jsh.Error) {
+	id := strconv.FormatUint(uint64(account.Id()), 10)
+
+	obj, err := jsh.NewObject(id, accountResourceType,
+		accountAttributes{Name: account.Name(), Type: account.Type().String()})
+	if err != nil {
+		return nil, err
+	}
+
+	obj.Relationships = map[string]*jsh.Relationship{
+		accountFundRelationship: newToOneRelationship(accountResourceType, id,
+			accountFundRelationship, fundResourceType, account.FundId()),
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,317 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type fakeAccount struct {
+	id          uint
+	fundId      uint
+	name        string
+	accountType domain.AccountType
+	version     uint
+}
+
+func (f *fakeAccount) Id() uint {
+	return f.id
+}
+
+func (f *fakeAccount) FundId() uint {
+	return f.fundId
+}
+
+func (f *fakeAccount) Name() string {
+	return f.name
+}
+
+func (f *fakeAccount) Type() domain.AccountType {
+	return f.accountType
+}
+
+func (f *fakeAccount) Version() uint {
+	return f.version
+}
+
+type fakeAccountRepository struct {
+	accounts      []*fakeAccount
+	funds         *fakeFundRepository
+	createCalled  bool
+	updateCalled  bool
+	getAllCalled  bool
+	createError   error
+	updateVersion uint
+}
+
+func (f *fakeAccountRepository) GetAll(ctx context.Context) ([]domain.Account, error) {
+	f.getAllCalled = true
+
+	var accounts []domain.Account
+	for _, account := range f.accounts {
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+func (f *fakeAccountRepository) GetByFund(ctx context.Context, fundId uint) ([]domain.Account, error) {
+	var accounts []domain.Account
+	for _, account := range f.accounts {
+		if account.fundId == fundId {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+func (f *fakeAccountRepository) Get(ctx context.Context, id uint) (domain.Account, error) {
+	for _, account := range f.accounts {
+		if account.id == id {
+			return account, nil
+		}
+	}
+	return nil, &domain.NotFoundError{Entity: "account", Id: strconv.FormatUint(uint64(id), 10)}
+}
+
+func (f *fakeAccountRepository) Create(ctx context.Context, fundId uint, name string, accountType domain.AccountType) (domain.Account, error) {
+	f.createCalled = true
+	if f.createError != nil {
+		return nil, f.createError
+	}
+
+	if f.funds != nil {
+		_, err := f.funds.Get(ctx, fundId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	account := &fakeAccount{uint(len(f.accounts) + 1), fundId, name, accountType, 1}
+	f.accounts = append(f.accounts, account)
+	return account, nil
+}
+
+func (f *fakeAccountRepository) Update(ctx context.Context, id uint, version uint, name string) (domain.Account, error) {
+	f.updateCalled = true
+	f.updateVersion = version
+
+	for _, account := range f.accounts {
+		if account.id == id {
+			if account.version != version {
+				return nil, &domain.ConcurrencyError{Entity: "account", Id: strconv.FormatUint(uint64(id), 10)}
+			}
+			account.name = name
+			account.version++
+			return account, nil
+		}
+	}
+	return nil, &domain.NotFoundError{Entity: "account", Id: strconv.FormatUint(uint64(id), 10)}
+}
+
+// newFakeAccountStores gives a fundStore and an accountStore over the same
+// fake repositories with one fund that has two accounts.
+func newFakeAccountStores() (*fundStore, *accountStore, *fakeAccountRepository) {
+	funds := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}},
+		{2, domain.USD, "Special", 1, time.Time{}}})
+	accounts := &fakeAccountRepository{
+		accounts: []*fakeAccount{
+			{1, 1, "Cash", domain.Asset, 1},
+			{2, 1, "Donations", domain.Income, 1},
+		},
+		funds: funds,
+	}
+
+	fundstore := &fundStore{funds, accounts}
+	return fundstore, &accountStore{accounts, fundstore}, accounts
+}
+
+func newAccountObject(t *testing.T, id string, attributes map[string]string, fundID string) *jsh.Object {
+	obj, jsherr := jsh.NewObject(id, "account", attributes)
+	require.Nil(t, jsherr, "Unable to create the account object.")
+
+	if fundID != "" {
+		obj.Relationships = map[string]*jsh.Relationship{
+			"fund": {Data: jsh.ResourceLinkage{{Type: "fund", ID: fundID}}},
+		}
+	}
+	return obj
+}
+
+func TestZeroAccountStoreListsWithISE(t *testing.T) {
+	var sut accountStore
+
+	_, jsherr := sut.List(context.Background())
+
+	require.NotNil(t, jsherr, "Zero accountStore unexpectedly listed accounts")
+	assert.Equal(t, http.StatusInternalServerError, jsherr.StatusCode(),
+		"Zero accountStore gave unexpected status on List()")
+}
+
+func TestAccountStoreListReturnsAccountsWithFund(t *testing.T) {
+	assert := assert.New(t)
+	_, sut, repository := newFakeAccountStores()
+
+	list, jsherr := sut.List(context.Background())
+
+	require.Nil(t, jsherr, "accountStore failed to list accounts")
+	assert.True(repository.getAllCalled, "Listing the accounts failed to call GetAll()")
+	require.Len(t, list, 2, "Unexpected number of accounts listed.")
+	for _, obj := range list {
+		assert.Equal("account", obj.Type, "Unexpected type of object returned.")
+		require.Contains(t, obj.Relationships, "fund", "Account has no fund relationship.")
+		assert.Equal("1", obj.Relationships["fund"].Data[0].ID, "Unexpected fund for account.")
+	}
+}
+
+func TestAccountStoreSaveCreatesAccountInFund(t *testing.T) {
+	assert := assert.New(t)
+	_, sut, repository := newFakeAccountStores()
+	ctx, response := newRequestContext(t, nil)
+	obj := newAccountObject(t, "", map[string]string{"name": "Bank", "type": "asset"}, "2")
+
+	result, jsherr := sut.Save(ctx, obj)
+
+	require.Nil(t, jsherr, "accountStore failed to save an account")
+	assert.True(repository.createCalled, "Saving an account failed to call Create()")
+	assert.Equal("2", result.Relationships["fund"].Data[0].ID, "Unexpected fund for saved account.")
+	assert.Equal(`"1"`, response.Header().Get("ETag"), "Unexpected ETag for saved account.")
+}
+
+func TestAccountStoreSaveWithoutFundIsError(t *testing.T) {
+	_, sut, repository := newFakeAccountStores()
+	obj := newAccountObject(t, "", map[string]string{"name": "Bank", "type": "asset"}, "")
+
+	_, jsherr := sut.Save(context.Background(), obj)
+
+	require.NotNil(t, jsherr, "accountStore unexpectedly saved an account without a fund")
+	assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(), "Unexpected status.")
+	assert.False(t, repository.createCalled, "Saving an account without a fund called Create()")
+}
+
+func TestAccountStoreSaveWithUnknownFundIsError(t *testing.T) {
+	_, sut, _ := newFakeAccountStores()
+	obj := newAccountObject(t, "", map[string]string{"name": "Bank", "type": "asset"}, "99")
+
+	_, jsherr := sut.Save(context.Background(), obj)
+
+	require.NotNil(t, jsherr, "accountStore unexpectedly saved an account in an unknown fund")
+	assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(), "Unexpected status.")
+}
+
+func TestAccountStoreSaveWithInvalidTypeIsError(t *testing.T) {
+	_, sut, repository := newFakeAccountStores()
+	obj := newAccountObject(t, "", map[string]string{"name": "Bank", "type": "bogus"}, "1")
+
+	_, jsherr := sut.Save(context.Background(), obj)
+
+	require.NotNil(t, jsherr, "accountStore unexpectedly saved an account with an invalid type")
+	assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(), "Unexpected status.")
+	assert.False(t, repository.createCalled, "Saving an invalid account called Create()")
+}
+
+func TestAccountStoreSaveWithArchivedFundIsConflict(t *testing.T) {
+	_, sut, repository := newFakeAccountStores()
+	repository.createError = &domain.ConflictError{Entity: "fund", Id: "1", Reason: "the fund is archived"}
+	obj := newAccountObject(t, "", map[string]string{"name": "Bank", "type": "asset"}, "1")
+
+	_, jsherr := sut.Save(context.Background(), obj)
+
+	require.NotNil(t, jsherr, "accountStore unexpectedly saved an account in an archived fund")
+	assert.Equal(t, http.StatusConflict, jsherr.StatusCode(), "Unexpected status.")
+}
+
+func TestAccountStoreGetWithUnknownIdIsNotFound(t *testing.T) {
+	_, sut, _ := newFakeAccountStores()
+
+	for _, id := range []string{"99", "bogus"} {
+		_, jsherr := sut.Get(context.Background(), id)
+
+		require.NotNil(t, jsherr, "accountStore unexpectedly got account %s", id)
+		assert.Equal(t, http.StatusNotFound, jsherr.StatusCode(), "Unexpected status for id %s.", id)
+	}
+}
+
+func TestAccountStoreUpdateWithStaleVersionIsPreconditionFailed(t *testing.T) {
+	_, sut, repository := newFakeAccountStores()
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"7"`})
+	obj := newAccountObject(t, "1", map[string]string{"name": "Petty Cash"}, "")
+
+	_, jsherr := sut.Update(ctx, obj)
+
+	require.NotNil(t, jsherr, "accountStore unexpectedly updated a stale account")
+	assert.Equal(t, http.StatusPreconditionFailed, jsherr.StatusCode(), "Unexpected status.")
+	assert.False(t, repository.updateCalled, "Updating a stale account called Update()")
+}
+
+func TestAccountStoreUpdateWithMatchingVersionUpdatesAccount(t *testing.T) {
+	assert := assert.New(t)
+	_, sut, repository := newFakeAccountStores()
+	ctx, response := newRequestContext(t, map[string]string{"If-Match": `"1"`})
+	obj := newAccountObject(t, "1", map[string]string{"name": "Petty Cash"}, "")
+
+	_, jsherr := sut.Update(ctx, obj)
+
+	require.Nil(t, jsherr, "accountStore failed to update an account")
+	assert.True(repository.updateCalled, "Updating an account failed to call Update()")
+	assert.Equal(uint(1), repository.updateVersion, "Update() called with unexpected version.")
+	assert.Equal(`"2"`, response.Header().Get("ETag"), "Unexpected ETag for updated account.")
+}
+
+func TestAccountStoreGetFundReturnsFundWithAccounts(t *testing.T) {
+	assert := assert.New(t)
+	_, sut, _ := newFakeAccountStores()
+
+	fund, jsherr := sut.GetFund(context.Background(), "2")
+
+	require.Nil(t, jsherr, "accountStore failed to get the fund of an account")
+	assert.Equal("fund", fund.Type, "Unexpected type of related object.")
+	assert.Equal("1", fund.ID, "Unexpected related fund.")
+	require.Contains(t, fund.Relationships, "accounts", "Fund has no accounts relationship.")
+	assert.Len(fund.Relationships["accounts"].Data, 2, "Unexpected number of accounts in fund.")
+}
+
+func TestFundStoreGetAccountsListsAccountsInFund(t *testing.T) {
+	sut, _, _ := newFakeAccountStores()
+
+	list, jsherr := sut.GetAccounts(context.Background(), "1")
+
+	require.Nil(t, jsherr, "fundStore failed to list the accounts of a fund")
+	assert.Len(t, list, 2, "Unexpected number of accounts in fund.")
+}
+
+func TestFundStoreGetAccountsWithUnknownFundIsNotFound(t *testing.T) {
+	sut, _, _ := newFakeAccountStores()
+
+	_, jsherr := sut.GetAccounts(context.Background(), "99")
+
+	require.NotNil(t, jsherr, "fundStore unexpectedly listed the accounts of an unknown fund")
+	assert.Equal(t, http.StatusNotFound, jsherr.StatusCode(), "Unexpected status.")
+}
+
+func TestFundStoreListIncludesAccountsRelationship(t *testing.T) {
+	sut, _, _ := newFakeAccountStores()
+
+	list, jsherr := sut.List(context.Background())
+
+	require.Nil(t, jsherr, "fundStore failed to list funds")
+	for _, obj := range list {
+		require.Contains(t, obj.Relationships, "accounts", "Fund has no accounts relationship.")
+		switch obj.ID {
+		case "1":
+			assert.Len(t, obj.Relationships["accounts"].Data, 2, "Unexpected accounts for fund 1.")
+		case "2":
+			assert.Len(t, obj.Relationships["accounts"].Data, 0, "Unexpected accounts for fund 2.")
+		}
+	}
+}
@@ -12,23 +12,106 @@ import (
 	"github.com/asaskevich/govalidator"
 	"github.com/derekdowling/jsh-api"
 	"github.com/sbosnick1/openacct/domain"
+	"github.com/sbosnick1/openacct/logger"
+	"goji.io"
+	"goji.io/pat"
+)
+
+var (
+	log = logger.New("apiservice")
 )
 
 func init() {
 	govalidator.TagMap["currency"] = domain.IsCurrency
+	govalidator.TagMap["currentcurrency"] = domain.IsCurrentCurrency
+	govalidator.TagMap["accounttype"] = domain.IsAccountType
+	govalidator.TagMap["charttemplate"] = domain.IsChartTemplate
+	govalidator.TagMap["recurrence"] = domain.IsRecurrence
+	govalidator.TagMap["date"] = isDate
 }
 
 const apiV1Prefix = "/v1"
 
+// resourceRelationships describes the relationships between the resources
+// of the api service.
+var resourceRelationships = relationshipMap{
+	fundResourceType:     {fundAccountsRelationship: accountResourceType},
+	accountResourceType:  {accountFundRelationship: fundResourceType},
+	scheduleResourceType: {scheduleFundRelationship: fundResourceType},
+	entryResourceType: {
+		entryFundRelationship:        fundResourceType,
+		entryReversesRelationship:    entryResourceType,
+		entryReversedByRelationship:  entryResourceType,
+		entryCorrectsRelationship:    entryResourceType,
+		entryCorrectedByRelationship: entryResourceType,
+	},
+	approvalRuleResourceType: {approvalRuleFundRelationship: fundResourceType},
+	holdingResourceType:      {holdingFundRelationship: fundResourceType},
+	receiptResourceType: {
+		receiptDonorRelationship:      donorResourceType,
+		receiptFundRelationship:       fundResourceType,
+		receiptReplacesRelationship:   receiptResourceType,
+		receiptReplacedByRelationship: receiptResourceType,
+	},
+}
+
 // New() is a factory for the api service to expose the provided
 // domain.Store. The returned handler will service request for resources
-// on a JSON API at URL's prefixed with "/v1".
-func New(store domain.Store) http.Handler {
-	return &rootAdaptor{newApi(store)}
+// on a JSON API at URL's prefixed with "/v1". The options adjust the
+// behaviour of the service. The handler records metrics for the requests
+// that it serves (see MetricsRegistry).
+func New(store domain.Store, options ...Option) http.Handler {
+	cfg := newConfig(options)
+	return newInstrumentedHandler(cfg.metrics, &rootAdaptor{newApi(store, options...)})
 }
 
-func newApi(store domain.Store) *jshapi.API {
+func newApi(store domain.Store, options ...Option) *jshapi.API {
+	cfg := newConfig(options)
+
+	funds := &fundStore{store.FundRepository(), store.AccountRepository()}
+	accounts := &accountStore{store.AccountRepository(), funds}
+	schedules := &scheduleStore{store.ScheduleRepository(), funds, cfg.now}
+	entries := &entryStore{store.JournalEntryRepository(), funds, cfg.identify}
+	approvalRules := &approvalRuleStore{store.ApprovalRuleRepository(), funds, cfg.identify}
+	donors := &donorStore{store.DonorRepository(), funds}
+	receipts := &receiptStore{store.ReceiptRepository(), donors, funds, cfg.receiptTemplate, cfg.now, cfg.identify}
+	holdings := &holdingStore{store.HoldingRepository(), funds}
+
 	api := jshapi.New(apiV1Prefix)
-	api.Add(newFundResource(store.FundRepository()))
+	api.UseC(withHTTP)
+	api.UseC(withTimeout(cfg.requestTimeout))
+	api.UseC(newIdempotencyMiddleware(store.IdempotencyRepository(), cfg))
+	api.UseC(newCompoundMiddleware(resourceRelationships, map[string]objectGetter{
+		fundResourceType:         funds.Get,
+		accountResourceType:      accounts.Get,
+		scheduleResourceType:     schedules.Get,
+		entryResourceType:        entries.Get,
+		approvalRuleResourceType: approvalRules.Get,
+		donorResourceType:        donors.Get,
+		receiptResourceType:      receipts.Get,
+		holdingResourceType:      holdings.Get,
+	}))
+	// Like the occurrences route below, the entry action, fund balances
+	// and giving routes must come before the resources whose paths they
+	// are under.
+	handleEntryActions(api, entries)
+	handleGiving(api, donors)
+	api.Add(newFundResource(funds))
+	api.Add(newAccountResource(accounts))
+	// The occurrences route must come before the schedule resource, which
+	// handles every path under its own.
+	api.HandleC(pat.Get(apiV1Prefix+"/"+scheduleResourceType+"/:id"+occurrencesPath),
+		goji.HandlerFunc(schedules.GetOccurrences))
+	api.Add(newScheduleResource(schedules))
+	api.Add(newEntryResource(entries))
+	api.Add(newApprovalRuleResource(approvalRules))
+	api.Add(newDonorResource(donors))
+	// The receipt action, PDF and export routes must come before the
+	// receipt resource.
+	handleReceipts(api, receipts)
+	api.Add(newReceiptResource(receipts))
+	api.Add(newHoldingResource(holdings))
+	api.HandleC(pat.Post(apiV1Prefix+operationsPath), &operationsHandler{store})
+	api.HandleC(pat.Get(apiV1Prefix+openAPIPath), newOpenAPIHandler(resourceDescriptions, resourceRelationships))
 	return api
 }
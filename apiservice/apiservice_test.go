@@ -7,6 +7,7 @@ package apiservice
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -16,19 +17,72 @@ import (
 )
 
 type fakeStore struct {
-	fundRepository domain.FundRepository
+	fundRepository         domain.FundRepository
+	accountRepository      domain.AccountRepository
+	idempotencyRepository  domain.IdempotencyRepository
+	journalEntryRepository domain.JournalEntryRepository
+	scheduleRepository     domain.ScheduleRepository
+	approvalRuleRepository domain.ApprovalRuleRepository
+	donorRepository        domain.DonorRepository
+	receiptRepository      domain.ReceiptRepository
+	holdingRepository      domain.HoldingRepository
+	transactions           int
+	rolledBack             bool
 }
 
 func (f *fakeStore) FundRepository() domain.FundRepository {
 	return f.fundRepository
 }
 
+func (f *fakeStore) AccountRepository() domain.AccountRepository {
+	return f.accountRepository
+}
+
+func (f *fakeStore) IdempotencyRepository() domain.IdempotencyRepository {
+	return f.idempotencyRepository
+}
+
+func (f *fakeStore) JournalEntryRepository() domain.JournalEntryRepository {
+	return f.journalEntryRepository
+}
+
+func (f *fakeStore) ScheduleRepository() domain.ScheduleRepository {
+	return f.scheduleRepository
+}
+
+func (f *fakeStore) ApprovalRuleRepository() domain.ApprovalRuleRepository {
+	return f.approvalRuleRepository
+}
+
+func (f *fakeStore) DonorRepository() domain.DonorRepository {
+	return f.donorRepository
+}
+
+func (f *fakeStore) ReceiptRepository() domain.ReceiptRepository {
+	return f.receiptRepository
+}
+
+func (f *fakeStore) HoldingRepository() domain.HoldingRepository {
+	return f.holdingRepository
+}
+
+// InTransaction runs fn with the fakeStore itself. It records whether fn
+// failed but the fake repositories can't undo the changes that fn made.
+func (f *fakeStore) InTransaction(ctx context.Context, fn func(tx domain.Store) error) error {
+	f.transactions++
+	err := fn(f)
+	if err != nil {
+		f.rolledBack = true
+	}
+	return err
+}
+
 func TestNewApiListsAllFunds(t *testing.T) {
 	assert := assert.New(t)
 	request, responsewriter := getRequestResponse(t, "/v1/fund")
-	fakerepository := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General"},
-		{2, domain.USD, "Special"}})
-	fakestore := &fakeStore{fakerepository}
+	fakerepository := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}},
+		{2, domain.USD, "Special", 1, time.Time{}}})
+	fakestore := &fakeStore{fundRepository: fakerepository}
 
 	sut := newApi(fakestore)
 	sut.ServeHTTPC(context.Background(), responsewriter, request)
@@ -40,9 +94,9 @@ func TestNewApiListsAllFunds(t *testing.T) {
 func TestNewListsAllFunds(t *testing.T) {
 	assert := assert.New(t)
 	request, responsewriter := getRequestResponse(t, "/v1/fund")
-	fakerepository := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General"},
-		{2, domain.USD, "Special"}})
-	fakestore := &fakeStore{fakerepository}
+	fakerepository := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}},
+		{2, domain.USD, "Special", 1, time.Time{}}})
+	fakestore := &fakeStore{fundRepository: fakerepository}
 
 	sut := New(fakestore)
 	sut.ServeHTTP(responsewriter, request)
@@ -54,9 +108,9 @@ func TestNewListsAllFunds(t *testing.T) {
 func TestListingFundsOnNewApiServiceIncludesAttributes(t *testing.T) {
 	assert := assert.New(t)
 	request, responsewriter := getRequestResponse(t, "/v1/fund")
-	fakerepository := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General"},
-		{2, domain.USD, "Special"}})
-	fakestore := &fakeStore{fakerepository}
+	fakerepository := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}},
+		{2, domain.USD, "Special", 1, time.Time{}}})
+	fakestore := &fakeStore{fundRepository: fakerepository}
 
 	sut := New(fakestore)
 	sut.ServeHTTP(responsewriter, request)
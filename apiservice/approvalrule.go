@@ -0,0 +1,216 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"strconv"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/jsh-api"
+	"github.com/sbosnick1/openacct/domain"
+	"golang.org/x/net/context"
+)
+
+const (
+	approvalRuleResourceType = "approval-rule"
+
+	approvalRuleFundRelationship = "fund"
+)
+
+// newApprovalRuleResource creates the resource for approval rules. A rule
+// is changed by deleting it and creating another so there is no PATCH
+// route.
+func newApprovalRuleResource(store *approvalRuleStore) *jshapi.Resource {
+	resource := jshapi.NewResource(approvalRuleResourceType)
+	resource.Post(store.Save)
+	resource.Get(store.Get)
+	resource.List(store.List)
+	resource.Delete(store.Delete)
+	resource.ToOne(approvalRuleFundRelationship, store.GetFund)
+	return resource
+}
+
+// approvalRuleAttributes are the attributes of an approval rule. The
+// threshold is in the minor units of the currency of the rule's fund and
+// a missing threshold is zero, so the rule applies to every entry.
+type approvalRuleAttributes struct {
+	Threshold int64  `json:"threshold" valid:"-"`
+	Role      string `json:"role,omitempty" valid:"-"`
+	TwoPerson bool   `json:"two-person,omitempty" valid:"-"`
+}
+
+// An approvalRuleStore is a store for the approval-rule resource type. It
+// adapts a domain.ApprovalRuleRepository to a json api spec. resource. Only
+// a principal that identify authenticates and that holds AdministratorRole
+// may create or delete a rule.
+type approvalRuleStore struct {
+	repository domain.ApprovalRuleRepository
+	funds      *fundStore
+	identify   func(r *http.Request) (domain.Principal, bool)
+}
+
+func (a *approvalRuleStore) Save(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if a.repository == nil {
+		return nil, jsh.ISE("approvalRuleStore requires an ApprovalRuleRepository")
+	}
+
+	jsherr := authorize(ctx, a.identify, requestFromContext(ctx), AdministratorRole)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	var attributes approvalRuleAttributes
+	jsherrs := object.Unmarshal(approvalRuleResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	fundID, jsherr := parseToOneRelationship(object, approvalRuleFundRelationship, fundResourceType)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	rule, err := a.repository.Create(ctx, fundID, attributes.Threshold, attributes.Role, attributes.TwoPerson)
+	if notfound, ok := err.(*domain.NotFoundError); ok && notfound.Entity == fundResourceType {
+		return nil, newRelationshipError(StatusUnprocessableEntity, "Invalid Relationship",
+			err.Error(), approvalRuleFundRelationship)
+	}
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createApprovalRuleObjectWithETag(ctx, rule)
+}
+
+func (a *approvalRuleStore) Get(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if a.repository == nil {
+		return nil, jsh.ISE("approvalRuleStore requires an ApprovalRuleRepository")
+	}
+
+	ruleID, jsherr := parseID(approvalRuleResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	rule, err := a.repository.Get(ctx, ruleID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createApprovalRuleObjectWithETag(ctx, rule)
+}
+
+func (a *approvalRuleStore) List(ctx context.Context) (jsh.List, jsh.ErrorType) {
+	if a.repository == nil {
+		return nil, jsh.ISE("approvalRuleStore requires an ApprovalRuleRepository")
+	}
+
+	rules, err := a.repository.GetAll(ctx)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	list := make(jsh.List, 0)
+	for _, rule := range rules {
+		obj, err := createApprovalRuleObject(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, obj)
+	}
+
+	return list, nil
+}
+
+func (a *approvalRuleStore) Delete(ctx context.Context, id string) jsh.ErrorType {
+	if a.repository == nil {
+		return jsh.ISE("approvalRuleStore requires an ApprovalRuleRepository")
+	}
+
+	jsherr := authorize(ctx, a.identify, requestFromContext(ctx), AdministratorRole)
+	if jsherr != nil {
+		return jsherr
+	}
+
+	ruleID, jsherr := parseID(approvalRuleResourceType, id)
+	if jsherr != nil {
+		return jsherr
+	}
+
+	version, anyversion, jsherr := ifMatchVersion(ctx)
+	if jsherr != nil {
+		return jsherr
+	}
+
+	rule, err := a.repository.Get(ctx, ruleID)
+	if err != nil {
+		return newJshError(ctx, err)
+	}
+
+	if !anyversion && rule.Version() != version {
+		return newJshError(ctx, &domain.ConcurrencyError{Entity: approvalRuleResourceType, Id: id})
+	}
+
+	err = a.repository.Delete(ctx, ruleID, rule.Version())
+	if err != nil {
+		return newJshError(ctx, err)
+	}
+
+	return nil
+}
+
+// GetFund gets the fund that the approval rule with the given id applies
+// to.
+func (a *approvalRuleStore) GetFund(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if a.repository == nil || a.funds == nil {
+		return nil, jsh.ISE("approvalRuleStore requires an ApprovalRuleRepository and a fundStore")
+	}
+
+	ruleID, jsherr := parseID(approvalRuleResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	rule, err := a.repository.Get(ctx, ruleID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return a.funds.getFundObject(ctx, rule.FundId())
+}
+
+// createApprovalRuleObjectWithETag creates the object for an approval rule
+// and sets the ETag for the rule's version on the response.
+func createApprovalRuleObjectWithETag(ctx context.Context, rule domain.ApprovalRule) (*jsh.Object, jsh.ErrorType) {
+	obj, jsherr := createApprovalRuleObject(rule)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	setETag(ctx, rule.Version())
+	return obj, nil
+}
+
+func createApprovalRuleObject(rule domain.ApprovalRule) (*jsh.Object, *jsh.Error) {
+	id := strconv.FormatUint(uint64(rule.Id()), 10)
+
+	obj, err := jsh.NewObject(id, approvalRuleResourceType, approvalRuleAttributes{
+		Threshold: rule.Threshold(),
+		Role:      rule.Role(),
+		TwoPerson: rule.IsTwoPerson(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	obj.Relationships = map[string]*jsh.Relationship{
+		approvalRuleFundRelationship: newToOneRelationship(approvalRuleResourceType, id,
+			approvalRuleFundRelationship, fundResourceType, rule.FundId()),
+	}
+
+	return obj, nil
+}
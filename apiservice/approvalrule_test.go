@@ -0,0 +1,78 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+// fakeApprovalRuleRepository is an ApprovalRuleRepository that only
+// records whether a rule was created or deleted.
+type fakeApprovalRuleRepository struct {
+	created bool
+	deleted bool
+}
+
+func (f *fakeApprovalRuleRepository) GetAll(ctx context.Context) ([]domain.ApprovalRule, error) {
+	return nil, nil
+}
+
+func (f *fakeApprovalRuleRepository) GetByFund(ctx context.Context, fundId uint) ([]domain.ApprovalRule, error) {
+	return nil, nil
+}
+
+func (f *fakeApprovalRuleRepository) Get(ctx context.Context, id uint) (domain.ApprovalRule, error) {
+	return nil, &domain.NotFoundError{Entity: approvalRuleResourceType, Id: "1"}
+}
+
+func (f *fakeApprovalRuleRepository) Create(ctx context.Context, fundId uint, threshold int64, role string,
+	twoPerson bool) (domain.ApprovalRule, error) {
+	f.created = true
+	return nil, nil
+}
+
+func (f *fakeApprovalRuleRepository) Delete(ctx context.Context, id uint, version uint) error {
+	f.deleted = true
+	return nil
+}
+
+// identifyAs gives an identify function that authenticates every request
+// as principal.
+func identifyAs(principal domain.Principal) func(r *http.Request) (domain.Principal, bool) {
+	return func(r *http.Request) (domain.Principal, bool) {
+		return principal, true
+	}
+}
+
+func TestApprovalRuleStoreSaveWithoutAdministratorIsForbidden(t *testing.T) {
+	repository := &fakeApprovalRuleRepository{}
+	ctx, _ := newRequestContext(t, nil)
+
+	sut := &approvalRuleStore{repository, nil, identifyAs(domain.Principal{Name: "clerk"})}
+	_, jsherr := sut.Save(ctx, newFundObject(t, "", nil))
+
+	if assert.NotNil(t, jsherr, "Save() did not fail") {
+		assert.Equal(t, http.StatusForbidden, jsherr.StatusCode(), "Unexpected status code")
+	}
+	assert.False(t, repository.created, "The rule was created.")
+}
+
+func TestApprovalRuleStoreDeleteWithoutAuthenticationIsUnauthorized(t *testing.T) {
+	repository := &fakeApprovalRuleRepository{}
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"1"`})
+
+	sut := &approvalRuleStore{repository, nil, nil}
+	jsherr := sut.Delete(ctx, "1")
+
+	if assert.NotNil(t, jsherr, "Delete() did not fail") {
+		assert.Equal(t, http.StatusUnauthorized, jsherr.StatusCode(), "Unexpected status code")
+	}
+	assert.False(t, repository.deleted, "The rule was deleted.")
+}
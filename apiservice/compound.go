@@ -0,0 +1,376 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"goji.io"
+	"golang.org/x/net/context"
+)
+
+const (
+	includeParameter     = "include"
+	fieldsParameterStart = "fields["
+	fieldsParameterEnd   = "]"
+)
+
+// An objectGetter gets the object for the resource with the given id. The
+// Get method of each store is an objectGetter.
+type objectGetter func(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType)
+
+// compoundHandler is goji middleware that turns the response to a GET
+// request into a compound document. The resources named by the include
+// query parameter are added to the included member of the document and
+// the fields[type] query parameters restrict the attributes and
+// relationships of the resources to the named fields.
+type compoundHandler struct {
+	next          goji.Handler
+	relationships relationshipMap
+	getters       map[string]objectGetter
+}
+
+func newCompoundMiddleware(relationships relationshipMap, getters map[string]objectGetter) func(goji.Handler) goji.Handler {
+	return func(next goji.Handler) goji.Handler {
+		return &compoundHandler{next, relationships, getters}
+	}
+}
+
+// resourceObject is the form of a resource object in a response that the
+// compoundHandler works with. The members that it doesn't change are kept
+// as they are.
+type resourceObject struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id"`
+	Attributes    map[string]json.RawMessage     `json:"attributes,omitempty"`
+	Relationships map[string]*relationshipObject `json:"relationships,omitempty"`
+	Links         json.RawMessage                `json:"links,omitempty"`
+	Meta          json.RawMessage                `json:"meta,omitempty"`
+}
+
+type relationshipObject struct {
+	Links json.RawMessage `json:"links,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Meta  json.RawMessage `json:"meta,omitempty"`
+}
+
+type resourceIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	LID  string `json:"lid,omitempty"`
+}
+
+// identifiers gives the resource identifiers that the relationship links
+// to. The data of a relationship may be null, a single identifier or a
+// list of identifiers.
+func (r *relationshipObject) identifiers() ([]resourceIdentifier, error) {
+	data := bytes.TrimSpace(r.Data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+
+	if data[0] == '[' {
+		var identifiers []resourceIdentifier
+		err := json.Unmarshal(data, &identifiers)
+		return identifiers, err
+	}
+
+	var identifier resourceIdentifier
+	err := json.Unmarshal(data, &identifier)
+	return []resourceIdentifier{identifier}, err
+}
+
+// bufferedResponse holds a response until the compoundHandler is done with
+// it. The headers are those of the underlying response writer.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
+
+func (h *compoundHandler) ServeHTTPC(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fields := parseFieldsets(query)
+	if r.Method != http.MethodGet || (query.Get(includeParameter) == "" && len(fields) == 0) {
+		h.next.ServeHTTPC(ctx, w, r)
+		return
+	}
+
+	buffer := &bufferedResponse{header: w.Header(), status: http.StatusOK}
+	h.next.ServeHTTPC(ctx, buffer, r)
+
+	body, jsherr := h.compound(ctx, r, buffer, fields)
+	if jsherr != nil {
+		w.Header().Del("Content-Length")
+		w.Header().Del(etagHeader)
+		sendError(w, r, jsherr)
+		return
+	}
+
+	w.Header().Del("Content-Length")
+	w.WriteHeader(buffer.status)
+	w.Write(body)
+}
+
+// compound gives the body of the compound document for the buffered
+// response. Responses other than successful ones are left as they are.
+func (h *compoundHandler) compound(ctx context.Context, r *http.Request,
+	buffer *bufferedResponse, fields map[string][]string) ([]byte, jsh.ErrorType) {
+	if buffer.status != http.StatusOK {
+		return buffer.body.Bytes(), nil
+	}
+
+	var document map[string]json.RawMessage
+	err := json.Unmarshal(buffer.body.Bytes(), &document)
+	if err != nil {
+		return nil, jsh.ISE(err.Error())
+	}
+
+	primary, isList, err := parsePrimaryData(document["data"])
+	if err != nil {
+		return nil, jsh.ISE(err.Error())
+	}
+
+	included, jsherr := h.include(ctx, h.primaryType(r.URL.Path), primary,
+		parseIncludePaths(r.URL.Query().Get(includeParameter)))
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	for _, object := range append(primary, included...) {
+		applyFieldset(object, fields)
+	}
+
+	if isList {
+		document["data"], err = json.Marshal(primary)
+	} else if len(primary) == 1 {
+		document["data"], err = json.Marshal(primary[0])
+	}
+	if err != nil {
+		return nil, jsh.ISE(err.Error())
+	}
+
+	if len(included) > 0 {
+		document["included"], err = json.Marshal(included)
+		if err != nil {
+			return nil, jsh.ISE(err.Error())
+		}
+	}
+
+	body, err := json.Marshal(document)
+	if err != nil {
+		return nil, jsh.ISE(err.Error())
+	}
+
+	return body, nil
+}
+
+// include gets the resources named by the include paths for the primary
+// resources of primaryType. Each resource is only included once and
+// resources that are in the primary data are not included.
+func (h *compoundHandler) include(ctx context.Context, primaryType string,
+	primary []*resourceObject, paths []string) ([]*resourceObject, jsh.ErrorType) {
+	// Included resources are not the subject of the request so they must
+	// not set response headers such as the ETag.
+	ctx = context.WithValue(ctx, responseContextKey, nil)
+
+	found := make(map[resourceIdentifier]*resourceObject)
+	for _, object := range primary {
+		found[resourceIdentifier{Type: object.Type, ID: object.ID}] = object
+	}
+
+	var included []*resourceObject
+	for _, relationshipPath := range paths {
+		current := primary
+		currentType := primaryType
+
+		for _, name := range strings.Split(relationshipPath, ".") {
+			relatedType, ok := h.relationships.relatedType(currentType, name)
+			if !ok {
+				return nil, badRelationshipPath(relationshipPath)
+			}
+
+			var next []*resourceObject
+			for _, object := range current {
+				relationship := object.Relationships[name]
+				if relationship == nil {
+					continue
+				}
+
+				identifiers, err := relationship.identifiers()
+				if err != nil {
+					return nil, jsh.ISE(err.Error())
+				}
+
+				for _, identifier := range identifiers {
+					related, ok := found[identifier]
+					if !ok {
+						var jsherr jsh.ErrorType
+						related, jsherr = h.get(ctx, identifier)
+						if jsherr != nil {
+							return nil, jsherr
+						}
+
+						found[identifier] = related
+						included = append(included, related)
+					}
+
+					next = append(next, related)
+				}
+			}
+
+			current = next
+			currentType = relatedType
+		}
+	}
+
+	return included, nil
+}
+
+// get gets the resource object for the resource with the given identifier.
+func (h *compoundHandler) get(ctx context.Context, identifier resourceIdentifier) (*resourceObject, jsh.ErrorType) {
+	getter, ok := h.getters[identifier.Type]
+	if !ok {
+		return nil, jsh.ISE("no store for resource type " + identifier.Type)
+	}
+
+	object, jsherr := getter(ctx, identifier.ID)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, jsh.ISE(err.Error())
+	}
+
+	var related resourceObject
+	err = json.Unmarshal(data, &related)
+	if err != nil {
+		return nil, jsh.ISE(err.Error())
+	}
+
+	return &related, nil
+}
+
+// primaryType gives the type of the primary resources of a response to a
+// request for the given path. For a related resource path such as
+// /v1/fund/1/accounts it is the type of the related resources.
+func (h *compoundHandler) primaryType(requestPath string) string {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(requestPath, apiV1Prefix), "/"), "/")
+	if len(segments) == 3 {
+		relatedType, _ := h.relationships.relatedType(segments[0], segments[2])
+		return relatedType
+	}
+
+	return segments[0]
+}
+
+// parsePrimaryData parses the data member of a document which is either a
+// single resource object or a list of them.
+func parsePrimaryData(data json.RawMessage) ([]*resourceObject, bool, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, false, nil
+	}
+
+	if data[0] == '[' {
+		var objects []*resourceObject
+		err := json.Unmarshal(data, &objects)
+		return objects, true, err
+	}
+
+	var object resourceObject
+	err := json.Unmarshal(data, &object)
+	return []*resourceObject{&object}, false, err
+}
+
+// parseIncludePaths parses the comma separated relationship paths of the
+// include query parameter.
+func parseIncludePaths(include string) []string {
+	var paths []string
+	for _, relationshipPath := range strings.Split(include, ",") {
+		relationshipPath = strings.TrimSpace(relationshipPath)
+		if relationshipPath != "" {
+			paths = append(paths, relationshipPath)
+		}
+	}
+
+	return paths
+}
+
+// parseFieldsets parses the fields[type] query parameters into the names of
+// the fields to keep for each type.
+func parseFieldsets(query map[string][]string) map[string][]string {
+	fields := make(map[string][]string)
+	for key, values := range query {
+		if !strings.HasPrefix(key, fieldsParameterStart) || !strings.HasSuffix(key, fieldsParameterEnd) {
+			continue
+		}
+
+		resourceType := strings.TrimSuffix(strings.TrimPrefix(key, fieldsParameterStart), fieldsParameterEnd)
+		names := []string{}
+		for _, value := range values {
+			names = append(names, parseIncludePaths(value)...)
+		}
+
+		fields[resourceType] = names
+	}
+
+	return fields
+}
+
+// applyFieldset removes the attributes and relationships of object that
+// are not in the fieldset for its type. An object whose type has no
+// fieldset is left as it is.
+func applyFieldset(object *resourceObject, fields map[string][]string) {
+	names, ok := fields[object.Type]
+	if !ok {
+		return
+	}
+
+	keep := make(map[string]bool)
+	for _, name := range names {
+		keep[name] = true
+	}
+
+	for name := range object.Attributes {
+		if !keep[name] {
+			delete(object.Attributes, name)
+		}
+	}
+
+	for name := range object.Relationships {
+		if !keep[name] {
+			delete(object.Relationships, name)
+		}
+	}
+}
+
+// sendError sends a JSON API error to the client.
+func sendError(w http.ResponseWriter, r *http.Request, jsherr jsh.ErrorType) {
+	if sendable, ok := jsherr.(jsh.Sendable); ok {
+		jsh.Send(w, r, sendable)
+		return
+	}
+
+	jsh.Send(w, r, newStatusError(jsherr.StatusCode(), http.StatusText(jsherr.StatusCode()), jsherr.Error()))
+}
@@ -0,0 +1,132 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type compoundDocument struct {
+	Data     json.RawMessage   `json:"data"`
+	Included []*resourceObject `json:"included"`
+}
+
+func serveCompound(t *testing.T, path string) (*httptest.ResponseRecorder, *fakeAccountRepository) {
+	funds, _, accounts := newFakeAccountStores()
+	fakestore := &fakeStore{fundRepository: funds.repository, accountRepository: accounts}
+	request, response := getRequestResponse(t, path)
+
+	sut := newApi(fakestore)
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	return response, accounts
+}
+
+func parseCompoundDocument(t *testing.T, response *httptest.ResponseRecorder) *compoundDocument {
+	var document compoundDocument
+	err := json.Unmarshal(response.Body.Bytes(), &document)
+	require.Nil(t, err, "Unable to parse the compound document.")
+	return &document
+}
+
+func TestGetFundWithIncludeAccountsIncludesAccounts(t *testing.T) {
+	assert := assert.New(t)
+
+	response, _ := serveCompound(t, "/v1/fund/1?include=accounts")
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	document := parseCompoundDocument(t, response)
+	require.Len(t, document.Included, 2, "Unexpected number of included resources.")
+	for _, included := range document.Included {
+		assert.Equal("account", included.Type, "Unexpected type of included resource.")
+	}
+	assert.Equal(`"1"`, response.Header().Get("ETag"), "Unexpected ETag for compound document.")
+}
+
+func TestGetAccountWithIncludeFundIncludesFund(t *testing.T) {
+	response, _ := serveCompound(t, "/v1/account/2?include=fund")
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	document := parseCompoundDocument(t, response)
+	require.Len(t, document.Included, 1, "Unexpected number of included resources.")
+	assert.Equal(t, "fund", document.Included[0].Type, "Unexpected type of included resource.")
+	assert.Equal(t, "1", document.Included[0].ID, "Unexpected included fund.")
+}
+
+func TestListAccountsWithIncludeFundIncludesFundOnce(t *testing.T) {
+	response, _ := serveCompound(t, "/v1/account?include=fund")
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	document := parseCompoundDocument(t, response)
+	assert.Len(t, document.Included, 1, "Unexpected number of included resources.")
+}
+
+func TestIncludeDoesNotRepeatPrimaryData(t *testing.T) {
+	response, _ := serveCompound(t, "/v1/account/1?include=fund.accounts")
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	document := parseCompoundDocument(t, response)
+	require.Len(t, document.Included, 2, "Unexpected number of included resources.")
+	for _, included := range document.Included {
+		assert.False(t, included.Type == "account" && included.ID == "1",
+			"The primary resource was also included.")
+	}
+}
+
+func TestIncludeWithUnknownRelationshipIsBadRequest(t *testing.T) {
+	response, _ := serveCompound(t, "/v1/fund/1?include=donors")
+
+	assert.Equal(t, http.StatusBadRequest, response.Code, "Unexpected status code.")
+}
+
+func TestSparseFieldsetRestrictsFields(t *testing.T) {
+	assert := assert.New(t)
+
+	response, _ := serveCompound(t, "/v1/fund/1?include=accounts&"+
+		url.QueryEscape("fields[fund]")+"=name&"+url.QueryEscape("fields[account]")+"=type")
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	document := parseCompoundDocument(t, response)
+	var fund resourceObject
+	require.Nil(t, json.Unmarshal(document.Data, &fund), "Unable to parse the primary data.")
+	assert.Contains(fund.Attributes, "name", "Fieldset removed a requested attribute.")
+	assert.NotContains(fund.Attributes, "currency", "Fieldset kept an unrequested attribute.")
+	assert.Empty(fund.Relationships, "Fieldset kept an unrequested relationship.")
+	for _, included := range document.Included {
+		assert.Contains(included.Attributes, "type", "Fieldset removed a requested attribute.")
+		assert.NotContains(included.Attributes, "name", "Fieldset kept an unrequested attribute.")
+	}
+}
+
+func TestGetFundAccountsListsRelatedAccounts(t *testing.T) {
+	response, _ := serveCompound(t, "/v1/fund/1/accounts")
+
+	assert.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+}
+
+func TestPrimaryTypeOfRelatedResourcePathIsRelatedType(t *testing.T) {
+	sut := compoundHandler{relationships: resourceRelationships}
+
+	assert.Equal(t, "fund", sut.primaryType("/v1/fund"), "Unexpected type for a list.")
+	assert.Equal(t, "fund", sut.primaryType("/v1/fund/1"), "Unexpected type for a resource.")
+	assert.Equal(t, "account", sut.primaryType("/v1/fund/1/accounts"),
+		"Unexpected type for related resources.")
+}
+
+func TestParseFieldsetsSplitsFieldNames(t *testing.T) {
+	query := url.Values{"fields[fund]": {"name,currency"}, "include": {"accounts"}}
+
+	sut := parseFieldsets(query)
+
+	assert.Equal(t, map[string][]string{"fund": {"name", "currency"}}, sut, "Unexpected fieldsets.")
+}
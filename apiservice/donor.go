@@ -0,0 +1,353 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/jsh-api"
+	"github.com/sbosnick1/openacct/domain"
+	"goji.io"
+	"goji.io/pat"
+	"golang.org/x/net/context"
+)
+
+const (
+	donorResourceType = "donor"
+
+	givingPath = "/giving"
+)
+
+// newDonorResource creates the resource for donors. Donors can't be
+// deleted, because their gifts refer to them, so there is no DELETE route.
+func newDonorResource(store *donorStore) *jshapi.Resource {
+	resource := jshapi.NewResource(donorResourceType)
+	resource.Post(store.Save)
+	resource.Get(store.Get)
+	resource.List(store.List)
+	resource.Patch(store.Update)
+	return resource
+}
+
+// donorAttributes are the attributes of a donor. Each address is a
+// complete postal address with its lines separated by newlines.
+type donorAttributes struct {
+	Name      string   `json:"name,omitempty" valid:"required"`
+	Addresses []string `json:"addresses,omitempty"`
+	Email     string   `json:"email,omitempty" valid:"email"`
+	TaxID     string   `json:"tax-id,omitempty"`
+}
+
+// donorPatchAttributes are the attributes of a donor that may be changed
+// by an update. Attributes that are not given keep their current value and
+// an empty email or tax-id removes it.
+type donorPatchAttributes struct {
+	Name      string    `json:"name,omitempty"`
+	Addresses *[]string `json:"addresses,omitempty"`
+	Email     *string   `json:"email,omitempty"`
+	TaxID     *string   `json:"tax-id,omitempty"`
+}
+
+// giftAttributes are one gift in the gifts member of the meta of the
+// response to a request for giving history. Display is the amount
+// formatted for the locale of the request.
+type giftAttributes struct {
+	Entry   string `json:"entry"`
+	Fund    string `json:"fund"`
+	Donor   string `json:"donor"`
+	Date    string `json:"date"`
+	Amount  int64  `json:"amount"`
+	Display string `json:"display"`
+}
+
+// givingTotalAttributes are one total in the totals member of the meta of
+// the response to a request for giving history. Display is the amount
+// formatted for the locale of the request.
+type givingTotalAttributes struct {
+	Fund    string `json:"fund"`
+	Donor   string `json:"donor"`
+	Year    int    `json:"year"`
+	Amount  int64  `json:"amount"`
+	Display string `json:"display"`
+}
+
+// givingDocument is the response to a request for the giving history of a
+// donor or of a fund.
+type givingDocument struct {
+	Links struct {
+		Self string `json:"self"`
+	} `json:"links"`
+	Meta struct {
+		Gifts  []giftAttributes        `json:"gifts"`
+		Totals []givingTotalAttributes `json:"totals"`
+	} `json:"meta"`
+}
+
+// A donorStore is a store for the donor resource type. It adapts a
+// domain.DonorRepository to a json api spec. resource. The currencies of
+// the amounts of giving history are those of the funds of funds.
+type donorStore struct {
+	repository domain.DonorRepository
+	funds      *fundStore
+}
+
+func (d *donorStore) Save(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if d.repository == nil {
+		return nil, jsh.ISE("donorStore requires a DonorRepository")
+	}
+
+	var attributes donorAttributes
+	jsherrs := object.Unmarshal(donorResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	donor, err := d.repository.Create(ctx, attributes.Name, attributes.Addresses, attributes.Email,
+		attributes.TaxID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createDonorObjectWithETag(ctx, donor)
+}
+
+func (d *donorStore) Get(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if d.repository == nil {
+		return nil, jsh.ISE("donorStore requires a DonorRepository")
+	}
+
+	donorID, jsherr := parseID(donorResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	donor, err := d.repository.Get(ctx, donorID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createDonorObjectWithETag(ctx, donor)
+}
+
+func (d *donorStore) List(ctx context.Context) (jsh.List, jsh.ErrorType) {
+	if d.repository == nil {
+		return nil, jsh.ISE("donorStore requires a DonorRepository")
+	}
+
+	donors, err := d.repository.GetAll(ctx)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	list := make(jsh.List, 0)
+	for _, donor := range donors {
+		obj, err := createDonorObject(donor)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, obj)
+	}
+
+	return list, nil
+}
+
+func (d *donorStore) Update(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if d.repository == nil {
+		return nil, jsh.ISE("donorStore requires a DonorRepository")
+	}
+
+	var attributes donorPatchAttributes
+	jsherrs := object.Unmarshal(donorResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	donorID, jsherr := parseID(donorResourceType, object.ID)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	version, anyversion, jsherr := ifMatchVersion(ctx)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	donor, err := d.repository.Get(ctx, donorID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	if !anyversion && donor.Version() != version {
+		return nil, newJshError(ctx, &domain.ConcurrencyError{Entity: donorResourceType, Id: object.ID})
+	}
+
+	name := donor.Name()
+	if attributes.Name != "" {
+		name = attributes.Name
+	}
+	addresses := donor.Addresses()
+	if attributes.Addresses != nil {
+		addresses = *attributes.Addresses
+	}
+	email := donor.Email()
+	if attributes.Email != nil {
+		email = *attributes.Email
+	}
+	taxID := donor.TaxId()
+	if attributes.TaxID != nil {
+		taxID = *attributes.TaxID
+	}
+
+	updated, err := d.repository.Update(ctx, donorID, donor.Version(), name, addresses, email, taxID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createDonorObjectWithETag(ctx, updated)
+}
+
+// GetDonorGiving serves the giving history of the donor whose id is in the
+// path of the request, as the gifts and totals members of the meta of the
+// response.
+func (d *donorStore) GetDonorGiving(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	d.serveGiving(ctx, w, r, donorResourceType, domain.DonorRepository.GiftsByDonor,
+		domain.DonorRepository.TotalsByDonor)
+}
+
+// GetFundGiving serves the giving history of the fund whose id is in the
+// path of the request, as the gifts and totals members of the meta of the
+// response.
+func (d *donorStore) GetFundGiving(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	d.serveGiving(ctx, w, r, fundResourceType, domain.DonorRepository.GiftsByFund,
+		domain.DonorRepository.TotalsByFund)
+}
+
+// serveGiving serves the giving history from gifts and totals of the
+// resource of resourceType whose id is in the path of the request.
+func (d *donorStore) serveGiving(ctx context.Context, w http.ResponseWriter, r *http.Request, resourceType string,
+	gifts func(domain.DonorRepository, context.Context, uint) ([]domain.Gift, error),
+	totals func(domain.DonorRepository, context.Context, uint) ([]domain.GivingTotal, error)) {
+	if d.repository == nil || d.funds == nil || d.funds.repository == nil {
+		jsh.Send(w, r, jsh.ISE("donorStore requires a DonorRepository and a fundStore"))
+		return
+	}
+
+	id := pat.Param(ctx, "id")
+	resourceID, jsherr := parseID(resourceType, id)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	history, err := gifts(d.repository, ctx, resourceID)
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	sums, err := totals(d.repository, ctx, resourceID)
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	// the currencies of the funds are looked up once each
+	currencies := make(map[uint]domain.Currency)
+	currency := func(fundID uint) (domain.Currency, error) {
+		if found, ok := currencies[fundID]; ok {
+			return found, nil
+		}
+
+		fund, err := d.funds.repository.Get(ctx, fundID)
+		if err != nil {
+			return domain.XXX, err
+		}
+		currencies[fundID] = fund.Currency()
+		return fund.Currency(), nil
+	}
+
+	locale := negotiateLocale(w, r)
+	var document givingDocument
+	document.Links.Self = path.Join(apiV1Prefix, resourceType, id, givingPath)
+	document.Meta.Gifts = make([]giftAttributes, 0, len(history))
+	for _, gift := range history {
+		giftCurrency, err := currency(gift.FundId)
+		if err != nil {
+			sendError(w, r, newJshError(ctx, err))
+			return
+		}
+
+		document.Meta.Gifts = append(document.Meta.Gifts, giftAttributes{
+			Entry:   strconv.FormatUint(uint64(gift.EntryId), 10),
+			Fund:    strconv.FormatUint(uint64(gift.FundId), 10),
+			Donor:   strconv.FormatUint(uint64(gift.DonorId), 10),
+			Date:    formatDate(gift.Date),
+			Amount:  gift.Amount,
+			Display: locale.Format(gift.Amount, giftCurrency),
+		})
+	}
+	document.Meta.Totals = make([]givingTotalAttributes, 0, len(sums))
+	for _, total := range sums {
+		totalCurrency, err := currency(total.FundId)
+		if err != nil {
+			sendError(w, r, newJshError(ctx, err))
+			return
+		}
+
+		document.Meta.Totals = append(document.Meta.Totals, givingTotalAttributes{
+			Fund:    strconv.FormatUint(uint64(total.FundId), 10),
+			Donor:   strconv.FormatUint(uint64(total.DonorId), 10),
+			Year:    total.Year,
+			Amount:  total.Amount,
+			Display: locale.Format(total.Amount, totalCurrency),
+		})
+	}
+
+	body, err := json.Marshal(document)
+	if err != nil {
+		jsh.Send(w, r, jsh.ISE(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", jsh.ContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// handleGiving registers the routes for the giving history of donors and
+// funds with api. They must be registered before the donor and fund
+// resources, which handle every path under their own.
+func handleGiving(api *jshapi.API, donors *donorStore) {
+	api.HandleC(pat.Get(apiV1Prefix+"/"+donorResourceType+"/:id"+givingPath), goji.HandlerFunc(donors.GetDonorGiving))
+	api.HandleC(pat.Get(apiV1Prefix+"/"+fundResourceType+"/:id"+givingPath), goji.HandlerFunc(donors.GetFundGiving))
+}
+
+// createDonorObjectWithETag creates the object for a donor and sets the
+// ETag for the donor's version on the response.
+func createDonorObjectWithETag(ctx context.Context, donor domain.Donor) (*jsh.Object, jsh.ErrorType) {
+	obj, jsherr := createDonorObject(donor)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	setETag(ctx, donor.Version())
+	return obj, nil
+}
+
+func createDonorObject(donor domain.Donor) (*jsh.Object, *jsh.Error) {
+	id := strconv.FormatUint(uint64(donor.Id()), 10)
+
+	return jsh.NewObject(id, donorResourceType, donorAttributes{
+		Name:      donor.Name(),
+		Addresses: donor.Addresses(),
+		Email:     donor.Email(),
+		TaxID:     donor.TaxId(),
+	})
+}
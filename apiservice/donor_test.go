@@ -0,0 +1,246 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type fakeDonor struct {
+	id        uint
+	name      string
+	addresses []string
+	email     string
+	taxId     string
+	version   uint
+}
+
+func (f *fakeDonor) Id() uint {
+	return f.id
+}
+
+func (f *fakeDonor) Name() string {
+	return f.name
+}
+
+func (f *fakeDonor) Addresses() []string {
+	return f.addresses
+}
+
+func (f *fakeDonor) Email() string {
+	return f.email
+}
+
+func (f *fakeDonor) TaxId() string {
+	return f.taxId
+}
+
+func (f *fakeDonor) Version() uint {
+	return f.version
+}
+
+type fakeDonorRepository struct {
+	donors []*fakeDonor
+	gifts  []domain.Gift
+}
+
+func (f *fakeDonorRepository) GetAll(ctx context.Context) ([]domain.Donor, error) {
+	var donors []domain.Donor
+	for _, donor := range f.donors {
+		donors = append(donors, donor)
+	}
+	return donors, nil
+}
+
+func (f *fakeDonorRepository) Get(ctx context.Context, id uint) (domain.Donor, error) {
+	for _, donor := range f.donors {
+		if donor.id == id {
+			return donor, nil
+		}
+	}
+	return nil, &domain.NotFoundError{Entity: "donor", Id: strconv.FormatUint(uint64(id), 10)}
+}
+
+func (f *fakeDonorRepository) Create(ctx context.Context, name string, addresses []string, email string,
+	taxId string) (domain.Donor, error) {
+	donor := &fakeDonor{uint(len(f.donors) + 1), name, addresses, email, taxId, 1}
+	f.donors = append(f.donors, donor)
+	return donor, nil
+}
+
+func (f *fakeDonorRepository) Update(ctx context.Context, id uint, version uint, name string, addresses []string,
+	email string, taxId string) (domain.Donor, error) {
+	found, err := f.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	donor := found.(*fakeDonor)
+	if donor.version != version {
+		return nil, &domain.ConcurrencyError{Entity: "donor", Id: strconv.FormatUint(uint64(id), 10)}
+	}
+
+	donor.name, donor.addresses, donor.email, donor.taxId = name, addresses, email, taxId
+	donor.version++
+	return donor, nil
+}
+
+func (f *fakeDonorRepository) GiftsByDonor(ctx context.Context, donorId uint) ([]domain.Gift, error) {
+	if _, err := f.Get(ctx, donorId); err != nil {
+		return nil, err
+	}
+
+	var gifts []domain.Gift
+	for _, gift := range f.gifts {
+		if gift.DonorId == donorId {
+			gifts = append(gifts, gift)
+		}
+	}
+	return gifts, nil
+}
+
+func (f *fakeDonorRepository) GiftsByFund(ctx context.Context, fundId uint) ([]domain.Gift, error) {
+	var gifts []domain.Gift
+	for _, gift := range f.gifts {
+		if gift.FundId == fundId {
+			gifts = append(gifts, gift)
+		}
+	}
+	return gifts, nil
+}
+
+func (f *fakeDonorRepository) TotalsByDonor(ctx context.Context, donorId uint) ([]domain.GivingTotal, error) {
+	gifts, err := f.GiftsByDonor(ctx, donorId)
+	if err != nil {
+		return nil, err
+	}
+	return totalGifts(gifts), nil
+}
+
+func (f *fakeDonorRepository) TotalsByFund(ctx context.Context, fundId uint) ([]domain.GivingTotal, error) {
+	gifts, err := f.GiftsByFund(ctx, fundId)
+	if err != nil {
+		return nil, err
+	}
+	return totalGifts(gifts), nil
+}
+
+// totalGifts totals gifts that are in the order of their years.
+func totalGifts(gifts []domain.Gift) []domain.GivingTotal {
+	var totals []domain.GivingTotal
+	for _, gift := range gifts {
+		last := len(totals) - 1
+		if last >= 0 && totals[last].DonorId == gift.DonorId && totals[last].Year == gift.Date.Year() {
+			totals[last].Amount += gift.Amount
+			continue
+		}
+		totals = append(totals, domain.GivingTotal{
+			DonorId: gift.DonorId,
+			FundId:  gift.FundId,
+			Year:    gift.Date.Year(),
+			Amount:  gift.Amount,
+		})
+	}
+	return totals
+}
+
+// newFakeDonorStore creates a donorStore backed by a fake repository with
+// one donor who gave to the first fund, whose currency is CAD, in each of
+// two years.
+func newFakeDonorStore() (*donorStore, *fakeDonorRepository) {
+	donors := &fakeDonorRepository{
+		donors: []*fakeDonor{{1, "Ada Lovelace", []string{"12 St James's Square\nLondon"},
+			"ada@example.org", "123-456-789", 1}},
+		gifts: []domain.Gift{
+			{EntryId: 1, FundId: 1, DonorId: 1, Date: time.Date(2025, time.December, 24, 0, 0, 0, 0, time.UTC), Amount: 5000},
+			{EntryId: 2, FundId: 1, DonorId: 1, Date: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), Amount: 7500},
+			{EntryId: 3, FundId: 1, DonorId: 1, Date: time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC), Amount: 2500},
+		},
+	}
+
+	funds := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}}})
+	return &donorStore{donors, &fundStore{funds, nil}}, donors
+}
+
+func TestDonorStoreSaveCreatesDonor(t *testing.T) {
+	sut, repository := newFakeDonorStore()
+	ctx, response := newRequestContext(t, nil)
+	obj, jsherr := jsh.NewObject("", "donor", map[string]interface{}{
+		"name":      "Alan Turing",
+		"addresses": []string{"Bletchley Park\nMilton Keynes"},
+		"tax-id":    "987-654-321",
+	})
+	require.Nil(t, jsherr, "Unable to create the donor object.")
+
+	result, err := sut.Save(ctx, obj)
+
+	require.Nil(t, err, "donorStore failed to save a donor")
+	assert.Equal(t, "2", result.ID, "Unexpected id for saved donor.")
+	assert.Equal(t, []string{"Bletchley Park\nMilton Keynes"}, repository.donors[1].addresses)
+	assert.Equal(t, "987-654-321", repository.donors[1].taxId)
+	assert.Equal(t, `"1"`, response.Header().Get("ETag"), "Unexpected ETag for saved donor.")
+}
+
+func TestDonorStoreUpdateKeepsAttributesNotGiven(t *testing.T) {
+	sut, repository := newFakeDonorStore()
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"1"`})
+	obj, jsherr := jsh.NewObject("1", "donor", map[string]interface{}{"name": "Ada King", "email": ""})
+	require.Nil(t, jsherr, "Unable to create the donor object.")
+
+	_, err := sut.Update(ctx, obj)
+
+	require.Nil(t, err, "donorStore failed to update a donor")
+	donor := repository.donors[0]
+	assert.Equal(t, "Ada King", donor.name, "The name was not changed.")
+	assert.Empty(t, donor.email, "The email was not removed.")
+	assert.Equal(t, "123-456-789", donor.taxId, "The tax id was not kept.")
+	assert.Len(t, donor.addresses, 1, "The addresses were not kept.")
+}
+
+func TestParsePostingsWithDonor(t *testing.T) {
+	actual, jsherr := parsePostings([]postingAttributes{{"1", 5000, ""}, {"4", -5000, "2"}})
+
+	require.Nil(t, jsherr, "Unable to parse the postings.")
+	assert.Equal(t, []domain.Posting{{AccountId: 1, Amount: 5000}, {AccountId: 4, Amount: -5000, DonorId: 2}},
+		actual)
+	assert.Equal(t, "2", formatPostings(actual)[1].Donor, "Unexpected donor of formatted posting.")
+}
+
+func TestNewApiServesDonorGiving(t *testing.T) {
+	store, repository := newFakeDonorStore()
+	request, response := getRequestResponse(t, "/v1/donor/1/giving")
+
+	sut := newApi(&fakeStore{fundRepository: store.funds.repository, donorRepository: repository})
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	var document givingDocument
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &document), "Unable to parse the response.")
+	assert.Len(t, document.Meta.Gifts, 3, "Unexpected number of gifts.")
+	assert.Equal(t, "2025-12-24", document.Meta.Gifts[0].Date, "Unexpected date of the first gift.")
+	assert.Equal(t, "CA$50.00", document.Meta.Gifts[0].Display, "Unexpected display of the first gift.")
+	assert.Equal(t, []givingTotalAttributes{{"1", "1", 2025, 5000, "CA$50.00"}, {"1", "1", 2026, 10000, "CA$100.00"}},
+		document.Meta.Totals)
+}
+
+func TestNewApiDonorGivingForMissingDonorIsNotFound(t *testing.T) {
+	store, repository := newFakeDonorStore()
+	request, response := getRequestResponse(t, "/v1/donor/2/giving")
+
+	sut := newApi(&fakeStore{fundRepository: store.funds.repository, donorRepository: repository})
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	assert.Equal(t, http.StatusNotFound, response.Code, "Unexpected status code.")
+}
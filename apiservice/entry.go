@@ -0,0 +1,618 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/jsh-api"
+	"github.com/sbosnick1/openacct/domain"
+	"goji.io"
+	"goji.io/pat"
+	"golang.org/x/net/context"
+)
+
+const (
+	entryResourceType = "entry"
+
+	entryFundRelationship        = "fund"
+	entryReversesRelationship    = "reverses"
+	entryReversedByRelationship  = "reversed-by"
+	entryCorrectsRelationship    = "corrects"
+	entryCorrectedByRelationship = "corrected-by"
+
+	submitPath   = "/submit"
+	approvePath  = "/approve"
+	rejectPath   = "/reject"
+	reversePath  = "/reverse"
+	correctPath  = "/correct"
+	balancesPath = "/balances"
+)
+
+// newEntryResource creates the resource for journal entries. Only entries
+// that have not been posted can be updated or deleted; a posted entry is
+// undone by the reverse and correct actions instead.
+func newEntryResource(store *entryStore) *jshapi.Resource {
+	resource := jshapi.NewResource(entryResourceType)
+	resource.Post(store.Save)
+	resource.Get(store.Get)
+	resource.List(store.List)
+	resource.Patch(store.Update)
+	resource.Delete(store.Delete)
+	resource.ToOne(entryFundRelationship, store.GetFund)
+	resource.ToOne(entryReversesRelationship, store.getLinked(domain.JournalEntry.ReversesId))
+	resource.ToOne(entryReversedByRelationship, store.getLinked(domain.JournalEntry.ReversedById))
+	resource.ToOne(entryCorrectsRelationship, store.getLinked(domain.JournalEntry.CorrectsId))
+	resource.ToOne(entryCorrectedByRelationship, store.getLinked(domain.JournalEntry.CorrectedById))
+	return resource
+}
+
+// entryAttributes are the attributes of a journal entry. The status,
+// submitted-by and reviewed-by attributes are ignored when creating an
+// entry; a new entry is always a draft.
+type entryAttributes struct {
+	Date        string              `json:"date,omitempty" valid:"required,date"`
+	Description string              `json:"description,omitempty" valid:"required"`
+	Postings    []postingAttributes `json:"postings,omitempty" valid:"required"`
+	Status      string              `json:"status,omitempty" valid:"-"`
+	SubmittedBy string              `json:"submitted-by,omitempty" valid:"-"`
+	ReviewedBy  string              `json:"reviewed-by,omitempty" valid:"-"`
+}
+
+// entryPatchAttributes are the attributes of an entry that may be changed
+// by an update. Attributes that are not given keep their current value.
+type entryPatchAttributes struct {
+	Date        string              `json:"date,omitempty" valid:"date"`
+	Description string              `json:"description,omitempty" valid:"-"`
+	Postings    []postingAttributes `json:"postings,omitempty" valid:"-"`
+}
+
+// reverseAttributes are the attributes of the entry in the request for the
+// reverse action, which are those of the reversal that it makes.
+type reverseAttributes struct {
+	Date string `json:"date,omitempty" valid:"required,date"`
+}
+
+// objectDocument is a JSON API document whose primary data is one object.
+type objectDocument struct {
+	Data *jsh.Object `json:"data"`
+}
+
+// balanceAttributes are the balance of one account in the balances member
+// of the meta of the response to a request for the balances of a fund.
+// Display is the balance formatted for the locale of the request.
+type balanceAttributes struct {
+	Account string `json:"account"`
+	Balance int64  `json:"balance"`
+	Display string `json:"display"`
+}
+
+// balancesDocument is the response to a request for the balances of the
+// accounts of a fund.
+type balancesDocument struct {
+	Links struct {
+		Self string `json:"self"`
+	} `json:"links"`
+	Meta struct {
+		Balances []balanceAttributes `json:"balances"`
+	} `json:"meta"`
+}
+
+// An entryStore is a store for the entry resource type. It adapts a
+// domain.JournalEntryRepository to a json api spec. resource. The
+// principal on whose behalf an entry is submitted, approved or rejected is
+// the one that identify authenticates.
+type entryStore struct {
+	repository domain.JournalEntryRepository
+	funds      *fundStore
+	identify   func(r *http.Request) (domain.Principal, bool)
+}
+
+func (e *entryStore) Save(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if e.repository == nil {
+		return nil, jsh.ISE("entryStore requires a JournalEntryRepository")
+	}
+
+	var attributes entryAttributes
+	jsherrs := object.Unmarshal(entryResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	fundID, jsherr := parseToOneRelationship(object, entryFundRelationship, fundResourceType)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	postings, jsherr := parsePostings(attributes.Postings)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	date, err := time.Parse(dateFormat, attributes.Date)
+	if err != nil {
+		// the validation on entryAttributes should have ensured
+		// this does not happen
+		return nil, jsh.InputError(err.Error(), "date")
+	}
+
+	entry, err := e.repository.Create(ctx, fundID, date, attributes.Description, postings)
+	if notfound, ok := err.(*domain.NotFoundError); ok && notfound.Entity == fundResourceType {
+		return nil, newRelationshipError(StatusUnprocessableEntity, "Invalid Relationship",
+			err.Error(), entryFundRelationship)
+	}
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createEntryObjectWithETag(ctx, entry)
+}
+
+func (e *entryStore) Get(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if e.repository == nil {
+		return nil, jsh.ISE("entryStore requires a JournalEntryRepository")
+	}
+
+	entryID, jsherr := parseID(entryResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	entry, err := e.repository.Get(ctx, entryID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createEntryObjectWithETag(ctx, entry)
+}
+
+func (e *entryStore) List(ctx context.Context) (jsh.List, jsh.ErrorType) {
+	if e.repository == nil {
+		return nil, jsh.ISE("entryStore requires a JournalEntryRepository")
+	}
+
+	entries, err := e.repository.GetAll(ctx)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	list := make(jsh.List, 0)
+	for _, entry := range entries {
+		obj, err := createEntryObject(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, obj)
+	}
+
+	return list, nil
+}
+
+func (e *entryStore) Update(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if e.repository == nil {
+		return nil, jsh.ISE("entryStore requires a JournalEntryRepository")
+	}
+
+	var attributes entryPatchAttributes
+	jsherrs := object.Unmarshal(entryResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	entry, jsherr := e.getForChange(ctx, object.ID)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	date := entry.Date()
+	if attributes.Date != "" {
+		parsed, err := time.Parse(dateFormat, attributes.Date)
+		if err != nil {
+			// the validation on entryPatchAttributes should have
+			// ensured this does not happen
+			return nil, jsh.InputError(err.Error(), "date")
+		}
+		date = parsed
+	}
+
+	description := entry.Description()
+	if attributes.Description != "" {
+		description = attributes.Description
+	}
+
+	postings := entry.Postings()
+	if attributes.Postings != nil {
+		postings, jsherr = parsePostings(attributes.Postings)
+		if jsherr != nil {
+			return nil, jsherr
+		}
+	}
+
+	updated, err := e.repository.Update(ctx, entry.Id(), entry.Version(), date, description, postings)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createEntryObjectWithETag(ctx, updated)
+}
+
+func (e *entryStore) Delete(ctx context.Context, id string) jsh.ErrorType {
+	if e.repository == nil {
+		return jsh.ISE("entryStore requires a JournalEntryRepository")
+	}
+
+	entry, jsherr := e.getForChange(ctx, id)
+	if jsherr != nil {
+		return jsherr
+	}
+
+	err := e.repository.Delete(ctx, entry.Id(), entry.Version())
+	if err != nil {
+		return newJshError(ctx, err)
+	}
+
+	return nil
+}
+
+// GetFund gets the fund that the entry with the given id is in.
+func (e *entryStore) GetFund(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if e.repository == nil || e.funds == nil {
+		return nil, jsh.ISE("entryStore requires a JournalEntryRepository and a fundStore")
+	}
+
+	entryID, jsherr := parseID(entryResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	entry, err := e.repository.Get(ctx, entryID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return e.funds.getFundObject(ctx, entry.FundId())
+}
+
+// getLinked gives a function that gets the entry that the entry with a
+// given id links to by the id given by link.
+func (e *entryStore) getLinked(link func(entry domain.JournalEntry) uint) func(ctx context.Context,
+	id string) (*jsh.Object, jsh.ErrorType) {
+	return func(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+		if e.repository == nil {
+			return nil, jsh.ISE("entryStore requires a JournalEntryRepository")
+		}
+
+		entryID, jsherr := parseID(entryResourceType, id)
+		if jsherr != nil {
+			return nil, jsherr
+		}
+
+		entry, err := e.repository.Get(ctx, entryID)
+		if err != nil {
+			return nil, newJshError(ctx, err)
+		}
+
+		linked := link(entry)
+		if linked == 0 {
+			return nil, newStatusError(http.StatusNotFound, "Not Found",
+				"The entry with id "+id+" has no such related entry.")
+		}
+
+		related, err := e.repository.Get(ctx, linked)
+		if err != nil {
+			return nil, newJshError(ctx, err)
+		}
+
+		return createEntryObject(related)
+	}
+}
+
+// Submit serves the submit action of an entry.
+func (e *entryStore) Submit(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	e.serveAction(ctx, w, r, http.StatusOK, e.workflow(domain.JournalEntryRepository.Submit))
+}
+
+// Approve serves the approve action of an entry.
+func (e *entryStore) Approve(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	e.serveAction(ctx, w, r, http.StatusOK, e.workflow(domain.JournalEntryRepository.Approve))
+}
+
+// Reject serves the reject action of an entry.
+func (e *entryStore) Reject(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	e.serveAction(ctx, w, r, http.StatusOK, e.workflow(domain.JournalEntryRepository.Reject))
+}
+
+// Reverse serves the reverse action of a posted entry. The request is a
+// document for an entry whose date attribute is the date of the reversal
+// and the response is the reversal.
+func (e *entryStore) Reverse(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	e.serveAction(ctx, w, r, http.StatusCreated, e.reverse)
+}
+
+// Correct serves the correct action of a posted entry. The request is a
+// document for the correction, which is made with the same date as the
+// reversal, and the response is the correction.
+func (e *entryStore) Correct(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	e.serveAction(ctx, w, r, http.StatusCreated, e.correct)
+}
+
+// An entryAction performs an action on entry on behalf of principal for a
+// request and gives the entry that is the result of the action.
+type entryAction func(ctx context.Context, r *http.Request, entry domain.JournalEntry,
+	principal domain.Principal) (domain.JournalEntry, jsh.ErrorType)
+
+// A workflowAction is one of the methods of a domain.JournalEntryRepository
+// that moves an entry through the approval workflow.
+type workflowAction func(repository domain.JournalEntryRepository, ctx context.Context, id uint, version uint,
+	principal domain.Principal) (domain.JournalEntry, error)
+
+// workflow gives the entryAction that performs action.
+func (e *entryStore) workflow(action workflowAction) entryAction {
+	return func(ctx context.Context, r *http.Request, entry domain.JournalEntry,
+		principal domain.Principal) (domain.JournalEntry, jsh.ErrorType) {
+		changed, err := action(e.repository, ctx, entry.Id(), entry.Version(), principal)
+		if err != nil {
+			return nil, newJshError(ctx, err)
+		}
+
+		return changed, nil
+	}
+}
+
+func (e *entryStore) reverse(ctx context.Context, r *http.Request, entry domain.JournalEntry,
+	principal domain.Principal) (domain.JournalEntry, jsh.ErrorType) {
+	object, jsherr := jsh.ParseObject(r)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	var attributes reverseAttributes
+	jsherrs := object.Unmarshal(entryResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	date, err := time.Parse(dateFormat, attributes.Date)
+	if err != nil {
+		// the validation on reverseAttributes should have ensured
+		// this does not happen
+		return nil, jsh.InputError(err.Error(), "date")
+	}
+
+	reversal, err := e.repository.Reverse(ctx, entry.Id(), entry.Version(), date, principal)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return reversal, nil
+}
+
+func (e *entryStore) correct(ctx context.Context, r *http.Request, entry domain.JournalEntry,
+	principal domain.Principal) (domain.JournalEntry, jsh.ErrorType) {
+	object, jsherr := jsh.ParseObject(r)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	var attributes entryAttributes
+	jsherrs := object.Unmarshal(entryResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	postings, jsherr := parsePostings(attributes.Postings)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	date, err := time.Parse(dateFormat, attributes.Date)
+	if err != nil {
+		// the validation on entryAttributes should have ensured
+		// this does not happen
+		return nil, jsh.InputError(err.Error(), "date")
+	}
+
+	correction, err := e.repository.Correct(ctx, entry.Id(), entry.Version(), date, attributes.Description,
+		postings, principal)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return correction, nil
+}
+
+// serveAction performs an action on the entry whose id is in the path of
+// the request on behalf of the principal that made the request. Like an
+// update, the request must have an If-Match header. The response has
+// status and is the entry that is the result of the action.
+func (e *entryStore) serveAction(ctx context.Context, w http.ResponseWriter, r *http.Request, status int,
+	action entryAction) {
+	if e.repository == nil {
+		jsh.Send(w, r, jsh.ISE("entryStore requires a JournalEntryRepository"))
+		return
+	}
+
+	principal, jsherr := authenticate(ctx, e.identify, r)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	entry, jsherr := e.getForChange(ctx, pat.Param(ctx, "id"))
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	result, jsherr := action(ctx, r, entry, principal)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	obj, jsherr := createEntryObjectWithETag(ctx, result)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	body, err := json.Marshal(objectDocument{obj})
+	if err != nil {
+		jsh.Send(w, r, jsh.ISE(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", jsh.ContentType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// GetBalances serves the balances of the accounts of the fund whose id is
+// in the path of the request, as the balances member of the meta of the
+// response. Only approved entries count toward the balances.
+func (e *entryStore) GetBalances(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if e.repository == nil || e.funds == nil || e.funds.accounts == nil {
+		jsh.Send(w, r, jsh.ISE("entryStore requires a JournalEntryRepository and a fundStore with accounts"))
+		return
+	}
+
+	id := pat.Param(ctx, "id")
+	fundID, jsherr := parseID(fundResourceType, id)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	fund, err := e.funds.repository.Get(ctx, fundID)
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	accounts, err := e.funds.accounts.GetByFund(ctx, fundID)
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	balances, err := e.repository.Balances(ctx, fundID)
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	locale := negotiateLocale(w, r)
+	var document balancesDocument
+	document.Links.Self = path.Join(apiV1Prefix, fundResourceType, id, balancesPath)
+	document.Meta.Balances = make([]balanceAttributes, 0, len(accounts))
+	for _, account := range accounts {
+		balance := balances[account.Id()]
+		document.Meta.Balances = append(document.Meta.Balances, balanceAttributes{
+			Account: strconv.FormatUint(uint64(account.Id()), 10),
+			Balance: balance,
+			Display: locale.Format(balance, fund.Currency()),
+		})
+	}
+
+	body, err := json.Marshal(document)
+	if err != nil {
+		jsh.Send(w, r, jsh.ISE(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", jsh.ContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// getForChange gets the entry with the given id as it must be for the
+// current request to change it. The version of the returned entry is the
+// version required by the If-Match header of the request.
+func (e *entryStore) getForChange(ctx context.Context, id string) (domain.JournalEntry, jsh.ErrorType) {
+	entryID, jsherr := parseID(entryResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	version, anyversion, jsherr := ifMatchVersion(ctx)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	entry, err := e.repository.Get(ctx, entryID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	if !anyversion && entry.Version() != version {
+		return nil, newJshError(ctx, &domain.ConcurrencyError{Entity: entryResourceType, Id: id})
+	}
+
+	return entry, nil
+}
+
+// handleEntryActions registers the routes for the actions of entries and
+// the balances of funds with api. They must be registered before the
+// entry and fund resources, which handle every path under their own.
+func handleEntryActions(api *jshapi.API, entries *entryStore) {
+	entryPath := apiV1Prefix + "/" + entryResourceType + "/:id"
+	api.HandleC(pat.Post(entryPath+submitPath), goji.HandlerFunc(entries.Submit))
+	api.HandleC(pat.Post(entryPath+approvePath), goji.HandlerFunc(entries.Approve))
+	api.HandleC(pat.Post(entryPath+rejectPath), goji.HandlerFunc(entries.Reject))
+	api.HandleC(pat.Post(entryPath+reversePath), goji.HandlerFunc(entries.Reverse))
+	api.HandleC(pat.Post(entryPath+correctPath), goji.HandlerFunc(entries.Correct))
+	api.HandleC(pat.Get(apiV1Prefix+"/"+fundResourceType+"/:id"+balancesPath), goji.HandlerFunc(entries.GetBalances))
+}
+
+// createEntryObjectWithETag creates the object for an entry and sets the
+// ETag for the entry's version on the response.
+func createEntryObjectWithETag(ctx context.Context, entry domain.JournalEntry) (*jsh.Object, jsh.ErrorType) {
+	obj, jsherr := createEntryObject(entry)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	setETag(ctx, entry.Version())
+	return obj, nil
+}
+
+func createEntryObject(entry domain.JournalEntry) (*jsh.Object, *jsh.Error) {
+	id := strconv.FormatUint(uint64(entry.Id()), 10)
+
+	obj, err := jsh.NewObject(id, entryResourceType, entryAttributes{
+		Date:        formatDate(entry.Date()),
+		Description: entry.Description(),
+		Postings:    formatPostings(entry.Postings()),
+		Status:      entry.Status().String(),
+		SubmittedBy: entry.SubmittedBy(),
+		ReviewedBy:  entry.ReviewedBy(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	obj.Relationships = map[string]*jsh.Relationship{
+		entryFundRelationship: newToOneRelationship(entryResourceType, id,
+			entryFundRelationship, fundResourceType, entry.FundId()),
+	}
+	for name, linked := range map[string]uint{
+		entryReversesRelationship:    entry.ReversesId(),
+		entryReversedByRelationship:  entry.ReversedById(),
+		entryCorrectsRelationship:    entry.CorrectsId(),
+		entryCorrectedByRelationship: entry.CorrectedById(),
+	} {
+		if linked != 0 {
+			obj.Relationships[name] = newToOneRelationship(entryResourceType, id, name, entryResourceType, linked)
+		}
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,490 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type fakeJournalEntry struct {
+	id          uint
+	fundId      uint
+	date        time.Time
+	description string
+	postings    []domain.Posting
+	status      domain.EntryStatus
+	submittedBy string
+	reviewedBy  string
+	reversesId  uint
+	reversedBy  uint
+	correctsId  uint
+	correctedBy uint
+	version     uint
+}
+
+func (f *fakeJournalEntry) Id() uint {
+	return f.id
+}
+
+func (f *fakeJournalEntry) FundId() uint {
+	return f.fundId
+}
+
+func (f *fakeJournalEntry) Date() time.Time {
+	return f.date
+}
+
+func (f *fakeJournalEntry) Description() string {
+	return f.description
+}
+
+func (f *fakeJournalEntry) Postings() []domain.Posting {
+	return f.postings
+}
+
+func (f *fakeJournalEntry) ScheduleId() uint {
+	return 0
+}
+
+func (f *fakeJournalEntry) Status() domain.EntryStatus {
+	return f.status
+}
+
+func (f *fakeJournalEntry) SubmittedBy() string {
+	return f.submittedBy
+}
+
+func (f *fakeJournalEntry) ReviewedBy() string {
+	return f.reviewedBy
+}
+
+func (f *fakeJournalEntry) ReversesId() uint {
+	return f.reversesId
+}
+
+func (f *fakeJournalEntry) ReversedById() uint {
+	return f.reversedBy
+}
+
+func (f *fakeJournalEntry) CorrectsId() uint {
+	return f.correctsId
+}
+
+func (f *fakeJournalEntry) CorrectedById() uint {
+	return f.correctedBy
+}
+
+func (f *fakeJournalEntry) Version() uint {
+	return f.version
+}
+
+// fakeJournalEntryRepository is a JournalEntryRepository whose entries
+// all need approval by someone other than their submitter.
+type fakeJournalEntryRepository struct {
+	entries  []*fakeJournalEntry
+	balances map[uint]int64
+}
+
+func (f *fakeJournalEntryRepository) GetAll(ctx context.Context) ([]domain.JournalEntry, error) {
+	var entries []domain.JournalEntry
+	for _, entry := range f.entries {
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (f *fakeJournalEntryRepository) GetByFund(ctx context.Context, fundId uint) ([]domain.JournalEntry, error) {
+	var entries []domain.JournalEntry
+	for _, entry := range f.entries {
+		if entry.fundId == fundId {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (f *fakeJournalEntryRepository) Get(ctx context.Context, id uint) (domain.JournalEntry, error) {
+	for _, entry := range f.entries {
+		if entry.id == id {
+			return entry, nil
+		}
+	}
+	return nil, &domain.NotFoundError{Entity: "entry", Id: strconv.FormatUint(uint64(id), 10)}
+}
+
+func (f *fakeJournalEntryRepository) Create(ctx context.Context, fundId uint, date time.Time, description string,
+	postings []domain.Posting) (domain.JournalEntry, error) {
+	entry := &fakeJournalEntry{id: uint(len(f.entries) + 1), fundId: fundId, date: date,
+		description: description, postings: postings, status: domain.Draft, version: 1}
+	f.entries = append(f.entries, entry)
+	return entry, nil
+}
+
+func (f *fakeJournalEntryRepository) Update(ctx context.Context, id uint, version uint, date time.Time,
+	description string, postings []domain.Posting) (domain.JournalEntry, error) {
+	return f.transition(id, version, domain.Draft, func(entry *fakeJournalEntry) error {
+		entry.date = date
+		entry.description = description
+		entry.postings = postings
+		return nil
+	})
+}
+
+func (f *fakeJournalEntryRepository) Delete(ctx context.Context, id uint, version uint) error {
+	_, err := f.transition(id, version, domain.Draft, func(entry *fakeJournalEntry) error {
+		for i := range f.entries {
+			if f.entries[i] == entry {
+				f.entries = append(f.entries[:i], f.entries[i+1:]...)
+				break
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func (f *fakeJournalEntryRepository) Submit(ctx context.Context, id uint, version uint,
+	submitter domain.Principal) (domain.JournalEntry, error) {
+	return f.transition(id, version, domain.Draft, func(entry *fakeJournalEntry) error {
+		entry.status = domain.Pending
+		entry.submittedBy = submitter.Name
+		return nil
+	})
+}
+
+func (f *fakeJournalEntryRepository) Approve(ctx context.Context, id uint, version uint,
+	approver domain.Principal) (domain.JournalEntry, error) {
+	return f.transition(id, version, domain.Pending, func(entry *fakeJournalEntry) error {
+		if approver.Name == entry.submittedBy {
+			return &domain.ForbiddenError{Entity: "entry", Id: "1", Reason: "self-approval"}
+		}
+		entry.status = domain.Approved
+		entry.reviewedBy = approver.Name
+		return nil
+	})
+}
+
+func (f *fakeJournalEntryRepository) Reject(ctx context.Context, id uint, version uint,
+	reviewer domain.Principal) (domain.JournalEntry, error) {
+	return f.transition(id, version, domain.Pending, func(entry *fakeJournalEntry) error {
+		entry.status = domain.Rejected
+		entry.reviewedBy = reviewer.Name
+		return nil
+	})
+}
+
+func (f *fakeJournalEntryRepository) Reverse(ctx context.Context, id uint, version uint, date time.Time,
+	reverser domain.Principal) (domain.JournalEntry, error) {
+	var reversal domain.JournalEntry
+	_, err := f.transition(id, version, domain.Approved, func(entry *fakeJournalEntry) error {
+		var postings []domain.Posting
+		for _, posting := range entry.postings {
+			postings = append(postings, domain.Posting{AccountId: posting.AccountId, Amount: -posting.Amount})
+		}
+
+		reversal, _ = f.Create(ctx, entry.fundId, date, "Reversal of "+entry.description, postings)
+		reversal.(*fakeJournalEntry).status = domain.Approved
+		reversal.(*fakeJournalEntry).reversesId = id
+		entry.reversedBy = reversal.Id()
+		return nil
+	})
+	return reversal, err
+}
+
+func (f *fakeJournalEntryRepository) Correct(ctx context.Context, id uint, version uint, date time.Time,
+	description string, postings []domain.Posting, corrector domain.Principal) (domain.JournalEntry, error) {
+	if _, err := f.Reverse(ctx, id, version, date, corrector); err != nil {
+		return nil, err
+	}
+
+	original, _ := f.Get(ctx, id)
+	correction, _ := f.Create(ctx, original.FundId(), date, description, postings)
+	correction.(*fakeJournalEntry).correctsId = id
+	original.(*fakeJournalEntry).correctedBy = correction.Id()
+	return correction, nil
+}
+
+func (f *fakeJournalEntryRepository) transition(id uint, version uint, from domain.EntryStatus,
+	change func(entry *fakeJournalEntry) error) (domain.JournalEntry, error) {
+	found, err := f.Get(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := found.(*fakeJournalEntry)
+	if entry.version != version {
+		return nil, &domain.ConcurrencyError{Entity: "entry", Id: strconv.FormatUint(uint64(id), 10)}
+	}
+	if entry.status != from {
+		return nil, &domain.ConflictError{Entity: "entry", Id: strconv.FormatUint(uint64(id), 10)}
+	}
+	if err := change(entry); err != nil {
+		return nil, err
+	}
+
+	entry.version++
+	return entry, nil
+}
+
+func (f *fakeJournalEntryRepository) Balances(ctx context.Context, fundId uint) (map[uint]int64, error) {
+	return f.balances, nil
+}
+
+// newFakeEntryStore creates an entryStore for a fund with two accounts and
+// a draft rent entry.
+func newFakeEntryStore() (*entryStore, *fakeJournalEntryRepository) {
+	funds := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}}})
+	accounts := &fakeAccountRepository{
+		accounts: []*fakeAccount{{1, 1, "Cash", domain.Asset, 1}, {2, 1, "Rent", domain.Expense, 1}},
+		funds:    funds,
+	}
+	entries := &fakeJournalEntryRepository{
+		entries: []*fakeJournalEntry{{id: 1, fundId: 1, date: time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC),
+			description: "Rent", postings: []domain.Posting{{AccountId: 2, Amount: 150000}, {AccountId: 1, Amount: -150000}},
+			status: domain.Draft, version: 1}},
+		balances: map[uint]int64{2: 2500},
+	}
+
+	return &entryStore{entries, &fundStore{funds, accounts}, identifyTestUser}, entries
+}
+
+// identifyTestUser authenticates the user of the request's basic
+// authorization whatever the password.
+var identifyTestUser = BasicAuth(func(username string, password string) (domain.Principal, bool) {
+	return domain.Principal{Name: username}, username != ""
+})
+
+// serveEntryAction serves a POST of the action of version of entry 1 by
+// user.
+func serveEntryAction(t *testing.T, store *entryStore, action string, version string, user string) *http.Response {
+	return serveEntryActionWithBody(t, store, action, version, user, "")
+}
+
+// serveEntryActionWithBody serves a POST of the action of version of entry
+// 1 by user with body as the request's document.
+func serveEntryActionWithBody(t *testing.T, store *entryStore, action string, version string, user string,
+	body string) *http.Response {
+	request, err := http.NewRequest(http.MethodPost, "/v1/entry/1"+action, strings.NewReader(body))
+	require.NoError(t, err, "Unable to create the request.")
+	response := httptest.NewRecorder()
+	request.Header.Set("Content-Type", jsh.ContentType)
+	request.Header.Set("If-Match", `"`+version+`"`)
+	request.SetBasicAuth(user, "")
+
+	sut := newApi(&fakeStore{
+		fundRepository:         store.funds.repository,
+		accountRepository:      store.funds.accounts,
+		journalEntryRepository: store.repository,
+	}, Identify(store.identify))
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	return response.Result()
+}
+
+func TestEntryStoreSaveCreatesDraftEntry(t *testing.T) {
+	sut, repository := newFakeEntryStore()
+	ctx, response := newRequestContext(t, nil)
+	obj, jsherr := jsh.NewObject("", "entry", map[string]interface{}{
+		"date":        "2026-10-02",
+		"description": "Supplies",
+		"postings":    []map[string]interface{}{{"account": "2", "amount": 2500}, {"account": "1", "amount": -2500}},
+	})
+	require.Nil(t, jsherr, "Unable to create the entry object.")
+	obj.Relationships = map[string]*jsh.Relationship{
+		"fund": {Data: jsh.ResourceLinkage{{Type: "fund", ID: "1"}}},
+	}
+
+	result, saveErr := sut.Save(ctx, obj)
+
+	require.Nil(t, saveErr, "entryStore failed to save an entry")
+	require.Len(t, repository.entries, 2, "The entry was not created.")
+	assert.Equal(t, time.Date(2026, time.October, 2, 0, 0, 0, 0, time.UTC), repository.entries[1].date)
+	var attributes entryAttributes
+	require.Nil(t, result.Unmarshal("entry", &attributes), "Unable to unmarshal the saved entry.")
+	assert.Equal(t, "draft", attributes.Status, "Unexpected status for the saved entry.")
+	assert.Equal(t, `"1"`, response.Header().Get("ETag"), "Unexpected ETag for the saved entry.")
+}
+
+func TestNewApiSubmitsEntryForSubmitter(t *testing.T) {
+	store, repository := newFakeEntryStore()
+
+	response := serveEntryAction(t, store, submitPath, "1", "clerk")
+
+	require.Equal(t, http.StatusOK, response.StatusCode, "Unexpected status code.")
+	assert.Equal(t, domain.Pending, repository.entries[0].status, "The entry was not submitted.")
+	assert.Equal(t, "clerk", repository.entries[0].submittedBy, "Unexpected submitter.")
+	assert.Equal(t, `"2"`, response.Header.Get("ETag"), "Unexpected ETag for the submitted entry.")
+}
+
+func TestNewApiApprovesEntry(t *testing.T) {
+	store, repository := newFakeEntryStore()
+	serveEntryAction(t, store, submitPath, "1", "clerk")
+
+	response := serveEntryAction(t, store, approvePath, "2", "treasurer")
+
+	require.Equal(t, http.StatusOK, response.StatusCode, "Unexpected status code.")
+	assert.Equal(t, domain.Approved, repository.entries[0].status, "The entry was not approved.")
+	assert.Equal(t, "treasurer", repository.entries[0].reviewedBy, "Unexpected reviewer.")
+}
+
+func TestNewApiSelfApprovalIsForbidden(t *testing.T) {
+	store, repository := newFakeEntryStore()
+	serveEntryAction(t, store, submitPath, "1", "clerk")
+
+	response := serveEntryAction(t, store, approvePath, "2", "clerk")
+
+	assert.Equal(t, http.StatusForbidden, response.StatusCode, "Unexpected status code.")
+	assert.Equal(t, domain.Pending, repository.entries[0].status, "The entry was unexpectedly approved.")
+}
+
+func TestNewApiEntryActionWithoutAuthenticationIsUnauthorized(t *testing.T) {
+	store, repository := newFakeEntryStore()
+
+	response := serveEntryAction(t, store, submitPath, "1", "")
+
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode, "Unexpected status code.")
+	assert.Equal(t, domain.Draft, repository.entries[0].status, "The entry was unexpectedly submitted.")
+}
+
+func TestNewApiRejectingDraftIsConflict(t *testing.T) {
+	store, _ := newFakeEntryStore()
+
+	response := serveEntryAction(t, store, rejectPath, "1", "treasurer")
+
+	assert.Equal(t, http.StatusConflict, response.StatusCode, "Unexpected status code.")
+}
+
+func TestNewApiGivesBalancesOfEveryAccountOfFund(t *testing.T) {
+	store, repository := newFakeEntryStore()
+	request, response := getRequestResponse(t, "/v1/fund/1/balances")
+
+	sut := newApi(&fakeStore{
+		fundRepository:         store.funds.repository,
+		accountRepository:      store.funds.accounts,
+		journalEntryRepository: repository,
+	})
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	var document balancesDocument
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &document), "Unable to parse the response.")
+	assert.Equal(t, []balanceAttributes{{"1", 0, "CA$0.00"}, {"2", 2500, "CA$25.00"}}, document.Meta.Balances)
+	assert.Equal(t, "en", response.Header().Get("Content-Language"), "Unexpected content language.")
+}
+
+func TestNewApiGivesBalancesForAcceptLanguage(t *testing.T) {
+	store, repository := newFakeEntryStore()
+	request, response := getRequestResponse(t, "/v1/fund/1/balances")
+	request.Header.Set("Accept-Language", "fr-CA, en;q=0.8")
+
+	sut := newApi(&fakeStore{
+		fundRepository:         store.funds.repository,
+		accountRepository:      store.funds.accounts,
+		journalEntryRepository: repository,
+	})
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	var document balancesDocument
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &document), "Unable to parse the response.")
+	assert.Equal(t, "25,00\u00a0$", document.Meta.Balances[1].Display, "Unexpected display of the balance.")
+	assert.Equal(t, "fr-CA", response.Header().Get("Content-Language"), "Unexpected content language.")
+	assert.Equal(t, "Accept-Language", response.Header().Get("Vary"), "Unexpected vary header.")
+}
+
+// postFakeEntry makes the entry of the fake entry store posted.
+func postFakeEntry(repository *fakeJournalEntryRepository) {
+	repository.entries[0].status = domain.Approved
+	repository.entries[0].version = 3
+}
+
+func TestEntryStoreUpdatePostedEntryIsConflict(t *testing.T) {
+	sut, repository := newFakeEntryStore()
+	postFakeEntry(repository)
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"3"`})
+	obj, jsherr := jsh.NewObject("1", "entry", map[string]interface{}{"description": "Other"})
+	require.Nil(t, jsherr, "Unable to create the entry object.")
+
+	_, err := sut.Update(ctx, obj)
+
+	require.NotNil(t, err, "entryStore unexpectedly updated a posted entry")
+	assert.Equal(t, http.StatusConflict, err.StatusCode(), "Unexpected status.")
+	assert.Equal(t, "Rent", repository.entries[0].description, "The posted entry was changed.")
+}
+
+func TestEntryStoreUpdateKeepsAttributesNotGiven(t *testing.T) {
+	sut, repository := newFakeEntryStore()
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"1"`})
+	obj, jsherr := jsh.NewObject("1", "entry", map[string]interface{}{"description": "October rent"})
+	require.Nil(t, jsherr, "Unable to create the entry object.")
+
+	_, err := sut.Update(ctx, obj)
+
+	require.Nil(t, err, "entryStore failed to update a draft entry")
+	assert.Equal(t, "October rent", repository.entries[0].description, "The description was not changed.")
+	assert.Len(t, repository.entries[0].postings, 2, "The postings were not kept.")
+}
+
+func TestEntryStoreDeletePostedEntryIsConflict(t *testing.T) {
+	sut, repository := newFakeEntryStore()
+	postFakeEntry(repository)
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"3"`})
+
+	err := sut.Delete(ctx, "1")
+
+	require.NotNil(t, err, "entryStore unexpectedly deleted a posted entry")
+	assert.Equal(t, http.StatusConflict, err.StatusCode(), "Unexpected status.")
+	assert.Len(t, repository.entries, 1, "The posted entry was deleted.")
+}
+
+func TestNewApiReversesPostedEntry(t *testing.T) {
+	store, repository := newFakeEntryStore()
+	postFakeEntry(repository)
+
+	response := serveEntryActionWithBody(t, store, reversePath, "3", "clerk",
+		`{"data": {"type": "entry", "attributes": {"date": "2026-10-31"}}}`)
+
+	require.Equal(t, http.StatusCreated, response.StatusCode, "Unexpected status code.")
+	require.Len(t, repository.entries, 2, "The reversal was not made.")
+	assert.Equal(t, time.Date(2026, time.October, 31, 0, 0, 0, 0, time.UTC), repository.entries[1].date)
+	assert.Equal(t, uint(1), repository.entries[1].reversesId, "The reversal is not linked to the entry.")
+}
+
+func TestNewApiCorrectsPostedEntry(t *testing.T) {
+	store, repository := newFakeEntryStore()
+	postFakeEntry(repository)
+
+	response := serveEntryActionWithBody(t, store, correctPath, "3", "clerk",
+		`{"data": {"type": "entry", "attributes": {"date": "2026-10-01", "description": "Rent",
+			"postings": [{"account": "2", "amount": 160000}, {"account": "1", "amount": -160000}]}}}`)
+
+	require.Equal(t, http.StatusCreated, response.StatusCode, "Unexpected status code.")
+	require.Len(t, repository.entries, 3, "The reversal and correction were not made.")
+	assert.Equal(t, uint(3), repository.entries[0].correctedBy, "The entry is not linked to its correction.")
+	assert.Equal(t, uint(2), repository.entries[0].reversedBy, "The entry is not linked to its reversal.")
+}
+
+func TestCreateEntryObjectLinksReversals(t *testing.T) {
+	entry := &fakeJournalEntry{id: 1, fundId: 1, status: domain.Approved, reversedBy: 2, version: 2}
+
+	actual, jsherr := createEntryObject(entry)
+
+	require.Nil(t, jsherr, "Unable to create the entry object.")
+	require.Contains(t, actual.Relationships, "reversed-by", "The reversal is not linked.")
+	assert.Equal(t, "2", actual.Relationships["reversed-by"].Data[0].ID, "Unexpected reversal.")
+	assert.NotContains(t, actual.Relationships, "corrected-by", "Unexpected link to a correction.")
+}
@@ -0,0 +1,90 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/sbosnick1/openacct/domain"
+	"golang.org/x/net/context"
+)
+
+// HTTP status codes that are used by the api service but which net/http
+// does not define.
+const (
+	StatusUnprocessableEntity  int = 422
+	StatusPreconditionRequired int = 428
+)
+
+// internalErrorDetail is the detail of every internal server error that
+// newJshError gives.
+const internalErrorDetail = "An internal error occurred. Quote the id of this error when reporting it."
+
+// newJshError maps an error returned by the domain to the JSON API error
+// that best describes it to a client. A request whose context was cancelled
+// or timed out is reported as unavailable. Errors that are not one of the
+// typed domain errors are reported as internal server errors whose detail
+// doesn't reveal the error; the error itself is only logged, with the id of
+// the request that ctx belongs to, which is also the id of the JSON API error.
+func newJshError(ctx context.Context, err error) jsh.ErrorType {
+	switch e := err.(type) {
+	case *domain.NotFoundError:
+		return jsh.NotFound(e.Entity, e.Id)
+	case *domain.DuplicateError:
+		return newAttributeError(http.StatusConflict, "Duplicate Attribute", e.Error(), e.Field)
+	case *domain.ValidationError:
+		return jsh.InputError(e.Error(), e.Field)
+	case *domain.InvalidCurrencyError:
+		return jsh.InputError(e.Error(), "currency")
+	case *domain.WithdrawnCurrencyError:
+		return jsh.InputError(e.Error(), "currency")
+	case *domain.ConflictError:
+		return newStatusError(http.StatusConflict, "Conflict", e.Error())
+	case *domain.ForbiddenError:
+		return newStatusError(http.StatusForbidden, "Forbidden", e.Error())
+	case *domain.ConcurrencyError:
+		return newStatusError(http.StatusPreconditionFailed, "Precondition Failed", e.Error())
+	}
+
+	switch err {
+	case context.Canceled:
+		return newStatusError(http.StatusServiceUnavailable, "Request Cancelled",
+			"The request was cancelled before it was complete.")
+	case context.DeadlineExceeded:
+		return newStatusError(http.StatusServiceUnavailable, "Request Timeout",
+			"The request took too long to complete.")
+	default:
+		log.WithError(err).WithField("request_id", requestIDFromContext(ctx)).
+			Error("Unexpected error from the domain.")
+		return newStatusError(http.StatusInternalServerError, "Internal Server Error", internalErrorDetail)
+	}
+}
+
+// newAttributeError creates a JSON API error with the given status whose
+// source points at the named attribute of the primary data.
+func newAttributeError(status int, title string, detail string, attribute string) *jsh.Error {
+	jsherr := jsh.InputError(detail, attribute)
+	jsherr.Status = status
+	jsherr.Title = title
+	return jsherr
+}
+
+// newRelationshipError creates a JSON API error with the given status whose
+// source points at the named relationship of the primary data.
+func newRelationshipError(status int, title string, detail string, relationship string) *jsh.Error {
+	jsherr := newAttributeError(status, title, detail, relationship)
+	jsherr.Source.Pointer = "/data/relationships/" + relationship
+	return jsherr
+}
+
+// newStatusError creates a JSON API error with the given status.
+func newStatusError(status int, title string, detail string) *jsh.Error {
+	return &jsh.Error{
+		Status: status,
+		Title:  title,
+		Detail: detail,
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type errorStatusPair struct {
+	err    error
+	status int
+}
+
+func TestNewJshErrorGivesExpectedStatus(t *testing.T) {
+	expected := []errorStatusPair{
+		{&domain.NotFoundError{Entity: "fund", Id: "1"}, http.StatusNotFound},
+		{&domain.DuplicateError{Entity: "fund", Field: "name", Value: "General"}, http.StatusConflict},
+		{&domain.ValidationError{Entity: "fund", Field: "name", Reason: "bad"}, StatusUnprocessableEntity},
+		{&domain.ConflictError{Entity: "fund", Id: "1", Reason: "bad"}, http.StatusConflict},
+		{&domain.ConcurrencyError{Entity: "fund", Id: "1"}, http.StatusPreconditionFailed},
+		{&domain.ForbiddenError{Entity: "entry", Id: "1", Reason: "bad"}, http.StatusForbidden},
+		{context.Canceled, http.StatusServiceUnavailable},
+		{context.DeadlineExceeded, http.StatusServiceUnavailable},
+		{errors.New("some database error"), http.StatusInternalServerError},
+	}
+
+	for _, pair := range expected {
+		actual := newJshError(context.Background(), pair.err)
+		assert.Equal(t, pair.status, actual.StatusCode(),
+			"Unexpected status for error %s", pair.err)
+	}
+}
+
+func TestNewJshErrorPointsAtDuplicateAttribute(t *testing.T) {
+	err := &domain.DuplicateError{Entity: "fund", Field: "name", Value: "General"}
+
+	actual := newJshError(context.Background(), err)
+
+	payload, jsonerr := json.Marshal(actual)
+	require.NoError(t, jsonerr, "Unable to marshal the JSON API error")
+	assert.Contains(t, string(payload), `"pointer":"/data/attributes/name"`,
+		"The JSON API error did not point at the duplicate attribute")
+}
+
+func TestNewJshErrorPointsAtInvalidAttribute(t *testing.T) {
+	err := &domain.ValidationError{Entity: "fund", Field: "name", Reason: "bad"}
+
+	actual := newJshError(context.Background(), err)
+
+	payload, jsonerr := json.Marshal(actual)
+	require.NoError(t, jsonerr, "Unable to marshal the JSON API error")
+	assert.Contains(t, string(payload), `"pointer":"/data/attributes/name"`,
+		"The JSON API error did not point at the invalid attribute")
+}
+
+func TestNewJshErrorDoesNotRevealUnexpectedError(t *testing.T) {
+	err := errors.New("Error 1146: Table 'openacct.funds' doesn't exist")
+
+	actual := newJshError(context.Background(), err)
+
+	payload, jsonerr := json.Marshal(actual)
+	require.NoError(t, jsonerr, "Unable to marshal the JSON API error")
+	assert.NotContains(t, string(payload), "openacct.funds", "The JSON API error revealed the unexpected error")
+	assert.Contains(t, string(payload), internalErrorDetail, "Unexpected detail for the unexpected error")
+}
@@ -6,6 +6,7 @@ package apiservice
 
 import (
 	"strconv"
+	"time"
 
 	jsh "github.com/derekdowling/go-json-spec-handler"
 	"github.com/derekdowling/jsh-api"
@@ -15,21 +16,48 @@ import (
 
 const (
 	fundResourceType = "fund"
+
+	fundAccountsRelationship = "accounts"
+
+	includeArchivedFilter = "filter[include-archived]"
 )
 
-func newFundResource(repository domain.FundRepository) *jshapi.Resource {
-	return jshapi.NewCRUDResource(fundResourceType, &fundStore{repository})
+func newFundResource(store *fundStore) *jshapi.Resource {
+	resource := jshapi.NewCRUDResource(fundResourceType, store)
+	resource.ToMany(fundAccountsRelationship, store.GetAccounts)
+	return resource
 }
 
+// fundAttributes are the attributes of a fund. The archived attributes are
+// only present for an archived fund and are ignored when creating a fund.
+// The template attribute is only used when creating a fund and names the
+// chart of accounts template whose accounts the new fund is given. A new
+// fund can't be given a currency that was withdrawn.
 type fundAttributes struct {
-	Name     string `json:"name,omitempty" valid:"required,utfletternum"`
-	Currency string `json:"currency,omitempty" valid:"required,currency"`
+	Name       string `json:"name,omitempty" valid:"required,utfletternum"`
+	Currency   string `json:"currency,omitempty" valid:"required,currentcurrency"`
+	Template   string `json:"template,omitempty" valid:"charttemplate"`
+	Archived   bool   `json:"archived,omitempty" valid:"-"`
+	ArchivedAt string `json:"archived-at,omitempty" valid:"-"`
+}
+
+// fundPatchAttributes are the attributes of a fund that may be changed by
+// an update. Attributes that are not given keep their current value. A fund
+// is archived or unarchived by an update that changes only the archived
+// attribute.
+type fundPatchAttributes struct {
+	Name     string `json:"name,omitempty" valid:"utfletternum"`
+	Currency string `json:"currency,omitempty" valid:"currency"`
+	Archived *bool  `json:"archived,omitempty" valid:"-"`
 }
 
 // A fundStore is a store for the fund resorce type. It adapts a
-// domain.FundRepository to a json api spec. resource.
+// domain.FundRepository to a json api spec. resource. The accounts
+// relationship of the funds is only included if the fundStore has an
+// AccountRepository.
 type fundStore struct {
 	repository domain.FundRepository
+	accounts   domain.AccountRepository
 }
 
 func (f *fundStore) Save(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
@@ -43,28 +71,37 @@ func (f *fundStore) Save(ctx context.Context, object *jsh.Object) (*jsh.Object,
 		return nil, jsherrs
 	}
 
-	currency, err := domain.ParseCurrency(attributes.Currency)
+	currency, err := domain.ParseCurrentCurrency(attributes.Currency)
 	if err != nil {
 		// the validation on fundAttributes should have ensured this
 		// does not happen
-		return nil, jsh.ISE(err.Error())
+		return nil, newJshError(ctx, err)
 	}
 
-	fund, err := f.repository.Create(attributes.Name, currency)
+	fund, err := f.repository.Create(ctx, attributes.Name, currency, attributes.Template)
 	if err != nil {
-		return nil, jsh.ISE(err.Error())
+		return nil, newJshError(ctx, err)
 	}
 
-	obj, jsherr := createFundObject(fund)
+	return f.createFundObjectWithETag(ctx, fund)
+}
+
+func (f *fundStore) Get(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if f.repository == nil {
+		return nil, jsh.ISE("fundStore requires a FundRepository")
+	}
+
+	fundID, jsherr := parseID(fundResourceType, id)
 	if jsherr != nil {
 		return nil, jsherr
 	}
 
-	return obj, nil
-}
+	fund, err := f.repository.Get(ctx, fundID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
 
-func (f *fundStore) Get(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
-	panic("not implemented")
+	return f.createFundObjectWithETag(ctx, fund)
 }
 
 func (f *fundStore) List(ctx context.Context) (jsh.List, jsh.ErrorType) {
@@ -72,14 +109,29 @@ func (f *fundStore) List(ctx context.Context) (jsh.List, jsh.ErrorType) {
 		return nil, jsh.ISE("fundStore requires a FundRepository")
 	}
 
-	fund, err := f.repository.GetAll()
+	includeArchived, jsherr := parseBoolParameter(ctx, includeArchivedFilter)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	getAll := f.repository.GetAll
+	if includeArchived {
+		getAll = f.repository.GetAllIncludingArchived
+	}
+
+	fund, err := getAll(ctx)
 	if err != nil {
-		return nil, jsh.ISE(err.Error())
+		return nil, newJshError(ctx, err)
+	}
+
+	accountIDs, jsherr := f.accountIDsByFund(ctx)
+	if jsherr != nil {
+		return nil, jsherr
 	}
 
 	list := make(jsh.List, 0)
-	for _, f := range fund {
-		obj, err := createFundObject(f)
+	for _, fund := range fund {
+		obj, err := f.createFundObject(fund, accountIDs[fund.Id()])
 		if err != nil {
 			return nil, err
 		}
@@ -91,21 +143,244 @@ func (f *fundStore) List(ctx context.Context) (jsh.List, jsh.ErrorType) {
 }
 
 func (f *fundStore) Update(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
-	panic("not implemented")
+	if f.repository == nil {
+		return nil, jsh.ISE("fundStore requires a FundRepository")
+	}
+
+	var attributes fundPatchAttributes
+	jsherrs := object.Unmarshal(fundResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	fund, jsherr := f.getForChange(ctx, object.ID)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	if attributes.Archived != nil {
+		return f.setArchived(ctx, fund, attributes)
+	}
+
+	name := fund.Name()
+	if attributes.Name != "" {
+		name = attributes.Name
+	}
+
+	currency := fund.Currency()
+	if attributes.Currency != "" {
+		var err error
+		currency, err = domain.ParseCurrency(attributes.Currency)
+		if err != nil {
+			// the validation on fundPatchAttributes should have
+			// ensured this does not happen
+			return nil, newJshError(ctx, err)
+		}
+	}
+
+	updated, err := f.repository.Update(ctx, fund.Id(), fund.Version(), name, currency)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return f.createFundObjectWithETag(ctx, updated)
 }
 
 func (f *fundStore) Delete(ctx context.Context, id string) jsh.ErrorType {
-	panic("not implemented")
+	if f.repository == nil {
+		return jsh.ISE("fundStore requires a FundRepository")
+	}
+
+	fund, jsherr := f.getForChange(ctx, id)
+	if jsherr != nil {
+		return jsherr
+	}
+
+	// Funds are never removed; deleting a fund archives it instead.
+	_, err := f.repository.Archive(ctx, fund.Id(), fund.Version())
+	if err != nil {
+		return newJshError(ctx, err)
+	}
+
+	return nil
+}
+
+// setArchived archives or unarchives a fund as required by the archived
+// attribute of an update.
+func (f *fundStore) setArchived(ctx context.Context, fund domain.Fund, attributes fundPatchAttributes) (*jsh.Object, jsh.ErrorType) {
+	if attributes.Name != "" || attributes.Currency != "" {
+		return nil, jsh.InputError("The archived attribute can't be changed together with other attributes.",
+			"archived")
+	}
+
+	archive := f.repository.Unarchive
+	if *attributes.Archived {
+		archive = f.repository.Archive
+	}
+
+	updated, err := archive(ctx, fund.Id(), fund.Version())
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return f.createFundObjectWithETag(ctx, updated)
 }
 
-func createFundObject(fund domain.Fund) (*jsh.Object, *jsh.Error) {
+// GetAccounts lists the accounts that belong to the fund with the given id.
+func (f *fundStore) GetAccounts(ctx context.Context, id string) (jsh.List, jsh.ErrorType) {
+	if f.repository == nil || f.accounts == nil {
+		return nil, jsh.ISE("fundStore requires a FundRepository and an AccountRepository")
+	}
+
+	fundID, jsherr := parseID(fundResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	_, err := f.repository.Get(ctx, fundID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	accounts, err := f.accounts.GetByFund(ctx, fundID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createAccountList(accounts)
+}
+
+// getForChange gets the fund with the given id as it must be for the
+// current request to change it. The version of the returned fund is the
+// version required by the If-Match header of the request, which need not be
+// the version currently in the repository.
+func (f *fundStore) getForChange(ctx context.Context, id string) (domain.Fund, jsh.ErrorType) {
+	fundID, jsherr := parseID(fundResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	version, anyversion, jsherr := ifMatchVersion(ctx)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	fund, err := f.repository.Get(ctx, fundID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	if !anyversion && fund.Version() != version {
+		return nil, newJshError(ctx, &domain.ConcurrencyError{Entity: fundResourceType, Id: id})
+	}
+
+	return fund, nil
+}
+
+// getFundObject gets the object for the fund with the given id.
+func (f *fundStore) getFundObject(ctx context.Context, id uint) (*jsh.Object, jsh.ErrorType) {
+	fund, err := f.repository.Get(ctx, id)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	accountIDs, jsherr := f.accountIDs(ctx, fund.Id())
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	return f.createFundObject(fund, accountIDs)
+}
+
+// accountIDs gives the ids of the accounts in the fund with the given id.
+func (f *fundStore) accountIDs(ctx context.Context, fundID uint) ([]uint, jsh.ErrorType) {
+	if f.accounts == nil {
+		return nil, nil
+	}
+
+	accounts, err := f.accounts.GetByFund(ctx, fundID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	var ids []uint
+	for _, account := range accounts {
+		ids = append(ids, account.Id())
+	}
+
+	return ids, nil
+}
+
+// accountIDsByFund gives the ids of the accounts in each fund keyed by the
+// id of the fund.
+func (f *fundStore) accountIDsByFund(ctx context.Context) (map[uint][]uint, jsh.ErrorType) {
+	ids := make(map[uint][]uint)
+	if f.accounts == nil {
+		return ids, nil
+	}
+
+	accounts, err := f.accounts.GetAll(ctx)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	for _, account := range accounts {
+		ids[account.FundId()] = append(ids[account.FundId()], account.Id())
+	}
+
+	return ids, nil
+}
+
+// createFundObjectWithETag creates the object for a fund and sets the ETag
+// for the fund's version on the response.
+func (f *fundStore) createFundObjectWithETag(ctx context.Context, fund domain.Fund) (*jsh.Object, jsh.ErrorType) {
+	accountIDs, jsherr := f.accountIDs(ctx, fund.Id())
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	obj, jsherr := f.createFundObject(fund, accountIDs)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	setETag(ctx, fund.Version())
+	return obj, nil
+}
+
+// createFundObject creates the object for a fund which has accounts with
+// the given ids.
+func (f *fundStore) createFundObject(fund domain.Fund, accountIDs []uint) (*jsh.Object, jsh.ErrorType) {
 	id := strconv.FormatUint(uint64(fund.Id()), 10)
 
-	obj, err := jsh.NewObject(id, fundResourceType,
-		fundAttributes{Name: fund.Name(), Currency: fund.Currency().String()})
+	attributes := fundAttributes{Name: fund.Name(), Currency: fund.Currency().String()}
+	if fund.IsArchived() {
+		attributes.Archived = true
+		attributes.ArchivedAt = fund.Archived().Format(time.RFC3339)
+	}
+
+	obj, err := jsh.NewObject(id, fundResourceType, attributes)
 	if err != nil {
 		return nil, err
 	}
 
+	if f.accounts != nil {
+		obj.Relationships = map[string]*jsh.Relationship{
+			fundAccountsRelationship: newToManyRelationship(fundResourceType, id,
+				fundAccountsRelationship, accountResourceType, accountIDs),
+		}
+	}
+
 	return obj, nil
 }
+
+// parseID parses the id of a resource of resourceType. An id that can't
+// be parsed can't name any resource.
+func parseID(resourceType string, id string) (uint, jsh.ErrorType) {
+	parsed, err := strconv.ParseUint(id, 10, 0)
+	if err != nil {
+		return 0, jsh.NotFound(resourceType, id)
+	}
+
+	return uint(parsed), nil
+}
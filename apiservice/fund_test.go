@@ -8,7 +8,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	jsh "github.com/derekdowling/go-json-spec-handler"
 	"github.com/derekdowling/jsh-api"
@@ -19,14 +21,12 @@ import (
 	"golang.org/x/net/context"
 )
 
-const (
-	StatusUnprocessableEntity int = 422
-)
-
 type fakeFund struct {
 	id       uint
 	currency domain.Currency
 	name     string
+	version  uint
+	archived time.Time
 }
 
 func (f *fakeFund) Currency() domain.Currency {
@@ -41,34 +41,143 @@ func (f *fakeFund) Id() uint {
 	return f.id
 }
 
+func (f *fakeFund) Version() uint {
+	return f.version
+}
+
+func (f *fakeFund) IsArchived() bool {
+	return !f.archived.IsZero()
+}
+
+func (f *fakeFund) Archived() time.Time {
+	return f.archived
+}
+
 type fakeFundRepository struct {
-	getAllCalled     bool
-	createFundCalled bool
-	nextID           uint
-	funds            []domain.Fund
+	getAllCalled      bool
+	getAllArchived    bool
+	createFundCalled  bool
+	createTemplate    string
+	updateFundCalled  bool
+	archiveFundCalled bool
+	nextID            uint
+	funds             []domain.Fund
+	err               error
 }
 
-func (f *fakeFundRepository) GetAll() ([]domain.Fund, error) {
+func (f *fakeFundRepository) GetAll(ctx context.Context) ([]domain.Fund, error) {
 	f.getAllCalled = true
+	if f.err != nil {
+		return nil, f.err
+	}
+	var funds []domain.Fund
+	for _, fund := range f.funds {
+		if !fund.IsArchived() {
+			funds = append(funds, fund)
+		}
+	}
+	return funds, nil
+}
+
+func (f *fakeFundRepository) GetAllIncludingArchived(ctx context.Context) ([]domain.Fund, error) {
+	f.getAllArchived = true
+	if f.err != nil {
+		return nil, f.err
+	}
 	return f.funds, nil
 }
 
-func (f *fakeFundRepository) Create(name string, currency domain.Currency) (domain.Fund, error) {
+func (f *fakeFundRepository) Create(ctx context.Context, name string, currency domain.Currency,
+	template string) (domain.Fund, error) {
 	f.createFundCalled = true
+	f.createTemplate = template
+	if f.err != nil {
+		return nil, f.err
+	}
 	f.nextID++
-	fund := fakeFund{f.nextID, currency, name}
+	fund := fakeFund{f.nextID, currency, name, 1, time.Time{}}
 	f.funds = append(f.funds, &fund)
 	return &fund, nil
 }
 
+func (f *fakeFundRepository) Get(ctx context.Context, id uint) (domain.Fund, error) {
+	for _, fund := range f.funds {
+		if fund.Id() == id {
+			return fund, nil
+		}
+	}
+	return nil, &domain.NotFoundError{Entity: "fund", Id: strconv.FormatUint(uint64(id), 10)}
+}
+
+func (f *fakeFundRepository) Update(ctx context.Context, id uint, version uint, name string, currency domain.Currency) (domain.Fund, error) {
+	f.updateFundCalled = true
+	if f.err != nil {
+		return nil, f.err
+	}
+	fund, err := f.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	fake := fund.(*fakeFund)
+	if fake.version != version {
+		return nil, &domain.ConcurrencyError{Entity: "fund", Id: strconv.FormatUint(uint64(id), 10)}
+	}
+	fake.name = name
+	fake.currency = currency
+	fake.version++
+	return fake, nil
+}
+
+func (f *fakeFundRepository) Archive(ctx context.Context, id uint, version uint) (domain.Fund, error) {
+	return f.setArchived(ctx, id, version, time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC))
+}
+
+func (f *fakeFundRepository) Unarchive(ctx context.Context, id uint, version uint) (domain.Fund, error) {
+	return f.setArchived(ctx, id, version, time.Time{})
+}
+
+func (f *fakeFundRepository) setArchived(ctx context.Context, id uint, version uint, archived time.Time) (domain.Fund, error) {
+	f.archiveFundCalled = true
+	if f.err != nil {
+		return nil, f.err
+	}
+	fund, err := f.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	fake := fund.(*fakeFund)
+	if fake.version != version {
+		return nil, &domain.ConcurrencyError{Entity: "fund", Id: strconv.FormatUint(uint64(id), 10)}
+	}
+	if fake.archived.IsZero() == archived.IsZero() {
+		return nil, &domain.ConflictError{Entity: "fund", Id: strconv.FormatUint(uint64(id), 10)}
+	}
+	fake.archived = archived
+	fake.version++
+	return fake, nil
+}
+
 func newFakeFundRepository(funds []fakeFund) *fakeFundRepository {
 	var realfunds []domain.Fund
-	for _, f := range funds {
-		realfunds = append(realfunds, &f)
+	for i := range funds {
+		realfunds = append(realfunds, &funds[i])
 	}
 	return &fakeFundRepository{funds: realfunds}
 }
 
+// newRequestContext gives a context for calling a fundStore directly as
+// the withHTTP middleware would for a request with the given headers.
+func newRequestContext(t *testing.T, headers map[string]string) (context.Context, *httptest.ResponseRecorder) {
+	request, response := getRequestResponse(t, "/fund")
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	ctx := context.WithValue(context.Background(), requestContextKey, request)
+	ctx = context.WithValue(ctx, responseContextKey, http.ResponseWriter(response))
+	return ctx, response
+}
+
 func newFundObject(t *testing.T, id string, attributes map[string]string) *jsh.Object {
 	obj, jsherr := jsh.NewObject(id, "fund", attributes)
 	if jsherr != nil {
@@ -102,7 +211,7 @@ func TestFundStoreListGetsAllFundsFromDomain(t *testing.T) {
 	assert := assert.New(t)
 	var rep fakeFundRepository
 
-	sut := fundStore{&rep}
+	sut := fundStore{repository: &rep}
 	_, err := sut.List(context.Background())
 
 	assert.NoError(err, "Unexpected error when listing funds.")
@@ -111,10 +220,10 @@ func TestFundStoreListGetsAllFundsFromDomain(t *testing.T) {
 
 func TestFundStoreListReturnsFundsFromDomain(t *testing.T) {
 	assert := assert.New(t)
-	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General"},
-		{2, domain.USD, "Special"}})
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}},
+		{2, domain.USD, "Special", 1, time.Time{}}})
 
-	sut := fundStore{rep}
+	sut := fundStore{repository: rep}
 	list, err := sut.List(context.Background())
 
 	require.NoError(t, err, "Unexpected error when listing funds.")
@@ -150,7 +259,7 @@ func TestFundStoreSaveWithoutNameIsError(t *testing.T) {
 	rep := newFakeFundRepository([]fakeFund{})
 	obj := newFundObject(t, "", map[string]string{"currency": "CAD"})
 
-	sut := fundStore{rep}
+	sut := fundStore{repository: rep}
 	_, jsherr := sut.Save(context.Background(), obj)
 
 	assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(),
@@ -164,7 +273,7 @@ func TestFundStoreSaveWithInvalidNameIsError(t *testing.T) {
 		rep := newFakeFundRepository([]fakeFund{})
 		obj := newFundObject(t, "", map[string]string{"currency": "CAD", "name": badname})
 
-		sut := fundStore{rep}
+		sut := fundStore{repository: rep}
 		_, jsherr := sut.Save(context.Background(), obj)
 
 		assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(),
@@ -176,7 +285,7 @@ func TestFundStoreSaveWithoutCurrencyIsError(t *testing.T) {
 	rep := newFakeFundRepository([]fakeFund{})
 	obj := newFundObject(t, "", map[string]string{"name": "General"})
 
-	sut := fundStore{rep}
+	sut := fundStore{repository: rep}
 	_, jsherr := sut.Save(context.Background(), obj)
 
 	assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(),
@@ -193,7 +302,7 @@ func TestFundStoreSaveWithInvalidCurrencyIsError(t *testing.T) {
 		obj := newFundObject(t, "",
 			map[string]string{"currency": badcurrency, "name": "General"})
 
-		sut := fundStore{rep}
+		sut := fundStore{repository: rep}
 		_, jsherr := sut.Save(context.Background(), obj)
 
 		assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(),
@@ -201,11 +310,22 @@ func TestFundStoreSaveWithInvalidCurrencyIsError(t *testing.T) {
 	}
 }
 
+func TestFundStoreSaveWithWithdrawnCurrencyIsError(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{})
+	obj := newFundObject(t, "", map[string]string{"currency": "DEM", "name": "General"})
+
+	sut := fundStore{repository: rep}
+	_, jsherr := sut.Save(context.Background(), obj)
+
+	assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(),
+		"fundStore gave unexpect status on Save()")
+}
+
 func TestFundStoreSaveCreatesFundInDomain(t *testing.T) {
 	rep := newFakeFundRepository([]fakeFund{})
 	obj := newFundObject(t, "", map[string]string{"currency": "CAD", "name": "General"})
 
-	sut := fundStore{rep}
+	sut := fundStore{repository: rep}
 	_, jsherr := sut.Save(context.Background(), obj)
 
 	assert.Nil(t, jsherr, "fundStore gave unexpected error on Save()")
@@ -215,13 +335,36 @@ func TestFundStoreSaveCreatesFundInDomain(t *testing.T) {
 	assert.Equal(t, domain.CAD, rep.funds[0].Currency(), "unexpected currency in the repository")
 }
 
+func TestFundStoreSavePassesTemplateToDomain(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{})
+	obj := newFundObject(t, "", map[string]string{"currency": "CAD", "name": "General", "template": "church"})
+
+	sut := fundStore{repository: rep}
+	_, jsherr := sut.Save(context.Background(), obj)
+
+	assert.Nil(t, jsherr, "fundStore gave unexpected error on Save()")
+	assert.Equal(t, "church", rep.createTemplate, "fundStore did not pass the template to CreateFund()")
+}
+
+func TestFundStoreSaveWithUnknownTemplateIsInvalid(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{})
+	obj := newFundObject(t, "", map[string]string{"currency": "CAD", "name": "General", "template": "unknown"})
+
+	sut := fundStore{repository: rep}
+	_, jsherr := sut.Save(context.Background(), obj)
+
+	require.NotNil(t, jsherr, "fundStore unexpectedly saved a fund with an unknown template")
+	assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(), "fundStore gave unexpect status on Save()")
+	assert.False(t, rep.createFundCalled, "fundStore called CreateFund() with an unknown template")
+}
+
 func TestFundStoreSaveReturnsCreatedFund(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
 	rep := newFakeFundRepository([]fakeFund{})
 	obj := newFundObject(t, "", map[string]string{"currency": "CAD", "name": "General"})
 
-	sut := fundStore{rep}
+	sut := fundStore{repository: rep}
 	actual, jsherr := sut.Save(context.Background(), obj)
 
 	require.Nil(jsherr, "fundStore gave unexpted error on Save()")
@@ -231,14 +374,256 @@ func TestFundStoreSaveReturnsCreatedFund(t *testing.T) {
 		"Unexpected attributes on the returned fund")
 }
 
+func TestFundStoreSaveWithDuplicateNameIsConflict(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{})
+	rep.err = &domain.DuplicateError{Entity: "fund", Field: "name", Value: "General"}
+	obj := newFundObject(t, "", map[string]string{"currency": "CAD", "name": "General"})
+
+	sut := fundStore{repository: rep}
+	_, jsherr := sut.Save(context.Background(), obj)
+
+	require.NotNil(t, jsherr, "fundStore unexpectedly saved a duplicate fund")
+	assert.Equal(t, http.StatusConflict, jsherr.StatusCode(),
+		"fundStore gave unexpect status on Save()")
+}
+
+func TestFundStoreSaveWithDomainValidationErrorIsError(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{})
+	rep.err = &domain.ValidationError{Entity: "fund", Field: "name", Reason: "must not be empty"}
+	obj := newFundObject(t, "", map[string]string{"currency": "CAD", "name": "General"})
+
+	sut := fundStore{repository: rep}
+	_, jsherr := sut.Save(context.Background(), obj)
+
+	require.NotNil(t, jsherr, "fundStore unexpectedly saved an invalid fund")
+	assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(),
+		"fundStore gave unexpect status on Save()")
+}
+
+func TestFundStoreGetReturnsFund(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}},
+		{2, domain.USD, "Special", 3, time.Time{}}})
+	ctx, response := newRequestContext(t, nil)
+
+	sut := fundStore{repository: rep}
+	actual, jsherr := sut.Get(ctx, "2")
+
+	require.Nil(jsherr, "fundStore gave unexpected error on Get()")
+	require.NotNil(actual, "fundStore returned nil fund on Get()")
+	assert.Equal("2", actual.ID, "returned fund had unexpected ID")
+	assert.JSONEq(`{"currency" : "USD", "name" : "Special"}`, string(actual.Attributes),
+		"Unexpected attributes on the returned fund")
+	assert.Equal(`"3"`, response.Header().Get("ETag"), "Unexpected ETag for the returned fund")
+}
+
+func TestFundStoreGetWithUnknownIdIsNotFound(t *testing.T) {
+	badids := []string{"3", "abc", ""}
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}}})
+
+	for _, badid := range badids {
+		sut := fundStore{repository: rep}
+		_, jsherr := sut.Get(context.Background(), badid)
+
+		require.NotNil(t, jsherr, "fundStore unexpectedly got a fund with id %s", badid)
+		assert.Equal(t, http.StatusNotFound, jsherr.StatusCode(),
+			"fundStore gave unexpected status on Get()")
+	}
+}
+
+func TestFundStoreUpdateWithMatchingVersionUpdatesFund(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 2, time.Time{}}})
+	ctx, response := newRequestContext(t, map[string]string{"If-Match": `"2"`})
+	obj := newFundObject(t, "1", map[string]string{"name": "Operating"})
+
+	sut := fundStore{repository: rep}
+	actual, jsherr := sut.Update(ctx, obj)
+
+	require.Nil(jsherr, "fundStore gave unexpected error on Update()")
+	require.NotNil(actual, "fundStore returned nil fund on Update()")
+	assert.True(rep.updateFundCalled, "fundStore did not call Update()")
+	assert.JSONEq(`{"currency" : "CAD", "name" : "Operating"}`, string(actual.Attributes),
+		"Unexpected attributes on the updated fund")
+	assert.Equal(`"3"`, response.Header().Get("ETag"), "Unexpected ETag for the updated fund")
+}
+
+func TestFundStoreUpdateWithStaleVersionIsPreconditionFailed(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 2, time.Time{}}})
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"1"`})
+	obj := newFundObject(t, "1", map[string]string{"name": "Operating"})
+
+	sut := fundStore{repository: rep}
+	_, jsherr := sut.Update(ctx, obj)
+
+	require.NotNil(t, jsherr, "fundStore unexpectedly updated a stale fund")
+	assert.Equal(t, http.StatusPreconditionFailed, jsherr.StatusCode(),
+		"fundStore gave unexpected status on Update()")
+	assert.False(t, rep.updateFundCalled, "fundStore unexpectedly called Update()")
+}
+
+func TestFundStoreUpdateWithoutIfMatchIsPreconditionRequired(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 2, time.Time{}}})
+	ctx, _ := newRequestContext(t, nil)
+	obj := newFundObject(t, "1", map[string]string{"name": "Operating"})
+
+	sut := fundStore{repository: rep}
+	_, jsherr := sut.Update(ctx, obj)
+
+	require.NotNil(t, jsherr, "fundStore unexpectedly updated a fund without If-Match")
+	assert.Equal(t, StatusPreconditionRequired, jsherr.StatusCode(),
+		"fundStore gave unexpected status on Update()")
+}
+
+func TestFundStoreUpdateWithConcurrentChangeIsPreconditionFailed(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 2, time.Time{}}})
+	rep.err = &domain.ConcurrencyError{Entity: "fund", Id: "1"}
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"2"`})
+	obj := newFundObject(t, "1", map[string]string{"name": "Operating"})
+
+	sut := fundStore{repository: rep}
+	_, jsherr := sut.Update(ctx, obj)
+
+	require.NotNil(t, jsherr, "fundStore unexpectedly updated a concurrently changed fund")
+	assert.Equal(t, http.StatusPreconditionFailed, jsherr.StatusCode(),
+		"fundStore gave unexpected status on Update()")
+}
+
+func TestFundStoreDeleteWithMatchingVersionArchivesFund(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 2, time.Time{}}})
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"2"`})
+
+	sut := fundStore{repository: rep}
+	jsherr := sut.Delete(ctx, "1")
+
+	assert.Nil(t, jsherr, "fundStore gave unexpected error on Delete()")
+	assert.True(t, rep.archiveFundCalled, "fundStore did not call Archive()")
+	require.Len(t, rep.funds, 1, "fundStore unexpectedly removed the fund")
+	assert.True(t, rep.funds[0].IsArchived(), "fundStore did not archive the fund")
+}
+
+func TestFundStoreDeleteWithAnyVersionArchivesFund(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 2, time.Time{}}})
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": "*"})
+
+	sut := fundStore{repository: rep}
+	jsherr := sut.Delete(ctx, "1")
+
+	assert.Nil(t, jsherr, "fundStore gave unexpected error on Delete()")
+	require.Len(t, rep.funds, 1, "fundStore unexpectedly removed the fund")
+	assert.True(t, rep.funds[0].IsArchived(), "fundStore did not archive the fund")
+}
+
+func TestFundStoreDeleteWithStaleVersionIsPreconditionFailed(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 2, time.Time{}}})
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"1"`})
+
+	sut := fundStore{repository: rep}
+	jsherr := sut.Delete(ctx, "1")
+
+	require.NotNil(t, jsherr, "fundStore unexpectedly deleted a stale fund")
+	assert.Equal(t, http.StatusPreconditionFailed, jsherr.StatusCode(),
+		"fundStore gave unexpected status on Delete()")
+	assert.False(t, rep.funds[0].IsArchived(), "fundStore unexpectedly archived the fund")
+}
+
+func TestFundStoreUpdateArchivedFalseUnarchivesFund(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 2, archived}})
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"2"`})
+	obj, err := jsh.NewObject("1", "fund", map[string]bool{"archived": false})
+	require.Nil(t, err, "Unable to create the fund object")
+
+	sut := fundStore{repository: rep}
+	actual, jsherr := sut.Update(ctx, obj)
+
+	require.Nil(t, jsherr, "fundStore gave unexpected error on Update()")
+	assert.False(t, rep.funds[0].IsArchived(), "fundStore did not unarchive the fund")
+	assert.JSONEq(t, `{"currency" : "CAD", "name" : "General"}`, string(actual.Attributes),
+		"Unexpected attributes on the unarchived fund")
+}
+
+func TestFundStoreUpdateArchivedWithOtherAttributesIsError(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 2, time.Time{}}})
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"2"`})
+	obj, err := jsh.NewObject("1", "fund",
+		map[string]interface{}{"archived": true, "name": "Operating"})
+	require.Nil(t, err, "Unable to create the fund object")
+
+	sut := fundStore{repository: rep}
+	_, jsherr := sut.Update(ctx, obj)
+
+	require.NotNil(t, jsherr, "fundStore unexpectedly updated the fund")
+	assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(),
+		"fundStore gave unexpected status on Update()")
+}
+
+func TestFundStoreGetIncludesArchivedAttributes(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 2, archived}})
+	ctx, _ := newRequestContext(t, nil)
+
+	sut := fundStore{repository: rep}
+	actual, jsherr := sut.Get(ctx, "1")
+
+	require.Nil(t, jsherr, "fundStore gave unexpected error on Get()")
+	assert.JSONEq(t, `{"currency" : "CAD", "name" : "General", "archived" : true,
+		"archived-at" : "2016-03-31T00:00:00Z"}`, string(actual.Attributes),
+		"Unexpected attributes on the archived fund")
+}
+
+func TestFundStoreListExcludesArchivedFunds(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}},
+		{2, domain.USD, "Special", 2, archived}})
+	ctx, _ := newRequestContext(t, nil)
+
+	sut := fundStore{repository: rep}
+	list, jsherr := sut.List(ctx)
+
+	require.Nil(t, jsherr, "fundStore gave unexpected error on List()")
+	require.Len(t, list, 1, "Unexpected number of funds returned.")
+	assert.Equal(t, "1", list[0].ID, "Unexpected fund returned.")
+}
+
+func TestFundStoreListWithIncludeArchivedFilterIncludesArchivedFunds(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	rep := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}},
+		{2, domain.USD, "Special", 2, archived}})
+	request, _ := getRequestResponse(t, "/fund?filter[include-archived]=true")
+	ctx := context.WithValue(context.Background(), requestContextKey, request)
+
+	sut := fundStore{repository: rep}
+	list, jsherr := sut.List(ctx)
+
+	require.Nil(t, jsherr, "fundStore gave unexpected error on List()")
+	assert.True(t, rep.getAllArchived, "fundStore did not call GetAllIncludingArchived()")
+	assert.Len(t, list, 2, "Unexpected number of funds returned.")
+}
+
+func TestFundStoreListWithBadIncludeArchivedFilterIsBadRequest(t *testing.T) {
+	rep := newFakeFundRepository([]fakeFund{})
+	request, _ := getRequestResponse(t, "/fund?filter[include-archived]=maybe")
+	ctx := context.WithValue(context.Background(), requestContextKey, request)
+
+	sut := fundStore{repository: rep}
+	_, jsherr := sut.List(ctx)
+
+	require.NotNil(t, jsherr, "fundStore unexpectedly listed funds")
+	assert.Equal(t, http.StatusBadRequest, jsherr.StatusCode(),
+		"fundStore gave unexpected status on List()")
+}
+
 func TestNewFundResource(t *testing.T) {
 	assert := assert.New(t)
-	fakerepository := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General"},
-		{2, domain.USD, "Special"}})
+	fakerepository := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}},
+		{2, domain.USD, "Special", 1, time.Time{}}})
 	request, respsonsewriter := getRequestResponse(t, "/fund")
 
 	sut := jshapi.New("/")
-	sut.Add(newFundResource(fakerepository))
+	sut.Add(newFundResource(&fundStore{repository: fakerepository}))
 	sut.ServeHTTPC(context.Background(), respsonsewriter, request)
 
 	assert.Equal(http.StatusOK, respsonsewriter.Code, "Unexpected status code.")
@@ -247,12 +632,12 @@ func TestNewFundResource(t *testing.T) {
 
 func TestNewFundResourceIncludesAttributes(t *testing.T) {
 	assert := assert.New(t)
-	fakerepository := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General"},
-		{2, domain.USD, "Special"}})
+	fakerepository := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}},
+		{2, domain.USD, "Special", 1, time.Time{}}})
 	request, respsonsewriter := getRequestResponse(t, "/fund")
 
 	sut := jshapi.New("/")
-	sut.Add(newFundResource(fakerepository))
+	sut.Add(newFundResource(&fundStore{repository: fakerepository}))
 	sut.ServeHTTPC(context.Background(), respsonsewriter, request)
 
 	doc := parseResponseBody(t, respsonsewriter, jsh.ListMode)
@@ -278,7 +663,7 @@ func TestNewFundResorceWithEmptyReporsitory(t *testing.T) {
 	request, respsonsewriter := getRequestResponse(t, "/fund")
 
 	sut := jshapi.New("/")
-	sut.Add(newFundResource(&fakerepository))
+	sut.Add(newFundResource(&fundStore{repository: &fakerepository}))
 	sut.ServeHTTPC(context.Background(), respsonsewriter, request)
 
 	assert.Equal(http.StatusOK, respsonsewriter.Code, "Unexpected status code.")
@@ -0,0 +1,258 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"strconv"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/jsh-api"
+	"github.com/sbosnick1/openacct/domain"
+	"golang.org/x/net/context"
+)
+
+const (
+	holdingResourceType = "holding"
+
+	holdingFundRelationship = "fund"
+)
+
+// newHoldingResource creates the resource for holdings.
+func newHoldingResource(store *holdingStore) *jshapi.Resource {
+	resource := jshapi.NewResource(holdingResourceType)
+	resource.Post(store.Save)
+	resource.Get(store.Get)
+	resource.List(store.List)
+	resource.Patch(store.Update)
+	resource.Delete(store.Delete)
+	resource.ToOne(holdingFundRelationship, store.GetFund)
+	return resource
+}
+
+// holdingAttributes are the attributes of a holding. The quantity is in
+// the smallest part of the unit that the precision allows and a missing
+// precision or quantity is zero.
+type holdingAttributes struct {
+	Name      string `json:"name,omitempty" valid:"required"`
+	Unit      string `json:"unit,omitempty" valid:"required"`
+	Precision int    `json:"precision" valid:"-"`
+	Quantity  int64  `json:"quantity" valid:"-"`
+}
+
+// holdingPatchAttributes are the attributes of a holding that may be
+// changed by an update. The unit and precision of a holding can't be
+// changed.
+type holdingPatchAttributes struct {
+	Name     string `json:"name,omitempty"`
+	Quantity *int64 `json:"quantity,omitempty"`
+}
+
+// A holdingStore is a store for the holding resource type. It adapts a
+// domain.HoldingRepository to a json api spec. resource.
+type holdingStore struct {
+	repository domain.HoldingRepository
+	funds      *fundStore
+}
+
+func (h *holdingStore) Save(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if h.repository == nil {
+		return nil, jsh.ISE("holdingStore requires a HoldingRepository")
+	}
+
+	var attributes holdingAttributes
+	jsherrs := object.Unmarshal(holdingResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	fundID, jsherr := parseToOneRelationship(object, holdingFundRelationship, fundResourceType)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	holding, err := h.repository.Create(ctx, fundID, attributes.Name, attributes.Unit, attributes.Precision,
+		attributes.Quantity)
+	if notfound, ok := err.(*domain.NotFoundError); ok && notfound.Entity == fundResourceType {
+		return nil, newRelationshipError(StatusUnprocessableEntity, "Invalid Relationship",
+			err.Error(), holdingFundRelationship)
+	}
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createHoldingObjectWithETag(ctx, holding)
+}
+
+func (h *holdingStore) Get(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if h.repository == nil {
+		return nil, jsh.ISE("holdingStore requires a HoldingRepository")
+	}
+
+	holdingID, jsherr := parseID(holdingResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	holding, err := h.repository.Get(ctx, holdingID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createHoldingObjectWithETag(ctx, holding)
+}
+
+func (h *holdingStore) List(ctx context.Context) (jsh.List, jsh.ErrorType) {
+	if h.repository == nil {
+		return nil, jsh.ISE("holdingStore requires a HoldingRepository")
+	}
+
+	holdings, err := h.repository.GetAll(ctx)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	list := make(jsh.List, 0)
+	for _, holding := range holdings {
+		obj, err := createHoldingObject(holding)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, obj)
+	}
+
+	return list, nil
+}
+
+func (h *holdingStore) Update(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if h.repository == nil {
+		return nil, jsh.ISE("holdingStore requires a HoldingRepository")
+	}
+
+	var attributes holdingPatchAttributes
+	jsherrs := object.Unmarshal(holdingResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	holdingID, jsherr := parseID(holdingResourceType, object.ID)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	version, anyversion, jsherr := ifMatchVersion(ctx)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	holding, err := h.repository.Get(ctx, holdingID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	if !anyversion && holding.Version() != version {
+		return nil, newJshError(ctx, &domain.ConcurrencyError{Entity: holdingResourceType, Id: object.ID})
+	}
+
+	name := holding.Name()
+	if attributes.Name != "" {
+		name = attributes.Name
+	}
+	quantity := holding.Quantity()
+	if attributes.Quantity != nil {
+		quantity = *attributes.Quantity
+	}
+
+	updated, err := h.repository.Update(ctx, holdingID, holding.Version(), name, quantity)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createHoldingObjectWithETag(ctx, updated)
+}
+
+func (h *holdingStore) Delete(ctx context.Context, id string) jsh.ErrorType {
+	if h.repository == nil {
+		return jsh.ISE("holdingStore requires a HoldingRepository")
+	}
+
+	holdingID, jsherr := parseID(holdingResourceType, id)
+	if jsherr != nil {
+		return jsherr
+	}
+
+	version, anyversion, jsherr := ifMatchVersion(ctx)
+	if jsherr != nil {
+		return jsherr
+	}
+
+	holding, err := h.repository.Get(ctx, holdingID)
+	if err != nil {
+		return newJshError(ctx, err)
+	}
+
+	if !anyversion && holding.Version() != version {
+		return newJshError(ctx, &domain.ConcurrencyError{Entity: holdingResourceType, Id: id})
+	}
+
+	err = h.repository.Delete(ctx, holdingID, holding.Version())
+	if err != nil {
+		return newJshError(ctx, err)
+	}
+
+	return nil
+}
+
+// GetFund gets the fund that the holding with the given id belongs to.
+func (h *holdingStore) GetFund(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if h.repository == nil || h.funds == nil {
+		return nil, jsh.ISE("holdingStore requires a HoldingRepository and a fundStore")
+	}
+
+	holdingID, jsherr := parseID(holdingResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	holding, err := h.repository.Get(ctx, holdingID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return h.funds.getFundObject(ctx, holding.FundId())
+}
+
+// createHoldingObjectWithETag creates the object for a holding and sets
+// the ETag for the holding's version on the response.
+func createHoldingObjectWithETag(ctx context.Context, holding domain.Holding) (*jsh.Object, jsh.ErrorType) {
+	obj, jsherr := createHoldingObject(holding)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	setETag(ctx, holding.Version())
+	return obj, nil
+}
+
+func createHoldingObject(holding domain.Holding) (*jsh.Object, *jsh.Error) {
+	id := strconv.FormatUint(uint64(holding.Id()), 10)
+
+	obj, err := jsh.NewObject(id, holdingResourceType, holdingAttributes{
+		Name:      holding.Name(),
+		Unit:      holding.Unit(),
+		Precision: holding.Precision(),
+		Quantity:  holding.Quantity(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	obj.Relationships = map[string]*jsh.Relationship{
+		holdingFundRelationship: newToOneRelationship(holdingResourceType, id,
+			holdingFundRelationship, fundResourceType, holding.FundId()),
+	}
+
+	return obj, nil
+}
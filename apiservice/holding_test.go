@@ -0,0 +1,204 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type fakeHolding struct {
+	id        uint
+	fundId    uint
+	name      string
+	unit      string
+	precision int
+	quantity  int64
+	version   uint
+}
+
+func (f *fakeHolding) Id() uint {
+	return f.id
+}
+
+func (f *fakeHolding) FundId() uint {
+	return f.fundId
+}
+
+func (f *fakeHolding) Name() string {
+	return f.name
+}
+
+func (f *fakeHolding) Unit() string {
+	return f.unit
+}
+
+func (f *fakeHolding) Precision() int {
+	return f.precision
+}
+
+func (f *fakeHolding) Quantity() int64 {
+	return f.quantity
+}
+
+func (f *fakeHolding) Version() uint {
+	return f.version
+}
+
+type fakeHoldingRepository struct {
+	holdings []*fakeHolding
+	funds    domain.FundRepository
+}
+
+func (f *fakeHoldingRepository) GetAll(ctx context.Context) ([]domain.Holding, error) {
+	var holdings []domain.Holding
+	for _, holding := range f.holdings {
+		holdings = append(holdings, holding)
+	}
+	return holdings, nil
+}
+
+func (f *fakeHoldingRepository) GetByFund(ctx context.Context, fundId uint) ([]domain.Holding, error) {
+	var holdings []domain.Holding
+	for _, holding := range f.holdings {
+		if holding.fundId == fundId {
+			holdings = append(holdings, holding)
+		}
+	}
+	return holdings, nil
+}
+
+func (f *fakeHoldingRepository) Get(ctx context.Context, id uint) (domain.Holding, error) {
+	for _, holding := range f.holdings {
+		if holding.id == id {
+			return holding, nil
+		}
+	}
+	return nil, &domain.NotFoundError{Entity: "holding", Id: strconv.FormatUint(uint64(id), 10)}
+}
+
+func (f *fakeHoldingRepository) Create(ctx context.Context, fundId uint, name string, unit string, precision int,
+	quantity int64) (domain.Holding, error) {
+	if _, err := f.funds.Get(ctx, fundId); err != nil {
+		return nil, err
+	}
+
+	holding := &fakeHolding{uint(len(f.holdings) + 1), fundId, name, unit, precision, quantity, 1}
+	f.holdings = append(f.holdings, holding)
+	return holding, nil
+}
+
+func (f *fakeHoldingRepository) Update(ctx context.Context, id uint, version uint, name string,
+	quantity int64) (domain.Holding, error) {
+	found, err := f.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	holding := found.(*fakeHolding)
+	if holding.version != version {
+		return nil, &domain.ConcurrencyError{Entity: "holding", Id: strconv.FormatUint(uint64(id), 10)}
+	}
+	holding.name = name
+	holding.quantity = quantity
+	holding.version++
+	return holding, nil
+}
+
+func (f *fakeHoldingRepository) Delete(ctx context.Context, id uint, version uint) error {
+	return nil
+}
+
+// newFakeHoldingStore creates a holdingStore backed by a fake repository
+// with one fund that holds gold.
+func newFakeHoldingStore() (*holdingStore, *fakeHoldingRepository) {
+	funds := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}}})
+	holdings := &fakeHoldingRepository{
+		holdings: []*fakeHolding{{1, 1, "Gold bullion", "XAU", 3, 12500, 1}},
+		funds:    funds,
+	}
+
+	return &holdingStore{holdings, &fundStore{funds, nil}}, holdings
+}
+
+func newHoldingObject(t *testing.T, id string, attributes map[string]interface{}, fundID string) *jsh.Object {
+	obj, jsherr := jsh.NewObject(id, "holding", attributes)
+	require.Nil(t, jsherr, "Unable to create the holding object.")
+
+	if fundID != "" {
+		obj.Relationships = map[string]*jsh.Relationship{
+			"fund": {Data: jsh.ResourceLinkage{{Type: "fund", ID: fundID}}},
+		}
+	}
+	return obj
+}
+
+func TestHoldingStoreSaveCreatesHoldingInFund(t *testing.T) {
+	sut, repository := newFakeHoldingStore()
+	ctx, response := newRequestContext(t, nil)
+	obj := newHoldingObject(t, "", map[string]interface{}{
+		"name": "Index fund", "unit": "VTI", "precision": 4, "quantity": 1000000}, "1")
+
+	result, jsherr := sut.Save(ctx, obj)
+
+	require.Nil(t, jsherr, "holdingStore failed to save a holding")
+	require.Len(t, repository.holdings, 2, "The holding was not created.")
+	assert.Equal(t, &fakeHolding{2, 1, "Index fund", "VTI", 4, 1000000, 1}, repository.holdings[1])
+	assert.Equal(t, "1", result.Relationships["fund"].Data[0].ID, "Unexpected fund for saved holding.")
+	assert.Equal(t, `"1"`, response.Header().Get("ETag"), "Unexpected ETag for saved holding.")
+}
+
+func TestHoldingStoreSaveWithUnknownFundIsError(t *testing.T) {
+	sut, _ := newFakeHoldingStore()
+	obj := newHoldingObject(t, "", map[string]interface{}{"name": "Silver", "unit": "XAG"}, "99")
+
+	_, jsherr := sut.Save(context.Background(), obj)
+
+	require.NotNil(t, jsherr, "holdingStore unexpectedly saved a holding in an unknown fund")
+	assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(), "Unexpected status.")
+}
+
+func TestHoldingStoreUpdateKeepsAttributesNotGiven(t *testing.T) {
+	sut, repository := newFakeHoldingStore()
+	ctx, response := newRequestContext(t, map[string]string{"If-Match": `"1"`})
+	obj := newHoldingObject(t, "1", map[string]interface{}{"quantity": 0}, "")
+
+	_, jsherr := sut.Update(ctx, obj)
+
+	require.Nil(t, jsherr, "holdingStore failed to update a holding")
+	holding := repository.holdings[0]
+	assert.Equal(t, int64(0), holding.quantity, "The quantity was not changed.")
+	assert.Equal(t, "Gold bullion", holding.name, "The name was not kept.")
+	assert.Equal(t, `"2"`, response.Header().Get("ETag"), "Unexpected ETag for updated holding.")
+}
+
+func TestHoldingStoreUpdateWithStaleVersionIsPreconditionFailed(t *testing.T) {
+	sut, repository := newFakeHoldingStore()
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"7"`})
+	obj := newHoldingObject(t, "1", map[string]interface{}{"name": "Gold"}, "")
+
+	_, jsherr := sut.Update(ctx, obj)
+
+	require.NotNil(t, jsherr, "holdingStore unexpectedly updated a stale holding")
+	assert.Equal(t, http.StatusPreconditionFailed, jsherr.StatusCode(), "Unexpected status.")
+	assert.Equal(t, uint(1), repository.holdings[0].version, "The stale holding was changed.")
+}
+
+func TestHoldingStoreGetFundGivesFundOfHolding(t *testing.T) {
+	sut, _ := newFakeHoldingStore()
+
+	fund, jsherr := sut.GetFund(context.Background(), "1")
+
+	require.Nil(t, jsherr, "holdingStore failed to get the fund of a holding")
+	assert.Equal(t, "1", fund.ID, "Unexpected fund of the holding.")
+}
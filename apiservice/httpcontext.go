@@ -0,0 +1,116 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"goji.io"
+	"golang.org/x/net/context"
+)
+
+type contextKey int
+
+const (
+	requestContextKey contextKey = iota
+	responseContextKey
+	ifMatchContextKey
+	requestIDContextKey
+	principalContextKey
+)
+
+// withHTTP is goji middleware that stores the request and response writer
+// in the context. The stores for the resources only receive the context so
+// this is how they read request headers and set response headers.
+func withHTTP(next goji.Handler) goji.Handler {
+	return goji.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		ctx = context.WithValue(ctx, requestContextKey, r)
+		ctx = context.WithValue(ctx, responseContextKey, w)
+		next.ServeHTTPC(ctx, w, r)
+	})
+}
+
+// withTimeout gives goji middleware that limits the time that next has to
+// serve a request to timeout. A timeout that isn't positive is no limit.
+func withTimeout(timeout time.Duration) func(goji.Handler) goji.Handler {
+	return func(next goji.Handler) goji.Handler {
+		if timeout <= 0 {
+			return next
+		}
+
+		return goji.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			next.ServeHTTPC(ctx, w, r)
+		})
+	}
+}
+
+// requestFromContext returns the request stored in ctx by withHTTP, or nil
+// if there is no such request.
+func requestFromContext(ctx context.Context) *http.Request {
+	request, _ := ctx.Value(requestContextKey).(*http.Request)
+	return request
+}
+
+// setResponseHeader sets a header on the response writer stored in ctx by
+// withHTTP. It does nothing if there is no such response writer.
+func setResponseHeader(ctx context.Context, key string, value string) {
+	response, ok := ctx.Value(responseContextKey).(http.ResponseWriter)
+	if !ok {
+		return
+	}
+
+	response.Header().Set(key, value)
+}
+
+// parseBoolParameter parses a boolean query parameter of the request
+// stored in ctx by withHTTP. A missing parameter is false.
+func parseBoolParameter(ctx context.Context, name string) (bool, jsh.ErrorType) {
+	request := requestFromContext(ctx)
+	if request == nil {
+		return false, nil
+	}
+
+	value := request.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, newStatusError(http.StatusBadRequest, "Invalid Query Parameter",
+			fmt.Sprintf("The query parameter %s must be true or false.", name))
+	}
+
+	return parsed, nil
+}
+
+// parseIntParameter parses an integer query parameter of the request stored
+// in ctx by withHTTP. A missing parameter is def and a value that is not
+// from min to max is an error.
+func parseIntParameter(ctx context.Context, name string, def int, min int, max int) (int, jsh.ErrorType) {
+	request := requestFromContext(ctx)
+	if request == nil {
+		return def, nil
+	}
+
+	value := request.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min || parsed > max {
+		return 0, newStatusError(http.StatusBadRequest, "Invalid Query Parameter",
+			fmt.Sprintf("The query parameter %s must be a number from %d to %d.", name, min, max))
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,72 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"goji.io"
+	"golang.org/x/net/context"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithHTTPStoresRequestInContext(t *testing.T) {
+	request, response := getRequestResponse(t, "/anything")
+	var actualRequest *http.Request
+	next := func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) {
+		actualRequest = requestFromContext(ctx)
+	}
+
+	sut := withHTTP(goji.HandlerFunc(next))
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	assert.Equal(t, request, actualRequest, "withHTTP did not store the request in the context")
+}
+
+func TestWithHTTPAllowsSettingResponseHeaders(t *testing.T) {
+	request, response := getRequestResponse(t, "/anything")
+	next := func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) {
+		setResponseHeader(ctx, "X-Test", "value")
+	}
+
+	sut := withHTTP(goji.HandlerFunc(next))
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	assert.Equal(t, "value", response.Header().Get("X-Test"),
+		"withHTTP did not allow setting a response header")
+}
+
+func TestWithTimeoutSetsDeadline(t *testing.T) {
+	request, response := getRequestResponse(t, "/anything")
+	var hasDeadline bool
+	next := func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) {
+		_, hasDeadline = ctx.Deadline()
+	}
+
+	sut := withTimeout(time.Minute)(goji.HandlerFunc(next))
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	assert.True(t, hasDeadline, "withTimeout did not set a deadline")
+}
+
+func TestWithoutTimeoutHasNoDeadline(t *testing.T) {
+	request, response := getRequestResponse(t, "/anything")
+	hasDeadline := true
+	next := func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) {
+		_, hasDeadline = ctx.Deadline()
+	}
+
+	sut := withTimeout(0)(goji.HandlerFunc(next))
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	assert.False(t, hasDeadline, "withTimeout(0) set a deadline")
+}
+
+func TestRequestFromEmptyContextIsNil(t *testing.T) {
+	assert.Nil(t, requestFromContext(context.Background()))
+}
@@ -0,0 +1,217 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/sbosnick1/openacct/domain"
+	"goji.io"
+	"golang.org/x/net/context"
+)
+
+const (
+	idempotencyKeyHeader          = "Idempotency-Key"
+	idempotentReplayedHeader      = "Idempotent-Replayed"
+	idempotentReplayedHeaderValue = "true"
+)
+
+// perRequestHeaders are the headers of a stored response that belong to
+// the request that it was the response to rather than to the response
+// itself. A replay keeps the values of these headers for the current
+// request.
+var perRequestHeaders = map[string]bool{
+	http.CanonicalHeaderKey(requestIDHeader): true,
+	"Date":                                   true,
+}
+
+// idempotencyHandler is goji middleware that makes POST requests with an
+// Idempotency-Key header safe to retry. The response to the first request
+// with a key is stored and replayed to later requests with the same key and
+// the same request body. A later request with the same key but a different
+// body is an error. Keys are scoped to the credentials in the Authorization
+// header of the request so that a request never gets the response to
+// another caller's request. A response that failed on the server or
+// refused the caller's credentials isn't stored, so a retry is performed
+// again.
+type idempotencyHandler struct {
+	next       goji.Handler
+	repository domain.IdempotencyRepository
+	expiry     time.Duration
+	now        func() time.Time
+}
+
+func newIdempotencyMiddleware(repository domain.IdempotencyRepository, cfg *config) func(goji.Handler) goji.Handler {
+	return func(next goji.Handler) goji.Handler {
+		return &idempotencyHandler{next, repository, cfg.idempotencyKeyExpiry, cfg.now}
+	}
+}
+
+// storedResponse is the form in which a response is kept for replay.
+type storedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// responseRecorder passes a response through to a http.ResponseWriter
+// while keeping a copy of its status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (h *idempotencyHandler) ServeHTTPC(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if r.Method != http.MethodPost || key == "" || h.repository == nil {
+		h.next.ServeHTTPC(ctx, w, r)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		jsh.Send(w, r, newStatusError(http.StatusBadRequest, "Bad Request", "Unable to read the request body."))
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	hash := hashRequest(r, body)
+	key = scopedKey(r, key)
+
+	now := h.now()
+	err = h.repository.DeleteCreatedBefore(ctx, now.Add(-h.expiry))
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	previous, err := h.repository.Get(ctx, key)
+	switch err.(type) {
+	case nil:
+		h.replay(ctx, w, r, previous, hash)
+		return
+	case *domain.NotFoundError:
+	default:
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	err = h.repository.Reserve(ctx, key, hash, now)
+	if err != nil {
+		sendError(w, r, newKeyInUseError(ctx, err))
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.next.ServeHTTPC(ctx, recorder, r)
+
+	// The key is released or completed even if the request was cancelled
+	// while it was being served, so this doesn't use the request's context.
+	cleanup := context.Background()
+
+	// A request that failed on the server, or whose credentials were
+	// refused, may be retried, perhaps with other credentials, so the key
+	// is released rather than keeping the failure for replay.
+	if recorder.status >= http.StatusInternalServerError || recorder.status == http.StatusUnauthorized ||
+		recorder.status == http.StatusForbidden {
+		h.repository.Release(cleanup, key)
+		return
+	}
+
+	// If the response can't be stored the key stays reserved and retries
+	// are refused until it expires. That is safer than performing the
+	// request a second time.
+	payload, err := json.Marshal(storedResponse{recorder.status, w.Header(), recorder.body.Bytes()})
+	if err == nil {
+		h.repository.Complete(cleanup, key, payload)
+	}
+}
+
+// replay sends the stored response for a previous request with the same
+// idempotency key as r.
+func (h *idempotencyHandler) replay(ctx context.Context, w http.ResponseWriter, r *http.Request,
+	previous domain.IdempotentRequest, hash string) {
+	if previous.RequestHash() != hash {
+		jsh.Send(w, r, newStatusError(StatusUnprocessableEntity, "Idempotency-Key Reused",
+			"The Idempotency-Key was already used for a different request."))
+		return
+	}
+
+	if previous.Response() == nil {
+		jsh.Send(w, r, newStatusError(http.StatusConflict, "Request In Progress",
+			"A request with this Idempotency-Key is still in progress."))
+		return
+	}
+
+	var stored storedResponse
+	err := json.Unmarshal(previous.Response(), &stored)
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	for name, values := range stored.Header {
+		if perRequestHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		w.Header()[name] = values
+	}
+	w.Header().Set(idempotentReplayedHeader, idempotentReplayedHeaderValue)
+	w.WriteHeader(stored.Status)
+	w.Write(stored.Body)
+}
+
+// newKeyInUseError gives the JSON API error for a failure to reserve an
+// idempotency key. A *domain.DuplicateError means that another request
+// with the same key started at the same time.
+func newKeyInUseError(ctx context.Context, err error) jsh.ErrorType {
+	if _, ok := err.(*domain.DuplicateError); ok {
+		return newStatusError(http.StatusConflict, "Request In Progress",
+			"A request with this Idempotency-Key is still in progress.")
+	}
+
+	return newJshError(ctx, err)
+}
+
+// scopedKey gives the key under which the response to r, which has the
+// idempotency key key, is kept. It is a hash of key and of the credentials
+// in the Authorization header of r so it always fits in the store.
+func scopedKey(r *http.Request, key string) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Header.Get("Authorization")))
+	hash.Write([]byte{0})
+	hash.Write([]byte(key))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// hashRequest gives a hash that identifies a request by its method, path
+// and body.
+func hashRequest(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(r.URL.Path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
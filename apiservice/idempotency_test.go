@@ -0,0 +1,276 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"goji.io"
+	"golang.org/x/net/context"
+)
+
+type fakeIdempotentRequest struct {
+	key         string
+	requestHash string
+	response    []byte
+	created     time.Time
+}
+
+func (f *fakeIdempotentRequest) Key() string {
+	return f.key
+}
+
+func (f *fakeIdempotentRequest) RequestHash() string {
+	return f.requestHash
+}
+
+func (f *fakeIdempotentRequest) Response() []byte {
+	return f.response
+}
+
+func (f *fakeIdempotentRequest) Created() time.Time {
+	return f.created
+}
+
+type fakeIdempotencyRepository struct {
+	requests map[string]*fakeIdempotentRequest
+}
+
+func newFakeIdempotencyRepository() *fakeIdempotencyRepository {
+	return &fakeIdempotencyRepository{make(map[string]*fakeIdempotentRequest)}
+}
+
+func (f *fakeIdempotencyRepository) Get(ctx context.Context, key string) (domain.IdempotentRequest, error) {
+	request, ok := f.requests[key]
+	if !ok {
+		return nil, &domain.NotFoundError{Entity: "idempotent request", Id: key}
+	}
+	return request, nil
+}
+
+func (f *fakeIdempotencyRepository) Reserve(ctx context.Context, key string, requestHash string, created time.Time) error {
+	if _, ok := f.requests[key]; ok {
+		return &domain.DuplicateError{Entity: "idempotent request", Field: "key", Value: key}
+	}
+	f.requests[key] = &fakeIdempotentRequest{key, requestHash, nil, created}
+	return nil
+}
+
+func (f *fakeIdempotencyRepository) Complete(ctx context.Context, key string, response []byte) error {
+	request, ok := f.requests[key]
+	if !ok {
+		return &domain.NotFoundError{Entity: "idempotent request", Id: key}
+	}
+	request.response = response
+	return nil
+}
+
+func (f *fakeIdempotencyRepository) Release(ctx context.Context, key string) error {
+	if _, ok := f.requests[key]; !ok {
+		return &domain.NotFoundError{Entity: "idempotent request", Id: key}
+	}
+	delete(f.requests, key)
+	return nil
+}
+
+func (f *fakeIdempotencyRepository) DeleteCreatedBefore(ctx context.Context, created time.Time) error {
+	for key, request := range f.requests {
+		if request.created.Before(created) {
+			delete(f.requests, key)
+		}
+	}
+	return nil
+}
+
+// countingHandler is a goji.Handler that counts its calls and responds
+// with a fixed status and body.
+type countingHandler struct {
+	calls  int
+	status int
+	body   string
+}
+
+func (c *countingHandler) ServeHTTPC(_ context.Context, w http.ResponseWriter, _ *http.Request) {
+	c.calls++
+	w.Header().Set("Location", "/v1/fund/1")
+	w.WriteHeader(c.status)
+	w.Write([]byte(c.body))
+}
+
+func newIdempotencyTestHandler(repository domain.IdempotencyRepository, next goji.Handler, now time.Time) goji.Handler {
+	cfg := newConfig([]Option{IdempotencyKeyExpiry(time.Hour)})
+	cfg.now = func() time.Time { return now }
+	return newIdempotencyMiddleware(repository, cfg)(next)
+}
+
+func postWithKey(t *testing.T, handler goji.Handler, key string, body string) *httptest.ResponseRecorder {
+	return postWithKeyAs(t, handler, "", key, body)
+}
+
+// postWithKeyAs posts body with key on behalf of user, or anonymously if
+// user is "".
+func postWithKeyAs(t *testing.T, handler goji.Handler, user string, key string, body string) *httptest.ResponseRecorder {
+	request, err := http.NewRequest(http.MethodPost, "/v1/fund", strings.NewReader(body))
+	require.NoError(t, err, "unable to create request.")
+	if user != "" {
+		request.SetBasicAuth(user, "secret")
+	}
+	if key != "" {
+		request.Header.Set("Idempotency-Key", key)
+	}
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTPC(context.Background(), response, request)
+	return response
+}
+
+func TestIdempotencyHandlerReplaysResponseForSameKeyAndBody(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	next := &countingHandler{status: http.StatusCreated, body: `{"data":{}}`}
+	sut := newIdempotencyTestHandler(newFakeIdempotencyRepository(), next, now)
+
+	first := postWithKey(t, sut, "key1", `{"data":{}}`)
+	second := postWithKey(t, sut, "key1", `{"data":{}}`)
+
+	assert.Equal(1, next.calls, "The request was performed more than once")
+	assert.Equal(http.StatusCreated, first.Code, "Unexpected status for the first request")
+	assert.Equal(http.StatusCreated, second.Code, "Unexpected status for the replayed request")
+	assert.Equal(first.Body.String(), second.Body.String(), "Unexpected body for the replayed request")
+	assert.Equal("/v1/fund/1", second.Header().Get("Location"), "Unexpected header for the replayed request")
+	assert.Equal("true", second.Header().Get("Idempotent-Replayed"), "Replayed request was not marked")
+}
+
+func TestIdempotencyHandlerReplayKeepsRequestIDOfCurrentRequest(t *testing.T) {
+	now := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	next := &countingHandler{status: http.StatusCreated, body: `{"data":{}}`}
+	sut := newIdempotencyTestHandler(newFakeIdempotencyRepository(), next, now)
+	request, err := http.NewRequest(http.MethodPost, "/v1/fund", strings.NewReader(`{"data":{}}`))
+	require.NoError(t, err, "unable to create request.")
+	request.Header.Set("Idempotency-Key", "key1")
+	first := httptest.NewRecorder()
+	first.Header().Set("X-Request-ID", "first")
+	sut.ServeHTTPC(context.Background(), first, request)
+
+	request, err = http.NewRequest(http.MethodPost, "/v1/fund", strings.NewReader(`{"data":{}}`))
+	require.NoError(t, err, "unable to create request.")
+	request.Header.Set("Idempotency-Key", "key1")
+	second := httptest.NewRecorder()
+	second.Header().Set("X-Request-ID", "second")
+	sut.ServeHTTPC(context.Background(), second, request)
+
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"), "The request was not replayed")
+	assert.Equal(t, "second", second.Header().Get("X-Request-ID"), "Unexpected request id for the replayed request")
+}
+
+func TestIdempotencyHandlerWithDifferentBodyIsError(t *testing.T) {
+	now := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	next := &countingHandler{status: http.StatusCreated, body: `{"data":{}}`}
+	sut := newIdempotencyTestHandler(newFakeIdempotencyRepository(), next, now)
+
+	postWithKey(t, sut, "key1", `{"data":{"id":"1"}}`)
+	actual := postWithKey(t, sut, "key1", `{"data":{"id":"2"}}`)
+
+	assert.Equal(t, 1, next.calls, "The request with a reused key was performed")
+	assert.Equal(t, StatusUnprocessableEntity, actual.Code, "Unexpected status for a reused key")
+}
+
+func TestIdempotencyHandlerWithDifferentKeysPerformsBothRequests(t *testing.T) {
+	now := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	next := &countingHandler{status: http.StatusCreated, body: `{"data":{}}`}
+	sut := newIdempotencyTestHandler(newFakeIdempotencyRepository(), next, now)
+
+	postWithKey(t, sut, "key1", `{"data":{}}`)
+	postWithKey(t, sut, "key2", `{"data":{}}`)
+
+	assert.Equal(t, 2, next.calls, "Unexpected number of requests performed")
+}
+
+func TestIdempotencyHandlerWithoutKeyPerformsEveryRequest(t *testing.T) {
+	now := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	repository := newFakeIdempotencyRepository()
+	next := &countingHandler{status: http.StatusCreated, body: `{"data":{}}`}
+	sut := newIdempotencyTestHandler(repository, next, now)
+
+	postWithKey(t, sut, "", `{"data":{}}`)
+	postWithKey(t, sut, "", `{"data":{}}`)
+
+	assert.Equal(t, 2, next.calls, "Unexpected number of requests performed")
+	assert.Len(t, repository.requests, 0, "A request without a key was recorded")
+}
+
+func TestIdempotencyHandlerPerformsRequestAgainAfterExpiry(t *testing.T) {
+	now := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	repository := newFakeIdempotencyRepository()
+	next := &countingHandler{status: http.StatusCreated, body: `{"data":{}}`}
+
+	postWithKey(t, newIdempotencyTestHandler(repository, next, now), "key1", `{"data":{}}`)
+	later := newIdempotencyTestHandler(repository, next, now.Add(2*time.Hour))
+	postWithKey(t, later, "key1", `{"data":{}}`)
+
+	assert.Equal(t, 2, next.calls, "The request was not performed again after the key expired")
+}
+
+func TestIdempotencyHandlerReleasesKeyOnServerError(t *testing.T) {
+	now := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	repository := newFakeIdempotencyRepository()
+	next := &countingHandler{status: http.StatusInternalServerError, body: `{"errors":[]}`}
+	sut := newIdempotencyTestHandler(repository, next, now)
+
+	postWithKey(t, sut, "key1", `{"data":{}}`)
+	postWithKey(t, sut, "key1", `{"data":{}}`)
+
+	assert.Equal(t, 2, next.calls, "The failed request was not performed again")
+}
+
+func TestIdempotencyHandlerDoesNotKeepRefusedCredentials(t *testing.T) {
+	now := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		repository := newFakeIdempotencyRepository()
+		next := &countingHandler{status: status, body: `{"errors":[]}`}
+		sut := newIdempotencyTestHandler(repository, next, now)
+
+		postWithKey(t, sut, "key1", `{"data":{}}`)
+		postWithKey(t, sut, "key1", `{"data":{}}`)
+
+		assert.Equal(t, 2, next.calls, "The request refused with %d was not performed again", status)
+	}
+}
+
+func TestIdempotencyHandlerScopesKeysToCredentials(t *testing.T) {
+	now := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	next := &countingHandler{status: http.StatusOK, body: `{"data":{}}`}
+	sut := newIdempotencyTestHandler(newFakeIdempotencyRepository(), next, now)
+
+	postWithKeyAs(t, sut, "clerk", "key1", `{"data":{}}`)
+	actual := postWithKeyAs(t, sut, "treasurer", "key1", `{"data":{}}`)
+
+	assert.Equal(t, 2, next.calls, "The request of another caller was replayed")
+	assert.Empty(t, actual.Header().Get(idempotentReplayedHeader), "The response was replayed")
+}
+
+func TestIdempotencyHandlerWithRequestInProgressIsConflict(t *testing.T) {
+	now := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	repository := newFakeIdempotencyRepository()
+	next := &countingHandler{status: http.StatusCreated, body: `{"data":{}}`}
+	sut := newIdempotencyTestHandler(repository, next, now)
+	body := `{"data":{}}`
+	request, err := http.NewRequest(http.MethodPost, "/v1/fund", strings.NewReader(body))
+	require.NoError(t, err, "unable to create request.")
+	repository.Reserve(context.Background(), scopedKey(request, "key1"), hashRequest(request, []byte(body)), now)
+
+	actual := postWithKey(t, sut, "key1", body)
+
+	assert.Equal(t, 0, next.calls, "The request in progress was performed again")
+	assert.Equal(t, http.StatusConflict, actual.Code, "Unexpected status for a request in progress")
+}
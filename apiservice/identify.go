@@ -0,0 +1,75 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/sbosnick1/openacct/domain"
+	"golang.org/x/net/context"
+)
+
+// AdministratorRole is the role that a principal must hold to create or
+// delete the approval rules of funds.
+const AdministratorRole = "administrator"
+
+// BasicAuth gives a function for the Identify option that authenticates
+// the user name and password of the request's basic authorization with
+// authenticate. A request without basic authorization isn't authenticated.
+func BasicAuth(authenticate func(username string, password string) (domain.Principal, bool)) func(r *http.Request) (domain.Principal, bool) {
+	return func(r *http.Request) (domain.Principal, bool) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			return domain.Principal{}, false
+		}
+
+		return authenticate(username, password)
+	}
+}
+
+// withAuthenticated gives a context derived from ctx and the principal in
+// which authenticate records the principal that it authenticates for the
+// request that ctx belongs to. The principal is the zero value until then.
+func withAuthenticated(ctx context.Context) (context.Context, *domain.Principal) {
+	authenticated := &domain.Principal{}
+	return context.WithValue(ctx, principalContextKey, authenticated), authenticated
+}
+
+// authenticate gives the principal that made r as given by identify and
+// records it in the principal given by withAuthenticated for ctx, if there
+// is one. It gives a 401 error if there is no identify function or r isn't
+// authenticated.
+func authenticate(ctx context.Context, identify func(r *http.Request) (domain.Principal, bool),
+	r *http.Request) (domain.Principal, jsh.ErrorType) {
+	if identify != nil && r != nil {
+		if principal, ok := identify(r); ok {
+			if authenticated, ok := ctx.Value(principalContextKey).(*domain.Principal); ok {
+				*authenticated = principal
+			}
+			return principal, nil
+		}
+	}
+
+	return domain.Principal{}, newStatusError(http.StatusUnauthorized, "Unauthorized",
+		"The request must be made by an authenticated user.")
+}
+
+// authorize checks that the principal that made r, as authenticated by
+// authenticate, holds role. It gives a 401 error if r isn't authenticated and a 403
+// error if the principal doesn't hold role.
+func authorize(ctx context.Context, identify func(r *http.Request) (domain.Principal, bool), r *http.Request,
+	role string) jsh.ErrorType {
+	principal, jsherr := authenticate(ctx, identify, r)
+	if jsherr != nil {
+		return jsherr
+	}
+	if !principal.HasRole(role) {
+		return newStatusError(http.StatusForbidden, "Forbidden",
+			"The request must be made by a user with the role "+role+".")
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// checkPassword authenticates the treasurer with the password "secret".
+func checkPassword(username string, password string) (domain.Principal, bool) {
+	if username != "treasurer" || password != "secret" {
+		return domain.Principal{}, false
+	}
+
+	return domain.Principal{Name: "treasurer", Roles: []string{"treasurer"}}, true
+}
+
+func TestBasicAuthAuthenticatesUserAndPassword(t *testing.T) {
+	request, err := http.NewRequest(http.MethodPost, "/v1/entry/1/approve", nil)
+	require.NoError(t, err, "Unable to create the request.")
+	request.SetBasicAuth("treasurer", "secret")
+
+	actual, ok := BasicAuth(checkPassword)(request)
+
+	assert.True(t, ok, "The request was not authenticated.")
+	assert.Equal(t, "treasurer", actual.Name, "Unexpected principal.")
+}
+
+func TestBasicAuthWithWrongPasswordIsNotAuthenticated(t *testing.T) {
+	request, err := http.NewRequest(http.MethodPost, "/v1/entry/1/approve", nil)
+	require.NoError(t, err, "Unable to create the request.")
+	request.SetBasicAuth("treasurer", "guess")
+
+	_, ok := BasicAuth(checkPassword)(request)
+
+	assert.False(t, ok, "The request was authenticated.")
+}
+
+func TestBasicAuthWithoutAuthorizationIsNotAuthenticated(t *testing.T) {
+	request, err := http.NewRequest(http.MethodPost, "/v1/entry/1/approve", nil)
+	require.NoError(t, err, "Unable to create the request.")
+
+	_, ok := BasicAuth(checkPassword)(request)
+
+	assert.False(t, ok, "The request was authenticated.")
+}
+
+func TestAuthorizeGivesExpectedStatus(t *testing.T) {
+	administrator := domain.Principal{Name: "admin", Roles: []string{AdministratorRole}}
+	tests := []struct {
+		name     string
+		identify func(r *http.Request) (domain.Principal, bool)
+		status   int
+	}{
+		{"no identify", nil, http.StatusUnauthorized},
+		{"not authenticated", BasicAuth(checkPassword), http.StatusUnauthorized},
+		{"without role", identifyAs(domain.Principal{Name: "clerk"}), http.StatusForbidden},
+		{"with role", identifyAs(administrator), 0},
+	}
+
+	for _, test := range tests {
+		request, err := http.NewRequest(http.MethodPost, "/v1/approval-rule", nil)
+		require.NoError(t, err, "Unable to create the request.")
+
+		jsherr := authorize(context.Background(), test.identify, request, AdministratorRole)
+
+		if test.status == 0 {
+			assert.Nil(t, jsherr, "Unexpected error for %s.", test.name)
+		} else if assert.NotNil(t, jsherr, "No error for %s.", test.name) {
+			assert.Equal(t, test.status, jsherr.StatusCode(), "Unexpected status for %s.", test.name)
+		}
+	}
+}
+
+func TestAuthenticateRecordsAuthenticatedPrincipal(t *testing.T) {
+	request, err := http.NewRequest(http.MethodPost, "/v1/entry/1/approve", nil)
+	require.NoError(t, err, "Unable to create the request.")
+	request.SetBasicAuth("treasurer", "secret")
+	ctx, authenticated := withAuthenticated(context.Background())
+
+	_, jsherr := authenticate(ctx, BasicAuth(checkPassword), request)
+
+	assert.Nil(t, jsherr, "Unexpected error.")
+	assert.Equal(t, "treasurer", authenticated.Name, "The principal was not recorded.")
+}
+
+func TestAuthenticateDoesNotRecordClaimedUser(t *testing.T) {
+	request, err := http.NewRequest(http.MethodPost, "/v1/entry/1/approve", nil)
+	require.NoError(t, err, "Unable to create the request.")
+	request.SetBasicAuth("treasurer", "guess")
+	ctx, authenticated := withAuthenticated(context.Background())
+
+	authenticate(ctx, BasicAuth(checkPassword), request)
+
+	assert.Empty(t, authenticated.Name, "The claimed user was recorded.")
+}
@@ -0,0 +1,79 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sbosnick1/openacct/domain"
+)
+
+const (
+	acceptLanguageHeader  = "Accept-Language"
+	contentLanguageHeader = "Content-Language"
+	varyHeader            = "Vary"
+)
+
+// languageRange is one language range of an Accept-Language header and
+// its quality.
+type languageRange struct {
+	tag     string
+	quality float64
+}
+
+// negotiateLocale gives the locale in which to format the amounts of the
+// response to r. It is the locale of the language range of the request's
+// Accept-Language header with the highest quality for which there is a
+// locale, or the domain.DefaultLocale. It sets the Content-Language and
+// Vary headers of the response for the locale.
+func negotiateLocale(w http.ResponseWriter, r *http.Request) domain.Locale {
+	locale := domain.DefaultLocale()
+	for _, accepted := range parseAcceptLanguage(r.Header.Get(acceptLanguageHeader)) {
+		if found, ok := domain.LookupLocale(accepted.tag); ok {
+			locale = found
+			break
+		}
+	}
+
+	w.Header().Set(contentLanguageHeader, locale.Tag())
+	w.Header().Add(varyHeader, acceptLanguageHeader)
+	return locale
+}
+
+// parseAcceptLanguage parses the language ranges of an Accept-Language
+// header in the order of their quality, highest first. A range with a
+// quality of zero, which is not acceptable, and the "*" range are left
+// out.
+func parseAcceptLanguage(header string) []languageRange {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		accepted := languageRange{tag: strings.TrimSpace(fields[0]), quality: 1}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			quality, err := strconv.ParseFloat(param[2:], 64)
+			if err != nil {
+				quality = 0
+			}
+			accepted.quality = quality
+		}
+
+		if accepted.tag != "" && accepted.tag != "*" && accepted.quality > 0 {
+			ranges = append(ranges, accepted)
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	return ranges
+}
@@ -0,0 +1,38 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAcceptLanguageOrdersByQuality(t *testing.T) {
+	actual := parseAcceptLanguage("de;q=0.5, fr-CA, *;q=0.1, en;q=0.8, ja;q=0")
+
+	assert.Equal(t, []languageRange{{"fr-CA", 1}, {"en", 0.8}, {"de", 0.5}}, actual)
+}
+
+func TestNegotiateLocaleSkipsLanguagesWithoutLocale(t *testing.T) {
+	request, response := getRequestResponse(t, "/v1/fund/1/balances")
+	request.Header.Set("Accept-Language", "tlh, de-AT;q=0.9")
+
+	sut := negotiateLocale(response, request)
+
+	assert.Equal(t, "de-AT", sut.Tag(), "Unexpected locale.")
+	assert.Equal(t, "de-AT", response.Header().Get("Content-Language"), "Unexpected content language.")
+}
+
+func TestNegotiateLocaleWithoutAcceptLanguageIsDefault(t *testing.T) {
+	request, _ := getRequestResponse(t, "/v1/fund/1/balances")
+	response := httptest.NewRecorder()
+
+	sut := negotiateLocale(response, request)
+
+	assert.Equal(t, "en", sut.Tag(), "Unexpected locale.")
+	assert.Equal(t, "Accept-Language", response.Header().Get("Vary"), "Unexpected vary header.")
+}
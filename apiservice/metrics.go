@@ -0,0 +1,161 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sbosnick1/openacct/metrics"
+)
+
+// otherResource is the resource label for requests for paths that are not
+// one of the api service's resources.
+const otherResource = "other"
+
+// metricResources are the resource labels for the first path segment under
+// the api prefix. Other paths are labelled otherResource so that the number
+// of label values stays small whatever clients request.
+var metricResources = map[string]bool{
+	fundResourceType:         true,
+	accountResourceType:      true,
+	scheduleResourceType:     true,
+	entryResourceType:        true,
+	approvalRuleResourceType: true,
+	donorResourceType:        true,
+	receiptResourceType:      true,
+	holdingResourceType:      true,
+	operationsPath[1:]:       true,
+	openAPIPath[1:]:          true,
+}
+
+// apiMetrics are the metrics that the api service records.
+type apiMetrics struct {
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+func newAPIMetrics(registry prometheus.Registerer) *apiMetrics {
+	return &apiMetrics{
+		requests: metrics.Register(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "openacct_api_requests_total",
+			Help: "The number of requests to the api service.",
+		}, []string{"resource", "method", "status"})).(*prometheus.CounterVec),
+		latency: metrics.Register(registry, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "openacct_api_request_duration_seconds",
+			Help:    "The time taken to serve requests to the api service.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"resource", "method"})).(*prometheus.HistogramVec),
+		errors: metrics.Register(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "openacct_api_errors_total",
+			Help: "The number of JSON API errors reported by the api service.",
+		}, []string{"status"})).(*prometheus.CounterVec),
+	}
+}
+
+// instrumentedHandler records the apiMetrics for each request that it
+// forwards to next.
+type instrumentedHandler struct {
+	next    http.Handler
+	metrics *apiMetrics
+}
+
+func newInstrumentedHandler(registry prometheus.Registerer, next http.Handler) http.Handler {
+	if registry == nil {
+		return next
+	}
+
+	return &instrumentedHandler{next, newAPIMetrics(registry)}
+}
+
+func (h *instrumentedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	recorder := &metricsRecorder{ResponseWriter: w}
+	h.next.ServeHTTP(recorder, r)
+
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+
+	resource := metricResource(r.URL.Path)
+	h.metrics.requests.WithLabelValues(resource, r.Method, strconv.Itoa(recorder.status)).Inc()
+	h.metrics.latency.WithLabelValues(resource, r.Method).Observe(time.Since(start).Seconds())
+
+	if recorder.status >= http.StatusBadRequest {
+		for _, status := range errorStatuses(recorder.body.Bytes(), recorder.status) {
+			h.metrics.errors.WithLabelValues(status).Inc()
+		}
+	}
+}
+
+// metricResource gives the resource label for a request path.
+func metricResource(path string) string {
+	if !strings.HasPrefix(path, apiV1Prefix+"/") {
+		return otherResource
+	}
+
+	segment := strings.SplitN(strings.TrimPrefix(path, apiV1Prefix+"/"), "/", 2)[0]
+	if !metricResources[segment] {
+		return otherResource
+	}
+
+	return segment
+}
+
+// errorStatuses gives the status of each of the JSON API errors in body. An
+// error response whose body has no JSON API errors counts as one error with
+// the status of the response.
+func errorStatuses(body []byte, status int) []string {
+	var document struct {
+		Errors []struct {
+			Status string `json:"status"`
+		} `json:"errors"`
+	}
+
+	if json.Unmarshal(body, &document) != nil || len(document.Errors) == 0 {
+		return []string{strconv.Itoa(status)}
+	}
+
+	var statuses []string
+	for _, e := range document.Errors {
+		if e.Status == "" {
+			e.Status = strconv.Itoa(status)
+		}
+		statuses = append(statuses, e.Status)
+	}
+
+	return statuses
+}
+
+// metricsRecorder records the status of a response and, for an error
+// response, a copy of its body.
+type metricsRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (m *metricsRecorder) WriteHeader(status int) {
+	if m.status == 0 {
+		m.status = status
+	}
+	m.ResponseWriter.WriteHeader(status)
+}
+
+func (m *metricsRecorder) Write(data []byte) (int, error) {
+	if m.status == 0 {
+		m.status = http.StatusOK
+	}
+	if m.status >= http.StatusBadRequest {
+		m.body.Write(data)
+	}
+	return m.ResponseWriter.Write(data)
+}
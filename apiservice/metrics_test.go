@@ -0,0 +1,90 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveInstrumented(t *testing.T, registry prometheus.Registerer, method string, path string,
+	handler http.HandlerFunc) {
+	request, response := getRequestResponse(t, path)
+	request.Method = method
+
+	sut := newInstrumentedHandler(registry, handler)
+	sut.ServeHTTP(response, request)
+}
+
+func TestInstrumentedHandlerCountsRequests(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	handler := func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	serveInstrumented(t, registry, http.MethodPost, "/v1/fund", handler)
+	serveInstrumented(t, registry, http.MethodPost, "/v1/fund", handler)
+
+	requests := newAPIMetrics(registry).requests
+	assert.Equal(t, float64(2), testutil.ToFloat64(requests.WithLabelValues(fundResourceType, http.MethodPost, "201")), "Unexpected request count.")
+}
+
+func TestInstrumentedHandlerCountsErrorsByStatus(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	handler := func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(StatusUnprocessableEntity)
+		w.Write([]byte(`{"errors":[{"status":"422"},{"status":"409"}]}`))
+	}
+
+	serveInstrumented(t, registry, http.MethodPatch, "/v1/account/1", handler)
+
+	errors := newAPIMetrics(registry).errors
+	assert.Equal(t, float64(1), testutil.ToFloat64(errors.WithLabelValues("422")), "Unexpected 422 error count.")
+	assert.Equal(t, float64(1), testutil.ToFloat64(errors.WithLabelValues("409")), "Unexpected 409 error count.")
+}
+
+func TestInstrumentedHandlerCountsErrorWithoutDocument(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	handler := func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}
+
+	serveInstrumented(t, registry, http.MethodGet, "/unknown", handler)
+
+	apiMetrics := newAPIMetrics(registry)
+	assert.Equal(t, float64(1), testutil.ToFloat64(apiMetrics.errors.WithLabelValues("404")), "Unexpected error count.")
+	assert.Equal(t, float64(1), testutil.ToFloat64(apiMetrics.requests.WithLabelValues(otherResource, http.MethodGet, "404")),
+		"Unexpected request count.")
+}
+
+func TestNewInstrumentedHandlerWithoutRegistryIsNext(t *testing.T) {
+	next := http.NotFoundHandler()
+
+	sut := newInstrumentedHandler(nil, next)
+
+	assert.NotNil(t, sut, "No handler.")
+	_, instrumented := sut.(*instrumentedHandler)
+	assert.False(t, instrumented, "The handler was instrumented without a registry.")
+}
+
+func TestMetricResourceLabelsKnownResources(t *testing.T) {
+	cases := map[string]string{
+		"/v1/fund":            "fund",
+		"/v1/fund/1/accounts": "fund",
+		"/v1/account/2":       "account",
+		"/v1/operations":      "operations",
+		"/v1/openapi.json":    "openapi.json",
+		"/v1/unknown/3":       otherResource,
+		"/fund":               otherResource,
+	}
+
+	for path, expected := range cases {
+		assert.Equal(t, expected, metricResource(path), "Unexpected resource for %s.", path)
+	}
+}
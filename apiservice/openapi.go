@@ -0,0 +1,669 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/sbosnick1/openacct/domain"
+	"goji.io"
+	"golang.org/x/net/context"
+)
+
+const (
+	openAPIPath        = "/openapi.json"
+	openAPIVersion     = "3.0.0"
+	openAPIContentType = "application/json"
+)
+
+// A resourceDescription describes a resource type for the OpenAPI
+// document. The attributes and patchAttributes are the (zero) structs that
+// the store for the resource type unmarshals; a resource type that can't be
+// updated has no patchAttributes. The methods are the HTTP
+// methods that the collection and the individual resources support. The
+// deleteSummary describes what deleting a resource does.
+type resourceDescription struct {
+	resourceType      string
+	summary           string
+	attributes        interface{}
+	patchAttributes   interface{}
+	collectionMethods []string
+	resourceMethods   []string
+	deleteSummary     string
+}
+
+// resourceDescriptions describes every resource that newApi adds to the
+// api service.
+var resourceDescriptions = []resourceDescription{
+	{
+		resourceType:      fundResourceType,
+		summary:           "A named collection of accounts that are all denominated in the same currency.",
+		attributes:        fundAttributes{},
+		patchAttributes:   fundPatchAttributes{},
+		collectionMethods: []string{http.MethodGet, http.MethodPost},
+		resourceMethods:   []string{http.MethodGet, http.MethodPatch, http.MethodDelete},
+		deleteSummary:     "Delete (archive) a fund.",
+	},
+	{
+		resourceType:      accountResourceType,
+		summary:           "A named account within a fund.",
+		attributes:        accountAttributes{},
+		patchAttributes:   accountPatchAttributes{},
+		collectionMethods: []string{http.MethodGet, http.MethodPost},
+		resourceMethods:   []string{http.MethodGet, http.MethodPatch},
+	},
+	{
+		resourceType:      scheduleResourceType,
+		summary:           "A template for an entry in a fund that recurs on the dates given by its rule.",
+		attributes:        scheduleAttributes{},
+		patchAttributes:   schedulePatchAttributes{},
+		collectionMethods: []string{http.MethodGet, http.MethodPost},
+		resourceMethods:   []string{http.MethodGet, http.MethodPatch, http.MethodDelete},
+		deleteSummary:     "Delete a schedule. The entries that it made are kept.",
+	},
+	{
+		resourceType:      entryResourceType,
+		summary:           "A journal entry in a fund that counts toward the balances once it is approved.",
+		attributes:        entryAttributes{},
+		patchAttributes:   entryPatchAttributes{},
+		collectionMethods: []string{http.MethodGet, http.MethodPost},
+		resourceMethods:   []string{http.MethodGet, http.MethodPatch, http.MethodDelete},
+		deleteSummary:     "Delete an entry that has not been posted.",
+	},
+	{
+		resourceType:      approvalRuleResourceType,
+		summary:           "A rule that entries in a fund of at least the threshold must be approved.",
+		attributes:        approvalRuleAttributes{},
+		collectionMethods: []string{http.MethodGet, http.MethodPost},
+		resourceMethods:   []string{http.MethodGet, http.MethodDelete},
+	},
+	{
+		resourceType:      donorResourceType,
+		summary:           "A person or organization whose gifts are credited to the income accounts of the funds.",
+		attributes:        donorAttributes{},
+		patchAttributes:   donorPatchAttributes{},
+		collectionMethods: []string{http.MethodGet, http.MethodPost},
+		resourceMethods:   []string{http.MethodGet, http.MethodPatch},
+	},
+	{
+		resourceType:      receiptResourceType,
+		summary:           "A numbered year-end receipt for the gifts of a donor to a fund that is voided instead of changed.",
+		attributes:        receiptAttributes{},
+		collectionMethods: []string{http.MethodGet},
+		resourceMethods:   []string{http.MethodGet},
+	},
+	{
+		resourceType:      holdingResourceType,
+		summary:           "A quantity of a unit other than its fund's currency, such as gold or shares, that a fund holds.",
+		attributes:        holdingAttributes{},
+		patchAttributes:   holdingPatchAttributes{},
+		collectionMethods: []string{http.MethodGet, http.MethodPost},
+		resourceMethods:   []string{http.MethodGet, http.MethodPatch, http.MethodDelete},
+	},
+}
+
+// validatorSchemas adds the constraints of a govalidator validator to the
+// schema of an attribute.
+var validatorSchemas = map[string]func(schema map[string]interface{}){
+	"utfletternum": func(schema map[string]interface{}) {
+		schema["pattern"] = `^[\p{L}\p{N}]+$`
+	},
+	"currency": func(schema map[string]interface{}) {
+		var codes []string
+		for _, currency := range domain.Currencies() {
+			codes = append(codes, currency.String())
+		}
+		schema["enum"] = codes
+	},
+	"currentcurrency": func(schema map[string]interface{}) {
+		var codes []string
+		for _, currency := range domain.Currencies() {
+			if _, withdrawn := currency.Withdrawn(); !withdrawn {
+				codes = append(codes, currency.String())
+			}
+		}
+		schema["enum"] = codes
+	},
+	"accounttype": func(schema map[string]interface{}) {
+		var names []string
+		for _, accountType := range domain.AccountTypes() {
+			names = append(names, accountType.String())
+		}
+		schema["enum"] = names
+	},
+	"charttemplate": func(schema map[string]interface{}) {
+		var ids []string
+		for _, template := range domain.ChartTemplates() {
+			ids = append(ids, template.ID)
+		}
+		schema["enum"] = ids
+		schema["writeOnly"] = true
+	},
+	"recurrence": func(schema map[string]interface{}) {
+		schema["description"] = "An iCalendar RRULE such as FREQ=MONTHLY;BYMONTHDAY=1."
+	},
+	"date": func(schema map[string]interface{}) {
+		schema["format"] = "date"
+	},
+	"email": func(schema map[string]interface{}) {
+		schema["format"] = "email"
+	},
+}
+
+// newOpenAPIHandler creates a handler that serves the OpenAPI document for
+// the described resources.
+func newOpenAPIHandler(descriptions []resourceDescription, relationships relationshipMap) goji.Handler {
+	document, err := json.Marshal(newOpenAPIDocument(descriptions, relationships))
+
+	return goji.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			jsh.Send(w, r, jsh.ISE(err.Error()))
+			return
+		}
+
+		w.Header().Set("Content-Type", openAPIContentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(document)
+	})
+}
+
+// newOpenAPIDocument creates the OpenAPI document for the described
+// resources. The paths in the document are relative to the "/v1" server.
+func newOpenAPIDocument(descriptions []resourceDescription, relationships relationshipMap) map[string]interface{} {
+	paths := make(map[string]interface{})
+	schemas := map[string]interface{}{
+		"error":           errorSchema(),
+		"errors":          errorsSchema(),
+		"relationship":    relationshipSchema(),
+		"atomic-document": atomicDocumentSchema(),
+	}
+
+	for _, description := range descriptions {
+		resourceType := description.resourceType
+		resource := resourceSchema(resourceType, resourceType+"-attributes", relationships[resourceType])
+		resource["description"] = description.summary
+		schemas[resourceType] = resource
+		schemas[resourceType+"-attributes"] = attributesSchema(description.attributes, true)
+		if description.patchAttributes != nil {
+			schemas[resourceType+"-patch"] = resourceSchema(resourceType, resourceType+"-patch-attributes", nil)
+			schemas[resourceType+"-patch-attributes"] = attributesSchema(description.patchAttributes, false)
+		}
+
+		collection := make(map[string]interface{})
+		for _, method := range description.collectionMethods {
+			collection[strings.ToLower(method)] = collectionOperation(description, method)
+		}
+		paths["/"+resourceType] = collection
+
+		item := map[string]interface{}{"parameters": []interface{}{idParameter()}}
+		for _, method := range description.resourceMethods {
+			item[strings.ToLower(method)] = resourceOperation(description, method)
+		}
+		paths["/"+resourceType+"/{id}"] = item
+
+		for name, relatedType := range relationships[resourceType] {
+			paths["/"+resourceType+"/{id}/"+name] = map[string]interface{}{
+				"parameters": []interface{}{idParameter()},
+				"get": map[string]interface{}{
+					"summary":   "Get the " + name + " related to a " + resourceType + ".",
+					"responses": responses(http.StatusOK, documentSchema(relatedType, true)),
+				},
+			}
+			paths["/"+resourceType+"/{id}/relationships/"+name] = map[string]interface{}{
+				"parameters": []interface{}{idParameter()},
+				"get": map[string]interface{}{
+					"summary": "Get the " + name + " relationship of a " + resourceType + ".",
+					"responses": responses(http.StatusOK, map[string]interface{}{
+						"$ref": "#/components/schemas/relationship",
+					}),
+				},
+			}
+		}
+	}
+
+	paths["/"+scheduleResourceType+"/{id}"+occurrencesPath] = map[string]interface{}{
+		"parameters": []interface{}{idParameter()},
+		"get": map[string]interface{}{
+			"summary": "Preview the next occurrences of a schedule in the occurrences member of meta.",
+			"parameters": []interface{}{map[string]interface{}{
+				"name":   countParameter,
+				"in":     "query",
+				"schema": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": maxOccurrenceCount},
+			}},
+			"responses": responses(http.StatusOK, map[string]interface{}{"type": "object"}),
+		},
+	}
+
+	for action, summary := range map[string]string{
+		submitPath:  "Submit a draft entry for approval.",
+		approvePath: "Approve a pending entry.",
+		rejectPath:  "Reject a pending entry.",
+	} {
+		paths["/"+entryResourceType+"/{id}"+action] = map[string]interface{}{
+			"parameters": []interface{}{idParameter()},
+			"post": map[string]interface{}{
+				"summary":    summary,
+				"parameters": []interface{}{headerParameter(ifMatchHeader, true)},
+				"responses":  responses(http.StatusOK, documentSchema(entryResourceType, false)),
+			},
+		}
+	}
+
+	for action, summary := range map[string]string{
+		reversePath: "Reverse a posted entry with a linked mirror entry on the date of the request's entry.",
+		correctPath: "Reverse a posted entry and replace it with the request's entry.",
+	} {
+		paths["/"+entryResourceType+"/{id}"+action] = map[string]interface{}{
+			"parameters": []interface{}{idParameter()},
+			"post": map[string]interface{}{
+				"summary":     summary,
+				"parameters":  []interface{}{headerParameter(ifMatchHeader, true)},
+				"requestBody": jsonContent(documentSchema(entryResourceType, false)),
+				"responses":   responses(http.StatusCreated, documentSchema(entryResourceType, false)),
+			},
+		}
+	}
+
+	paths["/"+fundResourceType+"/{id}"+balancesPath] = map[string]interface{}{
+		"parameters": []interface{}{idParameter()},
+		"get": map[string]interface{}{
+			"summary":    "Get the balances of the accounts of a fund from its approved entries in the balances member of meta.",
+			"parameters": []interface{}{headerParameter(acceptLanguageHeader, false)},
+			"responses":  responses(http.StatusOK, map[string]interface{}{"type": "object"}),
+		},
+	}
+
+	for resourceType, summary := range map[string]string{
+		donorResourceType: "Get the gifts of a donor and their totals by fund and year in the gifts and totals members of meta.",
+		fundResourceType:  "Get the gifts to a fund and their totals by donor and year in the gifts and totals members of meta.",
+	} {
+		paths["/"+resourceType+"/{id}"+givingPath] = map[string]interface{}{
+			"parameters": []interface{}{idParameter()},
+			"get": map[string]interface{}{
+				"summary":    summary,
+				"parameters": []interface{}{headerParameter(acceptLanguageHeader, false)},
+				"responses":  responses(http.StatusOK, map[string]interface{}{"type": "object"}),
+			},
+		}
+	}
+
+	yearParameter := map[string]interface{}{
+		"name":   "year",
+		"in":     "query",
+		"schema": map[string]interface{}{"type": "integer", "minimum": minReceiptYear, "maximum": maxReceiptYear},
+	}
+	paths["/"+receiptResourceType+issuePath] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"summary":     "Issue the receipts still to be issued for the year of the request's receipt.",
+			"requestBody": jsonContent(documentSchema(receiptResourceType, false)),
+			"responses":   responses(http.StatusCreated, documentSchema(receiptResourceType, true)),
+		},
+	}
+	paths["/"+receiptResourceType+exportPath] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"summary":    "Export the receipts of a year that are not void as a zip file of PDF documents.",
+			"parameters": []interface{}{yearParameter, headerParameter(acceptLanguageHeader, false)},
+			"responses":  fileResponses(zipContentType),
+		},
+	}
+	paths["/"+receiptResourceType+"/{id}"+voidPath] = map[string]interface{}{
+		"parameters": []interface{}{idParameter()},
+		"post": map[string]interface{}{
+			"summary":     "Void a receipt for the void-reason of the request's receipt.",
+			"parameters":  []interface{}{headerParameter(ifMatchHeader, true)},
+			"requestBody": jsonContent(documentSchema(receiptResourceType, false)),
+			"responses":   responses(http.StatusOK, documentSchema(receiptResourceType, false)),
+		},
+	}
+	paths["/"+receiptResourceType+"/{id}"+reissuePath] = map[string]interface{}{
+		"parameters": []interface{}{idParameter()},
+		"post": map[string]interface{}{
+			"summary":    "Void a receipt and replace it with a new receipt for the current gifts.",
+			"parameters": []interface{}{headerParameter(ifMatchHeader, true)},
+			"responses":  responses(http.StatusCreated, documentSchema(receiptResourceType, false)),
+		},
+	}
+	paths["/"+receiptResourceType+"/{id}"+pdfPath] = map[string]interface{}{
+		"parameters": []interface{}{idParameter()},
+		"get": map[string]interface{}{
+			"summary":    "Get a receipt as a PDF document.",
+			"parameters": []interface{}{headerParameter(acceptLanguageHeader, false)},
+			"responses":  fileResponses(pdfContentType),
+		},
+	}
+
+	paths[operationsPath] = map[string]interface{}{
+		"post": map[string]interface{}{
+			"summary": "Perform a list of add, update and remove operations atomically.",
+			"description": "Only the fund (add, update and remove) and account (add and update) " +
+				"resource types are supported. An operation on any other resource type is a " +
+				"400 Unsupported Operation error.",
+			"requestBody": jsonContent(map[string]interface{}{
+				"$ref": "#/components/schemas/atomic-document",
+			}),
+			"responses": responses(http.StatusOK, map[string]interface{}{"type": "object"}),
+		},
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":   "openacct",
+			"version": strings.TrimPrefix(apiV1Prefix, "/"),
+		},
+		"servers":    []interface{}{map[string]interface{}{"url": apiV1Prefix}},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas},
+	}
+}
+
+func collectionOperation(description resourceDescription, method string) map[string]interface{} {
+	resourceType := description.resourceType
+	switch method {
+	case http.MethodPost:
+		return map[string]interface{}{
+			"summary":     "Create a " + resourceType + ".",
+			"parameters":  []interface{}{headerParameter(idempotencyKeyHeader, false)},
+			"requestBody": jsonContent(documentSchema(resourceType, false)),
+			"responses":   responses(http.StatusCreated, documentSchema(resourceType, false)),
+		}
+	default:
+		return map[string]interface{}{
+			"summary":    "List the " + resourceType + " resources.",
+			"parameters": []interface{}{queryParameter(includeParameter)},
+			"responses":  responses(http.StatusOK, documentSchema(resourceType, true)),
+		}
+	}
+}
+
+func resourceOperation(description resourceDescription, method string) map[string]interface{} {
+	resourceType := description.resourceType
+	switch method {
+	case http.MethodPatch:
+		return map[string]interface{}{
+			"summary":    "Update a " + resourceType + ".",
+			"parameters": []interface{}{headerParameter(ifMatchHeader, true)},
+			"requestBody": jsonContent(map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"data": schemaRef(resourceType + "-patch")},
+			}),
+			"responses": responses(http.StatusOK, documentSchema(resourceType, false)),
+		}
+	case http.MethodDelete:
+		summary := description.deleteSummary
+		if summary == "" {
+			summary = "Delete a " + resourceType + "."
+		}
+		return map[string]interface{}{
+			"summary":    summary,
+			"parameters": []interface{}{headerParameter(ifMatchHeader, true)},
+			"responses":  responses(http.StatusOK, documentSchema(resourceType, false)),
+		}
+	default:
+		return map[string]interface{}{
+			"summary":    "Get a " + resourceType + ".",
+			"parameters": []interface{}{queryParameter(includeParameter)},
+			"responses":  responses(http.StatusOK, documentSchema(resourceType, false)),
+		}
+	}
+}
+
+// attributesSchema creates the schema for a struct of attributes from the
+// json and valid tags of its fields. The attributes that a client can't set
+// (valid:"-") are read only in the schema of a created resource.
+func attributesSchema(attributes interface{}, created bool) map[string]interface{} {
+	return objectSchema(reflect.TypeOf(attributes), created)
+}
+
+// objectSchema creates the schema for a struct type in the manner of
+// attributesSchema.
+func objectSchema(attributesType reflect.Type, created bool) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+
+	for i := 0; i < attributesType.NumField(); i++ {
+		field := attributesType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		property := typeSchema(field.Type)
+		for _, validator := range strings.Split(field.Tag.Get("valid"), ",") {
+			switch validator {
+			case "":
+			case "-":
+				if created {
+					property["readOnly"] = true
+				}
+			case "required":
+				required = append(required, name)
+			default:
+				if schema, ok := validatorSchemas[validator]; ok {
+					schema(property)
+				} else {
+					property["x-validators"] = append(stringsValue(property["x-validators"]), validator)
+				}
+			}
+		}
+
+		properties[name] = property
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// typeSchema gives the schema for the Go type of an attribute.
+func typeSchema(fieldType reflect.Type) map[string]interface{} {
+	switch fieldType.Kind() {
+	case reflect.Ptr:
+		return typeSchema(fieldType.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(fieldType.Elem())}
+	case reflect.Struct:
+		return objectSchema(fieldType, false)
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+func resourceSchema(resourceType string, attributesSchema string, relationships map[string]string) map[string]interface{} {
+	properties := map[string]interface{}{
+		"type":       map[string]interface{}{"type": "string", "enum": []string{resourceType}},
+		"id":         map[string]interface{}{"type": "string"},
+		"attributes": schemaRef(attributesSchema),
+	}
+
+	if len(relationships) > 0 {
+		relationshipProperties := make(map[string]interface{})
+		for name := range relationships {
+			relationshipProperties[name] = schemaRef("relationship")
+		}
+		properties["relationships"] = map[string]interface{}{
+			"type":       "object",
+			"properties": relationshipProperties,
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"required":   []string{"type"},
+		"properties": properties,
+	}
+}
+
+func documentSchema(resourceType string, list bool) map[string]interface{} {
+	data := schemaRef(resourceType)
+	if list {
+		data = map[string]interface{}{"type": "array", "items": data}
+	}
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data":     data,
+			"included": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+		},
+	}
+}
+
+func relationshipSchema() map[string]interface{} {
+	identifier := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"type": map[string]interface{}{"type": "string"},
+			"id":   map[string]interface{}{"type": "string"},
+		},
+	}
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"links": map[string]interface{}{"type": "object"},
+			"data":  map[string]interface{}{"type": "array", "items": identifier},
+		},
+	}
+}
+
+func errorSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"status": map[string]interface{}{"type": "string"},
+			"title":  map[string]interface{}{"type": "string"},
+			"detail": map[string]interface{}{"type": "string"},
+			"source": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pointer": map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+	}
+}
+
+func errorsSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"errors": map[string]interface{}{"type": "array", "items": schemaRef("error")},
+		},
+	}
+}
+
+func atomicDocumentSchema() map[string]interface{} {
+	operation := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"op"},
+		"properties": map[string]interface{}{
+			"op":   map[string]interface{}{"type": "string", "enum": []string{addOperation, updateOperation, removeOperation}},
+			"ref":  map[string]interface{}{"type": "object"},
+			"data": map[string]interface{}{"type": "object"},
+			"meta": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"version": map[string]interface{}{"type": "integer"},
+				},
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			atomicOperationsMember: map[string]interface{}{"type": "array", "items": operation},
+		},
+	}
+}
+
+// responses gives the responses of an operation: its successful response
+// and the JSON API errors that any operation may respond with.
+func responses(status int, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		strconv.Itoa(status): jsonContentWithDescription(http.StatusText(status), schema),
+		"default":            jsonContentWithDescription("A JSON API error document.", schemaRef("errors")),
+	}
+}
+
+// fileResponses gives the responses of an operation that responds with a
+// file of contentType.
+func fileResponses(contentType string) map[string]interface{} {
+	return map[string]interface{}{
+		strconv.Itoa(http.StatusOK): map[string]interface{}{
+			"description": http.StatusText(http.StatusOK),
+			"content":     map[string]interface{}{contentType: map[string]interface{}{}},
+		},
+		"default": jsonContentWithDescription("A JSON API error document.", schemaRef("errors")),
+	}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"content": map[string]interface{}{
+			jsh.ContentType: map[string]interface{}{"schema": schema},
+		},
+	}
+}
+
+func jsonContentWithDescription(description string, schema map[string]interface{}) map[string]interface{} {
+	content := jsonContent(schema)
+	content["description"] = description
+	return content
+}
+
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func idParameter() map[string]interface{} {
+	return map[string]interface{}{
+		"name":     "id",
+		"in":       "path",
+		"required": true,
+		"schema":   map[string]interface{}{"type": "string"},
+	}
+}
+
+func headerParameter(name string, required bool) map[string]interface{} {
+	return map[string]interface{}{
+		"name":     name,
+		"in":       "header",
+		"required": required,
+		"schema":   map[string]interface{}{"type": "string"},
+	}
+}
+
+func queryParameter(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":   name,
+		"in":     "query",
+		"schema": map[string]interface{}{"type": "string"},
+	}
+}
+
+func stringsValue(value interface{}) []string {
+	values, _ := value.([]string)
+	return values
+}
@@ -0,0 +1,120 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func describedTypes() map[string]resourceDescription {
+	described := make(map[string]resourceDescription)
+	for _, description := range resourceDescriptions {
+		described[description.resourceType] = description
+	}
+	return described
+}
+
+func TestEveryResourceOfNewApiIsDescribed(t *testing.T) {
+	described := describedTypes()
+
+	sut := newApi(&fakeStore{})
+
+	require.NotEmpty(t, sut.Resources, "newApi() added no resources.")
+	for resourceType := range sut.Resources {
+		assert.Contains(t, described, resourceType,
+			"The %s resource is not in resourceDescriptions.", resourceType)
+	}
+	assert.Len(t, resourceDescriptions, len(sut.Resources),
+		"resourceDescriptions describes a resource that newApi() does not add.")
+}
+
+func TestEveryRelatedResourceIsDescribed(t *testing.T) {
+	described := describedTypes()
+
+	for resourceType, relationships := range resourceRelationships {
+		assert.Contains(t, described, resourceType, "The %s resource is not described.", resourceType)
+		for name, relatedType := range relationships {
+			assert.Contains(t, described, relatedType,
+				"The %s relationship of %s is to an undescribed resource.", name, resourceType)
+		}
+	}
+}
+
+func TestEveryValidatorOfDescribedAttributesHasSchema(t *testing.T) {
+	for _, description := range resourceDescriptions {
+		for _, attributes := range []interface{}{description.attributes, description.patchAttributes} {
+			if attributes == nil {
+				continue
+			}
+			attributesType := reflect.TypeOf(attributes)
+			for i := 0; i < attributesType.NumField(); i++ {
+				for _, validator := range strings.Split(attributesType.Field(i).Tag.Get("valid"), ",") {
+					switch validator {
+					case "", "-", "required":
+					default:
+						assert.Contains(t, validatorSchemas, validator,
+							"The %s validator on %s has no schema.", validator, attributesType.Name())
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestAttributesSchemaDescribesConstraints(t *testing.T) {
+	assert := assert.New(t)
+
+	sut := attributesSchema(fundAttributes{}, true)
+
+	properties := sut["properties"].(map[string]interface{})
+	assert.Equal([]string{"name", "currency"}, sut["required"], "Unexpected required attributes.")
+	assert.Equal(`^[\p{L}\p{N}]+$`, properties["name"].(map[string]interface{})["pattern"],
+		"Unexpected pattern for name.")
+	assert.Contains(properties["currency"].(map[string]interface{})["enum"], "CAD",
+		"The currency enum is missing CAD.")
+	assert.Equal("boolean", properties["archived"].(map[string]interface{})["type"],
+		"Unexpected type for archived.")
+	assert.Equal(true, properties["archived"].(map[string]interface{})["readOnly"],
+		"archived is unexpectedly writable.")
+}
+
+func TestAttributesSchemaDescribesListsOfObjects(t *testing.T) {
+	sut := attributesSchema(scheduleAttributes{}, true)
+
+	postings := sut["properties"].(map[string]interface{})["postings"].(map[string]interface{})
+	require.Equal(t, "array", postings["type"], "Unexpected type for postings.")
+	items := postings["items"].(map[string]interface{})
+	assert.Equal(t, "object", items["type"], "Unexpected type for a posting.")
+	assert.Contains(t, items["properties"], "account", "A posting has no account.")
+	assert.Contains(t, items["properties"], "amount", "A posting has no amount.")
+}
+
+func TestNewApiServesOpenAPIDocument(t *testing.T) {
+	assert := assert.New(t)
+	request, response := getRequestResponse(t, "/v1/openapi.json")
+
+	sut := newApi(&fakeStore{})
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	var document struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &document), "Unable to parse the document.")
+	assert.Equal("3.0.0", document.OpenAPI, "Unexpected OpenAPI version.")
+	for _, path := range []string{"/fund", "/fund/{id}", "/fund/{id}/accounts",
+		"/account/{id}/relationships/fund", "/operations"} {
+		assert.Contains(document.Paths, path, "The document does not describe %s.", path)
+	}
+}
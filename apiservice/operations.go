@@ -0,0 +1,365 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/sbosnick1/openacct/domain"
+	"golang.org/x/net/context"
+)
+
+const (
+	operationsPath = "/operations"
+
+	atomicExtension        = "https://jsonapi.org/ext/atomic"
+	atomicOperationsMember = "atomic:operations"
+
+	addOperation    = "add"
+	updateOperation = "update"
+	removeOperation = "remove"
+)
+
+// An operationTarget holds the store functions that carry out operations
+// on one resource type. A nil function is an operation that the resource
+// type does not support. Only funds (add, update and remove) and accounts
+// (add and update) are operation targets; an operation on any other
+// resource type is a 400 Unsupported Operation error.
+type operationTarget struct {
+	save   func(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType)
+	update func(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType)
+	remove func(ctx context.Context, id string) jsh.ErrorType
+}
+
+// newOperationTargets gives the operation targets for the funds and
+// accounts whose repositories come from store.
+func newOperationTargets(store domain.Store) map[string]operationTarget {
+	funds := &fundStore{store.FundRepository(), store.AccountRepository()}
+	accounts := &accountStore{store.AccountRepository(), funds}
+
+	return map[string]operationTarget{
+		fundResourceType:    {funds.Save, funds.Update, funds.Delete},
+		accountResourceType: {accounts.Save, accounts.Update, nil},
+	}
+}
+
+// operationsRequest is the body of a request to the operations endpoint in
+// the style of the JSON API atomic operations extension.
+type operationsRequest struct {
+	Operations []*operation `json:"atomic:operations"`
+}
+
+type operationsResponse struct {
+	Results []*operationResult `json:"atomic:results"`
+}
+
+type operationResult struct {
+	Data *jsh.Object `json:"data,omitempty"`
+}
+
+// An operation adds, updates or removes one resource. The resource is
+// identified by ref or, for add and update, by data. A resource added by
+// an earlier operation can be identified by the lid that it was added with.
+// The version in meta is the version that an update or remove requires.
+type operation struct {
+	Op   string              `json:"op"`
+	Ref  *operationReference `json:"ref,omitempty"`
+	Data *operationData      `json:"data,omitempty"`
+	Meta struct {
+		Version *uint `json:"version,omitempty"`
+	} `json:"meta"`
+}
+
+type operationReference struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	LID  string `json:"lid,omitempty"`
+}
+
+type operationData struct {
+	resourceObject
+	LID string `json:"lid,omitempty"`
+}
+
+// An operationError is the error from the operation at index that caused
+// all of the operations to be rolled back.
+type operationError struct {
+	index  int
+	jsherr jsh.ErrorType
+}
+
+func (e *operationError) Error() string {
+	return fmt.Sprintf("operation %d failed: %s", e.index, e.jsherr.Error())
+}
+
+// operationsHandler performs a list of operations within one transaction
+// of its store. Either all of the operations succeed and the response has
+// the result of each of them or the first one to fail is reported and none
+// of them take effect.
+type operationsHandler struct {
+	store domain.Store
+}
+
+func (h *operationsHandler) ServeHTTPC(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		jsh.Send(w, r, jsh.ISE("operationsHandler requires a Store"))
+		return
+	}
+
+	var request operationsRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.Operations == nil {
+		jsh.Send(w, r, newStatusError(http.StatusBadRequest, "Invalid Operations",
+			"The request must have a list of "+atomicOperationsMember+"."))
+		return
+	}
+
+	// The operations are not the subject of the request so they must not
+	// set response headers such as the ETag.
+	ctx = context.WithValue(ctx, responseContextKey, nil)
+
+	var results []*operationResult
+	err = h.store.InTransaction(ctx, func(tx domain.Store) error {
+		var operr error
+		results, operr = performOperations(ctx, newOperationTargets(tx), request.Operations)
+		return operr
+	})
+
+	switch e := err.(type) {
+	case nil:
+	case *operationError:
+		sendError(w, r, newOperationError(e))
+		return
+	default:
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	body, err := json.Marshal(operationsResponse{results})
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", jsh.ContentType+`; ext="`+atomicExtension+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// performOperations performs each of the operations in order. It stops at
+// the first operation that fails and returns an *operationError for it.
+func performOperations(ctx context.Context, targets map[string]operationTarget,
+	operations []*operation) ([]*operationResult, error) {
+	lids := make(map[string]string)
+
+	var results []*operationResult
+	for index, op := range operations {
+		result, jsherr := performOperation(ctx, targets, lids, op)
+		if jsherr != nil {
+			return nil, &operationError{index, jsherr}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func performOperation(ctx context.Context, targets map[string]operationTarget,
+	lids map[string]string, op *operation) (*operationResult, jsh.ErrorType) {
+	if op == nil {
+		return nil, invalidOperation("The operation must be an object.")
+	}
+
+	var ifMatch string
+	if op.Meta.Version != nil {
+		ifMatch = formatETag(*op.Meta.Version)
+	}
+	ctx = withIfMatch(ctx, ifMatch)
+
+	switch op.Op {
+	case addOperation:
+		object, jsherr := op.object(ctx, lids)
+		if jsherr != nil {
+			return nil, jsherr
+		}
+
+		target := targets[object.Type]
+		if target.save == nil {
+			return nil, unsupportedOperation(op.Op, object.Type)
+		}
+
+		added, jsherr := target.save(ctx, object)
+		if jsherr != nil {
+			return nil, jsherr
+		}
+
+		if op.Data.LID != "" {
+			lids[op.Data.LID] = added.ID
+		}
+		return &operationResult{added}, nil
+
+	case updateOperation:
+		object, jsherr := op.object(ctx, lids)
+		if jsherr != nil {
+			return nil, jsherr
+		}
+
+		if op.Ref != nil {
+			resourceType, id, jsherr := op.Ref.resolve(lids)
+			if jsherr != nil {
+				return nil, jsherr
+			}
+			if resourceType != object.Type {
+				return nil, invalidOperation("The ref and the data must be the same type of resource.")
+			}
+			object.ID = id
+		}
+
+		target := targets[object.Type]
+		if target.update == nil {
+			return nil, unsupportedOperation(op.Op, object.Type)
+		}
+
+		updated, jsherr := target.update(ctx, object)
+		if jsherr != nil {
+			return nil, jsherr
+		}
+		return &operationResult{updated}, nil
+
+	case removeOperation:
+		if op.Ref == nil {
+			return nil, invalidOperation("A remove operation must have a ref.")
+		}
+
+		resourceType, id, jsherr := op.Ref.resolve(lids)
+		if jsherr != nil {
+			return nil, jsherr
+		}
+
+		target := targets[resourceType]
+		if target.remove == nil {
+			return nil, unsupportedOperation(op.Op, resourceType)
+		}
+
+		jsherr = target.remove(ctx, id)
+		if jsherr != nil {
+			return nil, jsherr
+		}
+		return &operationResult{}, nil
+
+	default:
+		return nil, invalidOperation("The op must be one of add, update or remove.")
+	}
+}
+
+// object gives the data of the operation as an object with the lids in its
+// relationships replaced by the ids of the resources that they identify.
+func (op *operation) object(ctx context.Context, lids map[string]string) (*jsh.Object, jsh.ErrorType) {
+	if op.Data == nil {
+		return nil, invalidOperation("An " + op.Op + " operation must have data.")
+	}
+
+	data := op.Data.resourceObject
+	if data.ID == "" && op.Data.LID != "" {
+		if id, ok := lids[op.Data.LID]; ok {
+			data.ID = id
+		}
+	}
+
+	for name, relationship := range data.Relationships {
+		identifiers, err := relationship.identifiers()
+		if err != nil {
+			return nil, invalidOperation("The " + name + " relationship is malformed.")
+		}
+
+		for i := range identifiers {
+			if identifiers[i].ID != "" {
+				continue
+			}
+
+			id, ok := lids[identifiers[i].LID]
+			if !ok {
+				return nil, unknownLID(identifiers[i].LID)
+			}
+			identifiers[i].ID = id
+			identifiers[i].LID = ""
+		}
+
+		relationship.Data, err = json.Marshal(identifiers)
+		if err != nil {
+			return nil, newJshError(ctx, err)
+		}
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	var object jsh.Object
+	err = json.Unmarshal(encoded, &object)
+	if err != nil {
+		return nil, invalidOperation("The data is not a resource object.")
+	}
+
+	return &object, nil
+}
+
+// resolve gives the type and the id of the resource that the reference
+// identifies.
+func (ref *operationReference) resolve(lids map[string]string) (string, string, jsh.ErrorType) {
+	if ref.ID != "" {
+		return ref.Type, ref.ID, nil
+	}
+
+	id, ok := lids[ref.LID]
+	if !ok {
+		return "", "", unknownLID(ref.LID)
+	}
+
+	return ref.Type, id, nil
+}
+
+// newOperationError gives the error to report for a failed operation. Its
+// source points at the operation that failed.
+func newOperationError(e *operationError) jsh.ErrorType {
+	status := e.jsherr.StatusCode()
+	if status >= http.StatusInternalServerError {
+		return e.jsherr
+	}
+
+	title := http.StatusText(status)
+	detail := e.jsherr.Error()
+	switch original := e.jsherr.(type) {
+	case *jsh.Error:
+		title, detail = original.Title, original.Detail
+	case jsh.ErrorList:
+		if len(original) > 0 {
+			title, detail = original[0].Title, original[0].Detail
+		}
+	}
+
+	jsherr := newAttributeError(status, title, detail, "")
+	jsherr.Source.Pointer = fmt.Sprintf("/%s/%d", atomicOperationsMember, e.index)
+	return jsherr
+}
+
+func invalidOperation(detail string) *jsh.Error {
+	return newStatusError(http.StatusBadRequest, "Invalid Operation", detail)
+}
+
+func unsupportedOperation(op string, resourceType string) *jsh.Error {
+	return newStatusError(http.StatusBadRequest, "Unsupported Operation",
+		fmt.Sprintf("The %s operation is not supported for the %q resource type.", op, resourceType))
+}
+
+func unknownLID(lid string) *jsh.Error {
+	return newStatusError(http.StatusBadRequest, "Unknown Local Identifier",
+		fmt.Sprintf("No earlier operation added a resource with lid %q.", lid))
+}
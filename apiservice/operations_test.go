@@ -0,0 +1,147 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type operationsDocument struct {
+	Results []struct {
+		Data *resourceObject `json:"data"`
+	} `json:"atomic:results"`
+	Errors []struct {
+		Status string `json:"status"`
+		Source struct {
+			Pointer string `json:"pointer"`
+		} `json:"source"`
+	} `json:"errors"`
+}
+
+func serveOperations(t *testing.T, body string) (*httptest.ResponseRecorder, *fakeStore) {
+	funds, _, accounts := newFakeAccountStores()
+	fakestore := &fakeStore{fundRepository: funds.repository, accountRepository: accounts}
+	request, err := http.NewRequest(http.MethodPost, "/v1/operations", strings.NewReader(body))
+	require.NoError(t, err, "Unable to create the request.")
+	response := httptest.NewRecorder()
+
+	sut := newApi(fakestore)
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	return response, fakestore
+}
+
+func parseOperationsDocument(t *testing.T, response *httptest.ResponseRecorder) *operationsDocument {
+	var document operationsDocument
+	err := json.Unmarshal(response.Body.Bytes(), &document)
+	require.NoError(t, err, "Unable to parse the operations response.")
+	return &document
+}
+
+func TestOperationsAddsFundAndAccountWithLid(t *testing.T) {
+	assert := assert.New(t)
+
+	response, fakestore := serveOperations(t, `{"atomic:operations": [
+		{"op": "add", "data": {"type": "fund", "lid": "new-fund",
+			"attributes": {"name": "Building", "currency": "CAD"}}},
+		{"op": "add", "data": {"type": "account",
+			"attributes": {"name": "Cash", "type": "asset"},
+			"relationships": {"fund": {"data": {"type": "fund", "lid": "new-fund"}}}}}
+	]}`)
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	assert.Equal(1, fakestore.transactions, "The operations did not use one transaction.")
+	assert.False(fakestore.rolledBack, "The operations were unexpectedly rolled back.")
+	document := parseOperationsDocument(t, response)
+	require.Len(t, document.Results, 2, "Unexpected number of results.")
+	fundID := document.Results[0].Data.ID
+	identifiers, err := document.Results[1].Data.Relationships["fund"].identifiers()
+	require.NoError(t, err, "Unable to parse the fund of the added account.")
+	require.Len(t, identifiers, 1, "Unexpected fund linkage for the added account.")
+	assert.Equal(fundID, identifiers[0].ID, "The added account is not in the added fund.")
+}
+
+func TestOperationsUpdateUsesVersionFromMeta(t *testing.T) {
+	response, _ := serveOperations(t, `{"atomic:operations": [
+		{"op": "update", "meta": {"version": 1},
+			"data": {"type": "account", "id": "1", "attributes": {"name": "Petty Cash"}}}
+	]}`)
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	document := parseOperationsDocument(t, response)
+	require.Len(t, document.Results, 1, "Unexpected number of results.")
+	assert.Equal(t, `"Petty Cash"`, string(document.Results[0].Data.Attributes["name"]),
+		"The account was not updated.")
+}
+
+func TestOperationsRemoveArchivesFund(t *testing.T) {
+	response, fakestore := serveOperations(t, `{"atomic:operations": [
+		{"op": "remove", "ref": {"type": "fund", "id": "2"}, "meta": {"version": 1}}
+	]}`)
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	fund, err := fakestore.fundRepository.Get(context.Background(), 2)
+	require.NoError(t, err, "Unable to get the removed fund.")
+	assert.True(t, fund.IsArchived(), "Removing the fund did not archive it.")
+}
+
+func TestOperationsFailureRollsBackAndPointsAtOperation(t *testing.T) {
+	response, fakestore := serveOperations(t, `{"atomic:operations": [
+		{"op": "add", "data": {"type": "fund", "attributes": {"name": "Building", "currency": "CAD"}}},
+		{"op": "update", "data": {"type": "account", "id": "1", "attributes": {"name": "Petty Cash"}}}
+	]}`)
+
+	assert.Equal(t, StatusPreconditionRequired, response.Code, "Unexpected status code.")
+	assert.True(t, fakestore.rolledBack, "The operations were not rolled back.")
+	document := parseOperationsDocument(t, response)
+	require.Len(t, document.Errors, 1, "Unexpected number of errors.")
+	assert.Equal(t, "/atomic:operations/1", document.Errors[0].Source.Pointer,
+		"The error does not point at the failed operation.")
+}
+
+func TestOperationsWithUnsupportedOperationIsBadRequest(t *testing.T) {
+	response, fakestore := serveOperations(t, `{"atomic:operations": [
+		{"op": "remove", "ref": {"type": "account", "id": "1"}, "meta": {"version": 1}}
+	]}`)
+
+	assert.Equal(t, http.StatusBadRequest, response.Code, "Unexpected status code.")
+	assert.True(t, fakestore.rolledBack, "The operations were not rolled back.")
+}
+
+func TestOperationsOnUnsupportedResourceTypeIsBadRequest(t *testing.T) {
+	response, fakestore := serveOperations(t, `{"atomic:operations": [
+		{"op": "add", "data": {"type": "donor", "attributes": {"name": "Ada Lovelace"}}}
+	]}`)
+
+	assert.Equal(t, http.StatusBadRequest, response.Code, "Unexpected status code.")
+	assert.True(t, fakestore.rolledBack, "The operations were not rolled back.")
+}
+
+func TestOperationsWithUnknownLidIsBadRequest(t *testing.T) {
+	response, _ := serveOperations(t, `{"atomic:operations": [
+		{"op": "add", "data": {"type": "account",
+			"attributes": {"name": "Cash", "type": "asset"},
+			"relationships": {"fund": {"data": {"type": "fund", "lid": "missing"}}}}}
+	]}`)
+
+	assert.Equal(t, http.StatusBadRequest, response.Code, "Unexpected status code.")
+}
+
+func TestOperationsWithoutOperationsIsBadRequest(t *testing.T) {
+	for _, body := range []string{`{}`, `not json`} {
+		response, fakestore := serveOperations(t, body)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code, "Unexpected status code for %s.", body)
+		assert.Equal(t, 0, fakestore.transactions, "A transaction was started for %s.", body)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/sbosnick1/openacct/metrics"
+)
+
+const (
+	defaultIdempotencyKeyExpiry = 24 * time.Hour
+)
+
+// An Option adjusts the behaviour of the api service returned by New().
+type Option func(*config)
+
+type config struct {
+	idempotencyKeyExpiry time.Duration
+	now                  func() time.Time
+	metrics              prometheus.Registerer
+	requestTimeout       time.Duration
+	identify             func(r *http.Request) (domain.Principal, bool)
+	receiptTemplate      *template.Template
+}
+
+func newConfig(options []Option) *config {
+	cfg := &config{
+		idempotencyKeyExpiry: defaultIdempotencyKeyExpiry,
+		now:                  time.Now,
+		metrics:              metrics.Default,
+		receiptTemplate:      defaultReceiptTemplate,
+	}
+
+	for _, option := range options {
+		option(cfg)
+	}
+
+	return cfg
+}
+
+// IdempotencyKeyExpiry sets how long the response to a request made with
+// an Idempotency-Key header is kept to be replayed to retries of the
+// request. The default is 24 hours.
+func IdempotencyKeyExpiry(expiry time.Duration) Option {
+	return func(cfg *config) {
+		cfg.idempotencyKeyExpiry = expiry
+	}
+}
+
+// MetricsRegistry sets the registry in which the api service records the
+// number, latency and errors of the requests it serves. The default is
+// metrics.Default. A nil registry turns off the metrics.
+func MetricsRegistry(registry prometheus.Registerer) Option {
+	return func(cfg *config) {
+		cfg.metrics = registry
+	}
+}
+
+// RequestTimeout sets how long the api service may take to serve a request.
+// When the timeout expires the request's database work is abandoned and the
+// request fails with 503 Service Unavailable. The default is no timeout.
+func RequestTimeout(timeout time.Duration) Option {
+	return func(cfg *config) {
+		cfg.requestTimeout = timeout
+	}
+}
+
+// Clock sets the function that gives the api service the current time. It
+// is used to expire idempotency keys and to resume schedules and preview
+// their occurrences. The default is time.Now.
+func Clock(now func() time.Time) Option {
+	return func(cfg *config) {
+		cfg.now = now
+	}
+}
+
+// Identify sets the function that authenticates the user that made a
+// request and gives the principal, with the user's roles, on whose behalf
+// the request submits, approves or rejects an entry or creates or deletes
+// an approval rule. The function reports false if it can't authenticate
+// the request, which then fails with 401 Unauthorized. BasicAuth gives such
+// a function. There is no default so, without this option, those requests
+// always fail.
+func Identify(identify func(r *http.Request) (domain.Principal, bool)) Option {
+	return func(cfg *config) {
+		cfg.identify = identify
+	}
+}
+
+// ReceiptTemplate sets the template that receipts are rendered from as PDF
+// documents. The template is executed with a ReceiptData and each line of
+// the result is a line of the document; a line that starts with "# " is a
+// heading. The default is DefaultReceiptTemplate.
+func ReceiptTemplate(tmpl *template.Template) Option {
+	return func(cfg *config) {
+		cfg.receiptTemplate = tmpl
+	}
+}
+
+// defaultReceiptTemplate is DefaultReceiptTemplate parsed.
+var defaultReceiptTemplate = template.Must(template.New(receiptResourceType).Parse(DefaultReceiptTemplate))
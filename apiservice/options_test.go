@@ -0,0 +1,70 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/sbosnick1/openacct/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConfigWithoutOptionsUsesDefaults(t *testing.T) {
+	sut := newConfig(nil)
+
+	assert.Equal(t, 24*time.Hour, sut.idempotencyKeyExpiry, "Unexpected default idempotency key expiry")
+	assert.NotNil(t, sut.now, "newConfig() did not set a clock")
+	assert.Equal(t, metrics.Default, sut.metrics, "Unexpected default metrics registry")
+	assert.Zero(t, sut.requestTimeout, "Unexpected default request timeout")
+}
+
+func TestIdempotencyKeyExpirySetsExpiry(t *testing.T) {
+	sut := newConfig([]Option{IdempotencyKeyExpiry(time.Minute)})
+
+	assert.Equal(t, time.Minute, sut.idempotencyKeyExpiry, "Unexpected idempotency key expiry")
+}
+
+func TestMetricsRegistrySetsRegistry(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	sut := newConfig([]Option{MetricsRegistry(registry)})
+
+	assert.Equal(t, registry, sut.metrics, "Unexpected metrics registry")
+}
+
+func TestRequestTimeoutSetsTimeout(t *testing.T) {
+	sut := newConfig([]Option{RequestTimeout(time.Second)})
+
+	assert.Equal(t, time.Second, sut.requestTimeout, "Unexpected request timeout")
+}
+
+func TestClockSetsClock(t *testing.T) {
+	now := time.Date(2026, time.October, 15, 12, 0, 0, 0, time.UTC)
+
+	sut := newConfig([]Option{Clock(func() time.Time { return now })})
+
+	assert.Equal(t, now, sut.now(), "Unexpected time from the clock")
+}
+
+func TestNewConfigWithoutIdentifyHasNoIdentify(t *testing.T) {
+	sut := newConfig(nil)
+
+	assert.Nil(t, sut.identify, "Unexpected default identify function")
+}
+
+func TestIdentifySetsIdentify(t *testing.T) {
+	treasurer := domain.Principal{Name: "treasurer", Roles: []string{"treasurer"}}
+	request, _ := http.NewRequest(http.MethodPost, "/v1/entry/1/approve", nil)
+
+	sut := newConfig([]Option{Identify(func(r *http.Request) (domain.Principal, bool) { return treasurer, true })})
+
+	actual, ok := sut.identify(request)
+	assert.True(t, ok, "The request was not authenticated")
+	assert.Equal(t, treasurer, actual)
+}
@@ -0,0 +1,92 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"strconv"
+	"time"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/sbosnick1/openacct/domain"
+)
+
+// dateFormat is the format of the date attributes of the resources.
+const dateFormat = "2006-01-02"
+
+// postingAttributes are the attributes of one posting of an entry. The
+// account is the id of an account resource and a positive amount is a
+// debit in the minor units of the currency of the account's fund. The
+// donor is the id of the donor resource that gave a posting to an income
+// account, if there is one.
+type postingAttributes struct {
+	Account string `json:"account"`
+	Amount  int64  `json:"amount"`
+	Donor   string `json:"donor,omitempty"`
+}
+
+// parsePostings parses the postings attribute of a resource.
+func parsePostings(attributes []postingAttributes) ([]domain.Posting, *jsh.Error) {
+	postings := make([]domain.Posting, 0, len(attributes))
+	for _, posting := range attributes {
+		accountID, err := strconv.ParseUint(posting.Account, 10, 0)
+		if err != nil {
+			return nil, jsh.InputError("The account of each posting must be the id of an account.", "postings")
+		}
+
+		var donorID uint64
+		if posting.Donor != "" {
+			donorID, err = strconv.ParseUint(posting.Donor, 10, 0)
+			if err != nil {
+				return nil, jsh.InputError("The donor of a posting must be the id of a donor.", "postings")
+			}
+		}
+
+		postings = append(postings, domain.Posting{
+			AccountId: uint(accountID),
+			Amount:    posting.Amount,
+			DonorId:   uint(donorID),
+		})
+	}
+
+	return postings, nil
+}
+
+// formatPostings gives the postings attribute of a resource.
+func formatPostings(postings []domain.Posting) []postingAttributes {
+	attributes := make([]postingAttributes, 0, len(postings))
+	for _, posting := range postings {
+		attributes = append(attributes, postingAttributes{
+			Account: strconv.FormatUint(uint64(posting.AccountId), 10),
+			Amount:  posting.Amount,
+			Donor:   formatOptionalID(posting.DonorId),
+		})
+	}
+
+	return attributes
+}
+
+// formatOptionalID gives the id attribute for id or "" if id is zero.
+func formatOptionalID(id uint) string {
+	if id == 0 {
+		return ""
+	}
+
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// isDate validates a string as a date attribute.
+func isDate(value string) bool {
+	_, err := time.Parse(dateFormat, value)
+	return err == nil
+}
+
+// formatDate gives the date attribute for t or "" if t is the zero time.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(dateFormat)
+}
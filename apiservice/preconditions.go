@@ -0,0 +1,69 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"golang.org/x/net/context"
+)
+
+const (
+	etagHeader    = "ETag"
+	ifMatchHeader = "If-Match"
+	anyVersion    = "*"
+)
+
+// formatETag gives the entity tag for a version of an entity.
+func formatETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// setETag sets the entity tag for a version of an entity on the response.
+func setETag(ctx context.Context, version uint) {
+	setResponseHeader(ctx, etagHeader, formatETag(version))
+}
+
+// withIfMatch gives a context in which ifMatchVersion uses value in place of
+// the If-Match header of the request. An empty value is a missing header.
+func withIfMatch(ctx context.Context, value string) context.Context {
+	return context.WithValue(ctx, ifMatchContextKey, value)
+}
+
+// ifMatchVersion returns the version of an entity that the request requires
+// through its If-Match header. The returned bool is true if the request
+// will accept any version (If-Match: *). A request without an If-Match
+// header is an error.
+func ifMatchVersion(ctx context.Context) (uint, bool, jsh.ErrorType) {
+	value, ok := ctx.Value(ifMatchContextKey).(string)
+	if !ok {
+		request := requestFromContext(ctx)
+		if request == nil {
+			return 0, false, jsh.ISE("the request is not available in the context")
+		}
+
+		value = request.Header.Get(ifMatchHeader)
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false, newStatusError(StatusPreconditionRequired, "Precondition Required",
+			"The If-Match header is required to change this resource.")
+	}
+	if value == anyVersion {
+		return 0, true, nil
+	}
+
+	version, err := strconv.ParseUint(strings.Trim(value, `"`), 10, 0)
+	if err != nil || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return 0, false, newStatusError(http.StatusPreconditionFailed, "Precondition Failed",
+			"The If-Match header does not match the current version of the resource.")
+	}
+
+	return uint(version), false, nil
+}
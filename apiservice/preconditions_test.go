@@ -0,0 +1,87 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatETagQuotesVersion(t *testing.T) {
+	assert.Equal(t, `"12"`, formatETag(12))
+}
+
+func TestIfMatchVersionParsesETag(t *testing.T) {
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"12"`})
+
+	version, anyversion, jsherr := ifMatchVersion(ctx)
+
+	require.Nil(t, jsherr, "ifMatchVersion() gave an unexpected error")
+	assert.Equal(t, uint(12), version, "Unexpected version from the If-Match header")
+	assert.False(t, anyversion, "ifMatchVersion() unexpectedly allowed any version")
+}
+
+func TestIfMatchVersionWithStarAllowsAnyVersion(t *testing.T) {
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": "*"})
+
+	_, anyversion, jsherr := ifMatchVersion(ctx)
+
+	require.Nil(t, jsherr, "ifMatchVersion() gave an unexpected error")
+	assert.True(t, anyversion, "ifMatchVersion() did not allow any version")
+}
+
+func TestIfMatchVersionWithoutHeaderIsPreconditionRequired(t *testing.T) {
+	ctx, _ := newRequestContext(t, nil)
+
+	_, _, jsherr := ifMatchVersion(ctx)
+
+	require.NotNil(t, jsherr, "ifMatchVersion() unexpectedly succeeded")
+	assert.Equal(t, StatusPreconditionRequired, jsherr.StatusCode())
+}
+
+func TestIfMatchVersionWithBadHeaderIsPreconditionFailed(t *testing.T) {
+	badvalues := []string{"12", `W/"12"`, `"abc"`, `"1", "2"`}
+
+	for _, badvalue := range badvalues {
+		ctx, _ := newRequestContext(t, map[string]string{"If-Match": badvalue})
+
+		_, _, jsherr := ifMatchVersion(ctx)
+
+		require.NotNil(t, jsherr, "ifMatchVersion() unexpectedly succeeded for %s", badvalue)
+		assert.Equal(t, http.StatusPreconditionFailed, jsherr.StatusCode())
+	}
+}
+
+func TestIfMatchVersionWithoutRequestIsISE(t *testing.T) {
+	_, _, jsherr := ifMatchVersion(context.Background())
+
+	require.NotNil(t, jsherr, "ifMatchVersion() unexpectedly succeeded")
+	assert.Equal(t, http.StatusInternalServerError, jsherr.StatusCode())
+}
+
+func TestIfMatchVersionPrefersValueInContext(t *testing.T) {
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"12"`})
+	ctx = withIfMatch(ctx, `"3"`)
+
+	version, _, jsherr := ifMatchVersion(ctx)
+
+	require.Nil(t, jsherr, "ifMatchVersion() gave an unexpected error")
+	assert.Equal(t, uint(3), version, "Unexpected version from the context")
+}
+
+func TestIfMatchVersionWithEmptyValueInContextIsPreconditionRequired(t *testing.T) {
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"12"`})
+	ctx = withIfMatch(ctx, "")
+
+	_, _, jsherr := ifMatchVersion(ctx)
+
+	require.NotNil(t, jsherr, "ifMatchVersion() unexpectedly gave a version")
+	assert.Equal(t, StatusPreconditionRequired, jsherr.StatusCode(), "Unexpected status")
+}
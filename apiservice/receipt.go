@@ -0,0 +1,666 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/jsh-api"
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/sbosnick1/openacct/pdf"
+	"goji.io"
+	"goji.io/pat"
+	"golang.org/x/net/context"
+)
+
+const (
+	receiptResourceType = "receipt"
+
+	receiptDonorRelationship      = "donor"
+	receiptFundRelationship       = "fund"
+	receiptReplacesRelationship   = "replaces"
+	receiptReplacedByRelationship = "replaced-by"
+
+	issuePath   = "/issue"
+	voidPath    = "/void"
+	reissuePath = "/reissue"
+	pdfPath     = "/pdf"
+	exportPath  = "/export"
+
+	pdfContentType = "application/pdf"
+	zipContentType = "application/zip"
+
+	minReceiptYear = 1900
+	maxReceiptYear = 9999
+)
+
+// DefaultReceiptTemplate is the template for receipts unless the
+// ReceiptTemplate option gives another. It shows the fields that the
+// Canada Revenue Agency and the IRS require of a receipt except for the
+// name and registration number of the charity, which a charity adds in
+// its own template.
+const DefaultReceiptTemplate = `{{if .Void}}# VOID
+{{end}}# Official Receipt for Income Tax Purposes
+
+Receipt number: {{.Number}}
+Date issued: {{.IssuedOn}}
+Tax year: {{.Year}}
+{{- if .Replaces}}
+This receipt replaces receipt number {{.Replaces}}, which is void.
+{{- end}}
+
+Donor:
+{{.DonorName}}
+{{range .DonorAddress}}{{.}}
+{{end}}
+Fund: {{.Fund}}
+Eligible amount of gifts: {{.Amount}} {{.Currency}}
+No goods or services were provided in return for these gifts.
+`
+
+// ReceiptData is the data that a receipt template is executed with. The
+// Amount is formatted without a symbol for the locale of the request, with
+// the decimal places of the Currency. Replaces is the number of the
+// receipt that the receipt replaces or zero.
+type ReceiptData struct {
+	Number       uint
+	Year         int
+	IssuedOn     string
+	DonorName    string
+	DonorAddress []string
+	Fund         string
+	Amount       string
+	Currency     string
+	Replaces     uint
+	Void         bool
+}
+
+// newReceiptResource creates the resource for receipts. Receipts are
+// issued, voided and reissued through actions and are never deleted, so
+// there are no POST, PATCH or DELETE routes.
+func newReceiptResource(store *receiptStore) *jshapi.Resource {
+	resource := jshapi.NewResource(receiptResourceType)
+	resource.Get(store.Get)
+	resource.List(store.List)
+	resource.ToOne(receiptDonorRelationship, store.GetDonor)
+	resource.ToOne(receiptFundRelationship, store.GetFund)
+	resource.ToOne(receiptReplacesRelationship, store.getLinked(domain.Receipt.ReplacesId))
+	resource.ToOne(receiptReplacedByRelationship, store.getLinked(domain.Receipt.ReplacedById))
+	return resource
+}
+
+// receiptAttributes are the attributes of a receipt. The amount is in the
+// minor units of the currency of the receipt's fund.
+type receiptAttributes struct {
+	Number       uint   `json:"number" valid:"-"`
+	Year         int    `json:"year" valid:"-"`
+	Amount       int64  `json:"amount" valid:"-"`
+	IssuedOn     string `json:"issued-on" valid:"-"`
+	DonorName    string `json:"donor-name" valid:"-"`
+	DonorAddress string `json:"donor-address,omitempty" valid:"-"`
+	Void         bool   `json:"void,omitempty" valid:"-"`
+	VoidReason   string `json:"void-reason,omitempty" valid:"-"`
+	VoidedBy     string `json:"voided-by,omitempty" valid:"-"`
+	ReissuedBy   string `json:"reissued-by,omitempty" valid:"-"`
+}
+
+// issueAttributes are the attributes of the receipt in the request for the
+// issue action, which gives the tax year to issue receipts for.
+type issueAttributes struct {
+	Year int `json:"year" valid:"required"`
+}
+
+// voidAttributes are the attributes of the receipt in the request for the
+// void action.
+type voidAttributes struct {
+	Reason string `json:"void-reason,omitempty" valid:"required"`
+}
+
+// listDocument is a JSON API document whose primary data is a list of
+// objects.
+type listDocument struct {
+	Data jsh.List `json:"data"`
+}
+
+// A receiptStore is a store for the receipt resource type. It adapts a
+// domain.ReceiptRepository to a json api spec. resource and renders
+// receipts as PDF documents with template. The issue, void and reissue
+// actions are taken on behalf of the principal that identify authenticates.
+type receiptStore struct {
+	repository domain.ReceiptRepository
+	donors     *donorStore
+	funds      *fundStore
+	template   *template.Template
+	now        func() time.Time
+	identify   func(r *http.Request) (domain.Principal, bool)
+}
+
+func (s *receiptStore) Get(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if s.repository == nil {
+		return nil, jsh.ISE("receiptStore requires a ReceiptRepository")
+	}
+
+	receipt, jsherr := s.get(ctx, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	return createReceiptObjectWithETag(ctx, receipt)
+}
+
+// List lists the receipts, or only those of the tax year given by the year
+// query parameter.
+func (s *receiptStore) List(ctx context.Context) (jsh.List, jsh.ErrorType) {
+	if s.repository == nil {
+		return nil, jsh.ISE("receiptStore requires a ReceiptRepository")
+	}
+
+	year, jsherr := parseIntParameter(ctx, "year", 0, minReceiptYear, maxReceiptYear)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	var receipts []domain.Receipt
+	var err error
+	if year == 0 {
+		receipts, err = s.repository.GetAll(ctx)
+	} else {
+		receipts, err = s.repository.GetByYear(ctx, year)
+	}
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createReceiptList(receipts)
+}
+
+// GetDonor gets the donor that the receipt with the given id is for.
+func (s *receiptStore) GetDonor(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if s.repository == nil || s.donors == nil || s.donors.repository == nil {
+		return nil, jsh.ISE("receiptStore requires a ReceiptRepository and a donorStore")
+	}
+
+	receipt, jsherr := s.get(ctx, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	donor, err := s.donors.repository.Get(ctx, receipt.DonorId())
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createDonorObject(donor)
+}
+
+// GetFund gets the fund that the receipt with the given id is for.
+func (s *receiptStore) GetFund(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if s.repository == nil || s.funds == nil {
+		return nil, jsh.ISE("receiptStore requires a ReceiptRepository and a fundStore")
+	}
+
+	receipt, jsherr := s.get(ctx, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	return s.funds.getFundObject(ctx, receipt.FundId())
+}
+
+// getLinked gives a function that gets the receipt that the receipt with a
+// given id links to by the id given by link.
+func (s *receiptStore) getLinked(link func(receipt domain.Receipt) uint) func(ctx context.Context,
+	id string) (*jsh.Object, jsh.ErrorType) {
+	return func(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+		if s.repository == nil {
+			return nil, jsh.ISE("receiptStore requires a ReceiptRepository")
+		}
+
+		receipt, jsherr := s.get(ctx, id)
+		if jsherr != nil {
+			return nil, jsherr
+		}
+
+		linked := link(receipt)
+		if linked == 0 {
+			return nil, newStatusError(http.StatusNotFound, "Not Found",
+				"The receipt with id "+id+" has no such related receipt.")
+		}
+
+		related, err := s.repository.Get(ctx, linked)
+		if err != nil {
+			return nil, newJshError(ctx, err)
+		}
+
+		obj, jsherr := createReceiptObject(related)
+		if jsherr != nil {
+			return nil, jsherr
+		}
+
+		return obj, nil
+	}
+}
+
+// Issue serves the issue action, which issues the receipts for the tax
+// year of the request's receipt that are still to be issued. It responds
+// with the list of receipts that it issued.
+func (s *receiptStore) Issue(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if s.repository == nil || s.now == nil {
+		jsh.Send(w, r, jsh.ISE("receiptStore requires a ReceiptRepository and a clock"))
+		return
+	}
+
+	if _, jsherr := authenticate(ctx, s.identify, r); jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	object, jsherr := jsh.ParseObject(r)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	var attributes issueAttributes
+	if jsherrs := object.Unmarshal(receiptResourceType, &attributes); jsherrs != nil {
+		sendError(w, r, jsherrs)
+		return
+	}
+	if attributes.Year < minReceiptYear || attributes.Year > maxReceiptYear {
+		sendError(w, r, newAttributeError(StatusUnprocessableEntity, "Invalid Attribute",
+			fmt.Sprintf("The year must be from %d to %d.", minReceiptYear, maxReceiptYear), "year"))
+		return
+	}
+
+	receipts, err := s.repository.Issue(ctx, attributes.Year, s.now())
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	list, listErr := createReceiptList(receipts)
+	if listErr != nil {
+		sendError(w, r, listErr)
+		return
+	}
+
+	status := http.StatusOK
+	if len(receipts) > 0 {
+		status = http.StatusCreated
+	}
+	sendDocument(ctx, w, r, status, listDocument{list})
+}
+
+// Void serves the void action, which makes the receipt whose id is in the
+// path void for the void-reason of the request's receipt.
+func (s *receiptStore) Void(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	s.serveAction(ctx, w, r, http.StatusOK, func(receipt domain.Receipt, principal domain.Principal) (domain.Receipt,
+		jsh.ErrorType) {
+		object, jsherr := jsh.ParseObject(r)
+		if jsherr != nil {
+			return nil, jsherr
+		}
+
+		var attributes voidAttributes
+		if jsherrs := object.Unmarshal(receiptResourceType, &attributes); jsherrs != nil {
+			return nil, jsherrs
+		}
+
+		voided, err := s.repository.Void(ctx, receipt.Id(), receipt.Version(), attributes.Reason, principal)
+		if err != nil {
+			return nil, newJshError(ctx, err)
+		}
+
+		return voided, nil
+	})
+}
+
+// Reissue serves the reissue action, which replaces the receipt whose id
+// is in the path with a new receipt. It responds with the new receipt.
+func (s *receiptStore) Reissue(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	s.serveAction(ctx, w, r, http.StatusCreated, func(receipt domain.Receipt, principal domain.Principal) (domain.Receipt,
+		jsh.ErrorType) {
+		replacement, err := s.repository.Reissue(ctx, receipt.Id(), receipt.Version(), s.now(), principal)
+		if err != nil {
+			return nil, newJshError(ctx, err)
+		}
+
+		return replacement, nil
+	})
+}
+
+// serveAction serves an action, on behalf of the principal that made the
+// request, on the receipt whose id is in the path of the request and that
+// has the version required by its If-Match header.
+func (s *receiptStore) serveAction(ctx context.Context, w http.ResponseWriter, r *http.Request, status int,
+	action func(receipt domain.Receipt, principal domain.Principal) (domain.Receipt, jsh.ErrorType)) {
+	if s.repository == nil || s.now == nil {
+		jsh.Send(w, r, jsh.ISE("receiptStore requires a ReceiptRepository and a clock"))
+		return
+	}
+
+	principal, jsherr := authenticate(ctx, s.identify, r)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	id := pat.Param(ctx, "id")
+	receipt, jsherr := s.get(ctx, id)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	version, anyversion, jsherr := ifMatchVersion(ctx)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+	if !anyversion && receipt.Version() != version {
+		sendError(w, r, newJshError(ctx, &domain.ConcurrencyError{Entity: receiptResourceType, Id: id}))
+		return
+	}
+
+	result, jsherr := action(receipt, principal)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	obj, jsherr := createReceiptObjectWithETag(ctx, result)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	sendDocument(ctx, w, r, status, objectDocument{obj})
+}
+
+// GetPDF serves the receipt whose id is in the path of the request as a
+// PDF document.
+func (s *receiptStore) GetPDF(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if s.repository == nil || s.funds == nil || s.template == nil {
+		jsh.Send(w, r, jsh.ISE("receiptStore requires a ReceiptRepository, a fundStore and a template"))
+		return
+	}
+
+	receipt, jsherr := s.get(ctx, pat.Param(ctx, "id"))
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	document, err := s.render(ctx, receipt, negotiateLocale(w, r))
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", pdfContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+receiptFileName(receipt)+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(document)
+}
+
+// Export serves a zip file of the PDF documents of the receipts of the tax
+// year given by the year query parameter that are not void.
+func (s *receiptStore) Export(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if s.repository == nil || s.funds == nil || s.template == nil {
+		jsh.Send(w, r, jsh.ISE("receiptStore requires a ReceiptRepository, a fundStore and a template"))
+		return
+	}
+
+	year, jsherr := parseIntParameter(ctx, "year", 0, minReceiptYear, maxReceiptYear)
+	if jsherr == nil && year == 0 {
+		jsherr = newStatusError(http.StatusBadRequest, "Invalid Query Parameter",
+			"The query parameter year is required.")
+	}
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	receipts, err := s.repository.GetByYear(ctx, year)
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	locale := negotiateLocale(w, r)
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for _, receipt := range receipts {
+		if receipt.IsVoid() {
+			continue
+		}
+
+		document, err := s.render(ctx, receipt, locale)
+		if err != nil {
+			sendError(w, r, newJshError(ctx, err))
+			return
+		}
+
+		file, err := archive.Create(receiptFileName(receipt))
+		if err == nil {
+			_, err = file.Write(document)
+		}
+		if err != nil {
+			sendError(w, r, newJshError(ctx, err))
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", zipContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="receipts-%d.zip"`, year))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buffer.Bytes())
+}
+
+// get gets the receipt with the given id.
+func (s *receiptStore) get(ctx context.Context, id string) (domain.Receipt, jsh.ErrorType) {
+	receiptID, jsherr := parseID(receiptResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	receipt, err := s.repository.Get(ctx, receiptID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return receipt, nil
+}
+
+// render renders receipt as a PDF document with its amount formatted for
+// locale.
+func (s *receiptStore) render(ctx context.Context, receipt domain.Receipt, locale domain.Locale) ([]byte, error) {
+	fund, err := s.funds.repository.Get(ctx, receipt.FundId())
+	if err != nil {
+		return nil, err
+	}
+
+	data := ReceiptData{
+		Number:    receipt.Number(),
+		Year:      receipt.Year(),
+		IssuedOn:  formatDate(receipt.IssuedOn()),
+		DonorName: receipt.DonorName(),
+		Fund:      fund.Name(),
+		Amount:    locale.FormatNumber(receipt.Amount(), fund.Currency()),
+		Currency:  fund.Currency().String(),
+		Void:      receipt.IsVoid(),
+	}
+	if receipt.DonorAddress() != "" {
+		data.DonorAddress = strings.Split(receipt.DonorAddress(), "\n")
+	}
+	if receipt.ReplacesId() != 0 {
+		replaced, err := s.repository.Get(ctx, receipt.ReplacesId())
+		if err != nil {
+			return nil, err
+		}
+		data.Replaces = replaced.Number()
+	}
+
+	return renderReceipt(s.template, data)
+}
+
+// The layout of a rendered receipt, in points.
+const (
+	receiptMargin      = 72
+	receiptTextSize    = 11
+	receiptHeadingSize = 16
+	receiptLineSpacing = 1.4
+)
+
+// receiptText executes tmpl with data and gives the lines of the result.
+func receiptText(tmpl *template.Template, data ReceiptData) ([]string, error) {
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, data); err != nil {
+		return nil, err
+	}
+
+	return strings.Split(strings.TrimRight(text.String(), "\n"), "\n"), nil
+}
+
+// renderReceipt executes tmpl with data and renders each line of the
+// result as a line of a PDF document. A line that starts with "# " is a
+// heading.
+func renderReceipt(tmpl *template.Template, data ReceiptData) ([]byte, error) {
+	lines, err := receiptText(tmpl, data)
+	if err != nil {
+		return nil, err
+	}
+
+	document := pdf.New(pdf.LetterWidth, pdf.LetterHeight)
+	page := document.AddPage()
+	y := float64(pdf.LetterHeight - receiptMargin)
+	for _, line := range lines {
+		font, size := pdf.Regular, float64(receiptTextSize)
+		if strings.HasPrefix(line, "# ") {
+			font, size, line = pdf.Bold, receiptHeadingSize, line[2:]
+		}
+
+		y -= size * receiptLineSpacing
+		if y < receiptMargin {
+			page = document.AddPage()
+			y = pdf.LetterHeight - receiptMargin - size*receiptLineSpacing
+		}
+		page.Text(receiptMargin, y, font, size, line)
+	}
+
+	var out bytes.Buffer
+	if _, err := document.WriteTo(&out); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// receiptFileName gives the name of the file of the PDF document of
+// receipt.
+func receiptFileName(receipt domain.Receipt) string {
+	return fmt.Sprintf("receipt-%d.pdf", receipt.Number())
+}
+
+// sendDocument sends document as the JSON API response with status.
+func sendDocument(ctx context.Context, w http.ResponseWriter, r *http.Request, status int, document interface{}) {
+	body, err := json.Marshal(document)
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", jsh.ContentType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// handleReceipts registers the routes for the actions, PDF documents and
+// export of receipts with api. They must be registered before the receipt
+// resource, which handles every path under its own.
+func handleReceipts(api *jshapi.API, receipts *receiptStore) {
+	receiptPath := apiV1Prefix + "/" + receiptResourceType
+	api.HandleC(pat.Post(receiptPath+issuePath), goji.HandlerFunc(receipts.Issue))
+	api.HandleC(pat.Get(receiptPath+exportPath), goji.HandlerFunc(receipts.Export))
+	api.HandleC(pat.Post(receiptPath+"/:id"+voidPath), goji.HandlerFunc(receipts.Void))
+	api.HandleC(pat.Post(receiptPath+"/:id"+reissuePath), goji.HandlerFunc(receipts.Reissue))
+	api.HandleC(pat.Get(receiptPath+"/:id"+pdfPath), goji.HandlerFunc(receipts.GetPDF))
+}
+
+// createReceiptObjectWithETag creates the object for a receipt and sets
+// the ETag for the receipt's version on the response.
+func createReceiptObjectWithETag(ctx context.Context, receipt domain.Receipt) (*jsh.Object, jsh.ErrorType) {
+	obj, jsherr := createReceiptObject(receipt)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	setETag(ctx, receipt.Version())
+	return obj, nil
+}
+
+func createReceiptList(receipts []domain.Receipt) (jsh.List, jsh.ErrorType) {
+	list := make(jsh.List, 0)
+	for _, receipt := range receipts {
+		obj, err := createReceiptObject(receipt)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, obj)
+	}
+
+	return list, nil
+}
+
+func createReceiptObject(receipt domain.Receipt) (*jsh.Object, *jsh.Error) {
+	id := strconv.FormatUint(uint64(receipt.Id()), 10)
+
+	obj, err := jsh.NewObject(id, receiptResourceType, receiptAttributes{
+		Number:       receipt.Number(),
+		Year:         receipt.Year(),
+		Amount:       receipt.Amount(),
+		IssuedOn:     formatDate(receipt.IssuedOn()),
+		DonorName:    receipt.DonorName(),
+		DonorAddress: receipt.DonorAddress(),
+		Void:         receipt.IsVoid(),
+		VoidReason:   receipt.VoidReason(),
+		VoidedBy:     receipt.VoidedBy(),
+		ReissuedBy:   receipt.ReissuedBy(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	obj.Relationships = map[string]*jsh.Relationship{
+		receiptDonorRelationship: newToOneRelationship(receiptResourceType, id,
+			receiptDonorRelationship, donorResourceType, receipt.DonorId()),
+		receiptFundRelationship: newToOneRelationship(receiptResourceType, id,
+			receiptFundRelationship, fundResourceType, receipt.FundId()),
+	}
+	if receipt.ReplacesId() != 0 {
+		obj.Relationships[receiptReplacesRelationship] = newToOneRelationship(receiptResourceType, id,
+			receiptReplacesRelationship, receiptResourceType, receipt.ReplacesId())
+	}
+	if receipt.ReplacedById() != 0 {
+		obj.Relationships[receiptReplacedByRelationship] = newToOneRelationship(receiptResourceType, id,
+			receiptReplacedByRelationship, receiptResourceType, receipt.ReplacedById())
+	}
+
+	return obj, nil
+}
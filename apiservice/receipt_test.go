@@ -0,0 +1,322 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type fakeReceipt struct {
+	id           uint
+	number       uint
+	donorId      uint
+	fundId       uint
+	year         int
+	amount       int64
+	issuedOn     time.Time
+	donorName    string
+	donorAddress string
+	voidReason   string
+	replacesId   uint
+	replacedById uint
+	voidedBy     string
+	reissuedBy   string
+	version      uint
+}
+
+func (f *fakeReceipt) Id() uint {
+	return f.id
+}
+
+func (f *fakeReceipt) Number() uint {
+	return f.number
+}
+
+func (f *fakeReceipt) DonorId() uint {
+	return f.donorId
+}
+
+func (f *fakeReceipt) FundId() uint {
+	return f.fundId
+}
+
+func (f *fakeReceipt) Year() int {
+	return f.year
+}
+
+func (f *fakeReceipt) Amount() int64 {
+	return f.amount
+}
+
+func (f *fakeReceipt) IssuedOn() time.Time {
+	return f.issuedOn
+}
+
+func (f *fakeReceipt) DonorName() string {
+	return f.donorName
+}
+
+func (f *fakeReceipt) DonorAddress() string {
+	return f.donorAddress
+}
+
+func (f *fakeReceipt) IsVoid() bool {
+	return f.voidReason != ""
+}
+
+func (f *fakeReceipt) VoidReason() string {
+	return f.voidReason
+}
+
+func (f *fakeReceipt) ReplacesId() uint {
+	return f.replacesId
+}
+
+func (f *fakeReceipt) ReplacedById() uint {
+	return f.replacedById
+}
+
+func (f *fakeReceipt) VoidedBy() string {
+	return f.voidedBy
+}
+
+func (f *fakeReceipt) ReissuedBy() string {
+	return f.reissuedBy
+}
+
+func (f *fakeReceipt) Version() uint {
+	return f.version
+}
+
+type fakeReceiptRepository struct {
+	receipts []*fakeReceipt
+}
+
+func (f *fakeReceiptRepository) GetAll(ctx context.Context) ([]domain.Receipt, error) {
+	var receipts []domain.Receipt
+	for _, receipt := range f.receipts {
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+func (f *fakeReceiptRepository) GetByYear(ctx context.Context, year int) ([]domain.Receipt, error) {
+	var receipts []domain.Receipt
+	for _, receipt := range f.receipts {
+		if receipt.year == year {
+			receipts = append(receipts, receipt)
+		}
+	}
+	return receipts, nil
+}
+
+func (f *fakeReceiptRepository) Get(ctx context.Context, id uint) (domain.Receipt, error) {
+	for _, receipt := range f.receipts {
+		if receipt.id == id {
+			return receipt, nil
+		}
+	}
+	return nil, &domain.NotFoundError{Entity: "receipt", Id: strconv.FormatUint(uint64(id), 10)}
+}
+
+func (f *fakeReceiptRepository) Issue(ctx context.Context, year int, on time.Time) ([]domain.Receipt, error) {
+	return nil, nil
+}
+
+func (f *fakeReceiptRepository) Void(ctx context.Context, id uint, version uint, reason string,
+	voider domain.Principal) (domain.Receipt, error) {
+	found, err := f.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := found.(*fakeReceipt)
+	if receipt.version != version {
+		return nil, &domain.ConcurrencyError{Entity: "receipt", Id: strconv.FormatUint(uint64(id), 10)}
+	}
+
+	receipt.voidReason = reason
+	receipt.voidedBy = voider.Name
+	receipt.version++
+	return receipt, nil
+}
+
+func (f *fakeReceiptRepository) Reissue(ctx context.Context, id uint, version uint, on time.Time,
+	reissuer domain.Principal) (domain.Receipt, error) {
+	return nil, &domain.ConflictError{Entity: "receipt", Id: strconv.FormatUint(uint64(id), 10)}
+}
+
+// newFakeReceiptStore creates a fake store with a donor, a fund and two
+// receipts for 2026, the second of which is void.
+func newFakeReceiptStore() *fakeStore {
+	_, donors := newFakeDonorStore()
+	receipts := &fakeReceiptRepository{receipts: []*fakeReceipt{
+		{id: 1, number: 1, donorId: 1, fundId: 1, year: 2026, amount: 10000,
+			issuedOn: time.Date(2027, time.February, 15, 0, 0, 0, 0, time.UTC), donorName: "Ada Lovelace",
+			donorAddress: "12 St James's Square\nLondon", version: 1},
+		{id: 2, number: 2, donorId: 1, fundId: 1, year: 2026, amount: 500,
+			issuedOn: time.Date(2027, time.February, 15, 0, 0, 0, 0, time.UTC), donorName: "Ada Lovelace",
+			voidReason: "Duplicate", version: 2},
+	}}
+
+	return &fakeStore{
+		fundRepository:    newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}}}),
+		donorRepository:   donors,
+		receiptRepository: receipts,
+	}
+}
+
+func TestReceiptTextExecutesTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("receipt").Parse(DefaultReceiptTemplate))
+
+	actual, err := receiptText(tmpl, ReceiptData{
+		Number:       7,
+		Year:         2026,
+		IssuedOn:     "2027-02-15",
+		DonorName:    "Ada Lovelace",
+		DonorAddress: []string{"12 St James's Square", "London"},
+		Fund:         "General",
+		Amount:       "100.00",
+		Currency:     "CAD",
+	})
+
+	require.NoError(t, err, "Unable to execute the template.")
+	assert.Equal(t, "# Official Receipt for Income Tax Purposes", actual[0], "The heading is missing.")
+	assert.Contains(t, actual, "Receipt number: 7", "The receipt number is missing.")
+	assert.Contains(t, actual, "12 St James's Square", "The donor's address is missing.")
+	assert.NotContains(t, actual, "# VOID", "A receipt that isn't void is marked void.")
+}
+
+func TestReceiptTextMarksVoidReceipt(t *testing.T) {
+	tmpl := template.Must(template.New("receipt").Parse(DefaultReceiptTemplate))
+
+	actual, err := receiptText(tmpl, ReceiptData{Number: 7, Void: true, Replaces: 3})
+
+	require.NoError(t, err, "Unable to execute the template.")
+	assert.Equal(t, "# VOID", actual[0], "A void receipt is not marked void.")
+	assert.Contains(t, actual, "This receipt replaces receipt number 3, which is void.",
+		"The replaced receipt is missing.")
+}
+
+func TestRenderReceiptWritesPDFOfNonLatinText(t *testing.T) {
+	tmpl := template.Must(template.New("receipt").Parse(DefaultReceiptTemplate))
+
+	actual, err := renderReceipt(tmpl, ReceiptData{
+		Number:       7,
+		DonorName:    "Ζωή Παπαδοπούλου",
+		DonorAddress: []string{"ул. Тверская, 1", "Москва"},
+		Amount:       "100,00",
+		Currency:     "EUR",
+	})
+
+	require.NoError(t, err, "Unable to render the receipt.")
+	assert.True(t, bytes.HasPrefix(actual, []byte("%PDF-")), "The receipt is not a PDF document.")
+}
+
+// serveReceiptAction serves a POST of the action of receipt 1 by user, or
+// anonymously if user is "", with body as the request's document.
+func serveReceiptAction(t *testing.T, store *fakeStore, action string, user string, body string) *httptest.ResponseRecorder {
+	request, err := http.NewRequest(http.MethodPost, "/v1/receipt/1"+action, strings.NewReader(body))
+	require.NoError(t, err, "Unable to create the request.")
+	request.Header.Set("Content-Type", jsh.ContentType)
+	request.Header.Set("If-Match", `"1"`)
+	if user != "" {
+		request.SetBasicAuth(user, "")
+	}
+	response := httptest.NewRecorder()
+
+	sut := newApi(store, Identify(identifyTestUser))
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	return response
+}
+
+func TestNewApiVoidsReceipt(t *testing.T) {
+	store := newFakeReceiptStore()
+
+	response := serveReceiptAction(t, store, "/void", "treasurer",
+		`{"data": {"type": "receipt", "attributes": {"void-reason": "Wrong address"}}}`)
+
+	assert.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	assert.Equal(t, `"2"`, response.Header().Get("ETag"), "Unexpected ETag for the void receipt.")
+	receipt := store.receiptRepository.(*fakeReceiptRepository).receipts[0]
+	assert.Equal(t, "Wrong address", receipt.voidReason, "The receipt was not voided.")
+	assert.Equal(t, "treasurer", receipt.voidedBy, "The voider was not recorded.")
+}
+
+func TestNewApiReceiptActionsWithoutPrincipalAreUnauthorized(t *testing.T) {
+	for _, action := range []string{"/void", "/reissue"} {
+		store := newFakeReceiptStore()
+
+		response := serveReceiptAction(t, store, action, "",
+			`{"data": {"type": "receipt", "attributes": {"void-reason": "Wrong address"}}}`)
+
+		assert.Equal(t, http.StatusUnauthorized, response.Code, "Unexpected status code for %s.", action)
+		receipt := store.receiptRepository.(*fakeReceiptRepository).receipts[0]
+		assert.Empty(t, receipt.voidReason, "The receipt was changed by %s.", action)
+	}
+}
+
+func TestNewApiServesReceiptPDF(t *testing.T) {
+	request, response := getRequestResponse(t, "/v1/receipt/1/pdf")
+
+	sut := newApi(newFakeReceiptStore())
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	assert.Equal(t, pdfContentType, response.Header().Get("Content-Type"), "Unexpected content type.")
+	assert.Contains(t, response.Body.String(), "(Eligible amount of gifts: 100.00 CAD)",
+		"The amount is missing from the receipt.")
+}
+
+func TestNewApiServesReceiptPDFForAcceptLanguage(t *testing.T) {
+	request, response := getRequestResponse(t, "/v1/receipt/1/pdf")
+	request.Header.Set("Accept-Language", "fr-CA")
+
+	sut := newApi(newFakeReceiptStore())
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	assert.Equal(t, "fr-CA", response.Header().Get("Content-Language"), "Unexpected content language.")
+	assert.Contains(t, response.Body.String(), "(Eligible amount of gifts: 100,00 CAD)",
+		"The amount is not formatted for the locale.")
+}
+
+func TestNewApiExportsReceiptsThatAreNotVoid(t *testing.T) {
+	request, response := getRequestResponse(t, "/v1/receipt/export?year=2026")
+
+	sut := newApi(newFakeReceiptStore())
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	assert.Equal(t, zipContentType, response.Header().Get("Content-Type"), "Unexpected content type.")
+	archive, err := zip.NewReader(bytes.NewReader(response.Body.Bytes()), int64(response.Body.Len()))
+	require.NoError(t, err, "Unable to read the zip file.")
+	require.Len(t, archive.File, 1, "Unexpected number of receipts exported.")
+	assert.Equal(t, "receipt-1.pdf", archive.File[0].Name, "Unexpected file exported.")
+}
+
+func TestNewApiExportWithoutYearIsBadRequest(t *testing.T) {
+	request, response := getRequestResponse(t, "/v1/receipt/export")
+
+	sut := newApi(newFakeReceiptStore())
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	assert.Equal(t, http.StatusBadRequest, response.Code, "Unexpected status code.")
+}
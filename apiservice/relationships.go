@@ -0,0 +1,86 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+)
+
+// A relationshipMap describes the relationships of each resource type. It
+// maps a resource type to the names of its relationships and each name to
+// the type of the related resources.
+type relationshipMap map[string]map[string]string
+
+// relatedType gives the type of the resources related to resourceType by
+// the named relationship and whether there is such a relationship.
+func (r relationshipMap) relatedType(resourceType string, name string) (string, bool) {
+	related, ok := r[resourceType][name]
+	return related, ok
+}
+
+// newToManyRelationship creates the relationship object for the named
+// relationship of a resource that links to the related resources with
+// the given ids.
+func newToManyRelationship(resourceType string, id string, name string,
+	relatedType string, relatedIds []uint) *jsh.Relationship {
+	linkage := make(jsh.ResourceLinkage, 0, len(relatedIds))
+	for _, relatedId := range relatedIds {
+		linkage = append(linkage, &jsh.ResourceIdentifier{
+			Type: relatedType,
+			ID:   strconv.FormatUint(uint64(relatedId), 10),
+		})
+	}
+
+	return &jsh.Relationship{
+		Links: newRelationshipLinks(resourceType, id, name),
+		Data:  linkage,
+	}
+}
+
+// newToOneRelationship creates the relationship object for the named
+// relationship of a resource that links to the related resource with
+// the given id.
+func newToOneRelationship(resourceType string, id string, name string,
+	relatedType string, relatedId uint) *jsh.Relationship {
+	return newToManyRelationship(resourceType, id, name, relatedType, []uint{relatedId})
+}
+
+func newRelationshipLinks(resourceType string, id string, name string) *jsh.Links {
+	resourcePath := path.Join(apiV1Prefix, resourceType, id)
+	return &jsh.Links{
+		Self:    &jsh.Link{HREF: path.Join(resourcePath, "relationships", name)},
+		Related: &jsh.Link{HREF: path.Join(resourcePath, name)},
+	}
+}
+
+// parseToOneRelationship gives the id of the resource of relatedType that
+// is linked to by the named relationship of object.
+func parseToOneRelationship(object *jsh.Object, name string, relatedType string) (uint, *jsh.Error) {
+	relationship, ok := object.Relationships[name]
+	if !ok || relationship == nil || len(relationship.Data) != 1 {
+		return 0, newRelationshipError(StatusUnprocessableEntity, "Invalid Relationship",
+			"The "+name+" relationship must link to exactly one "+relatedType+".", name)
+	}
+
+	identifier := relationship.Data[0]
+	relatedId, err := strconv.ParseUint(identifier.ID, 10, 0)
+	if identifier.Type != relatedType || err != nil {
+		return 0, newRelationshipError(StatusUnprocessableEntity, "Invalid Relationship",
+			"The "+name+" relationship must link to a "+relatedType+".", name)
+	}
+
+	return uint(relatedId), nil
+}
+
+// badRelationshipPath gives the error for an include parameter that names
+// a relationship that does not exist.
+func badRelationshipPath(relationshipPath string) *jsh.Error {
+	return newStatusError(http.StatusBadRequest, "Invalid Include",
+		"The relationship path "+relationshipPath+" does not exist.")
+}
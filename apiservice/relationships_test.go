@@ -0,0 +1,79 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"net/http"
+	"testing"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelationshipMapGivesRelatedType(t *testing.T) {
+	sut := relationshipMap{"fund": {"accounts": "account"}}
+
+	related, ok := sut.relatedType("fund", "accounts")
+	_, missing := sut.relatedType("fund", "donors")
+
+	assert.True(t, ok, "relationshipMap is missing a relationship.")
+	assert.Equal(t, "account", related, "Unexpected related type.")
+	assert.False(t, missing, "relationshipMap has an unexpected relationship.")
+}
+
+func TestNewToManyRelationshipLinksToRelatedResources(t *testing.T) {
+	assert := assert.New(t)
+
+	sut := newToManyRelationship("fund", "1", "accounts", "account", []uint{3, 4})
+
+	require.Len(t, sut.Data, 2, "Unexpected number of related resources.")
+	assert.Equal("account", sut.Data[0].Type, "Unexpected type of related resource.")
+	assert.Equal("3", sut.Data[0].ID, "Unexpected id of related resource.")
+	assert.Equal("4", sut.Data[1].ID, "Unexpected id of related resource.")
+	assert.Equal("/v1/fund/1/relationships/accounts", sut.Links.Self.HREF, "Unexpected self link.")
+	assert.Equal("/v1/fund/1/accounts", sut.Links.Related.HREF, "Unexpected related link.")
+}
+
+func TestNewToManyRelationshipWithoutRelatedResourcesHasEmptyData(t *testing.T) {
+	sut := newToManyRelationship("fund", "1", "accounts", "account", nil)
+
+	assert.NotNil(t, sut.Data, "Relationship data is unexpectedly null.")
+	assert.Len(t, sut.Data, 0, "Unexpected number of related resources.")
+}
+
+func TestParseToOneRelationshipGivesRelatedId(t *testing.T) {
+	obj := newAccountObject(t, "", map[string]string{"name": "Cash"}, "12")
+
+	id, jsherr := parseToOneRelationship(obj, "fund", "fund")
+
+	require.Nil(t, jsherr, "parseToOneRelationship failed")
+	assert.Equal(t, uint(12), id, "Unexpected related id.")
+}
+
+func TestParseToOneRelationshipWithBadLinkageIsError(t *testing.T) {
+	missing := newAccountObject(t, "", map[string]string{"name": "Cash"}, "")
+	wrongType := newAccountObject(t, "", map[string]string{"name": "Cash"}, "12")
+	wrongType.Relationships["fund"].Data[0].Type = "donor"
+	badId := newAccountObject(t, "", map[string]string{"name": "Cash"}, "twelve")
+	tooMany := newAccountObject(t, "", map[string]string{"name": "Cash"}, "12")
+	tooMany.Relationships["fund"].Data = append(tooMany.Relationships["fund"].Data,
+		&jsh.ResourceIdentifier{Type: "fund", ID: "13"})
+
+	for _, obj := range []*jsh.Object{missing, wrongType, badId, tooMany} {
+		_, jsherr := parseToOneRelationship(obj, "fund", "fund")
+
+		require.NotNil(t, jsherr, "parseToOneRelationship unexpectedly succeeded")
+		assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(), "Unexpected status.")
+		assert.Equal(t, "/data/relationships/fund", jsherr.Source.Pointer, "Unexpected source pointer.")
+	}
+}
+
+func TestBadRelationshipPathIsBadRequest(t *testing.T) {
+	sut := badRelationshipPath("accounts.donors")
+
+	assert.Equal(t, http.StatusBadRequest, sut.StatusCode(), "Unexpected status.")
+	assert.Contains(t, sut.Detail, "accounts.donors", "Error does not name the path.")
+}
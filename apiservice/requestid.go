@@ -0,0 +1,164 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+
+	maxRequestIDLength = 128
+)
+
+// requestIDFromContext returns the id of the request that ctx belongs to,
+// or "" if there is no such id.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// requestID gives the id for a request. It is the id in the request's
+// X-Request-ID header if that is a reasonable id and a new id otherwise.
+func requestID(r *http.Request) string {
+	id := r.Header.Get(requestIDHeader)
+	if isValidRequestID(id) {
+		return id
+	}
+
+	return newRequestID()
+}
+
+// isValidRequestID reports whether id is short and made up of printable
+// ASCII characters other than space so that it is safe to log and to echo.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	var id [16]byte
+	_, err := rand.Read(id[:])
+	if err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+
+	return hex.EncodeToString(id[:])
+}
+
+// accessRecorder records the status of a response for the access log. The
+// body of an error response is held back so that the request id can be
+// added to each of its errors before it is sent.
+type accessRecorder struct {
+	http.ResponseWriter
+	requestID string
+	status    int
+	buffered  *bytes.Buffer
+}
+
+func newAccessRecorder(w http.ResponseWriter, requestID string) *accessRecorder {
+	return &accessRecorder{ResponseWriter: w, requestID: requestID}
+}
+
+func (a *accessRecorder) WriteHeader(status int) {
+	if a.status != 0 {
+		return
+	}
+
+	a.status = status
+	if status >= http.StatusBadRequest {
+		a.buffered = &bytes.Buffer{}
+		return
+	}
+
+	a.ResponseWriter.WriteHeader(status)
+}
+
+func (a *accessRecorder) Write(data []byte) (int, error) {
+	if a.status == 0 {
+		a.WriteHeader(http.StatusOK)
+	}
+
+	if a.buffered != nil {
+		return a.buffered.Write(data)
+	}
+
+	return a.ResponseWriter.Write(data)
+}
+
+// finish sends the held back body of an error response with the request
+// id as the id of each of its errors.
+func (a *accessRecorder) finish() {
+	if a.status == 0 {
+		a.status = http.StatusOK
+	}
+
+	if a.buffered == nil {
+		return
+	}
+
+	body := withErrorIDs(a.buffered.Bytes(), a.requestID)
+	if len(body) != a.buffered.Len() {
+		a.Header().Del("Content-Length")
+	}
+
+	a.ResponseWriter.WriteHeader(a.status)
+	a.ResponseWriter.Write(body)
+}
+
+// withErrorIDs gives body with id as the id of each of the errors in it
+// that doesn't already have one. A body that isn't a JSON API document with
+// errors is left as it is.
+func withErrorIDs(body []byte, id string) []byte {
+	var document map[string]json.RawMessage
+	if json.Unmarshal(body, &document) != nil {
+		return body
+	}
+
+	var errors []map[string]json.RawMessage
+	if json.Unmarshal(document["errors"], &errors) != nil || len(errors) == 0 {
+		return body
+	}
+
+	encodedID, err := json.Marshal(id)
+	if err != nil {
+		return body
+	}
+
+	for _, e := range errors {
+		if _, ok := e["id"]; !ok && e != nil {
+			e["id"] = encodedID
+		}
+	}
+
+	document["errors"], err = json.Marshal(errors)
+	if err != nil {
+		return body
+	}
+
+	updated, err := json.Marshal(document)
+	if err != nil {
+		return body
+	}
+
+	return updated
+}
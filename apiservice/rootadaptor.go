@@ -6,23 +6,49 @@ package apiservice
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/Sirupsen/logrus"
+	"github.com/sbosnick1/openacct/domain"
 	"goji.io"
 	"golang.org/x/net/context"
 )
 
-// rootAdaptor adapts a goji.Handler as an http.Handler using context.Background()
-// as the context. rootAdaptor should be used to adapt the root handler of a hierarchry.
+// rootAdaptor adapts a goji.Handler as an http.Handler using a context derived
+// from the request's own context that holds the id of the request. The context
+// is done when the client goes away. rootAdaptor should
+// be used to adapt the root handler of a hierarchry. The request id is taken from
+// the X-Request-ID header of the request, or generated if there isn't one, and is
+// echoed in the X-Request-ID header of the response and as the id of any JSON API
+// errors. rootAdaptor logs one access log entry for each request with the
+// principal that the request was authenticated as, or "" if it wasn't.
 // The zero valued rootAdaptor responds to all requests with "status not found".
 type rootAdaptor struct {
 	root goji.Handler
 }
 
 func (r *rootAdaptor) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+	id := requestID(request)
+	response.Header().Set(requestIDHeader, id)
+
+	recorder := newAccessRecorder(response, id)
+	authenticated := &domain.Principal{}
 	if r.root == nil {
-		response.WriteHeader(http.StatusNotFound)
-		return
+		recorder.WriteHeader(http.StatusNotFound)
+	} else {
+		ctx := context.WithValue(request.Context(), requestIDContextKey, id)
+		ctx, authenticated = withAuthenticated(ctx)
+		r.root.ServeHTTPC(ctx, recorder, request)
 	}
+	recorder.finish()
 
-	r.root.ServeHTTPC(context.Background(), response, request)
+	log.WithFields(logrus.Fields{
+		"request_id": id,
+		"method":     request.Method,
+		"path":       request.URL.Path,
+		"status":     recorder.status,
+		"duration":   time.Since(start).String(),
+		"principal":  authenticated.Name,
+	}).Info("Served request.")
 }
@@ -5,6 +5,7 @@
 package apiservice
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -39,10 +40,10 @@ func TestZeroRootAdaptorRespondsNotFound(t *testing.T) {
 func TestRootAdaptorForwardsRequestAndResponse(t *testing.T) {
 	request, response := getRequestResponse(t, "/anything")
 	var actualRequest *http.Request
-	var actualResponse http.ResponseWriter
 	rootHandler := func(_ context.Context, resp http.ResponseWriter, req *http.Request) {
 		actualRequest = req
-		actualResponse = resp
+		resp.WriteHeader(http.StatusCreated)
+		resp.Write([]byte("body"))
 	}
 
 	sut := rootAdaptor{goji.HandlerFunc(rootHandler)}
@@ -51,12 +52,12 @@ func TestRootAdaptorForwardsRequestAndResponse(t *testing.T) {
 	if actualRequest != request {
 		t.Errorf("rootAdaptor failed to forward the request")
 	}
-	if actualResponse != response {
+	if response.Code != http.StatusCreated || response.Body.String() != "body" {
 		t.Errorf("rootAdaptor failed to forward the response writer")
 	}
 }
 
-func TestRootAdaptorUsesBackgroundContest(t *testing.T) {
+func TestRootAdaptorUsesContextWithRequestID(t *testing.T) {
 	request, response := getRequestResponse(t, "/anything")
 	var actualContext context.Context
 	rootHandler := func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) {
@@ -66,7 +67,89 @@ func TestRootAdaptorUsesBackgroundContest(t *testing.T) {
 	sut := rootAdaptor{goji.HandlerFunc(rootHandler)}
 	sut.ServeHTTP(response, request)
 
-	if actualContext != context.Background() {
-		t.Errorf("rootAdaptor failed to use context.Background() as the context")
+	id := requestIDFromContext(actualContext)
+	if id == "" {
+		t.Errorf("rootAdaptor failed to store a request id in the context")
+	}
+	if response.Header().Get(requestIDHeader) != id {
+		t.Errorf("rootAdaptor failed to echo the request id in the response")
+	}
+}
+
+func TestRootAdaptorUsesRequestContext(t *testing.T) {
+	request, response := getRequestResponse(t, "/anything")
+	requestContext, cancel := context.WithCancel(context.Background())
+	request = request.WithContext(requestContext)
+	var actualContext context.Context
+	rootHandler := func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) {
+		actualContext = ctx
+	}
+
+	sut := rootAdaptor{goji.HandlerFunc(rootHandler)}
+	sut.ServeHTTP(response, request)
+	cancel()
+
+	if actualContext.Err() != context.Canceled {
+		t.Errorf("rootAdaptor failed to derive the context from the request's context")
+	}
+}
+
+func TestRootAdaptorAcceptsRequestID(t *testing.T) {
+	request, response := getRequestResponse(t, "/anything")
+	request.Header.Set(requestIDHeader, "abc-123")
+	var actualID string
+	rootHandler := func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) {
+		actualID = requestIDFromContext(ctx)
+	}
+
+	sut := rootAdaptor{goji.HandlerFunc(rootHandler)}
+	sut.ServeHTTP(response, request)
+
+	if actualID != "abc-123" {
+		t.Errorf("rootAdaptor used request id '%s' but 'abc-123' was expected", actualID)
+	}
+	if response.Header().Get(requestIDHeader) != "abc-123" {
+		t.Errorf("rootAdaptor failed to echo the given request id")
+	}
+}
+
+func TestRootAdaptorReplacesUnsafeRequestID(t *testing.T) {
+	request, response := getRequestResponse(t, "/anything")
+	request.Header.Set(requestIDHeader, "bad id\n")
+
+	var sut rootAdaptor
+	sut.ServeHTTP(response, request)
+
+	id := response.Header().Get(requestIDHeader)
+	if id == "" || id == "bad id\n" {
+		t.Errorf("rootAdaptor echoed the unsafe request id '%s'", id)
+	}
+}
+
+func TestRootAdaptorAddsRequestIDToErrors(t *testing.T) {
+	request, response := getRequestResponse(t, "/anything")
+	request.Header.Set(requestIDHeader, "abc-123")
+	rootHandler := func(_ context.Context, resp http.ResponseWriter, _ *http.Request) {
+		resp.WriteHeader(http.StatusNotFound)
+		resp.Write([]byte(`{"errors":[{"status":"404","title":"Not Found"}]}`))
+	}
+
+	sut := rootAdaptor{goji.HandlerFunc(rootHandler)}
+	sut.ServeHTTP(response, request)
+
+	var document struct {
+		Errors []struct {
+			ID string `json:"id"`
+		} `json:"errors"`
+	}
+	err := json.Unmarshal(response.Body.Bytes(), &document)
+	if err != nil || len(document.Errors) != 1 {
+		t.Fatalf("rootAdaptor sent an invalid error document '%s'", response.Body.String())
+	}
+	if document.Errors[0].ID != "abc-123" {
+		t.Errorf("rootAdaptor set the error id '%s' but 'abc-123' was expected", document.Errors[0].ID)
+	}
+	if response.Code != http.StatusNotFound {
+		t.Errorf("rootAdaptor responded with code '%d' but '%d' was expected", response.Code, http.StatusNotFound)
 	}
 }
@@ -0,0 +1,341 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/derekdowling/jsh-api"
+	"github.com/sbosnick1/openacct/domain"
+	"goji.io/pat"
+	"golang.org/x/net/context"
+)
+
+const (
+	scheduleResourceType = "schedule"
+
+	scheduleFundRelationship = "fund"
+
+	occurrencesPath        = "/occurrences"
+	countParameter         = "count"
+	defaultOccurrenceCount = 10
+	maxOccurrenceCount     = 100
+)
+
+func newScheduleResource(store *scheduleStore) *jshapi.Resource {
+	resource := jshapi.NewCRUDResource(scheduleResourceType, store)
+	resource.ToOne(scheduleFundRelationship, store.GetFund)
+	return resource
+}
+
+// scheduleAttributes are the attributes of a schedule. The rule is an
+// iCalendar RRULE (see domain.Recurrence). The paused and
+// materialized-through attributes are ignored when creating a schedule.
+type scheduleAttributes struct {
+	Description string              `json:"description,omitempty" valid:"required"`
+	Rule        string              `json:"rule,omitempty" valid:"required,recurrence"`
+	Start       string              `json:"start,omitempty" valid:"required,date"`
+	Postings    []postingAttributes `json:"postings,omitempty" valid:"required"`
+	Paused      bool                `json:"paused,omitempty" valid:"-"`
+	Through     string              `json:"materialized-through,omitempty" valid:"-"`
+}
+
+// schedulePatchAttributes are the attributes of a schedule that may be
+// changed by an update. A schedule is paused or resumed by an update that
+// changes the paused attribute; nothing else about it can be changed.
+type schedulePatchAttributes struct {
+	Paused *bool `json:"paused,omitempty" valid:"-"`
+}
+
+// occurrencesDocument is the response to a request for the next
+// occurrences of a schedule.
+type occurrencesDocument struct {
+	Links struct {
+		Self string `json:"self"`
+	} `json:"links"`
+	Meta struct {
+		Occurrences []string `json:"occurrences"`
+	} `json:"meta"`
+}
+
+// A scheduleStore is a store for the schedule resource type. It adapts a
+// domain.ScheduleRepository to a json api spec. resource. Schedules are
+// resumed and their occurrences previewed as of the time given by now.
+type scheduleStore struct {
+	repository domain.ScheduleRepository
+	funds      *fundStore
+	now        func() time.Time
+}
+
+func (s *scheduleStore) Save(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if s.repository == nil {
+		return nil, jsh.ISE("scheduleStore requires a ScheduleRepository")
+	}
+
+	var attributes scheduleAttributes
+	jsherrs := object.Unmarshal(scheduleResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	fundID, jsherr := parseToOneRelationship(object, scheduleFundRelationship, fundResourceType)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	postings, jsherr := parsePostings(attributes.Postings)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	start, err := time.Parse(dateFormat, attributes.Start)
+	if err != nil {
+		// the validation on scheduleAttributes should have ensured
+		// this does not happen
+		return nil, jsh.InputError(err.Error(), "start")
+	}
+
+	schedule, err := s.repository.Create(ctx, fundID, attributes.Description, attributes.Rule, start, postings)
+	if notfound, ok := err.(*domain.NotFoundError); ok && notfound.Entity == fundResourceType {
+		return nil, newRelationshipError(StatusUnprocessableEntity, "Invalid Relationship",
+			err.Error(), scheduleFundRelationship)
+	}
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createScheduleObjectWithETag(ctx, schedule)
+}
+
+func (s *scheduleStore) Get(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if s.repository == nil {
+		return nil, jsh.ISE("scheduleStore requires a ScheduleRepository")
+	}
+
+	scheduleID, jsherr := parseID(scheduleResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	schedule, err := s.repository.Get(ctx, scheduleID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createScheduleObjectWithETag(ctx, schedule)
+}
+
+func (s *scheduleStore) List(ctx context.Context) (jsh.List, jsh.ErrorType) {
+	if s.repository == nil {
+		return nil, jsh.ISE("scheduleStore requires a ScheduleRepository")
+	}
+
+	schedules, err := s.repository.GetAll(ctx)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	list := make(jsh.List, 0)
+	for _, schedule := range schedules {
+		obj, err := createScheduleObject(schedule)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, obj)
+	}
+
+	return list, nil
+}
+
+func (s *scheduleStore) Update(ctx context.Context, object *jsh.Object) (*jsh.Object, jsh.ErrorType) {
+	if s.repository == nil {
+		return nil, jsh.ISE("scheduleStore requires a ScheduleRepository")
+	}
+
+	var attributes schedulePatchAttributes
+	jsherrs := object.Unmarshal(scheduleResourceType, &attributes)
+	if jsherrs != nil {
+		return nil, jsherrs
+	}
+
+	if attributes.Paused == nil {
+		return nil, jsh.InputError("Only the paused attribute of a schedule can be changed.", "paused")
+	}
+
+	schedule, jsherr := s.getForChange(ctx, object.ID)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	var updated domain.Schedule
+	var err error
+	if *attributes.Paused {
+		updated, err = s.repository.Pause(ctx, schedule.Id(), schedule.Version())
+	} else {
+		updated, err = s.repository.Resume(ctx, schedule.Id(), schedule.Version(), s.now())
+	}
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return createScheduleObjectWithETag(ctx, updated)
+}
+
+func (s *scheduleStore) Delete(ctx context.Context, id string) jsh.ErrorType {
+	if s.repository == nil {
+		return jsh.ISE("scheduleStore requires a ScheduleRepository")
+	}
+
+	schedule, jsherr := s.getForChange(ctx, id)
+	if jsherr != nil {
+		return jsherr
+	}
+
+	err := s.repository.Delete(ctx, schedule.Id(), schedule.Version())
+	if err != nil {
+		return newJshError(ctx, err)
+	}
+
+	return nil
+}
+
+// GetFund gets the fund that the schedule with the given id makes entries
+// in.
+func (s *scheduleStore) GetFund(ctx context.Context, id string) (*jsh.Object, jsh.ErrorType) {
+	if s.repository == nil || s.funds == nil {
+		return nil, jsh.ISE("scheduleStore requires a ScheduleRepository and a fundStore")
+	}
+
+	scheduleID, jsherr := parseID(scheduleResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	schedule, err := s.repository.Get(ctx, scheduleID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	return s.funds.getFundObject(ctx, schedule.FundId())
+}
+
+// GetOccurrences serves the dates of the next occurrences of a schedule
+// that are still to be materialized, as the occurrences member of the
+// meta of the response. The count query parameter is how many to give.
+func (s *scheduleStore) GetOccurrences(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if s.repository == nil {
+		jsh.Send(w, r, jsh.ISE("scheduleStore requires a ScheduleRepository"))
+		return
+	}
+
+	id := pat.Param(ctx, "id")
+	scheduleID, jsherr := parseID(scheduleResourceType, id)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	count, jsherr := parseIntParameter(ctx, countParameter, defaultOccurrenceCount, 1, maxOccurrenceCount)
+	if jsherr != nil {
+		sendError(w, r, jsherr)
+		return
+	}
+
+	schedule, err := s.repository.Get(ctx, scheduleID)
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	occurrences, err := domain.NextOccurrences(schedule, s.now(), count)
+	if err != nil {
+		sendError(w, r, newJshError(ctx, err))
+		return
+	}
+
+	var document occurrencesDocument
+	document.Links.Self = path.Join(apiV1Prefix, scheduleResourceType, id, occurrencesPath)
+	document.Meta.Occurrences = make([]string, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		document.Meta.Occurrences = append(document.Meta.Occurrences, formatDate(occurrence))
+	}
+
+	body, err := json.Marshal(document)
+	if err != nil {
+		jsh.Send(w, r, jsh.ISE(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", jsh.ContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// getForChange gets the schedule with the given id as it must be for the
+// current request to change it. The version of the returned schedule is
+// the version required by the If-Match header of the request.
+func (s *scheduleStore) getForChange(ctx context.Context, id string) (domain.Schedule, jsh.ErrorType) {
+	scheduleID, jsherr := parseID(scheduleResourceType, id)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	version, anyversion, jsherr := ifMatchVersion(ctx)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	schedule, err := s.repository.Get(ctx, scheduleID)
+	if err != nil {
+		return nil, newJshError(ctx, err)
+	}
+
+	if !anyversion && schedule.Version() != version {
+		return nil, newJshError(ctx, &domain.ConcurrencyError{Entity: scheduleResourceType, Id: id})
+	}
+
+	return schedule, nil
+}
+
+// createScheduleObjectWithETag creates the object for a schedule and sets
+// the ETag for the schedule's version on the response.
+func createScheduleObjectWithETag(ctx context.Context, schedule domain.Schedule) (*jsh.Object, jsh.ErrorType) {
+	obj, jsherr := createScheduleObject(schedule)
+	if jsherr != nil {
+		return nil, jsherr
+	}
+
+	setETag(ctx, schedule.Version())
+	return obj, nil
+}
+
+func createScheduleObject(schedule domain.Schedule) (*jsh.Object, *jsh.Error) {
+	id := strconv.FormatUint(uint64(schedule.Id()), 10)
+
+	obj, err := jsh.NewObject(id, scheduleResourceType, scheduleAttributes{
+		Description: schedule.Description(),
+		Rule:        schedule.Rule(),
+		Start:       formatDate(schedule.Start()),
+		Postings:    formatPostings(schedule.Postings()),
+		Paused:      schedule.IsPaused(),
+		Through:     formatDate(schedule.Through()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	obj.Relationships = map[string]*jsh.Relationship{
+		scheduleFundRelationship: newToOneRelationship(scheduleResourceType, id,
+			scheduleFundRelationship, fundResourceType, schedule.FundId()),
+	}
+
+	return obj, nil
+}
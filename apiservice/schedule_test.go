@@ -0,0 +1,281 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package apiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	jsh "github.com/derekdowling/go-json-spec-handler"
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type fakeSchedule struct {
+	id          uint
+	fundId      uint
+	description string
+	rule        string
+	start       time.Time
+	postings    []domain.Posting
+	paused      bool
+	through     time.Time
+	version     uint
+}
+
+func (f *fakeSchedule) Id() uint {
+	return f.id
+}
+
+func (f *fakeSchedule) FundId() uint {
+	return f.fundId
+}
+
+func (f *fakeSchedule) Description() string {
+	return f.description
+}
+
+func (f *fakeSchedule) Rule() string {
+	return f.rule
+}
+
+func (f *fakeSchedule) Start() time.Time {
+	return f.start
+}
+
+func (f *fakeSchedule) Postings() []domain.Posting {
+	return f.postings
+}
+
+func (f *fakeSchedule) IsPaused() bool {
+	return f.paused
+}
+
+func (f *fakeSchedule) Through() time.Time {
+	return f.through
+}
+
+func (f *fakeSchedule) Version() uint {
+	return f.version
+}
+
+type fakeScheduleRepository struct {
+	schedules       []*fakeSchedule
+	funds           *fakeFundRepository
+	createdPostings []domain.Posting
+	resumedOn       time.Time
+}
+
+func (f *fakeScheduleRepository) GetAll(ctx context.Context) ([]domain.Schedule, error) {
+	var schedules []domain.Schedule
+	for _, schedule := range f.schedules {
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+func (f *fakeScheduleRepository) GetActive(ctx context.Context) ([]domain.Schedule, error) {
+	var schedules []domain.Schedule
+	for _, schedule := range f.schedules {
+		if !schedule.paused {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules, nil
+}
+
+func (f *fakeScheduleRepository) Get(ctx context.Context, id uint) (domain.Schedule, error) {
+	for _, schedule := range f.schedules {
+		if schedule.id == id {
+			return schedule, nil
+		}
+	}
+	return nil, &domain.NotFoundError{Entity: "schedule", Id: strconv.FormatUint(uint64(id), 10)}
+}
+
+func (f *fakeScheduleRepository) Create(ctx context.Context, fundId uint, description string, rule string,
+	start time.Time, postings []domain.Posting) (domain.Schedule, error) {
+	if f.funds != nil {
+		_, err := f.funds.Get(ctx, fundId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f.createdPostings = postings
+	schedule := &fakeSchedule{uint(len(f.schedules) + 1), fundId, description, rule, start, postings,
+		false, time.Time{}, 1}
+	f.schedules = append(f.schedules, schedule)
+	return schedule, nil
+}
+
+func (f *fakeScheduleRepository) Delete(ctx context.Context, id uint, version uint) error {
+	for i, schedule := range f.schedules {
+		if schedule.id == id {
+			f.schedules = append(f.schedules[:i], f.schedules[i+1:]...)
+			return nil
+		}
+	}
+	return &domain.NotFoundError{Entity: "schedule", Id: strconv.FormatUint(uint64(id), 10)}
+}
+
+func (f *fakeScheduleRepository) Pause(ctx context.Context, id uint, version uint) (domain.Schedule, error) {
+	return f.setPaused(ctx, id, version, true)
+}
+
+func (f *fakeScheduleRepository) Resume(ctx context.Context, id uint, version uint, on time.Time) (domain.Schedule, error) {
+	f.resumedOn = on
+	return f.setPaused(ctx, id, version, false)
+}
+
+func (f *fakeScheduleRepository) setPaused(ctx context.Context, id uint, version uint, paused bool) (domain.Schedule, error) {
+	schedule, err := f.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	fake := schedule.(*fakeSchedule)
+	if fake.paused == paused {
+		return nil, &domain.ConflictError{Entity: "schedule", Id: strconv.FormatUint(uint64(id), 10)}
+	}
+	fake.paused = paused
+	fake.version++
+	return fake, nil
+}
+
+func (f *fakeScheduleRepository) Materialize(ctx context.Context, id uint, occurrence time.Time) (domain.JournalEntry, error) {
+	return nil, &domain.ConflictError{Entity: "schedule", Id: strconv.FormatUint(uint64(id), 10)}
+}
+
+var fakeScheduleNow = time.Date(2026, time.October, 15, 12, 0, 0, 0, time.UTC)
+
+// newFakeScheduleStore gives a scheduleStore over fake repositories with
+// one fund and a schedule for rent in it on the 1st of each month.
+func newFakeScheduleStore() (*scheduleStore, *fakeScheduleRepository) {
+	funds := newFakeFundRepository([]fakeFund{{1, domain.CAD, "General", 1, time.Time{}}})
+	schedules := &fakeScheduleRepository{
+		schedules: []*fakeSchedule{{1, 1, "Rent", "FREQ=MONTHLY;BYMONTHDAY=1",
+			time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC),
+			[]domain.Posting{{AccountId: 2, Amount: 150000}, {AccountId: 1, Amount: -150000}},
+			false, time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC), 1}},
+		funds: funds,
+	}
+
+	now := func() time.Time { return fakeScheduleNow }
+	return &scheduleStore{schedules, &fundStore{funds, nil}, now}, schedules
+}
+
+func newScheduleObject(t *testing.T, id string, attributes map[string]interface{}, fundID string) *jsh.Object {
+	obj, jsherr := jsh.NewObject(id, "schedule", attributes)
+	require.Nil(t, jsherr, "Unable to create the schedule object.")
+
+	if fundID != "" {
+		obj.Relationships = map[string]*jsh.Relationship{
+			"fund": {Data: jsh.ResourceLinkage{{Type: "fund", ID: fundID}}},
+		}
+	}
+	return obj
+}
+
+func TestScheduleStoreSaveCreatesScheduleWithPostings(t *testing.T) {
+	assert := assert.New(t)
+	sut, repository := newFakeScheduleStore()
+	ctx, response := newRequestContext(t, nil)
+	obj := newScheduleObject(t, "", map[string]interface{}{
+		"description": "Payroll",
+		"rule":        "FREQ=WEEKLY;INTERVAL=2",
+		"start":       "2026-10-16",
+		"postings":    []map[string]interface{}{{"account": "3", "amount": 250000}, {"account": "1", "amount": -250000}},
+	}, "1")
+
+	result, jsherr := sut.Save(ctx, obj)
+
+	require.Nil(t, jsherr, "scheduleStore failed to save a schedule")
+	assert.Equal([]domain.Posting{{AccountId: 3, Amount: 250000}, {AccountId: 1, Amount: -250000}},
+		repository.createdPostings, "Unexpected postings passed to Create().")
+	assert.Equal("1", result.Relationships["fund"].Data[0].ID, "Unexpected fund for saved schedule.")
+	assert.Equal(`"1"`, response.Header().Get("ETag"), "Unexpected ETag for saved schedule.")
+}
+
+func TestScheduleStoreSaveWithBadRuleIsError(t *testing.T) {
+	sut, repository := newFakeScheduleStore()
+	obj := newScheduleObject(t, "", map[string]interface{}{
+		"description": "Payroll",
+		"rule":        "FREQ=HOURLY",
+		"start":       "2026-10-16",
+		"postings":    []map[string]interface{}{{"account": "3", "amount": 1}, {"account": "1", "amount": -1}},
+	}, "1")
+
+	_, jsherr := sut.Save(context.Background(), obj)
+
+	require.NotNil(t, jsherr, "scheduleStore unexpectedly saved a schedule with a bad rule")
+	assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(), "Unexpected status.")
+	assert.Nil(t, repository.createdPostings, "Saving a schedule with a bad rule called Create()")
+}
+
+func TestScheduleStoreUpdatePausesSchedule(t *testing.T) {
+	sut, repository := newFakeScheduleStore()
+	ctx, response := newRequestContext(t, map[string]string{"If-Match": `"1"`})
+	obj := newScheduleObject(t, "1", map[string]interface{}{"paused": true}, "")
+
+	_, jsherr := sut.Update(ctx, obj)
+
+	require.Nil(t, jsherr, "scheduleStore failed to pause a schedule")
+	assert.True(t, repository.schedules[0].paused, "The schedule was not paused.")
+	assert.Equal(t, `"2"`, response.Header().Get("ETag"), "Unexpected ETag for paused schedule.")
+}
+
+func TestScheduleStoreUpdateResumesScheduleNow(t *testing.T) {
+	sut, repository := newFakeScheduleStore()
+	repository.schedules[0].paused = true
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"1"`})
+	obj := newScheduleObject(t, "1", map[string]interface{}{"paused": false}, "")
+
+	_, jsherr := sut.Update(ctx, obj)
+
+	require.Nil(t, jsherr, "scheduleStore failed to resume a schedule")
+	assert.False(t, repository.schedules[0].paused, "The schedule was not resumed.")
+	assert.Equal(t, fakeScheduleNow, repository.resumedOn, "The schedule was not resumed now.")
+}
+
+func TestScheduleStoreUpdateWithoutPausedIsError(t *testing.T) {
+	sut, _ := newFakeScheduleStore()
+	ctx, _ := newRequestContext(t, map[string]string{"If-Match": `"1"`})
+	obj := newScheduleObject(t, "1", map[string]interface{}{"description": "Other"}, "")
+
+	_, jsherr := sut.Update(ctx, obj)
+
+	require.NotNil(t, jsherr, "scheduleStore unexpectedly updated a schedule")
+	assert.Equal(t, StatusUnprocessableEntity, jsherr.StatusCode(), "Unexpected status.")
+}
+
+func TestNewApiPreviewsScheduleOccurrences(t *testing.T) {
+	store, repository := newFakeScheduleStore()
+	request, response := getRequestResponse(t, "/v1/schedule/1/occurrences?count=3")
+
+	sut := newApi(&fakeStore{fundRepository: store.funds.repository, scheduleRepository: repository},
+		Clock(store.now))
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	var document occurrencesDocument
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &document), "Unable to parse the response.")
+	assert.Equal(t, []string{"2026-11-01", "2026-12-01", "2027-01-01"}, document.Meta.Occurrences)
+}
+
+func TestNewApiPreviewWithBadCountIsBadRequest(t *testing.T) {
+	store, repository := newFakeScheduleStore()
+	request, response := getRequestResponse(t, "/v1/schedule/1/occurrences?count=1000")
+
+	sut := newApi(&fakeStore{fundRepository: store.funds.repository, scheduleRepository: repository})
+	sut.ServeHTTPC(context.Background(), response, request)
+
+	assert.Equal(t, http.StatusBadRequest, response.Code, "Unexpected status code.")
+}
@@ -0,0 +1,186 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sbosnick1/openacct/domain"
+	"golang.org/x/net/context"
+)
+
+const (
+	accountResourceType     = "account"
+	accountFundRelationship = "fund"
+)
+
+// An Account is a named account within a fund. The Version is only known
+// for an Account that was returned by Get, Create or Update; it is zero for
+// an Account returned by a list.
+type Account struct {
+	ID      string
+	FundID  string
+	Name    string
+	Type    domain.AccountType
+	Version uint
+}
+
+type accountAttributes struct {
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+}
+
+// An AccountService creates, reads and updates accounts. Accounts can't be
+// deleted.
+type AccountService struct {
+	client *Client
+}
+
+// Create creates an account with the given name and type in the fund with
+// the given id.
+func (s *AccountService) Create(ctx context.Context, fundID string, name string,
+	accountType domain.AccountType) (*Account, error) {
+	body, err := newAccountObject("", accountAttributes{Name: name, Type: accountType.String()})
+	if err != nil {
+		return nil, err
+	}
+	body.Relationships = map[string]*relationship{
+		accountFundRelationship: {Data: []*identifier{{fundResourceType, fundID}}},
+	}
+
+	return s.send(ctx, http.MethodPost, accountResourceType, body, "")
+}
+
+// Get gets the account with the given id.
+func (s *AccountService) Get(ctx context.Context, id string) (*Account, error) {
+	return s.send(ctx, http.MethodGet, resourcePath(accountResourceType, id), nil, "")
+}
+
+// Update changes the name of the account with the given id. The update
+// only succeeds if the account is still at version.
+func (s *AccountService) Update(ctx context.Context, id string, version uint, name string) (*Account, error) {
+	body, err := newAccountObject(id, accountAttributes{Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.send(ctx, http.MethodPatch, resourcePath(accountResourceType, id), body, formatIfMatch(version))
+}
+
+// List lists all of the accounts.
+func (s *AccountService) List(ctx context.Context) ([]*Account, error) {
+	return drainAccounts(ctx, s.Iterate())
+}
+
+// ListByFund lists the accounts in the fund with the given id.
+func (s *AccountService) ListByFund(ctx context.Context, fundID string) ([]*Account, error) {
+	return drainAccounts(ctx, s.iterate(resourcePath(fundResourceType, fundID)+"/"+fundAccountsRelationship))
+}
+
+// Iterate gives an iterator over the accounts that fetches them a page at
+// a time.
+func (s *AccountService) Iterate() *AccountIterator {
+	return s.iterate(accountResourceType)
+}
+
+func (s *AccountService) iterate(path string) *AccountIterator {
+	next, err := s.client.resolve(path)
+	return &AccountIterator{pager: pager{client: s.client, next: next, err: err}}
+}
+
+func drainAccounts(ctx context.Context, iterator *AccountIterator) ([]*Account, error) {
+	accounts := []*Account{}
+	for iterator.Next(ctx) {
+		accounts = append(accounts, iterator.Account())
+	}
+
+	return accounts, iterator.Err()
+}
+
+// send sends a request for a single account and gives the account that is
+// the response.
+func (s *AccountService) send(ctx context.Context, method string, path string,
+	body *object, ifMatch string) (*Account, error) {
+	urlStr, err := s.client.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.do(ctx, method, urlStr, body, ifMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := resp.object()
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := newAccount(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	account.Version = resp.version
+	return account, nil
+}
+
+func newAccountObject(id string, attributes accountAttributes) (*object, error) {
+	encoded, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &object{Type: accountResourceType, ID: id, Attributes: encoded}, nil
+}
+
+func newAccount(obj *object) (*Account, error) {
+	var attributes accountAttributes
+	err := json.Unmarshal(obj.Attributes, &attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	accountType, err := domain.ParseAccountType(attributes.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &Account{ID: obj.ID, Name: attributes.Name, Type: accountType}
+	if fundIDs := obj.ids(accountFundRelationship); len(fundIDs) == 1 {
+		account.FundID = fundIDs[0]
+	}
+
+	return account, nil
+}
+
+// An AccountIterator iterates over a list of accounts in the same way that
+// a FundIterator iterates over a list of funds.
+type AccountIterator struct {
+	pager   pager
+	account *Account
+}
+
+// Next moves to the next account. It returns false when there are no more
+// accounts or there was an error.
+func (i *AccountIterator) Next(ctx context.Context) bool {
+	if !i.pager.advance(ctx) {
+		return false
+	}
+
+	i.account, i.pager.err = newAccount(i.pager.current)
+	return i.pager.err == nil
+}
+
+// Account gives the current account.
+func (i *AccountIterator) Account() *Account {
+	return i.account
+}
+
+// Err gives the error that stopped the iteration, if any.
+func (i *AccountIterator) Err() error {
+	return i.pager.err
+}
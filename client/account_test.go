@@ -0,0 +1,65 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+const cashAccount = `{"type": "account", "id": "4",
+	"attributes": {"name": "Cash", "type": "asset"},
+	"relationships": {"fund": {"data": [{"type": "fund", "id": "1"}]}}}`
+
+func TestAccountServiceCreateLinksFund(t *testing.T) {
+	var body map[string]*object
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		writeDocument(w, http.StatusCreated, `"1"`, `{"data": `+cashAccount+`}`)
+	})
+	defer server.Close()
+
+	account, err := sut.Accounts().Create(context.Background(), "1", "Cash", domain.Asset)
+
+	require.NoError(t, err, "Create() failed.")
+	assert.Equal(t, []string{"1"}, body["data"].ids("fund"), "Unexpected fund sent.")
+	assert.Equal(t, &Account{ID: "4", FundID: "1", Name: "Cash", Type: domain.Asset, Version: 1},
+		account, "Unexpected account.")
+}
+
+func TestAccountServiceListByFundUsesRelatedPath(t *testing.T) {
+	var path string
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		writeDocument(w, http.StatusOK, "", `{"data": [`+cashAccount+`]}`)
+	})
+	defer server.Close()
+
+	accounts, err := sut.Accounts().ListByFund(context.Background(), "1")
+
+	require.NoError(t, err, "ListByFund() failed.")
+	assert.Equal(t, "/v1/fund/1/accounts", path, "Unexpected path.")
+	assert.Len(t, accounts, 1, "Unexpected number of accounts.")
+}
+
+func TestAccountServiceUpdateWithStaleVersionIsPreconditionFailed(t *testing.T) {
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeDocument(w, http.StatusPreconditionFailed, "",
+			`{"errors": [{"status": "412", "title": "Precondition Failed"}]}`)
+	})
+	defer server.Close()
+
+	_, err := sut.Accounts().Update(context.Background(), "4", 1, "Petty Cash")
+
+	assert.True(t, IsPreconditionFailed(err), "Unexpected error: %v", err)
+}
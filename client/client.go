@@ -0,0 +1,281 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+// Package client provides a typed Go client for the openacct JSON API.
+//
+// A Client is created for the base URL of the api service, for example
+// "http://localhost:8080/v1/", and gives access to a service for each
+// resource type:
+//
+//	c, err := client.New("http://localhost:8080/v1/")
+//	fund, err := c.Funds().Create(ctx, "General", domain.CAD)
+//
+// Errors reported by the api service are returned as *client.Error.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+const (
+	contentType   = "application/vnd.api+json"
+	etagHeader    = "ETag"
+	ifMatchHeader = "If-Match"
+)
+
+// AnyVersion is the version to give to an update or a delete that should
+// succeed whatever the current version of the resource is.
+const AnyVersion uint = 0
+
+// A Client is a client for the openacct JSON API.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+}
+
+// An Option adjusts the behaviour of a Client.
+type Option func(*Client)
+
+// WithHTTPClient makes a Client send its requests with httpClient instead
+// of http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New creates a Client for the api service at baseURL.
+func New(baseURL string, options ...Option) (*Client, error) {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{baseURL: parsed, httpClient: http.DefaultClient}
+	for _, option := range options {
+		option(c)
+	}
+
+	return c, nil
+}
+
+// Funds gives the service for the fund resources.
+func (c *Client) Funds() *FundService {
+	return &FundService{c}
+}
+
+// Accounts gives the service for the account resources.
+func (c *Client) Accounts() *AccountService {
+	return &AccountService{c}
+}
+
+// document is the form of a JSON API document that the client reads.
+type document struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []*Error        `json:"errors"`
+	Links  struct {
+		Next json.RawMessage `json:"next"`
+	} `json:"links"`
+}
+
+type object struct {
+	Type          string                   `json:"type"`
+	ID            string                   `json:"id,omitempty"`
+	Attributes    json.RawMessage          `json:"attributes,omitempty"`
+	Relationships map[string]*relationship `json:"relationships,omitempty"`
+}
+
+type relationship struct {
+	Data []*identifier `json:"data"`
+}
+
+type identifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// ids gives the ids of the resources that the named relationship of the
+// object links to.
+func (o *object) ids(name string) []string {
+	var ids []string
+	if relationship, ok := o.Relationships[name]; ok && relationship != nil {
+		for _, identifier := range relationship.Data {
+			ids = append(ids, identifier.ID)
+		}
+	}
+	return ids
+}
+
+// response is a response from the api service.
+type response struct {
+	document *document
+	version  uint
+}
+
+// object gives the single object that is the primary data of the response.
+func (r *response) object() (*object, error) {
+	var obj object
+	err := json.Unmarshal(r.document.Data, &obj)
+	if err != nil {
+		return nil, err
+	}
+	return &obj, nil
+}
+
+// list gives the list of objects that is the primary data of the response.
+func (r *response) list() ([]*object, error) {
+	var objs []*object
+	err := json.Unmarshal(r.document.Data, &objs)
+	if err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+// next gives the URL of the next page of a list or "" for the last page.
+func (r *response) next() string {
+	next := bytes.TrimSpace(r.document.Links.Next)
+	if len(next) == 0 || bytes.Equal(next, []byte("null")) {
+		return ""
+	}
+
+	var href string
+	if json.Unmarshal(next, &href) == nil {
+		return href
+	}
+
+	var link struct {
+		HREF string `json:"href"`
+	}
+	json.Unmarshal(next, &link)
+	return link.HREF
+}
+
+// resolve gives the URL for a path relative to the base URL.
+func (c *Client) resolve(path string) (string, error) {
+	relative, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	return c.baseURL.ResolveReference(relative).String(), nil
+}
+
+// do sends a request for the given URL. A body that isn't nil is sent as
+// the primary data of a JSON API document and an ifMatch that isn't empty
+// is sent as the If-Match header.
+func (c *Client) do(ctx context.Context, method string, urlStr string,
+	body *object, ifMatch string) (*response, error) {
+	var payload io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(map[string]*object{"data": body})
+		if err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequest(method, urlStr, payload)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", contentType)
+	if body != nil {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if ifMatch != "" {
+		request.Header.Set(ifMatchHeader, ifMatch)
+	}
+
+	httpResponse, err := ctxhttp.Do(ctx, c.httpClient, request)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	var doc document
+	err = json.NewDecoder(httpResponse.Body).Decode(&doc)
+	if err != nil && err != io.EOF {
+		if httpResponse.StatusCode >= http.StatusBadRequest {
+			return nil, &Error{Status: httpResponse.StatusCode, Title: http.StatusText(httpResponse.StatusCode)}
+		}
+		return nil, err
+	}
+
+	if httpResponse.StatusCode >= http.StatusBadRequest {
+		return nil, newResponseError(httpResponse.StatusCode, doc.Errors)
+	}
+
+	return &response{&doc, parseETag(httpResponse.Header.Get(etagHeader))}, nil
+}
+
+// formatIfMatch gives the If-Match header for a version.
+func formatIfMatch(version uint) string {
+	if version == AnyVersion {
+		return "*"
+	}
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// parseETag gives the version in an ETag or zero if there isn't one.
+func parseETag(etag string) uint {
+	version, err := strconv.ParseUint(strings.Trim(etag, `"`), 10, 0)
+	if err != nil {
+		return 0
+	}
+	return uint(version)
+}
+
+// resourcePath gives the path of a resource relative to the base URL.
+func resourcePath(resourceType string, id string) string {
+	return fmt.Sprintf("%s/%s", resourceType, url.QueryEscape(id))
+}
+
+// A pager pages through the list of resources at a URL by following the
+// next links of the pages.
+type pager struct {
+	client  *Client
+	next    string
+	page    []*object
+	current *object
+	err     error
+}
+
+// advance moves to the next resource in the list. It returns false at the
+// end of the list or if a page can't be fetched.
+func (p *pager) advance(ctx context.Context) bool {
+	for len(p.page) == 0 {
+		if p.err != nil || p.next == "" {
+			return false
+		}
+
+		resp, err := p.client.do(ctx, http.MethodGet, p.next, nil, "")
+		if err != nil {
+			p.err = err
+			return false
+		}
+
+		p.page, p.err = resp.list()
+		p.next = ""
+		if next := resp.next(); next != "" && p.err == nil {
+			p.next, p.err = p.client.resolve(next)
+		}
+	}
+
+	p.current, p.page = p.page[0], p.page[1:]
+	return true
+}
@@ -0,0 +1,115 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// newTestClient gives a Client for a test server that uses handler to
+// respond to requests.
+func newTestClient(t *testing.T, handler http.HandlerFunc) (*Client, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	sut, err := New(server.URL + "/v1")
+	require.NoError(t, err, "Unable to create the client.")
+	return sut, server
+}
+
+func writeDocument(w http.ResponseWriter, status int, etag string, body string) {
+	w.Header().Set("Content-Type", contentType)
+	if etag != "" {
+		w.Header().Set(etagHeader, etag)
+	}
+	w.WriteHeader(status)
+	fmt.Fprint(w, body)
+}
+
+func TestNewAddsTrailingSlashToBaseURL(t *testing.T) {
+	sut, err := New("http://example.com/v1")
+
+	require.NoError(t, err, "New() failed.")
+	actual, err := sut.resolve("fund")
+	require.NoError(t, err, "resolve() failed.")
+	assert.Equal(t, "http://example.com/v1/fund", actual, "Unexpected resolved URL.")
+}
+
+func TestWithHTTPClientReplacesDefaultClient(t *testing.T) {
+	httpClient := &http.Client{}
+
+	sut, err := New("http://example.com/v1/", WithHTTPClient(httpClient))
+
+	require.NoError(t, err, "New() failed.")
+	assert.Equal(t, httpClient, sut.httpClient, "The http client was not replaced.")
+}
+
+func TestFormatIfMatchQuotesVersion(t *testing.T) {
+	assert.Equal(t, `"3"`, formatIfMatch(3), "Unexpected If-Match for a version.")
+	assert.Equal(t, "*", formatIfMatch(AnyVersion), "Unexpected If-Match for any version.")
+}
+
+func TestParseETagGivesVersion(t *testing.T) {
+	assert.Equal(t, uint(3), parseETag(`"3"`), "Unexpected version for an ETag.")
+	assert.Equal(t, uint(0), parseETag(""), "Unexpected version for a missing ETag.")
+}
+
+func TestPagerFollowsNextLinks(t *testing.T) {
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			writeDocument(w, http.StatusOK, "", `{"data": [{"type": "fund", "id": "1"}],
+				"links": {"next": "/v1/fund?page=2"}}`)
+		default:
+			writeDocument(w, http.StatusOK, "", `{"data": [{"type": "fund", "id": "2"}],
+				"links": {"next": null}}`)
+		}
+	})
+	defer server.Close()
+	next, _ := sut.resolve("fund")
+	pager := pager{client: sut, next: next}
+
+	var ids []string
+	for pager.advance(context.Background()) {
+		ids = append(ids, pager.current.ID)
+	}
+
+	require.NoError(t, pager.err, "Paging failed.")
+	assert.Equal(t, []string{"1", "2"}, ids, "Unexpected resources from the pages.")
+}
+
+func TestDoReturnsErrorFromResponse(t *testing.T) {
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeDocument(w, http.StatusConflict, "", `{"errors": [{"status": "409",
+			"title": "Duplicate Attribute", "source": {"pointer": "/data/attributes/name"}}]}`)
+	})
+	defer server.Close()
+	urlStr, _ := sut.resolve("fund")
+
+	_, err := sut.do(context.Background(), http.MethodPost, urlStr, &object{Type: "fund"}, "")
+
+	require.Error(t, err, "do() unexpectedly succeeded.")
+	assert.True(t, IsConflict(err), "Unexpected error: %v", err)
+	assert.Equal(t, "/data/attributes/name", err.(*Error).Source.Pointer, "Unexpected pointer.")
+}
+
+func TestDoWithCancelledContextFails(t *testing.T) {
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeDocument(w, http.StatusOK, "", `{"data": []}`)
+	})
+	defer server.Close()
+	urlStr, _ := sut.resolve("fund")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := sut.do(ctx, http.MethodGet, urlStr, nil, "")
+
+	assert.Error(t, err, "do() unexpectedly succeeded with a cancelled context.")
+}
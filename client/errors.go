@@ -0,0 +1,72 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// An Error is an error reported by the api service. Pointer is the JSON
+// pointer to the part of the request that caused the error, if any.
+type Error struct {
+	Status int    `json:"status,string"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+	Source struct {
+		Pointer string `json:"pointer"`
+	} `json:"source"`
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%d %s", e.Status, e.Title)
+	}
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Title, e.Detail)
+}
+
+// newResponseError gives the error for a response with the given status.
+// It is the first of the errors in the response or a generic error for the
+// status if the response has none.
+func newResponseError(status int, errors []*Error) *Error {
+	if len(errors) > 0 && errors[0] != nil {
+		e := *errors[0]
+		if e.Status == 0 {
+			e.Status = status
+		}
+		return &e
+	}
+
+	return &Error{Status: status, Title: http.StatusText(status)}
+}
+
+// IsNotFound reports whether err is an Error for a resource that doesn't
+// exist.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err is an Error for a request that conflicts
+// with the current state of a resource, such as a duplicate name.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsPreconditionFailed reports whether err is an Error for an update or a
+// delete of a resource that someone else changed first.
+func IsPreconditionFailed(err error) bool {
+	return hasStatus(err, http.StatusPreconditionFailed)
+}
+
+// IsInvalid reports whether err is an Error for a request with an invalid
+// attribute or relationship.
+func IsInvalid(err error) bool {
+	return hasStatus(err, 422)
+}
+
+func hasStatus(err error, status int) bool {
+	e, ok := err.(*Error)
+	return ok && e.Status == status
+}
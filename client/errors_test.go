@@ -0,0 +1,33 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package client
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewResponseErrorUsesFirstError(t *testing.T) {
+	sut := newResponseError(http.StatusNotFound, []*Error{{Title: "Not Found", Detail: "No fund 3"}})
+
+	assert.Equal(t, http.StatusNotFound, sut.Status, "Unexpected status.")
+	assert.Equal(t, "404 Not Found: No fund 3", sut.Error(), "Unexpected error message.")
+}
+
+func TestNewResponseErrorWithoutErrorsUsesStatus(t *testing.T) {
+	sut := newResponseError(http.StatusPreconditionFailed, nil)
+
+	assert.True(t, IsPreconditionFailed(sut), "Unexpected error: %v", sut)
+}
+
+func TestErrorPredicatesCheckStatus(t *testing.T) {
+	assert.True(t, IsNotFound(&Error{Status: http.StatusNotFound}))
+	assert.True(t, IsInvalid(&Error{Status: 422}))
+	assert.False(t, IsConflict(&Error{Status: http.StatusNotFound}))
+	assert.False(t, IsNotFound(errors.New("not found")))
+}
@@ -0,0 +1,225 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sbosnick1/openacct/domain"
+	"golang.org/x/net/context"
+)
+
+const (
+	fundResourceType         = "fund"
+	fundAccountsRelationship = "accounts"
+)
+
+// A Fund is a named collection of accounts that are all denominated in the
+// same currency. The Version is only known for a Fund that was returned by
+// Get, Create, Update or Unarchive; it is zero for a Fund returned by a list.
+type Fund struct {
+	ID         string
+	Name       string
+	Currency   domain.Currency
+	Archived   bool
+	ArchivedAt time.Time
+	AccountIDs []string
+	Version    uint
+}
+
+type fundAttributes struct {
+	Name       string `json:"name,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+	Template   string `json:"template,omitempty"`
+	Archived   *bool  `json:"archived,omitempty"`
+	ArchivedAt string `json:"archived-at,omitempty"`
+}
+
+// A FundService creates, reads, updates and deletes funds.
+type FundService struct {
+	client *Client
+}
+
+// ListOptions adjust the funds that List and Iterate give.
+type ListOptions struct {
+	IncludeArchived bool
+}
+
+// Create creates a fund with the given name and currency.
+func (s *FundService) Create(ctx context.Context, name string, currency domain.Currency) (*Fund, error) {
+	attributes := fundAttributes{Name: name, Currency: currency.String()}
+	return s.send(ctx, http.MethodPost, fundResourceType, "", attributes, "")
+}
+
+// CreateFromTemplate creates a fund with the given name and currency that
+// has the accounts of the chart of accounts template with the given id,
+// such as "nonprofit-basic", "church" or "small-business".
+func (s *FundService) CreateFromTemplate(ctx context.Context, name string, currency domain.Currency,
+	template string) (*Fund, error) {
+	attributes := fundAttributes{Name: name, Currency: currency.String(), Template: template}
+	return s.send(ctx, http.MethodPost, fundResourceType, "", attributes, "")
+}
+
+// Get gets the fund with the given id.
+func (s *FundService) Get(ctx context.Context, id string) (*Fund, error) {
+	return s.send(ctx, http.MethodGet, resourcePath(fundResourceType, id), "", nil, "")
+}
+
+// Update changes the name and currency of the fund with the given id. The
+// update only succeeds if the fund is still at version.
+func (s *FundService) Update(ctx context.Context, id string, version uint, name string,
+	currency domain.Currency) (*Fund, error) {
+	attributes := fundAttributes{Name: name, Currency: currency.String()}
+	return s.send(ctx, http.MethodPatch, resourcePath(fundResourceType, id), id,
+		attributes, formatIfMatch(version))
+}
+
+// Unarchive makes an archived fund with the given id available again. It
+// only succeeds if the fund is still at version.
+func (s *FundService) Unarchive(ctx context.Context, id string, version uint) (*Fund, error) {
+	archived := false
+	return s.send(ctx, http.MethodPatch, resourcePath(fundResourceType, id), id,
+		fundAttributes{Archived: &archived}, formatIfMatch(version))
+}
+
+// Delete archives the fund with the given id. It only succeeds if the fund
+// is still at version. The api service sends no fund in its response.
+func (s *FundService) Delete(ctx context.Context, id string, version uint) error {
+	urlStr, err := s.client.resolve(resourcePath(fundResourceType, id))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.do(ctx, http.MethodDelete, urlStr, nil, formatIfMatch(version))
+	return err
+}
+
+// List lists all of the funds.
+func (s *FundService) List(ctx context.Context, options ListOptions) ([]*Fund, error) {
+	iterator := s.Iterate(options)
+
+	funds := []*Fund{}
+	for iterator.Next(ctx) {
+		funds = append(funds, iterator.Fund())
+	}
+
+	return funds, iterator.Err()
+}
+
+// Iterate gives an iterator over the funds that fetches them a page at a
+// time.
+func (s *FundService) Iterate(options ListOptions) *FundIterator {
+	path := fundResourceType
+	if options.IncludeArchived {
+		path += "?filter%5Binclude-archived%5D=true"
+	}
+
+	next, err := s.client.resolve(path)
+	return &FundIterator{pager: pager{client: s.client, next: next, err: err}}
+}
+
+// send sends a request for a single fund and gives the fund that is the
+// response. Attributes that aren't nil are sent as the fund.
+func (s *FundService) send(ctx context.Context, method string, path string, id string,
+	attributes interface{}, ifMatch string) (*Fund, error) {
+	urlStr, err := s.client.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var body *object
+	if attributes != nil {
+		encoded, err := json.Marshal(attributes)
+		if err != nil {
+			return nil, err
+		}
+		body = &object{Type: fundResourceType, ID: id, Attributes: encoded}
+	}
+
+	resp, err := s.client.do(ctx, method, urlStr, body, ifMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := resp.object()
+	if err != nil {
+		return nil, err
+	}
+
+	fund, err := newFund(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	fund.Version = resp.version
+	return fund, nil
+}
+
+func newFund(obj *object) (*Fund, error) {
+	var attributes fundAttributes
+	err := json.Unmarshal(obj.Attributes, &attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := domain.ParseCurrency(attributes.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	fund := &Fund{
+		ID:         obj.ID,
+		Name:       attributes.Name,
+		Currency:   currency,
+		Archived:   attributes.Archived != nil && *attributes.Archived,
+		AccountIDs: obj.ids(fundAccountsRelationship),
+	}
+
+	if attributes.ArchivedAt != "" {
+		fund.ArchivedAt, err = time.Parse(time.RFC3339, attributes.ArchivedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return fund, nil
+}
+
+// A FundIterator iterates over a list of funds:
+//
+//	iterator := c.Funds().Iterate(client.ListOptions{})
+//	for iterator.Next(ctx) {
+//	    fund := iterator.Fund()
+//	}
+//	if err := iterator.Err(); err != nil {
+//	    ...
+//	}
+type FundIterator struct {
+	pager pager
+	fund  *Fund
+}
+
+// Next moves to the next fund. It returns false when there are no more
+// funds or there was an error.
+func (i *FundIterator) Next(ctx context.Context) bool {
+	if !i.pager.advance(ctx) {
+		return false
+	}
+
+	i.fund, i.pager.err = newFund(i.pager.current)
+	return i.pager.err == nil
+}
+
+// Fund gives the current fund.
+func (i *FundIterator) Fund() *Fund {
+	return i.fund
+}
+
+// Err gives the error that stopped the iteration, if any.
+func (i *FundIterator) Err() error {
+	return i.pager.err
+}
@@ -0,0 +1,148 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sbosnick1/openacct/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+const generalFund = `{"type": "fund", "id": "1",
+	"attributes": {"name": "General", "currency": "CAD"},
+	"relationships": {"accounts": {"data": [{"type": "account", "id": "4"}]}}}`
+
+func TestFundServiceCreatePostsFund(t *testing.T) {
+	assert := assert.New(t)
+	var method, path string
+	var body map[string]*object
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		writeDocument(w, http.StatusCreated, `"1"`, `{"data": `+generalFund+`}`)
+	})
+	defer server.Close()
+
+	fund, err := sut.Funds().Create(context.Background(), "General", domain.CAD)
+
+	require.NoError(t, err, "Create() failed.")
+	assert.Equal(http.MethodPost, method, "Unexpected method.")
+	assert.Equal("/v1/fund", path, "Unexpected path.")
+	assert.JSONEq(`{"name": "General", "currency": "CAD"}`, string(body["data"].Attributes),
+		"Unexpected attributes sent.")
+	assert.Equal(&Fund{ID: "1", Name: "General", Currency: domain.CAD,
+		AccountIDs: []string{"4"}, Version: 1}, fund, "Unexpected fund.")
+}
+
+func TestFundServiceCreateFromTemplatePostsTemplate(t *testing.T) {
+	var body map[string]*object
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		writeDocument(w, http.StatusCreated, `"1"`, `{"data": `+generalFund+`}`)
+	})
+	defer server.Close()
+
+	_, err := sut.Funds().CreateFromTemplate(context.Background(), "General", domain.CAD, "church")
+
+	require.NoError(t, err, "CreateFromTemplate() failed.")
+	assert.JSONEq(t, `{"name": "General", "currency": "CAD", "template": "church"}`,
+		string(body["data"].Attributes), "Unexpected attributes sent.")
+}
+
+func TestFundServiceGetParsesArchivedFund(t *testing.T) {
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeDocument(w, http.StatusOK, `"3"`, `{"data": {"type": "fund", "id": "2",
+			"attributes": {"name": "Special", "currency": "USD",
+				"archived": true, "archived-at": "2016-03-31T00:00:00Z"}}}`)
+	})
+	defer server.Close()
+
+	fund, err := sut.Funds().Get(context.Background(), "2")
+
+	require.NoError(t, err, "Get() failed.")
+	assert.True(t, fund.Archived, "The fund is unexpectedly not archived.")
+	assert.Equal(t, time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC), fund.ArchivedAt,
+		"Unexpected archived time.")
+	assert.Equal(t, uint(3), fund.Version, "Unexpected version.")
+}
+
+func TestFundServiceGetUnknownFundIsNotFound(t *testing.T) {
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeDocument(w, http.StatusNotFound, "", `{"errors": [{"status": "404", "title": "Not Found"}]}`)
+	})
+	defer server.Close()
+
+	_, err := sut.Funds().Get(context.Background(), "9")
+
+	assert.True(t, IsNotFound(err), "Unexpected error: %v", err)
+}
+
+func TestFundServiceUpdateSendsIfMatch(t *testing.T) {
+	var method, ifMatch string
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		method, ifMatch = r.Method, r.Header.Get(ifMatchHeader)
+		writeDocument(w, http.StatusOK, `"2"`, `{"data": `+generalFund+`}`)
+	})
+	defer server.Close()
+
+	fund, err := sut.Funds().Update(context.Background(), "1", 1, "General", domain.CAD)
+
+	require.NoError(t, err, "Update() failed.")
+	assert.Equal(t, http.MethodPatch, method, "Unexpected method.")
+	assert.Equal(t, `"1"`, ifMatch, "Unexpected If-Match header.")
+	assert.Equal(t, uint(2), fund.Version, "Unexpected version.")
+}
+
+func TestFundServiceDeleteSendsIfMatch(t *testing.T) {
+	var method, ifMatch string
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		method, ifMatch = r.Method, r.Header.Get(ifMatchHeader)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	defer server.Close()
+
+	err := sut.Funds().Delete(context.Background(), "1", AnyVersion)
+
+	require.NoError(t, err, "Delete() failed.")
+	assert.Equal(t, http.MethodDelete, method, "Unexpected method.")
+	assert.Equal(t, "*", ifMatch, "Unexpected If-Match header.")
+}
+
+func TestFundServiceListWithIncludeArchivedSetsFilter(t *testing.T) {
+	var filter string
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		filter = r.URL.Query().Get("filter[include-archived]")
+		writeDocument(w, http.StatusOK, "", `{"data": [`+generalFund+`]}`)
+	})
+	defer server.Close()
+
+	funds, err := sut.Funds().List(context.Background(), ListOptions{IncludeArchived: true})
+
+	require.NoError(t, err, "List() failed.")
+	assert.Equal(t, "true", filter, "Unexpected include-archived filter.")
+	require.Len(t, funds, 1, "Unexpected number of funds.")
+	assert.Equal(t, "General", funds[0].Name, "Unexpected fund.")
+}
+
+func TestFundServiceListOfNoFundsIsEmpty(t *testing.T) {
+	sut, server := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeDocument(w, http.StatusOK, "", `{"data": []}`)
+	})
+	defer server.Close()
+
+	funds, err := sut.Funds().List(context.Background(), ListOptions{})
+
+	require.NoError(t, err, "List() failed.")
+	assert.Empty(t, funds, "Unexpected funds.")
+}
@@ -5,18 +5,196 @@
 package openacctapi
 
 import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sbosnick1/openacct/apiservice"
 	"github.com/sbosnick1/openacct/domain"
+	"github.com/sbosnick1/openacct/health"
+	"github.com/sbosnick1/openacct/logger"
+	"github.com/sbosnick1/openacct/metrics"
 	"net/http"
+	"os"
+	"text/template"
+	"time"
 )
 
-func BuildApiHandler(dsn string) (http.Handler, error) {
+// The paths of the endpoints served by the api handler in addition to the
+// api service.
+const (
+	HealthPath = "/healthz"
+	ReadyPath  = "/readyz"
+)
+
+// The paths of the endpoints served by the admin handler.
+const (
+	LogLevelsPath = "/loglevels"
+	MetricsPath   = "/metrics"
+)
+
+// ReceiptTemplateEnv is the environment variable that names the receipt
+// template file when the ReceiptTemplateFile option isn't given.
+const ReceiptTemplateEnv = "OPENACCT_RECEIPT_TEMPLATE"
+
+// DefaultSchedulerInterval is a suitable interval at which to run the
+// scheduler built by BuildScheduler. Occurrences are due on a date so there
+// is little point in running it more often.
+const DefaultSchedulerInterval = time.Hour
+
+// An Option adjusts the handler built by BuildApiHandler.
+type Option func(*buildConfig)
+
+type buildConfig struct {
+	healthCheckTimeout time.Duration
+	logConfigFile      string
+	metrics            *prometheus.Registry
+	identify           func(r *http.Request) (domain.Principal, bool)
+	receiptTemplate    string
+}
+
+// ReceiptTemplateFile sets the file of the text/template that receipts are
+// rendered from, in the form described by apiservice.ReceiptTemplate. A
+// charity uses it to add its name and registration number to its receipts.
+// The default is the file named by the OPENACCT_RECEIPT_TEMPLATE
+// environment variable, if it is set, and otherwise
+// apiservice.DefaultReceiptTemplate.
+func ReceiptTemplateFile(path string) Option {
+	return func(cfg *buildConfig) {
+		cfg.receiptTemplate = path
+	}
+}
+
+// ErrNoIdentify is the error from BuildApiHandler when it isn't given the
+// Identify option.
+var ErrNoIdentify = errors.New("openacctapi: the api handler requires an Identify option")
+
+// Identify sets the function that authenticates the user that made a
+// request to the api service and gives the principal, with the user's
+// roles, on whose behalf the request acts (see apiservice.Identify and
+// apiservice.BasicAuth). It is required.
+func Identify(identify func(r *http.Request) (domain.Principal, bool)) Option {
+	return func(cfg *buildConfig) {
+		cfg.identify = identify
+	}
+}
+
+// HealthCheckTimeout sets how long each of the checks made for the readiness
+// endpoint may take before it fails. The default is health.DefaultTimeout.
+func HealthCheckTimeout(timeout time.Duration) Option {
+	return func(cfg *buildConfig) {
+		cfg.healthCheckTimeout = timeout
+	}
+}
+
+// LogConfigFile sets the logging configuration file, in the form read by
+// logger.ConfigFromFile, that BuildApiHandler applies to the loggers of all
+// packages. The default is the file named by the OPENACCT_LOG_CONFIG
+// environment variable, if it is set.
+func LogConfigFile(path string) Option {
+	return func(cfg *buildConfig) {
+		cfg.logConfigFile = path
+	}
+}
+
+// MetricsRegistry sets the registry in which the handler built by
+// BuildApiHandler records the metrics of its requests and of its database
+// connection pool. Serve the registry with BuildAdminHandler. A registry
+// belongs to a single handler. The default is a new registry for each
+// handler.
+func MetricsRegistry(registry *prometheus.Registry) Option {
+	return func(cfg *buildConfig) {
+		cfg.metrics = registry
+	}
+}
+
+// BuildApiHandler builds the handler for the api service backed by the
+// database given by dsn. The handler also serves a liveness endpoint at
+// HealthPath and a readiness endpoint at ReadyPath which checks that the
+// database can be reached and has the expected schema. If there is a
+// logging configuration file it is applied before anything else is done.
+// The receipt template file, if there is one, is parsed before the database
+// is opened. BuildApiHandler returns ErrNoIdentify if it isn't given the Identify
+// option.
+func BuildApiHandler(dsn string, options ...Option) (http.Handler, error) {
+	cfg := &buildConfig{
+		healthCheckTimeout: health.DefaultTimeout,
+		logConfigFile:      os.Getenv(logger.ConfigFileEnv),
+		metrics:            prometheus.NewRegistry(),
+		receiptTemplate:    os.Getenv(ReceiptTemplateEnv),
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+	if cfg.identify == nil {
+		return nil, ErrNoIdentify
+	}
+
+	if cfg.logConfigFile != "" {
+		config, err := logger.ConfigFromFile(cfg.logConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		logger.Configure(config)
+	}
+
+	apiOptions := []apiservice.Option{
+		apiservice.MetricsRegistry(cfg.metrics),
+		apiservice.Identify(cfg.identify),
+	}
+	if cfg.receiptTemplate != "" {
+		tmpl, err := template.ParseFiles(cfg.receiptTemplate)
+		if err != nil {
+			return nil, err
+		}
+		apiOptions = append(apiOptions, apiservice.ReceiptTemplate(tmpl))
+	}
+
+	store, err := domain.New(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats, ok := store.(domain.DBStatsProvider); ok {
+		err = metrics.RegisterDBStats(cfg.metrics, stats.DBStats)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var checks []health.Check
+	if checker, ok := store.(domain.HealthChecker); ok {
+		checks = append(checks,
+			health.Check{Name: "database", Fn: checker.Ping},
+			health.Check{Name: "schema", Fn: checker.CheckSchema})
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(HealthPath, health.Handler(cfg.healthCheckTimeout))
+	mux.Handle(ReadyPath, health.Handler(cfg.healthCheckTimeout, checks...))
+	mux.Handle("/", apiservice.New(store, apiOptions...))
+
+	return mux, nil
+}
+
+// BuildScheduler builds the scheduler that materializes the due occurrences
+// of the schedules in the database given by dsn. The caller runs it, for
+// example with Run(ctx, DefaultSchedulerInterval) in its own goroutine.
+func BuildScheduler(dsn string) (*domain.Scheduler, error) {
 	store, err := domain.New(dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	handler := apiservice.New(store)
+	return domain.NewScheduler(store, time.Now), nil
+}
+
+// BuildAdminHandler builds the handler for the administrative endpoints
+// which serves the metrics in registry, the registry given to
+// BuildApiHandler by MetricsRegistry. It must be served on an address that
+// only administrators can reach.
+func BuildAdminHandler(registry *prometheus.Registry) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(LogLevelsPath, logger.Handler())
+	mux.Handle(MetricsPath, metrics.Handler(registry))
 
-	return handler, nil
+	return mux
 }
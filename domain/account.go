@@ -0,0 +1,176 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/net/context"
+)
+
+const (
+	accountEntity string = "account"
+)
+
+// An Account is a named account within a Fund. Transactions in an Account
+// are denominated in the currency of its Fund. The Version of an Account
+// changes every time the Account is updated.
+type Account interface {
+	Id() uint
+	FundId() uint
+	Name() string
+	Type() AccountType
+	Version() uint
+}
+
+type accountImpl struct {
+	ID             uint
+	AccountFundID  uint        `sql:"not null;unique_index:idx_account_fund_name"`
+	AccountName    string      `sql:"size:255;unique_index:idx_account_fund_name"`
+	AccountType    AccountType `sql:"not null"`
+	AccountVersion uint        `sql:"not null;default:1"`
+}
+
+func (a *accountImpl) Id() uint {
+	return a.ID
+}
+
+func (a *accountImpl) FundId() uint {
+	return a.AccountFundID
+}
+
+func (a *accountImpl) Name() string {
+	return a.AccountName
+}
+
+func (a *accountImpl) Type() AccountType {
+	return a.AccountType
+}
+
+func (a *accountImpl) Version() uint {
+	return a.AccountVersion
+}
+
+// The AccountRepository is the means of accessing the Account's in the
+// store. Create and Update return a *ValidationError if the name is empty
+// and a *DuplicateError if the fund already has an account with the same
+// name. Create returns a *NotFoundError if there is no fund with the given
+// id and a *ConflictError if the fund is archived. Get and Update return a
+// *NotFoundError if there is no account with the given id. Update only
+// changes the account if its version is the given version and otherwise
+// returns a *ConcurrencyError.
+type AccountRepository interface {
+	GetAll(ctx context.Context) ([]Account, error)
+	GetByFund(ctx context.Context, fundId uint) ([]Account, error)
+	Get(ctx context.Context, id uint) (Account, error)
+	Create(ctx context.Context, fundId uint, name string, accountType AccountType) (Account, error)
+	Update(ctx context.Context, id uint, version uint, name string) (Account, error)
+}
+
+type accountRepository struct {
+	db *gorm.DB
+}
+
+func (a *accountRepository) GetAll(ctx context.Context) ([]Account, error) {
+	return a.find(ctx, withContext(ctx, a.db))
+}
+
+func (a *accountRepository) GetByFund(ctx context.Context, fundId uint) ([]Account, error) {
+	return a.find(ctx, withContext(ctx, a.db).Where("account_fund_id = ?", fundId))
+}
+
+func (a *accountRepository) find(ctx context.Context, db *gorm.DB) ([]Account, error) {
+	var accounts []accountImpl
+
+	err := db.Order("id").Find(&accounts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Account
+	for i := range accounts {
+		ret = append(ret, &accounts[i])
+	}
+
+	return ret, nil
+}
+
+func (a *accountRepository) Get(ctx context.Context, id uint) (Account, error) {
+	var account accountImpl
+
+	err := withContext(ctx, a.db).First(&account, id).Error
+	if isRecordNotFound(err) {
+		return nil, &NotFoundError{accountEntity, formatId(id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (a *accountRepository) Create(ctx context.Context, fundId uint, name string,
+	accountType AccountType) (Account, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, &ValidationError{accountEntity, "name", "must not be empty"}
+	}
+	if !accountType.IsValid() {
+		return nil, &ValidationError{accountEntity, "type", "must be a known account type"}
+	}
+
+	fund, err := (&fundRepository{a.db}).Get(ctx, fundId)
+	if err != nil {
+		return nil, err
+	}
+	if fund.IsArchived() {
+		return nil, &ConflictError{fundEntity, formatId(fundId), "the fund is archived"}
+	}
+	account := accountImpl{
+		AccountFundID:  fundId,
+		AccountName:    name,
+		AccountType:    accountType,
+		AccountVersion: 1,
+	}
+
+	err = withContext(ctx, a.db).Create(&account).Error
+	if isDuplicateEntry(err) {
+		return nil, &DuplicateError{accountEntity, "name", name}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (a *accountRepository) Update(ctx context.Context, id uint, version uint, name string) (Account, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, &ValidationError{accountEntity, "name", "must not be empty"}
+	}
+
+	result := withContext(ctx, a.db).Model(&accountImpl{}).
+		Where("id = ? AND account_version = ?", id, version).
+		Updates(map[string]interface{}{
+			"account_name":    name,
+			"account_version": version + 1,
+		})
+	if isDuplicateEntry(result.Error) {
+		return nil, &DuplicateError{accountEntity, "name", name}
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		_, err := a.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, &ConcurrencyError{accountEntity, formatId(id)}
+	}
+
+	return a.Get(ctx, id)
+}
@@ -0,0 +1,133 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func insertAccounts(t *testing.T, db *gorm.DB, accounts []accountImpl) {
+	for _, account := range accounts {
+		err := db.Create(&account).Error
+		require.NoError(t, err, "Unable to create an account.")
+	}
+}
+
+func TestAccountRepositoryGetByFundRetreivesFundsAccounts(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}, {2, USD, "Special", 1, nil}})
+	insertAccounts(t, db, []accountImpl{{1, 1, "Cash", Asset, 1},
+		{2, 2, "Cash", Asset, 1}, {3, 1, "Donations", Income, 1}})
+
+	sut := accountRepository{db}
+	actual, err := sut.GetByFund(context.Background(), 1)
+
+	require.NoError(t, err, "Unable to get the fund's accounts.")
+	require.Len(t, actual, 2, "Unexpected number of accounts returned from GetByFund().")
+	assert.Equal(t, accountImpl{1, 1, "Cash", Asset, 1}, *actual[0].(*accountImpl))
+	assert.Equal(t, accountImpl{3, 1, "Donations", Income, 1}, *actual[1].(*accountImpl))
+}
+
+func TestAccountRepositoryGetAllRetreivesAllAccounts(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}, {2, USD, "Special", 1, nil}})
+	insertAccounts(t, db, []accountImpl{{1, 1, "Cash", Asset, 1}, {2, 2, "Cash", Asset, 1}})
+
+	sut := accountRepository{db}
+	actual, err := sut.GetAll(context.Background())
+
+	require.NoError(t, err, "Unable to get all accounts.")
+	assert.Len(t, actual, 2, "Unexpected number of accounts returned from GetAll().")
+}
+
+func TestAccountRepositoryCreateReturnsNewAccount(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}})
+
+	sut := accountRepository{db}
+	actual, err := sut.Create(context.Background(), 1, "Cash", Asset)
+
+	require.NoError(t, err, "Unable to create new account")
+	assert.NotZero(t, actual.Id(), "Id of the returned account was zero")
+	assert.Equal(t, uint(1), actual.FundId())
+	assert.Equal(t, "Cash", actual.Name())
+	assert.Equal(t, Asset, actual.Type())
+	assert.Equal(t, uint(1), actual.Version())
+}
+
+func TestAccountRepositoryCreateInMissingFundIsNotFoundError(t *testing.T) {
+	db := getEmptyDb(t)
+
+	sut := accountRepository{db}
+	_, err := sut.Create(context.Background(), 1, "Cash", Asset)
+
+	require.Error(t, err, "Create() in a missing fund unexpectedly succeeded")
+	assert.IsType(t, &NotFoundError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestAccountRepositoryCreateInArchivedFundIsConflictError(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 2, &archived}})
+
+	sut := accountRepository{db}
+	_, err := sut.Create(context.Background(), 1, "Cash", Asset)
+
+	require.Error(t, err, "Create() in an archived fund unexpectedly succeeded")
+	assert.IsType(t, &ConflictError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestAccountRepositoryCreateWithDuplicateNameIsDuplicateError(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}})
+	insertAccounts(t, db, []accountImpl{{1, 1, "Cash", Asset, 1}})
+
+	sut := accountRepository{db}
+	_, err := sut.Create(context.Background(), 1, "Cash", Asset)
+
+	require.Error(t, err, "Create() with a duplicate name unexpectedly succeeded")
+	assert.IsType(t, &DuplicateError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestAccountRepositoryCreateWithInvalidTypeIsValidationError(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}})
+
+	sut := accountRepository{db}
+	_, err := sut.Create(context.Background(), 1, "Cash", 0)
+
+	require.Error(t, err, "Create() with an invalid type unexpectedly succeeded")
+	assert.IsType(t, &ValidationError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestAccountRepositoryUpdateChangesNameAndVersion(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}})
+	insertAccounts(t, db, []accountImpl{{1, 1, "Cash", Asset, 1}})
+
+	sut := accountRepository{db}
+	actual, err := sut.Update(context.Background(), 1, 1, "Petty Cash")
+
+	require.NoError(t, err, "Unable to update account")
+	assert.Equal(t, accountImpl{1, 1, "Petty Cash", Asset, 2}, *actual.(*accountImpl))
+}
+
+func TestAccountRepositoryUpdateWithStaleVersionIsConcurrencyError(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}})
+	insertAccounts(t, db, []accountImpl{{1, 1, "Cash", Asset, 2}})
+
+	sut := accountRepository{db}
+	_, err := sut.Update(context.Background(), 1, 1, "Petty Cash")
+
+	require.Error(t, err, "Update() with a stale version unexpectedly succeeded")
+	assert.IsType(t, &ConcurrencyError{}, err, "Update() returned an unexpected type of error")
+}
@@ -0,0 +1,70 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+var invalidAccountTypeErrorFormat string = "Invalid account type value: %s."
+
+// AccountType classifies an Account by the part of the accounting equation
+// it belongs to.
+type AccountType uint
+
+const (
+	Asset AccountType = iota + 1
+	Liability
+	Equity
+	Income
+	Expense
+)
+
+var accountTypeNames = []string{"", "asset", "liability", "equity", "income", "expense"}
+
+type InvalidAccountTypeError struct {
+	invalidValue string
+}
+
+func (e *InvalidAccountTypeError) Error() string {
+	return fmt.Sprintf(invalidAccountTypeErrorFormat, e.invalidValue)
+}
+
+// IsValid reports whether the AccountType is one of the defined account types.
+func (a AccountType) IsValid() bool {
+	return a >= Asset && a <= Expense
+}
+
+// String returns the string representation of the AccountType.
+func (a AccountType) String() string {
+	if !a.IsValid() {
+		return ""
+	}
+
+	return accountTypeNames[a]
+}
+
+// ParseAccountType returns the AccountType for a given string representation.
+func ParseAccountType(value string) (AccountType, error) {
+	for i := Asset; i <= Expense; i++ {
+		if strings.EqualFold(value, accountTypeNames[i]) {
+			return i, nil
+		}
+	}
+
+	return 0, &InvalidAccountTypeError{value}
+}
+
+// AccountTypes returns all of the defined account types.
+func AccountTypes() []AccountType {
+	return []AccountType{Asset, Liability, Equity, Income, Expense}
+}
+
+// IsAccountType validates the string representation as an AccountType.
+func IsAccountType(value string) bool {
+	_, err := ParseAccountType(value)
+	return err == nil
+}
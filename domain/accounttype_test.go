@@ -0,0 +1,61 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type accountTypeNamePair struct {
+	accountType AccountType
+	name        string
+}
+
+func TestAccountTypeStringGivesExpectedNames(t *testing.T) {
+	expected := []accountTypeNamePair{
+		{Asset, "asset"}, {Liability, "liability"},
+		{Equity, "equity"}, {Income, "income"},
+		{Expense, "expense"}, {0, ""}, {Expense + 1, ""},
+	}
+
+	for _, pair := range expected {
+		assert.Equal(t, pair.name, pair.accountType.String())
+	}
+}
+
+func TestParseAccountTypeGivesExpectedAccountTypes(t *testing.T) {
+	expected := []accountTypeNamePair{
+		{Asset, "asset"}, {Liability, "LIABILITY"},
+		{Equity, "Equity"}, {Income, "income"},
+		{Expense, "expense"},
+	}
+
+	for _, pair := range expected {
+		actual, err := ParseAccountType(pair.name)
+		assert.NoError(t, err, "ParseAccountType() returned an unexpected error.")
+		assert.Equal(t, pair.accountType, actual)
+	}
+}
+
+func TestParseAccountTypeWithBadInputIsError(t *testing.T) {
+	badinput := []string{"", "assets", "revenue", "123"}
+
+	for _, input := range badinput {
+		_, err := ParseAccountType(input)
+		assert.Error(t, err, "ParseAccountType() failed to return an expected error.")
+		assert.False(t, IsAccountType(input))
+	}
+}
+
+func TestAccountTypesAreAllValid(t *testing.T) {
+	actual := AccountTypes()
+
+	assert.Len(t, actual, 5, "Unexpected number of account types.")
+	for _, accountType := range actual {
+		assert.True(t, accountType.IsValid(), "AccountTypes() gave an invalid account type.")
+	}
+}
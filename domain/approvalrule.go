@@ -0,0 +1,231 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/net/context"
+)
+
+const (
+	approvalRuleEntity string = "approval-rule"
+)
+
+// A Principal is the person on whose behalf an entry is submitted,
+// approved or rejected and the roles that they hold. A Principal with an
+// empty Name is anonymous.
+type Principal struct {
+	Name  string
+	Roles []string
+}
+
+// HasRole reports whether the Principal holds role.
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// An ApprovalRule requires that the entries of its Fund whose amount is at
+// least its Threshold be approved before they count toward the balances of
+// the accounts. The amount of an entry is the total of its debits in the
+// minor units of the fund's currency. An entry that a rule applies to can
+// only be approved or rejected by a Principal that holds the rule's Role,
+// unless the Role is empty. A TwoPerson rule also prevents the Principal
+// that submitted an entry from approving it.
+type ApprovalRule interface {
+	Id() uint
+	FundId() uint
+	Threshold() int64
+	Role() string
+	IsTwoPerson() bool
+	Version() uint
+}
+
+type approvalRuleImpl struct {
+	ID            uint
+	RuleFundID    uint   `sql:"not null;index"`
+	RuleThreshold int64  `sql:"not null"`
+	RuleRole      string `sql:"size:255"`
+	RuleTwoPerson bool   `sql:"not null;default:false"`
+	RuleVersion   uint   `sql:"not null;default:1"`
+}
+
+func (a *approvalRuleImpl) Id() uint {
+	return a.ID
+}
+
+func (a *approvalRuleImpl) FundId() uint {
+	return a.RuleFundID
+}
+
+func (a *approvalRuleImpl) Threshold() int64 {
+	return a.RuleThreshold
+}
+
+func (a *approvalRuleImpl) Role() string {
+	return a.RuleRole
+}
+
+func (a *approvalRuleImpl) IsTwoPerson() bool {
+	return a.RuleTwoPerson
+}
+
+func (a *approvalRuleImpl) Version() uint {
+	return a.RuleVersion
+}
+
+// The ApprovalRuleRepository is the means of accessing the ApprovalRule's
+// in the store. Create returns a *ValidationError if the threshold is
+// negative, a *NotFoundError if there is no fund with the given id and a
+// *ConflictError if the fund is archived. Get and Delete return a
+// *NotFoundError if there is no rule with the given id. Delete only
+// deletes the rule if its version is the given version and otherwise
+// returns a *ConcurrencyError. A change to the rules of a fund applies to
+// the entries that are pending as well as to those submitted later.
+type ApprovalRuleRepository interface {
+	GetAll(ctx context.Context) ([]ApprovalRule, error)
+	GetByFund(ctx context.Context, fundId uint) ([]ApprovalRule, error)
+	Get(ctx context.Context, id uint) (ApprovalRule, error)
+	Create(ctx context.Context, fundId uint, threshold int64, role string, twoPerson bool) (ApprovalRule, error)
+	Delete(ctx context.Context, id uint, version uint) error
+}
+
+type approvalRuleRepository struct {
+	db *gorm.DB
+}
+
+func (a *approvalRuleRepository) GetAll(ctx context.Context) ([]ApprovalRule, error) {
+	return a.find(ctx, withContext(ctx, a.db))
+}
+
+func (a *approvalRuleRepository) GetByFund(ctx context.Context, fundId uint) ([]ApprovalRule, error) {
+	return a.find(ctx, withContext(ctx, a.db).Where("rule_fund_id = ?", fundId))
+}
+
+func (a *approvalRuleRepository) find(ctx context.Context, db *gorm.DB) ([]ApprovalRule, error) {
+	var rules []approvalRuleImpl
+
+	err := db.Order("id").Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []ApprovalRule
+	for i := range rules {
+		ret = append(ret, &rules[i])
+	}
+
+	return ret, nil
+}
+
+func (a *approvalRuleRepository) Get(ctx context.Context, id uint) (ApprovalRule, error) {
+	var rule approvalRuleImpl
+
+	err := withContext(ctx, a.db).First(&rule, id).Error
+	if isRecordNotFound(err) {
+		return nil, &NotFoundError{approvalRuleEntity, formatId(id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (a *approvalRuleRepository) Create(ctx context.Context, fundId uint, threshold int64, role string,
+	twoPerson bool) (ApprovalRule, error) {
+	if threshold < 0 {
+		return nil, &ValidationError{approvalRuleEntity, "threshold", "must not be negative"}
+	}
+
+	fund, err := (&fundRepository{a.db}).Get(ctx, fundId)
+	if err != nil {
+		return nil, err
+	}
+	if fund.IsArchived() {
+		return nil, &ConflictError{fundEntity, formatId(fundId), "the fund is archived"}
+	}
+	rule := approvalRuleImpl{
+		RuleFundID:    fundId,
+		RuleThreshold: threshold,
+		RuleRole:      strings.TrimSpace(role),
+		RuleTwoPerson: twoPerson,
+		RuleVersion:   1,
+	}
+
+	err = withContext(ctx, a.db).Create(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (a *approvalRuleRepository) Delete(ctx context.Context, id uint, version uint) error {
+	result := withContext(ctx, a.db).Where("id = ? AND rule_version = ?", id, version).Delete(&approvalRuleImpl{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		_, err := a.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return &ConcurrencyError{approvalRuleEntity, formatId(id)}
+	}
+
+	return nil
+}
+
+// applicable gives the rules of the fund with the given id that apply to
+// an entry of amount.
+func (a *approvalRuleRepository) applicable(ctx context.Context, fundId uint, amount int64) ([]ApprovalRule, error) {
+	return a.find(ctx, withContext(ctx, a.db).Where("rule_fund_id = ? AND rule_threshold <= ?", fundId, amount))
+}
+
+// checkReviewer checks that reviewer may approve, or reject, the entry
+// with the given id that was submitted by submitter under rules. It
+// returns a *ForbiddenError if they may not.
+func checkReviewer(rules []ApprovalRule, id uint, submitter string, reviewer Principal, approving bool) error {
+	for _, rule := range rules {
+		if rule.Role() != "" && !reviewer.HasRole(rule.Role()) {
+			return &ForbiddenError{journalEntryEntity, formatId(id),
+				"the reviewer must hold the role " + rule.Role()}
+		}
+		if !approving || !rule.IsTwoPerson() {
+			continue
+		}
+		if reviewer.Name == "" {
+			return &ForbiddenError{journalEntryEntity, formatId(id), "the approver must be identified"}
+		}
+		if reviewer.Name == submitter {
+			return &ForbiddenError{journalEntryEntity, formatId(id),
+				"the entry must be approved by someone other than its submitter"}
+		}
+	}
+
+	return nil
+}
+
+// entryAmount gives the amount of an entry with postings, which is the
+// total of its debits.
+func entryAmount(postings []Posting) int64 {
+	var amount int64
+	for _, posting := range postings {
+		if posting.Amount > 0 {
+			amount += posting.Amount
+		}
+	}
+
+	return amount
+}
@@ -0,0 +1,108 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func insertApprovalRules(t *testing.T, db *gorm.DB, rules []approvalRuleImpl) {
+	for _, rule := range rules {
+		err := db.Create(&rule).Error
+		require.NoError(t, err, "Unable to create an approval rule.")
+	}
+}
+
+func TestApprovalRuleRepositoryCreateReturnsNewRule(t *testing.T) {
+	db := getLedgerDb(t)
+
+	sut := approvalRuleRepository{db}
+	actual, err := sut.Create(context.Background(), 1, 100000, " treasurer ", true)
+
+	require.NoError(t, err, "Unable to create a new approval rule.")
+	assert.NotZero(t, actual.Id(), "Id of the returned rule was zero")
+	assert.Equal(t, uint(1), actual.FundId())
+	assert.Equal(t, int64(100000), actual.Threshold())
+	assert.Equal(t, "treasurer", actual.Role())
+	assert.True(t, actual.IsTwoPerson())
+	assert.Equal(t, uint(1), actual.Version())
+}
+
+func TestApprovalRuleRepositoryCreateWithNegativeThresholdIsValidationError(t *testing.T) {
+	db := getLedgerDb(t)
+
+	sut := approvalRuleRepository{db}
+	_, err := sut.Create(context.Background(), 1, -1, "", false)
+
+	assert.IsType(t, &ValidationError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestApprovalRuleRepositoryCreateInArchivedFundIsConflictError(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 2, &archived}})
+
+	sut := approvalRuleRepository{db}
+	_, err := sut.Create(context.Background(), 1, 0, "", false)
+
+	assert.IsType(t, &ConflictError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestApprovalRuleRepositoryGetByFundRetrievesFundsRules(t *testing.T) {
+	db := getLedgerDb(t)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 0, "", false, 1}, {2, 2, 0, "", false, 1}})
+
+	sut := approvalRuleRepository{db}
+	actual, err := sut.GetByFund(context.Background(), 1)
+
+	require.NoError(t, err, "Unable to get the fund's approval rules.")
+	require.Len(t, actual, 1, "Unexpected number of rules returned from GetByFund().")
+	assert.Equal(t, uint(1), actual[0].Id())
+}
+
+func TestApprovalRuleRepositoryDeleteOldVersionIsConcurrencyError(t *testing.T) {
+	db := getLedgerDb(t)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 0, "", false, 2}})
+
+	sut := approvalRuleRepository{db}
+	err := sut.Delete(context.Background(), 1, 1)
+
+	assert.IsType(t, &ConcurrencyError{}, err, "Delete() returned an unexpected type of error")
+}
+
+func TestCheckReviewer(t *testing.T) {
+	rules := []ApprovalRule{&approvalRuleImpl{RuleRole: "treasurer", RuleTwoPerson: true}}
+	tests := []struct {
+		submitter string
+		reviewer  Principal
+		approving bool
+		allowed   bool
+	}{
+		{"clerk", treasurer, true, true},
+		{"clerk", Principal{Name: "clerk"}, true, false},
+		{"treasurer", treasurer, true, false},
+		{"treasurer", treasurer, false, true},
+		{"clerk", Principal{Roles: []string{"treasurer"}}, true, false},
+	}
+
+	for _, test := range tests {
+		err := checkReviewer(rules, 1, test.submitter, test.reviewer, test.approving)
+		if test.allowed {
+			assert.NoError(t, err, "Unexpected error for %+v.", test)
+		} else {
+			assert.IsType(t, &ForbiddenError{}, err, "Unexpected error for %+v.", test)
+		}
+	}
+}
+
+func TestEntryAmountIsTotalOfDebits(t *testing.T) {
+	assert.Equal(t, int64(150000), entryAmount([]Posting{{1, 100000, 0}, {2, 50000, 0}, {3, -150000, 0}}))
+}
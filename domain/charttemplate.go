@@ -0,0 +1,141 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// The chart of accounts templates are the JSON files in the charttemplates
+// directory. The id of a template is the name of its file without the
+// ".json" extension and each file has the form:
+//
+//	{"name": "Church", "accounts": [{"name": "Operating Cash", "type": "asset"}]}
+//
+//go:embed charttemplates/*.json
+var chartTemplateFiles embed.FS
+
+// A ChartTemplate is a standard chart of accounts that can be given to a
+// fund when it is created.
+type ChartTemplate struct {
+	ID       string
+	Name     string
+	Accounts []ChartTemplateAccount
+}
+
+// A ChartTemplateAccount is one of the accounts in a ChartTemplate.
+type ChartTemplateAccount struct {
+	Name string
+	Type AccountType
+}
+
+var chartTemplates = mustLoadChartTemplates()
+
+// ChartTemplates returns all of the chart of accounts templates in order of
+// their ids.
+func ChartTemplates() []*ChartTemplate {
+	var templates []*ChartTemplate
+	for _, template := range chartTemplates {
+		templates = append(templates, template)
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].ID < templates[j].ID
+	})
+
+	return templates
+}
+
+// GetChartTemplate returns the chart of accounts template with the given id.
+// The boolean is false if there is no such template.
+func GetChartTemplate(id string) (*ChartTemplate, bool) {
+	template, ok := chartTemplates[id]
+	return template, ok
+}
+
+// IsChartTemplate validates the string as the id of a chart of accounts
+// template.
+func IsChartTemplate(id string) bool {
+	_, ok := chartTemplates[id]
+	return ok
+}
+
+// mustLoadChartTemplates loads the embedded chart of accounts templates. The
+// templates are part of the program so a malformed one is a programming
+// error.
+func mustLoadChartTemplates() map[string]*ChartTemplate {
+	templates, err := loadChartTemplates()
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}
+
+func loadChartTemplates() (map[string]*ChartTemplate, error) {
+	files, err := chartTemplateFiles.ReadDir("charttemplates")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*ChartTemplate)
+	for _, file := range files {
+		data, err := chartTemplateFiles.ReadFile(path.Join("charttemplates", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		id := strings.TrimSuffix(file.Name(), ".json")
+		template, err := parseChartTemplate(id, data)
+		if err != nil {
+			return nil, err
+		}
+
+		templates[id] = template
+	}
+
+	return templates, nil
+}
+
+// parseChartTemplate parses the JSON form of the template with the given id.
+func parseChartTemplate(id string, data []byte) (*ChartTemplate, error) {
+	var file struct {
+		Name     string `json:"name"`
+		Accounts []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"accounts"`
+	}
+
+	err := json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("chart of accounts template %s: %v", id, err)
+	}
+
+	template := &ChartTemplate{ID: id, Name: file.Name}
+	names := make(map[string]bool)
+	for _, account := range file.Accounts {
+		if strings.TrimSpace(account.Name) == "" {
+			return nil, fmt.Errorf("chart of accounts template %s: an account has no name", id)
+		}
+		if names[account.Name] {
+			return nil, fmt.Errorf("chart of accounts template %s: the account %s is repeated", id, account.Name)
+		}
+		names[account.Name] = true
+
+		accountType, err := ParseAccountType(account.Type)
+		if err != nil {
+			return nil, fmt.Errorf("chart of accounts template %s: %v", id, err)
+		}
+
+		template.Accounts = append(template.Accounts, ChartTemplateAccount{account.Name, accountType})
+	}
+
+	return template, nil
+}
@@ -0,0 +1,66 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChartTemplatesIncludesStandardTemplates(t *testing.T) {
+	var ids []string
+	for _, template := range ChartTemplates() {
+		ids = append(ids, template.ID)
+	}
+
+	assert.Equal(t, []string{"church", "nonprofit-basic", "small-business"}, ids,
+		"Unexpected chart of accounts templates.")
+}
+
+func TestChartTemplatesHaveAccountsOfEveryType(t *testing.T) {
+	for _, template := range ChartTemplates() {
+		types := make(map[AccountType]bool)
+		for _, account := range template.Accounts {
+			types[account.Type] = true
+		}
+
+		assert.NotEmpty(t, template.Name, "The %s template has no name.", template.ID)
+		for _, accountType := range AccountTypes() {
+			assert.True(t, types[accountType], "The %s template has no %s account.", template.ID, accountType)
+		}
+	}
+}
+
+func TestGetChartTemplateGivesTemplate(t *testing.T) {
+	actual, ok := GetChartTemplate("church")
+
+	require.True(t, ok, "GetChartTemplate() did not find the church template.")
+	assert.Equal(t, "church", actual.ID, "Unexpected template id.")
+	assert.Contains(t, actual.Accounts, ChartTemplateAccount{"Tithes and Offerings", Income},
+		"Unexpected accounts in the church template.")
+}
+
+func TestIsChartTemplate(t *testing.T) {
+	assert.True(t, IsChartTemplate("small-business"))
+	assert.False(t, IsChartTemplate("unknown"))
+	assert.False(t, IsChartTemplate(""))
+}
+
+func TestParseChartTemplateWithBadTemplateIsError(t *testing.T) {
+	bad := []string{
+		`not json`,
+		`{"name": "Bad", "accounts": [{"name": "Cash", "type": "money"}]}`,
+		`{"name": "Bad", "accounts": [{"name": " ", "type": "asset"}]}`,
+		`{"name": "Bad", "accounts": [{"name": "Cash", "type": "asset"}, {"name": "Cash", "type": "asset"}]}`,
+	}
+
+	for _, data := range bad {
+		_, err := parseChartTemplate("bad", []byte(data))
+
+		assert.Error(t, err, "parseChartTemplate(%s) unexpectedly succeeded.", data)
+	}
+}
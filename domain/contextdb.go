@@ -0,0 +1,102 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"database/sql"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/net/context"
+)
+
+// contextDB is the connection of a gorm.DB that is outside of a transaction.
+// It sends each statement through the context-aware methods of database/sql
+// so the driver stops the statement if ctx is done while it is running.
+// contextDB has no Begin method so gorm doesn't start a transaction, without
+// the context, around a statement on its own; work that needs more than one
+// statement uses Store.InTransaction.
+type contextDB struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+func (c *contextDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.db.ExecContext(c.ctx, query, args...)
+}
+
+func (c *contextDB) Prepare(query string) (*sql.Stmt, error) {
+	return c.db.PrepareContext(c.ctx, query)
+}
+
+func (c *contextDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.db.QueryContext(c.ctx, query, args...)
+}
+
+func (c *contextDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.db.QueryRowContext(c.ctx, query, args...)
+}
+
+// contextTx is the connection of a gorm.DB that is within a transaction. It
+// sends each statement through the context-aware methods of database/sql in
+// the same way as contextDB.
+type contextTx struct {
+	tx  *sql.Tx
+	ctx context.Context
+}
+
+func (c *contextTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.tx.ExecContext(c.ctx, query, args...)
+}
+
+func (c *contextTx) Prepare(query string) (*sql.Stmt, error) {
+	return c.tx.PrepareContext(c.ctx, query)
+}
+
+func (c *contextTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.tx.QueryContext(c.ctx, query, args...)
+}
+
+func (c *contextTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.tx.QueryRowContext(c.ctx, query, args...)
+}
+
+func (c *contextTx) Commit() error {
+	return c.tx.Commit()
+}
+
+func (c *contextTx) Rollback() error {
+	return c.tx.Rollback()
+}
+
+// withContext gives a gorm.DB on the same connection, or within the same
+// transaction, as db whose statements are sent to the database with ctx. The
+// driver stops a statement that is running when ctx is done and a statement
+// isn't sent at all once ctx is done; either way the error is ctx.Err(). db
+// must not have any conditions; they belong on the gorm.DB that withContext
+// gives.
+func withContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	var conn gorm.SQLCommon
+	switch c := db.CommonDB().(type) {
+	case *sql.DB:
+		conn = &contextDB{c, ctx}
+	case *contextDB:
+		conn = &contextDB{c.db, ctx}
+	case *sql.Tx:
+		conn = &contextTx{c, ctx}
+	case *contextTx:
+		conn = &contextTx{c.tx, ctx}
+	default:
+		return db
+	}
+
+	// gorm.Open doesn't fail for a gorm.SQLCommon; it only fails to
+	// open a data source name.
+	bound, err := gorm.Open(mysqlDialect, conn)
+	if err != nil {
+		return db
+	}
+
+	return bound
+}
@@ -7,13 +7,23 @@
 package domain
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
-	"strings"
+	"time"
 )
 
+// The number of possible three letter currency codes.
+const codeCount = 26 * 26 * 26
+
 var invalidCurrencyErrorFormat string = "Invalid currency value: %s."
+var withdrawnCurrencyErrorFormat string = "The currency %s was withdrawn in %s."
 
 // Currency provides a representation of ISO 4217 currency values.
+//
+// The values of the Currency constants are their positions in the generated
+// list of currencies and so are not stable, so a Currency is stored in a
+// database and marshaled to JSON as its ISO 4217 code.
 type Currency uint
 
 type InvalidCurrencyError struct {
@@ -24,11 +34,59 @@ func (e *InvalidCurrencyError) Error() string {
 	return fmt.Sprintf(invalidCurrencyErrorFormat, e.invalidValue)
 }
 
+// A WithdrawnCurrencyError is the error for a withdrawn Currency where
+// only a current Currency is allowed.
+type WithdrawnCurrencyError struct {
+	currency Currency
+}
+
+func (e *WithdrawnCurrencyError) Error() string {
+	withdrawn, _ := e.currency.Withdrawn()
+	return fmt.Sprintf(withdrawnCurrencyErrorFormat, e.currency, withdrawn.Format("January 2006"))
+}
+
+// currencyIndex gives the Currency plus one for the index of each code that
+// codeIndex gives, or zero for a code that is not a Currency. It makes
+// ParseCurrency take the same time for every code.
+var currencyIndex [codeCount]uint16
+
+func init() {
+	// This loop relies on the manner in which currencyStringsBlock is
+	// generated by mkcurrency.go. The string block is generated in the
+	// same order as the Currency constants and each element of the block
+	// is 3 uppercase characters from [A-Z].
+	for i := 0; i+3 <= len(currencyStringsBlock); i += 3 {
+		index, _ := codeIndex(currencyStringsBlock[i : i+3])
+		currencyIndex[index] = uint16(i/3 + 1)
+	}
+}
+
+// codeIndex gives the index of a three letter code, ignoring case, among
+// all of the possible codes or false if value isn't three ASCII letters.
+func codeIndex(value string) (int, bool) {
+	if len(value) != 3 {
+		return 0, false
+	}
+
+	index := 0
+	for i := 0; i < 3; i++ {
+		// setting the 0x20 bit lowers the case of an ASCII letter
+		letter := value[i] | 0x20
+		if letter < 'a' || letter > 'z' {
+			return 0, false
+		}
+		index = index*26 + int(letter-'a')
+	}
+
+	return index, true
+}
+
 type currencyInfo struct {
 	symbol     string
 	name       string
 	number     int
 	minorUnits int
+	kind       CurrencyKind
 }
 
 // String returns the string representation of the Currency.
@@ -44,25 +102,129 @@ func (c Currency) String() string {
 	return currencyStringsBlock[c*3 : c*3+3]
 }
 
+// Symbol returns the symbol of the Currency that is used unless a Locale
+// gives another, such as "US$" or "€", or its code if it has no symbol.
+func (c Currency) Symbol() string {
+	return c.info().symbol
+}
+
+// MinorUnits returns the number of decimal places of the minor units of
+// the Currency, such as 2 for cents or 0 for a Currency without them.
+func (c Currency) MinorUnits() int {
+	return c.info().minorUnits
+}
+
+// info returns the currencyInfo of the Currency, or that of XXX if the
+// Currency isn't valid.
+func (c Currency) info() *currencyInfo {
+	if int(c) >= len(currencyInfos) {
+		c = XXX
+	}
+
+	return &currencyInfos[c]
+}
+
+// Value gives the ISO 4217 code of the Currency as the value that is stored
+// in a database.
+func (c Currency) Value() (driver.Value, error) {
+	return c.String(), nil
+}
+
+// Scan sets the Currency from the ISO 4217 code stored in a database.
+func (c *Currency) Scan(src interface{}) error {
+	var code string
+	switch value := src.(type) {
+	case []byte:
+		code = string(value)
+	case string:
+		code = value
+	default:
+		return fmt.Errorf("Unable to scan a currency from a %T.", src)
+	}
+
+	return c.Set(code)
+}
+
+// MarshalText marshals the Currency as its ISO 4217 code.
+func (c Currency) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText unmarshals the Currency from its ISO 4217 code.
+func (c *Currency) UnmarshalText(text []byte) error {
+	return c.Set(string(text))
+}
+
+// Set sets the Currency from its ISO 4217 code so that a *Currency is a
+// flag.Value.
+func (c *Currency) Set(value string) error {
+	currency, err := ParseCurrency(value)
+	if err != nil {
+		return err
+	}
+
+	*c = currency
+	return nil
+}
+
+// MarshalJSON marshals the Currency as its ISO 4217 code.
+func (c Currency) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// UnmarshalJSON unmarshals the Currency from its ISO 4217 code.
+func (c *Currency) UnmarshalJSON(data []byte) error {
+	var code string
+	if err := json.Unmarshal(data, &code); err != nil {
+		return err
+	}
+
+	return c.Set(code)
+}
+
 // ParseCurrency returns the Currency for a given string representations.
+// Withdrawn currencies are parsed as well so that existing values that use
+// them stay valid; use ParseCurrentCurrency to reject them.
 func ParseCurrency(value string) (Currency, error) {
-	if len(value) != 3 {
+	index, ok := codeIndex(value)
+	if !ok || currencyIndex[index] == 0 {
 		return XXX, &InvalidCurrencyError{value}
 	}
 
-	// This loop relies on the manner in which currencyStringBlock
-	// is generated by mkcurrency.go. The string block is generated in the
-	// same order as the Currency constants and each element of the block
-	// is 3 uppercase charaters from [A-Z]. This loop does a linear scan
-	// looking for a match. If this ends up being a perfomance problem
-	// sort.Search() may be an option.
+	return Currency(currencyIndex[index] - 1), nil
+}
+
+// ParseCurrentCurrency returns the Currency for a given string
+// representation as ParseCurrency does but returns a WithdrawnCurrencyError
+// for a Currency that was withdrawn.
+func ParseCurrentCurrency(value string) (Currency, error) {
+	currency, err := ParseCurrency(value)
+	if err != nil {
+		return XXX, err
+	}
+	if _, withdrawn := currency.Withdrawn(); withdrawn {
+		return XXX, &WithdrawnCurrencyError{currency}
+	}
+
+	return currency, nil
+}
+
+// Withdrawn returns the month in which the Currency was withdrawn and true
+// or the zero time and false if it is still current.
+func (c Currency) Withdrawn() (time.Time, bool) {
+	withdrawn, ok := currencyWithdrawals[c]
+	return withdrawn, ok
+}
+
+// Currencies returns all of the Currency values, including those that
+// were withdrawn, in the order of their constants.
+func Currencies() []Currency {
+	currencies := make([]Currency, 0, len(currencyStringsBlock)/3)
 	for i := 0; i+3 <= len(currencyStringsBlock); i += 3 {
-		if strings.EqualFold(value, currencyStringsBlock[i:i+3]) {
-			return Currency(i / 3), nil
-		}
+		currencies = append(currencies, Currency(i/3))
 	}
 
-	return XXX, &InvalidCurrencyError{value}
+	return currencies
 }
 
 // IsCurrency valdiates the string representation as a Currency
@@ -70,3 +232,10 @@ func IsCurrency(value string) bool {
 	_, err := ParseCurrency(value)
 	return err == nil
 }
+
+// IsCurrentCurrency validates the string representation as a Currency
+// that has not been withdrawn.
+func IsCurrentCurrency(value string) bool {
+	_, err := ParseCurrentCurrency(value)
+	return err == nil
+}
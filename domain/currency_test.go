@@ -5,9 +5,16 @@
 package domain
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"flag"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type currencyCodePair struct {
@@ -21,8 +28,10 @@ func TestCurrencyStringGivesExpectedCurrencyCodes(t *testing.T) {
 		{CAD, "CAD"}, {USD, "USD"},
 		{EUR, "EUR"}, {GBP, "GBP"},
 		{XXX, "XXX"}, {XTS, "XTS"},
-		{ZWL, "ZWL"}, {ZWL + 1, "XXX"},
-		{ZWL + 2, "XXX"},
+		{ZWL, "ZWL"}, {DEM, "DEM"},
+		{ZWR, "ZWR"}, {CLF, "CLF"},
+		{UYI, "UYI"}, {UYI + 1, "XXX"},
+		{UYI + 2, "XXX"},
 	}
 
 	for _, pair := range expected {
@@ -96,3 +105,206 @@ func TestIsCurrencyIsFalseWithBadInput(t *testing.T) {
 		assert.False(t, IsCurrency(value))
 	}
 }
+
+func TestCurrenciesIncludesEveryCurrency(t *testing.T) {
+	actual := Currencies()
+
+	assert.Len(t, actual, int(UYI)+1, "Unexpected number of currencies.")
+	assert.Equal(t, XXX, actual[0], "Unexpected first currency.")
+	assert.Equal(t, UYI, actual[len(actual)-1], "Unexpected last currency.")
+}
+
+func TestCurrencyValuesKeepTheirMeaning(t *testing.T) {
+	// these are values that funds already store; the withdrawn currencies
+	// must be added after them
+	expected := map[Currency]uint{XXX: 0, AED: 1, CAD: 26, EUR: 44, USD: 145, ZWL: 171, ZWR: 206}
+
+	for currency, value := range expected {
+		assert.Equal(t, value, uint(currency), "The value of %s changed.", currency)
+	}
+}
+
+func TestParseCurrencyGivesWithdrawnCurrencies(t *testing.T) {
+	for _, code := range []string{"DEM", "frf", "ZMK"} {
+		_, err := ParseCurrency(code)
+
+		assert.NoError(t, err, "ParseCurrency() rejected the withdrawn currency %s.", code)
+	}
+}
+
+func TestParseCurrentCurrencyWithWithdrawnCurrencyIsError(t *testing.T) {
+	_, err := ParseCurrentCurrency("DEM")
+
+	assert.IsType(t, &WithdrawnCurrencyError{}, err, "ParseCurrentCurrency() returned an unexpected error")
+	assert.EqualError(t, err, "The currency DEM was withdrawn in March 2002.")
+}
+
+func TestParseCurrentCurrencyGivesCurrentCurrency(t *testing.T) {
+	actual, err := ParseCurrentCurrency("cad")
+
+	assert.NoError(t, err, "ParseCurrentCurrency() returned an unexpected error.")
+	assert.Equal(t, CAD, actual)
+}
+
+func TestIsCurrentCurrencyIsFalseForWithdrawnAndInvalidCurrencies(t *testing.T) {
+	for _, value := range []string{"DEM", "zwd", "UUU", ""} {
+		assert.False(t, IsCurrentCurrency(value), "%s is a current currency.", value)
+	}
+}
+
+func TestCurrencyWithdrawnGivesMonthOfWithdrawal(t *testing.T) {
+	withdrawn, ok := ZWN.Withdrawn()
+
+	assert.True(t, ok, "ZWN is not withdrawn.")
+	assert.Equal(t, time.Date(2008, time.August, 1, 0, 0, 0, 0, time.UTC), withdrawn)
+}
+
+func TestCurrencyWithdrawnIsFalseForCurrentCurrency(t *testing.T) {
+	// EUR is in the historic table for Serbia and Montenegro but is still
+	// current elsewhere
+	for _, currency := range []Currency{CAD, EUR} {
+		_, ok := currency.Withdrawn()
+
+		assert.False(t, ok, "%s is withdrawn.", currency)
+	}
+}
+
+func TestCurrencyValueIsCode(t *testing.T) {
+	actual, err := CAD.Value()
+
+	assert.NoError(t, err, "Value() returned an unexpected error.")
+	assert.Equal(t, "CAD", actual)
+}
+
+func TestCurrencyScanParsesCode(t *testing.T) {
+	for _, src := range []interface{}{[]byte("EUR"), "EUR"} {
+		var sut Currency
+		err := sut.Scan(src)
+
+		assert.NoError(t, err, "Scan() returned an unexpected error.")
+		assert.Equal(t, EUR, sut)
+	}
+}
+
+func TestCurrencyScanWithBadValueIsError(t *testing.T) {
+	for _, src := range []interface{}{"UUU", int64(26), nil} {
+		var sut Currency
+		err := sut.Scan(src)
+
+		assert.Error(t, err, "Scan() failed to return an expected error for %v.", src)
+	}
+}
+
+func TestCurrencyMarshalsToJSONAsCode(t *testing.T) {
+	actual, err := json.Marshal(map[string]Currency{"currency": USD})
+
+	require.NoError(t, err, "Unable to marshal the currency.")
+	assert.JSONEq(t, `{"currency": "USD"}`, string(actual))
+}
+
+func TestCurrencyUnmarshalsFromJSONCode(t *testing.T) {
+	var actual Currency
+	err := json.Unmarshal([]byte(`"gbp"`), &actual)
+
+	require.NoError(t, err, "Unable to unmarshal the currency.")
+	assert.Equal(t, GBP, actual)
+}
+
+func TestCurrencyUnmarshalWithBadCodeIsError(t *testing.T) {
+	var actual Currency
+	err := json.Unmarshal([]byte(`"UUU"`), &actual)
+
+	assert.IsType(t, &InvalidCurrencyError{}, err, "Unmarshal returned an unexpected error")
+}
+
+func TestCurrencyImplementsMarshalingInterfaces(t *testing.T) {
+	currency := CAD
+
+	assert.Implements(t, (*encoding.TextMarshaler)(nil), currency)
+	assert.Implements(t, (*encoding.TextUnmarshaler)(nil), &currency)
+	assert.Implements(t, (*json.Marshaler)(nil), currency)
+	assert.Implements(t, (*json.Unmarshaler)(nil), &currency)
+	assert.Implements(t, (*flag.Value)(nil), &currency)
+	assert.Implements(t, (*driver.Valuer)(nil), currency)
+	assert.Implements(t, (*sql.Scanner)(nil), &currency)
+}
+
+func TestCurrencyMarshalsAsMapKey(t *testing.T) {
+	actual, err := json.Marshal(map[Currency]int{CAD: 1})
+	require.NoError(t, err, "Unable to marshal the map.")
+	assert.JSONEq(t, `{"CAD": 1}`, string(actual))
+
+	var unmarshaled map[Currency]int
+	require.NoError(t, json.Unmarshal(actual, &unmarshaled), "Unable to unmarshal the map.")
+	assert.Equal(t, map[Currency]int{CAD: 1}, unmarshaled)
+}
+
+func TestCurrencyIsFlagValue(t *testing.T) {
+	sut := XXX
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Var(&sut, "currency", "the currency")
+
+	err := flags.Parse([]string{"-currency", "jpy"})
+
+	require.NoError(t, err, "Unable to parse the flag.")
+	assert.Equal(t, JPY, sut)
+}
+
+func TestCurrencyUnmarshalTextWithBadCodeIsError(t *testing.T) {
+	sut := CAD
+
+	err := sut.UnmarshalText([]byte("C4D"))
+
+	assert.IsType(t, &InvalidCurrencyError{}, err, "UnmarshalText() returned an unexpected error")
+	assert.Equal(t, CAD, sut, "UnmarshalText() changed the currency on an error.")
+}
+
+func TestParseCurrencyWithNonLetterNextToLettersIsError(t *testing.T) {
+	// these are one bit away from upper or lower case letters
+	for _, value := range []string{"@AD", "CA[", "`ad", "ca{", "CA\xc4"} {
+		_, err := ParseCurrency(value)
+
+		assert.Error(t, err, "ParseCurrency() accepted %q.", value)
+	}
+}
+
+func TestParseCurrencyGivesEveryCurrency(t *testing.T) {
+	for _, currency := range Currencies() {
+		actual, err := ParseCurrency(currency.String())
+
+		assert.NoError(t, err, "ParseCurrency() returned an unexpected error.")
+		assert.Equal(t, currency, actual)
+	}
+}
+
+var benchmarkCurrency Currency
+
+func BenchmarkParseCurrency(b *testing.B) {
+	for _, code := range []string{"AED", "ZWL", "zwr", "UUU"} {
+		b.Run(code, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				benchmarkCurrency, _ = ParseCurrency(code)
+			}
+		})
+	}
+}
+
+func BenchmarkCurrencyUnmarshalText(b *testing.B) {
+	text := []byte("USD")
+	for i := 0; i < b.N; i++ {
+		benchmarkCurrency.UnmarshalText(text)
+	}
+}
+
+func BenchmarkCurrencyMarshalText(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		USD.MarshalText()
+	}
+}
+
+func BenchmarkCurrencyUnmarshalJSON(b *testing.B) {
+	data := []byte(`"USD"`)
+	for i := 0; i < b.N; i++ {
+		json.Unmarshal(data, &benchmarkCurrency)
+	}
+}
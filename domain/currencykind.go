@@ -0,0 +1,41 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+// CurrencyKind classifies a Currency by what ISO 4217 assigns its code to.
+type CurrencyKind uint
+
+const (
+	// OrdinaryCurrency is the money of one or more countries.
+	OrdinaryCurrency CurrencyKind = iota
+	// FundCurrency is a fund code, such as a unit of account indexed to
+	// inflation (CLF) or next day dollars (USN).
+	FundCurrency
+	// MetalCurrency is a precious metal, such as one troy ounce of gold
+	// (XAU).
+	MetalCurrency
+	// BondUnitCurrency is a unit of the European bond markets.
+	BondUnitCurrency
+	// TestingCurrency is the code reserved for testing (XTS).
+	TestingCurrency
+	// NoCurrency is the code for a transaction without a currency (XXX).
+	NoCurrency
+)
+
+var currencyKindNames = []string{"ordinary", "fund", "metal", "bond-unit", "testing", "none"}
+
+// String returns the string representation of the CurrencyKind.
+func (k CurrencyKind) String() string {
+	if int(k) >= len(currencyKindNames) {
+		return ""
+	}
+
+	return currencyKindNames[k]
+}
+
+// Kind returns the CurrencyKind of the Currency.
+func (c Currency) Kind() CurrencyKind {
+	return c.info().kind
+}
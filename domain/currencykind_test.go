@@ -0,0 +1,49 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrencyKindClassifiesCurrencies(t *testing.T) {
+	expected := map[Currency]CurrencyKind{
+		CAD: OrdinaryCurrency,
+		DEM: OrdinaryCurrency,
+		CLF: FundCurrency,
+		USN: FundCurrency,
+		XAU: MetalCurrency,
+		XAG: MetalCurrency,
+		XBA: BondUnitCurrency,
+		XTS: TestingCurrency,
+		XXX: NoCurrency,
+	}
+
+	for currency, kind := range expected {
+		assert.Equal(t, kind, currency.Kind(), "Unexpected kind of %s.", currency)
+	}
+}
+
+func TestParseCurrencyGivesFundCurrencies(t *testing.T) {
+	for _, code := range []string{"BOV", "clf", "USN"} {
+		actual, err := ParseCurrentCurrency(code)
+
+		assert.NoError(t, err, "ParseCurrentCurrency() rejected the fund currency %s.", code)
+		assert.Equal(t, FundCurrency, actual.Kind(), "Unexpected kind of %s.", code)
+	}
+}
+
+func TestInvalidCurrencyHasKindOfXXX(t *testing.T) {
+	assert.Equal(t, NoCurrency, Currency(math.MaxUint16).Kind())
+}
+
+func TestCurrencyKindString(t *testing.T) {
+	assert.Equal(t, "metal", MetalCurrency.String())
+	assert.Equal(t, "bond-unit", BondUnitCurrency.String())
+	assert.Equal(t, "", CurrencyKind(99).String())
+}
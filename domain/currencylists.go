@@ -2,12 +2,15 @@
 // Use of this source code is governed by the GNU General Public License version 3.
 // See the file COPYING for your rights under that license.
 
-// This file is generated by mkcurrency.go from ISO4217-TABL-a1.xml.
+// This file is generated by mkcurrency.go from ISO4217-tabl-a1.xml,
+// ISO4217-tabl-a3.xml and cldr-numbers.json.
 // DO NOT EDIT THIS FILE DIRECTLY.
 // To regenerate this file run "go generate"
 
 package domain
 
+import "time"
+
 const (
 	XXX Currency = iota // The codes assigned for transactions where no currency is involved
 	AED		    // UAE Dirham
@@ -181,6 +184,307 @@ const (
 	ZAR		    // Rand
 	ZMW		    // Zambian Kwacha
 	ZWL		    // Zimbabwe Dollar
+	ADP		    // Andorran Peseta (withdrawn 2003-07)
+	ATS		    // Schilling (withdrawn 2002-03)
+	AZM		    // Azerbaijanian Manat (withdrawn 2005-12)
+	BEF		    // Belgian Franc (withdrawn 2002-03)
+	BGL		    // Lev (withdrawn 2003-11)
+	CSD		    // Serbian Dinar (withdrawn 2006-10)
+	CYP		    // Cyprus Pound (withdrawn 2008-01)
+	DEM		    // Deutsche Mark (withdrawn 2002-03)
+	EEK		    // Kroon (withdrawn 2011-01)
+	ESP		    // Spanish Peseta (withdrawn 2002-03)
+	FIM		    // Markka (withdrawn 2002-03)
+	FRF		    // French Franc (withdrawn 2002-03)
+	GHC		    // Cedi (withdrawn 2008-01)
+	GRD		    // Drachma (withdrawn 2002-03)
+	IEP		    // Irish Pound (withdrawn 2002-03)
+	ITL		    // Italian Lira (withdrawn 2002-03)
+	LUF		    // Luxembourg Franc (withdrawn 2002-03)
+	MGF		    // Malagasy Franc (withdrawn 2004-12)
+	MTL		    // Maltese Lira (withdrawn 2008-01)
+	MZM		    // Mozambique Metical (withdrawn 2006-06)
+	NLG		    // Netherlands Guilder (withdrawn 2002-03)
+	PTE		    // Portuguese Escudo (withdrawn 2002-03)
+	ROL		    // Old Leu (withdrawn 2005-06)
+	SDD		    // Sudanese Dinar (withdrawn 2007-07)
+	SIT		    // Tolar (withdrawn 2007-01)
+	SKK		    // Slovak Koruna (withdrawn 2009-01)
+	SRG		    // Surinam Guilder (withdrawn 2003-12)
+	TMM		    // Turkmenistan Manat (withdrawn 2009-01)
+	TRL		    // Old Turkish Lira (withdrawn 2005-12)
+	VEB		    // Bolivar (withdrawn 2008-01)
+	YUM		    // New Dinar (withdrawn 2003-07)
+	ZMK		    // Zambian Kwacha (withdrawn 2012-12)
+	ZWD		    // Zimbabwe Dollar (withdrawn 2006-08)
+	ZWN		    // Zimbabwe Dollar (withdrawn 2008-08)
+	ZWR		    // Zimbabwe Dollar (withdrawn 2009-06)
+	BOV		    // Mvdol
+	CHE		    // WIR Euro
+	CHW		    // WIR Franc
+	CLF		    // Unidad de Fomento
+	COU		    // Unidad de Valor Real
+	MXV		    // Mexican Unidad de Inversion (UDI)
+	USN		    // US Dollar (Next day)
+	USS		    // US Dollar (Same day)
+	UYI		    // Uruguay Peso en Unidades Indexadas (URUIURUI)
 )
 
-var currencyStringsBlock string = "XXXAEDAFNALLAMDANGAOAARSAUDAWGAZNBAMBBDBDTBGNBHDBIFBMDBNDBOBBRLBSDBTNBWPBYRBZDCADCDFCHFCLPCNYCOPCRCCUCCUPCVECZKDJFDKKDOPDZDEGPERNETBEURFJDFKPGBPGELGHSGIPGMDGNFGTQGYDHKDHNLHRKHTGHUFIDRILSINRIQDIRRISKJMDJODJPYKESKGSKHRKMFKPWKRWKWDKYDKZTLAKLBPLKRLRDLSLLTLLVLLYDMADMDLMGAMKDMMKMNTMOPMROMURMVRMWKMXNMYRMZNNADNGNNIONOKNPRNZDOMRPABPENPGKPHPPKRPLNPYGQARRONRSDRUBRWFSARSBDSCRSDGSEKSGDSHPSLLSOSSRDSSPSTDSVCSYPSZLTHBTJSTMTTNDTOPTRYTTDTWDTZSUAHUGXUSDUYUUZSVEFVNDVUVWSTXAFXAGXAUXBAXBBXBCXBDXCDXDRXOFXPDXPFXPTXSUXTSXUAYERZARZMWZWL"
+var currencyStringsBlock string = "XXXAEDAFNALLAMDANGAOAARSAUDAWGAZNBAMBBDBDTBGNBHDBIFBMDBNDBOBBRLBSDBTNBWPBYRBZDCADCDFCHFCLPCNYCOPCRCCUCCUPCVECZKDJFDKKDOPDZDEGPERNETBEURFJDFKPGBPGELGHSGIPGMDGNFGTQGYDHKDHNLHRKHTGHUFIDRILSINRIQDIRRISKJMDJODJPYKESKGSKHRKMFKPWKRWKWDKYDKZTLAKLBPLKRLRDLSLLTLLVLLYDMADMDLMGAMKDMMKMNTMOPMROMURMVRMWKMXNMYRMZNNADNGNNIONOKNPRNZDOMRPABPENPGKPHPPKRPLNPYGQARRONRSDRUBRWFSARSBDSCRSDGSEKSGDSHPSLLSOSSRDSSPSTDSVCSYPSZLTHBTJSTMTTNDTOPTRYTTDTWDTZSUAHUGXUSDUYUUZSVEFVNDVUVWSTXAFXAGXAUXBAXBBXBCXBDXCDXDRXOFXPDXPFXPTXSUXTSXUAYERZARZMWZWLADPATSAZMBEFBGLCSDCYPDEMEEKESPFIMFRFGHCGRDIEPITLLUFMGFMTLMZMNLGPTEROLSDDSITSKKSRGTMMTRLVEBYUMZMKZWDZWNZWRBOVCHECHWCLFCOUMXVUSNUSSUYI"
+
+var currencyInfos = [...]currencyInfo{
+	XXX: {symbol: "XXX", name: "The codes assigned for transactions where no currency is involved", number: 999, minorUnits: 0, kind: NoCurrency},
+	AED: {symbol: "AED", name: "UAE Dirham", number: 784, minorUnits: 2, kind: OrdinaryCurrency},
+	AFN: {symbol: "AFN", name: "Afghani", number: 971, minorUnits: 2, kind: OrdinaryCurrency},
+	ALL: {symbol: "ALL", name: "Lek", number: 8, minorUnits: 2, kind: OrdinaryCurrency},
+	AMD: {symbol: "AMD", name: "Armenian Dram", number: 51, minorUnits: 2, kind: OrdinaryCurrency},
+	ANG: {symbol: "ANG", name: "Netherlands Antillean Guilder", number: 532, minorUnits: 2, kind: OrdinaryCurrency},
+	AOA: {symbol: "AOA", name: "Kwanza", number: 973, minorUnits: 2, kind: OrdinaryCurrency},
+	ARS: {symbol: "ARS", name: "Argentine Peso", number: 32, minorUnits: 2, kind: OrdinaryCurrency},
+	AUD: {symbol: "A$", name: "Australian Dollar", number: 36, minorUnits: 2, kind: OrdinaryCurrency},
+	AWG: {symbol: "AWG", name: "Aruban Florin", number: 533, minorUnits: 2, kind: OrdinaryCurrency},
+	AZN: {symbol: "AZN", name: "Azerbaijanian Manat", number: 944, minorUnits: 2, kind: OrdinaryCurrency},
+	BAM: {symbol: "BAM", name: "Convertible Mark", number: 977, minorUnits: 2, kind: OrdinaryCurrency},
+	BBD: {symbol: "BBD", name: "Barbados Dollar", number: 52, minorUnits: 2, kind: OrdinaryCurrency},
+	BDT: {symbol: "BDT", name: "Taka", number: 50, minorUnits: 2, kind: OrdinaryCurrency},
+	BGN: {symbol: "BGN", name: "Bulgarian Lev", number: 975, minorUnits: 2, kind: OrdinaryCurrency},
+	BHD: {symbol: "BHD", name: "Bahraini Dinar", number: 48, minorUnits: 3, kind: OrdinaryCurrency},
+	BIF: {symbol: "BIF", name: "Burundi Franc", number: 108, minorUnits: 0, kind: OrdinaryCurrency},
+	BMD: {symbol: "BMD", name: "Bermudian Dollar", number: 60, minorUnits: 2, kind: OrdinaryCurrency},
+	BND: {symbol: "BND", name: "Brunei Dollar", number: 96, minorUnits: 2, kind: OrdinaryCurrency},
+	BOB: {symbol: "BOB", name: "Boliviano", number: 68, minorUnits: 2, kind: OrdinaryCurrency},
+	BRL: {symbol: "R$", name: "Brazilian Real", number: 986, minorUnits: 2, kind: OrdinaryCurrency},
+	BSD: {symbol: "BSD", name: "Bahamian Dollar", number: 44, minorUnits: 2, kind: OrdinaryCurrency},
+	BTN: {symbol: "BTN", name: "Ngultrum", number: 64, minorUnits: 2, kind: OrdinaryCurrency},
+	BWP: {symbol: "BWP", name: "Pula", number: 72, minorUnits: 2, kind: OrdinaryCurrency},
+	BYR: {symbol: "BYR", name: "Belarussian Ruble", number: 974, minorUnits: 0, kind: OrdinaryCurrency},
+	BZD: {symbol: "BZD", name: "Belize Dollar", number: 84, minorUnits: 2, kind: OrdinaryCurrency},
+	CAD: {symbol: "CA$", name: "Canadian Dollar", number: 124, minorUnits: 2, kind: OrdinaryCurrency},
+	CDF: {symbol: "CDF", name: "Congolese Franc", number: 976, minorUnits: 2, kind: OrdinaryCurrency},
+	CHF: {symbol: "CHF", name: "Swiss Franc", number: 756, minorUnits: 2, kind: OrdinaryCurrency},
+	CLP: {symbol: "CLP", name: "Chilean Peso", number: 152, minorUnits: 0, kind: OrdinaryCurrency},
+	CNY: {symbol: "CN¥", name: "Yuan Renminbi", number: 156, minorUnits: 2, kind: OrdinaryCurrency},
+	COP: {symbol: "COP", name: "Colombian Peso", number: 170, minorUnits: 2, kind: OrdinaryCurrency},
+	CRC: {symbol: "CRC", name: "Costa Rican Colon", number: 188, minorUnits: 2, kind: OrdinaryCurrency},
+	CUC: {symbol: "CUC", name: "Peso Convertible", number: 931, minorUnits: 2, kind: OrdinaryCurrency},
+	CUP: {symbol: "CUP", name: "Cuban Peso", number: 192, minorUnits: 2, kind: OrdinaryCurrency},
+	CVE: {symbol: "CVE", name: "Cape Verde Escudo", number: 132, minorUnits: 2, kind: OrdinaryCurrency},
+	CZK: {symbol: "CZK", name: "Czech Koruna", number: 203, minorUnits: 2, kind: OrdinaryCurrency},
+	DJF: {symbol: "DJF", name: "Djibouti Franc", number: 262, minorUnits: 0, kind: OrdinaryCurrency},
+	DKK: {symbol: "DKK", name: "Danish Krone", number: 208, minorUnits: 2, kind: OrdinaryCurrency},
+	DOP: {symbol: "DOP", name: "Dominican Peso", number: 214, minorUnits: 2, kind: OrdinaryCurrency},
+	DZD: {symbol: "DZD", name: "Algerian Dinar", number: 12, minorUnits: 2, kind: OrdinaryCurrency},
+	EGP: {symbol: "EGP", name: "Egyptian Pound", number: 818, minorUnits: 2, kind: OrdinaryCurrency},
+	ERN: {symbol: "ERN", name: "Nakfa", number: 232, minorUnits: 2, kind: OrdinaryCurrency},
+	ETB: {symbol: "ETB", name: "Ethiopian Birr", number: 230, minorUnits: 2, kind: OrdinaryCurrency},
+	EUR: {symbol: "€", name: "Euro", number: 978, minorUnits: 2, kind: OrdinaryCurrency},
+	FJD: {symbol: "FJD", name: "Fiji Dollar", number: 242, minorUnits: 2, kind: OrdinaryCurrency},
+	FKP: {symbol: "FKP", name: "Falkland Islands Pound", number: 238, minorUnits: 2, kind: OrdinaryCurrency},
+	GBP: {symbol: "£", name: "Pound Sterling", number: 826, minorUnits: 2, kind: OrdinaryCurrency},
+	GEL: {symbol: "GEL", name: "Lari", number: 981, minorUnits: 2, kind: OrdinaryCurrency},
+	GHS: {symbol: "GHS", name: "Ghana Cedi", number: 936, minorUnits: 2, kind: OrdinaryCurrency},
+	GIP: {symbol: "GIP", name: "Gibraltar Pound", number: 292, minorUnits: 2, kind: OrdinaryCurrency},
+	GMD: {symbol: "GMD", name: "Dalasi", number: 270, minorUnits: 2, kind: OrdinaryCurrency},
+	GNF: {symbol: "GNF", name: "Guinea Franc", number: 324, minorUnits: 0, kind: OrdinaryCurrency},
+	GTQ: {symbol: "GTQ", name: "Quetzal", number: 320, minorUnits: 2, kind: OrdinaryCurrency},
+	GYD: {symbol: "GYD", name: "Guyana Dollar", number: 328, minorUnits: 2, kind: OrdinaryCurrency},
+	HKD: {symbol: "HK$", name: "Hong Kong Dollar", number: 344, minorUnits: 2, kind: OrdinaryCurrency},
+	HNL: {symbol: "HNL", name: "Lempira", number: 340, minorUnits: 2, kind: OrdinaryCurrency},
+	HRK: {symbol: "HRK", name: "Croatian Kuna", number: 191, minorUnits: 2, kind: OrdinaryCurrency},
+	HTG: {symbol: "HTG", name: "Gourde", number: 332, minorUnits: 2, kind: OrdinaryCurrency},
+	HUF: {symbol: "HUF", name: "Forint", number: 348, minorUnits: 2, kind: OrdinaryCurrency},
+	IDR: {symbol: "IDR", name: "Rupiah", number: 360, minorUnits: 2, kind: OrdinaryCurrency},
+	ILS: {symbol: "₪", name: "New Israeli Sheqel", number: 376, minorUnits: 2, kind: OrdinaryCurrency},
+	INR: {symbol: "₹", name: "Indian Rupee", number: 356, minorUnits: 2, kind: OrdinaryCurrency},
+	IQD: {symbol: "IQD", name: "Iraqi Dinar", number: 368, minorUnits: 3, kind: OrdinaryCurrency},
+	IRR: {symbol: "IRR", name: "Iranian Rial", number: 364, minorUnits: 2, kind: OrdinaryCurrency},
+	ISK: {symbol: "ISK", name: "Iceland Krona", number: 352, minorUnits: 0, kind: OrdinaryCurrency},
+	JMD: {symbol: "JMD", name: "Jamaican Dollar", number: 388, minorUnits: 2, kind: OrdinaryCurrency},
+	JOD: {symbol: "JOD", name: "Jordanian Dinar", number: 400, minorUnits: 3, kind: OrdinaryCurrency},
+	JPY: {symbol: "JP¥", name: "Yen", number: 392, minorUnits: 0, kind: OrdinaryCurrency},
+	KES: {symbol: "KES", name: "Kenyan Shilling", number: 404, minorUnits: 2, kind: OrdinaryCurrency},
+	KGS: {symbol: "KGS", name: "Som", number: 417, minorUnits: 2, kind: OrdinaryCurrency},
+	KHR: {symbol: "KHR", name: "Riel", number: 116, minorUnits: 2, kind: OrdinaryCurrency},
+	KMF: {symbol: "KMF", name: "Comoro Franc", number: 174, minorUnits: 0, kind: OrdinaryCurrency},
+	KPW: {symbol: "KPW", name: "North Korean Won", number: 408, minorUnits: 2, kind: OrdinaryCurrency},
+	KRW: {symbol: "₩", name: "Won", number: 410, minorUnits: 0, kind: OrdinaryCurrency},
+	KWD: {symbol: "KWD", name: "Kuwaiti Dinar", number: 414, minorUnits: 3, kind: OrdinaryCurrency},
+	KYD: {symbol: "KYD", name: "Cayman Islands Dollar", number: 136, minorUnits: 2, kind: OrdinaryCurrency},
+	KZT: {symbol: "KZT", name: "Tenge", number: 398, minorUnits: 2, kind: OrdinaryCurrency},
+	LAK: {symbol: "LAK", name: "Kip", number: 418, minorUnits: 2, kind: OrdinaryCurrency},
+	LBP: {symbol: "LBP", name: "Lebanese Pound", number: 422, minorUnits: 2, kind: OrdinaryCurrency},
+	LKR: {symbol: "LKR", name: "Sri Lanka Rupee", number: 144, minorUnits: 2, kind: OrdinaryCurrency},
+	LRD: {symbol: "LRD", name: "Liberian Dollar", number: 430, minorUnits: 2, kind: OrdinaryCurrency},
+	LSL: {symbol: "LSL", name: "Loti", number: 426, minorUnits: 2, kind: OrdinaryCurrency},
+	LTL: {symbol: "LTL", name: "Lithuanian Litas", number: 440, minorUnits: 2, kind: OrdinaryCurrency},
+	LVL: {symbol: "LVL", name: "Latvian Lats", number: 428, minorUnits: 2, kind: OrdinaryCurrency},
+	LYD: {symbol: "LYD", name: "Libyan Dinar", number: 434, minorUnits: 3, kind: OrdinaryCurrency},
+	MAD: {symbol: "MAD", name: "Moroccan Dirham", number: 504, minorUnits: 2, kind: OrdinaryCurrency},
+	MDL: {symbol: "MDL", name: "Moldovan Leu", number: 498, minorUnits: 2, kind: OrdinaryCurrency},
+	MGA: {symbol: "MGA", name: "Malagasy Ariary", number: 969, minorUnits: 2, kind: OrdinaryCurrency},
+	MKD: {symbol: "MKD", name: "Denar", number: 807, minorUnits: 2, kind: OrdinaryCurrency},
+	MMK: {symbol: "MMK", name: "Kyat", number: 104, minorUnits: 2, kind: OrdinaryCurrency},
+	MNT: {symbol: "MNT", name: "Tugrik", number: 496, minorUnits: 2, kind: OrdinaryCurrency},
+	MOP: {symbol: "MOP", name: "Pataca", number: 446, minorUnits: 2, kind: OrdinaryCurrency},
+	MRO: {symbol: "MRO", name: "Ouguiya", number: 478, minorUnits: 2, kind: OrdinaryCurrency},
+	MUR: {symbol: "MUR", name: "Mauritius Rupee", number: 480, minorUnits: 2, kind: OrdinaryCurrency},
+	MVR: {symbol: "MVR", name: "Rufiyaa", number: 462, minorUnits: 2, kind: OrdinaryCurrency},
+	MWK: {symbol: "MWK", name: "Kwacha", number: 454, minorUnits: 2, kind: OrdinaryCurrency},
+	MXN: {symbol: "MX$", name: "Mexican Peso", number: 484, minorUnits: 2, kind: OrdinaryCurrency},
+	MYR: {symbol: "MYR", name: "Malaysian Ringgit", number: 458, minorUnits: 2, kind: OrdinaryCurrency},
+	MZN: {symbol: "MZN", name: "Mozambique Metical", number: 943, minorUnits: 2, kind: OrdinaryCurrency},
+	NAD: {symbol: "NAD", name: "Namibia Dollar", number: 516, minorUnits: 2, kind: OrdinaryCurrency},
+	NGN: {symbol: "NGN", name: "Naira", number: 566, minorUnits: 2, kind: OrdinaryCurrency},
+	NIO: {symbol: "NIO", name: "Cordoba Oro", number: 558, minorUnits: 2, kind: OrdinaryCurrency},
+	NOK: {symbol: "NOK", name: "Norwegian Krone", number: 578, minorUnits: 2, kind: OrdinaryCurrency},
+	NPR: {symbol: "NPR", name: "Nepalese Rupee", number: 524, minorUnits: 2, kind: OrdinaryCurrency},
+	NZD: {symbol: "NZ$", name: "New Zealand Dollar", number: 554, minorUnits: 2, kind: OrdinaryCurrency},
+	OMR: {symbol: "OMR", name: "Rial Omani", number: 512, minorUnits: 3, kind: OrdinaryCurrency},
+	PAB: {symbol: "PAB", name: "Balboa", number: 590, minorUnits: 2, kind: OrdinaryCurrency},
+	PEN: {symbol: "PEN", name: "Nuevo Sol", number: 604, minorUnits: 2, kind: OrdinaryCurrency},
+	PGK: {symbol: "PGK", name: "Kina", number: 598, minorUnits: 2, kind: OrdinaryCurrency},
+	PHP: {symbol: "PHP", name: "Philippine Peso", number: 608, minorUnits: 2, kind: OrdinaryCurrency},
+	PKR: {symbol: "PKR", name: "Pakistan Rupee", number: 586, minorUnits: 2, kind: OrdinaryCurrency},
+	PLN: {symbol: "PLN", name: "Zloty", number: 985, minorUnits: 2, kind: OrdinaryCurrency},
+	PYG: {symbol: "PYG", name: "Guarani", number: 600, minorUnits: 0, kind: OrdinaryCurrency},
+	QAR: {symbol: "QAR", name: "Qatari Rial", number: 634, minorUnits: 2, kind: OrdinaryCurrency},
+	RON: {symbol: "RON", name: "New Romanian Leu", number: 946, minorUnits: 2, kind: OrdinaryCurrency},
+	RSD: {symbol: "RSD", name: "Serbian Dinar", number: 941, minorUnits: 2, kind: OrdinaryCurrency},
+	RUB: {symbol: "RUB", name: "Russian Ruble", number: 643, minorUnits: 2, kind: OrdinaryCurrency},
+	RWF: {symbol: "RWF", name: "Rwanda Franc", number: 646, minorUnits: 0, kind: OrdinaryCurrency},
+	SAR: {symbol: "SAR", name: "Saudi Riyal", number: 682, minorUnits: 2, kind: OrdinaryCurrency},
+	SBD: {symbol: "SBD", name: "Solomon Islands Dollar", number: 90, minorUnits: 2, kind: OrdinaryCurrency},
+	SCR: {symbol: "SCR", name: "Seychelles Rupee", number: 690, minorUnits: 2, kind: OrdinaryCurrency},
+	SDG: {symbol: "SDG", name: "Sudanese Pound", number: 938, minorUnits: 2, kind: OrdinaryCurrency},
+	SEK: {symbol: "SEK", name: "Swedish Krona", number: 752, minorUnits: 2, kind: OrdinaryCurrency},
+	SGD: {symbol: "SGD", name: "Singapore Dollar", number: 702, minorUnits: 2, kind: OrdinaryCurrency},
+	SHP: {symbol: "SHP", name: "Saint Helena Pound", number: 654, minorUnits: 2, kind: OrdinaryCurrency},
+	SLL: {symbol: "SLL", name: "Leone", number: 694, minorUnits: 2, kind: OrdinaryCurrency},
+	SOS: {symbol: "SOS", name: "Somali Shilling", number: 706, minorUnits: 2, kind: OrdinaryCurrency},
+	SRD: {symbol: "SRD", name: "Surinam Dollar", number: 968, minorUnits: 2, kind: OrdinaryCurrency},
+	SSP: {symbol: "SSP", name: "South Sudanese Pound", number: 728, minorUnits: 2, kind: OrdinaryCurrency},
+	STD: {symbol: "STD", name: "Dobra", number: 678, minorUnits: 2, kind: OrdinaryCurrency},
+	SVC: {symbol: "SVC", name: "El Salvador Colon", number: 222, minorUnits: 2, kind: OrdinaryCurrency},
+	SYP: {symbol: "SYP", name: "Syrian Pound", number: 760, minorUnits: 2, kind: OrdinaryCurrency},
+	SZL: {symbol: "SZL", name: "Lilangeni", number: 748, minorUnits: 2, kind: OrdinaryCurrency},
+	THB: {symbol: "THB", name: "Baht", number: 764, minorUnits: 2, kind: OrdinaryCurrency},
+	TJS: {symbol: "TJS", name: "Somoni", number: 972, minorUnits: 2, kind: OrdinaryCurrency},
+	TMT: {symbol: "TMT", name: "Turkmenistan New Manat", number: 934, minorUnits: 2, kind: OrdinaryCurrency},
+	TND: {symbol: "TND", name: "Tunisian Dinar", number: 788, minorUnits: 3, kind: OrdinaryCurrency},
+	TOP: {symbol: "TOP", name: "Pa’anga", number: 776, minorUnits: 2, kind: OrdinaryCurrency},
+	TRY: {symbol: "TRY", name: "Turkish Lira", number: 949, minorUnits: 2, kind: OrdinaryCurrency},
+	TTD: {symbol: "TTD", name: "Trinidad and Tobago Dollar", number: 780, minorUnits: 2, kind: OrdinaryCurrency},
+	TWD: {symbol: "NT$", name: "New Taiwan Dollar", number: 901, minorUnits: 2, kind: OrdinaryCurrency},
+	TZS: {symbol: "TZS", name: "Tanzanian Shilling", number: 834, minorUnits: 2, kind: OrdinaryCurrency},
+	UAH: {symbol: "UAH", name: "Hryvnia", number: 980, minorUnits: 2, kind: OrdinaryCurrency},
+	UGX: {symbol: "UGX", name: "Uganda Shilling", number: 800, minorUnits: 0, kind: OrdinaryCurrency},
+	USD: {symbol: "US$", name: "US Dollar", number: 840, minorUnits: 2, kind: OrdinaryCurrency},
+	UYU: {symbol: "UYU", name: "Peso Uruguayo", number: 858, minorUnits: 2, kind: OrdinaryCurrency},
+	UZS: {symbol: "UZS", name: "Uzbekistan Sum", number: 860, minorUnits: 2, kind: OrdinaryCurrency},
+	VEF: {symbol: "VEF", name: "Bolivar", number: 937, minorUnits: 2, kind: OrdinaryCurrency},
+	VND: {symbol: "₫", name: "Dong", number: 704, minorUnits: 0, kind: OrdinaryCurrency},
+	VUV: {symbol: "VUV", name: "Vatu", number: 548, minorUnits: 0, kind: OrdinaryCurrency},
+	WST: {symbol: "WST", name: "Tala", number: 882, minorUnits: 2, kind: OrdinaryCurrency},
+	XAF: {symbol: "FCFA", name: "CFA Franc BEAC", number: 950, minorUnits: 0, kind: OrdinaryCurrency},
+	XAG: {symbol: "XAG", name: "Silver", number: 961, minorUnits: 0, kind: MetalCurrency},
+	XAU: {symbol: "XAU", name: "Gold", number: 959, minorUnits: 0, kind: MetalCurrency},
+	XBA: {symbol: "XBA", name: "Bond Markets Unit European Composite Unit (EURCO)", number: 955, minorUnits: 0, kind: BondUnitCurrency},
+	XBB: {symbol: "XBB", name: "Bond Markets Unit European Monetary Unit (E.M.U.-6)", number: 956, minorUnits: 0, kind: BondUnitCurrency},
+	XBC: {symbol: "XBC", name: "Bond Markets Unit European Unit of Account 9 (E.U.A.-9)", number: 957, minorUnits: 0, kind: BondUnitCurrency},
+	XBD: {symbol: "XBD", name: "Bond Markets Unit European Unit of Account 17 (E.U.A.-17)", number: 958, minorUnits: 0, kind: BondUnitCurrency},
+	XCD: {symbol: "EC$", name: "East Caribbean Dollar", number: 951, minorUnits: 2, kind: OrdinaryCurrency},
+	XDR: {symbol: "XDR", name: "SDR (Special Drawing Right)", number: 960, minorUnits: 0, kind: OrdinaryCurrency},
+	XOF: {symbol: "CFA", name: "CFA Franc BCEAO", number: 952, minorUnits: 0, kind: OrdinaryCurrency},
+	XPD: {symbol: "XPD", name: "Palladium", number: 964, minorUnits: 0, kind: MetalCurrency},
+	XPF: {symbol: "CFPF", name: "CFP Franc", number: 953, minorUnits: 0, kind: OrdinaryCurrency},
+	XPT: {symbol: "XPT", name: "Platinum", number: 962, minorUnits: 0, kind: MetalCurrency},
+	XSU: {symbol: "XSU", name: "Sucre", number: 994, minorUnits: 0, kind: OrdinaryCurrency},
+	XTS: {symbol: "XTS", name: "Codes specifically reserved for testing purposes", number: 963, minorUnits: 0, kind: TestingCurrency},
+	XUA: {symbol: "XUA", name: "ADB Unit of Account", number: 965, minorUnits: 0, kind: OrdinaryCurrency},
+	YER: {symbol: "YER", name: "Yemeni Rial", number: 886, minorUnits: 2, kind: OrdinaryCurrency},
+	ZAR: {symbol: "ZAR", name: "Rand", number: 710, minorUnits: 2, kind: OrdinaryCurrency},
+	ZMW: {symbol: "ZMW", name: "Zambian Kwacha", number: 967, minorUnits: 2, kind: OrdinaryCurrency},
+	ZWL: {symbol: "ZWL", name: "Zimbabwe Dollar", number: 932, minorUnits: 2, kind: OrdinaryCurrency},
+	ADP: {symbol: "ADP", name: "Andorran Peseta", number: 20, minorUnits: 2, kind: OrdinaryCurrency},
+	ATS: {symbol: "ATS", name: "Schilling", number: 40, minorUnits: 2, kind: OrdinaryCurrency},
+	AZM: {symbol: "AZM", name: "Azerbaijanian Manat", number: 31, minorUnits: 2, kind: OrdinaryCurrency},
+	BEF: {symbol: "BEF", name: "Belgian Franc", number: 56, minorUnits: 2, kind: OrdinaryCurrency},
+	BGL: {symbol: "BGL", name: "Lev", number: 100, minorUnits: 2, kind: OrdinaryCurrency},
+	CSD: {symbol: "CSD", name: "Serbian Dinar", number: 891, minorUnits: 2, kind: OrdinaryCurrency},
+	CYP: {symbol: "CYP", name: "Cyprus Pound", number: 196, minorUnits: 2, kind: OrdinaryCurrency},
+	DEM: {symbol: "DEM", name: "Deutsche Mark", number: 276, minorUnits: 2, kind: OrdinaryCurrency},
+	EEK: {symbol: "EEK", name: "Kroon", number: 233, minorUnits: 2, kind: OrdinaryCurrency},
+	ESP: {symbol: "ESP", name: "Spanish Peseta", number: 724, minorUnits: 2, kind: OrdinaryCurrency},
+	FIM: {symbol: "FIM", name: "Markka", number: 246, minorUnits: 2, kind: OrdinaryCurrency},
+	FRF: {symbol: "FRF", name: "French Franc", number: 250, minorUnits: 2, kind: OrdinaryCurrency},
+	GHC: {symbol: "GHC", name: "Cedi", number: 288, minorUnits: 2, kind: OrdinaryCurrency},
+	GRD: {symbol: "GRD", name: "Drachma", number: 300, minorUnits: 2, kind: OrdinaryCurrency},
+	IEP: {symbol: "IEP", name: "Irish Pound", number: 372, minorUnits: 2, kind: OrdinaryCurrency},
+	ITL: {symbol: "ITL", name: "Italian Lira", number: 380, minorUnits: 2, kind: OrdinaryCurrency},
+	LUF: {symbol: "LUF", name: "Luxembourg Franc", number: 442, minorUnits: 2, kind: OrdinaryCurrency},
+	MGF: {symbol: "MGF", name: "Malagasy Franc", number: 450, minorUnits: 2, kind: OrdinaryCurrency},
+	MTL: {symbol: "MTL", name: "Maltese Lira", number: 470, minorUnits: 2, kind: OrdinaryCurrency},
+	MZM: {symbol: "MZM", name: "Mozambique Metical", number: 508, minorUnits: 2, kind: OrdinaryCurrency},
+	NLG: {symbol: "NLG", name: "Netherlands Guilder", number: 528, minorUnits: 2, kind: OrdinaryCurrency},
+	PTE: {symbol: "PTE", name: "Portuguese Escudo", number: 620, minorUnits: 2, kind: OrdinaryCurrency},
+	ROL: {symbol: "ROL", name: "Old Leu", number: 642, minorUnits: 2, kind: OrdinaryCurrency},
+	SDD: {symbol: "SDD", name: "Sudanese Dinar", number: 736, minorUnits: 2, kind: OrdinaryCurrency},
+	SIT: {symbol: "SIT", name: "Tolar", number: 705, minorUnits: 2, kind: OrdinaryCurrency},
+	SKK: {symbol: "SKK", name: "Slovak Koruna", number: 703, minorUnits: 2, kind: OrdinaryCurrency},
+	SRG: {symbol: "SRG", name: "Surinam Guilder", number: 740, minorUnits: 2, kind: OrdinaryCurrency},
+	TMM: {symbol: "TMM", name: "Turkmenistan Manat", number: 795, minorUnits: 2, kind: OrdinaryCurrency},
+	TRL: {symbol: "TRL", name: "Old Turkish Lira", number: 792, minorUnits: 2, kind: OrdinaryCurrency},
+	VEB: {symbol: "VEB", name: "Bolivar", number: 862, minorUnits: 2, kind: OrdinaryCurrency},
+	YUM: {symbol: "YUM", name: "New Dinar", number: 891, minorUnits: 2, kind: OrdinaryCurrency},
+	ZMK: {symbol: "ZMK", name: "Zambian Kwacha", number: 894, minorUnits: 2, kind: OrdinaryCurrency},
+	ZWD: {symbol: "ZWD", name: "Zimbabwe Dollar", number: 716, minorUnits: 2, kind: OrdinaryCurrency},
+	ZWN: {symbol: "ZWN", name: "Zimbabwe Dollar", number: 942, minorUnits: 2, kind: OrdinaryCurrency},
+	ZWR: {symbol: "ZWR", name: "Zimbabwe Dollar", number: 935, minorUnits: 2, kind: OrdinaryCurrency},
+	BOV: {symbol: "BOV", name: "Mvdol", number: 984, minorUnits: 2, kind: FundCurrency},
+	CHE: {symbol: "CHE", name: "WIR Euro", number: 947, minorUnits: 2, kind: FundCurrency},
+	CHW: {symbol: "CHW", name: "WIR Franc", number: 948, minorUnits: 2, kind: FundCurrency},
+	CLF: {symbol: "CLF", name: "Unidad de Fomento", number: 990, minorUnits: 0, kind: FundCurrency},
+	COU: {symbol: "COU", name: "Unidad de Valor Real", number: 970, minorUnits: 2, kind: FundCurrency},
+	MXV: {symbol: "MXV", name: "Mexican Unidad de Inversion (UDI)", number: 979, minorUnits: 2, kind: FundCurrency},
+	USN: {symbol: "USN", name: "US Dollar (Next day)", number: 997, minorUnits: 2, kind: FundCurrency},
+	USS: {symbol: "USS", name: "US Dollar (Same day)", number: 998, minorUnits: 2, kind: FundCurrency},
+	UYI: {symbol: "UYI", name: "Uruguay Peso en Unidades Indexadas (URUIURUI)", number: 940, minorUnits: 0, kind: FundCurrency},
+}
+
+var currencyWithdrawals = map[Currency]time.Time{
+	ADP: time.Date(2003, time.July, 1, 0, 0, 0, 0, time.UTC),
+	ATS: time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC),
+	AZM: time.Date(2005, time.December, 1, 0, 0, 0, 0, time.UTC),
+	BEF: time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC),
+	BGL: time.Date(2003, time.November, 1, 0, 0, 0, 0, time.UTC),
+	CSD: time.Date(2006, time.October, 1, 0, 0, 0, 0, time.UTC),
+	CYP: time.Date(2008, time.January, 1, 0, 0, 0, 0, time.UTC),
+	DEM: time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC),
+	EEK: time.Date(2011, time.January, 1, 0, 0, 0, 0, time.UTC),
+	ESP: time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC),
+	FIM: time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC),
+	FRF: time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC),
+	GHC: time.Date(2008, time.January, 1, 0, 0, 0, 0, time.UTC),
+	GRD: time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC),
+	IEP: time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC),
+	ITL: time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC),
+	LUF: time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC),
+	MGF: time.Date(2004, time.December, 1, 0, 0, 0, 0, time.UTC),
+	MTL: time.Date(2008, time.January, 1, 0, 0, 0, 0, time.UTC),
+	MZM: time.Date(2006, time.June, 1, 0, 0, 0, 0, time.UTC),
+	NLG: time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC),
+	PTE: time.Date(2002, time.March, 1, 0, 0, 0, 0, time.UTC),
+	ROL: time.Date(2005, time.June, 1, 0, 0, 0, 0, time.UTC),
+	SDD: time.Date(2007, time.July, 1, 0, 0, 0, 0, time.UTC),
+	SIT: time.Date(2007, time.January, 1, 0, 0, 0, 0, time.UTC),
+	SKK: time.Date(2009, time.January, 1, 0, 0, 0, 0, time.UTC),
+	SRG: time.Date(2003, time.December, 1, 0, 0, 0, 0, time.UTC),
+	TMM: time.Date(2009, time.January, 1, 0, 0, 0, 0, time.UTC),
+	TRL: time.Date(2005, time.December, 1, 0, 0, 0, 0, time.UTC),
+	VEB: time.Date(2008, time.January, 1, 0, 0, 0, 0, time.UTC),
+	YUM: time.Date(2003, time.July, 1, 0, 0, 0, 0, time.UTC),
+	ZMK: time.Date(2012, time.December, 1, 0, 0, 0, 0, time.UTC),
+	ZWD: time.Date(2006, time.August, 1, 0, 0, 0, 0, time.UTC),
+	ZWN: time.Date(2008, time.August, 1, 0, 0, 0, 0, time.UTC),
+	ZWR: time.Date(2009, time.June, 1, 0, 0, 0, 0, time.UTC),
+}
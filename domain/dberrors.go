@@ -0,0 +1,45 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	mysqlErrDupEntry        uint16 = 1062
+	mysqlErrLockWaitTimeout uint16 = 1205
+	mysqlErrLockDeadlock    uint16 = 1213
+)
+
+// isDuplicateEntry reports whether err is the error returned by the
+// database when an insert or update would violate a unique index.
+func isDuplicateEntry(err error) bool {
+	switch e := err.(type) {
+	case *mysql.MySQLError:
+		return e.Number == mysqlErrDupEntry
+	}
+
+	return false
+}
+
+// isRetryable reports whether err is the error returned by the database
+// when a transaction fails because of a deadlock or a lock wait timeout, in
+// which case trying the transaction again may succeed.
+func isRetryable(err error) bool {
+	switch e := err.(type) {
+	case *mysql.MySQLError:
+		return e.Number == mysqlErrLockDeadlock || e.Number == mysqlErrLockWaitTimeout
+	}
+
+	return false
+}
+
+// isRecordNotFound reports whether err is the error returned by gorm when
+// a query for a single record finds nothing.
+func isRecordNotFound(err error) bool {
+	return err == gorm.ErrRecordNotFound
+}
@@ -0,0 +1,49 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsDuplicateEntryForMySQLDuplicateEntry(t *testing.T) {
+	err := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+
+	assert.True(t, isDuplicateEntry(err))
+}
+
+func TestIsDuplicateEntryIsFalseForOtherErrors(t *testing.T) {
+	errs := []error{nil, errors.New("some error"),
+		&mysql.MySQLError{Number: 1146, Message: "Table doesn't exist"}}
+
+	for _, err := range errs {
+		assert.False(t, isDuplicateEntry(err))
+	}
+}
+
+func TestIsRetryableForDeadlockAndLockWaitTimeout(t *testing.T) {
+	assert.True(t, isRetryable(&mysql.MySQLError{Number: 1213, Message: "Deadlock found"}))
+	assert.True(t, isRetryable(&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}))
+}
+
+func TestIsRetryableIsFalseForOtherErrors(t *testing.T) {
+	errs := []error{nil, errors.New("some error"),
+		&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}}
+
+	for _, err := range errs {
+		assert.False(t, isRetryable(err))
+	}
+}
+
+func TestIsRecordNotFound(t *testing.T) {
+	assert.True(t, isRecordNotFound(gorm.ErrRecordNotFound))
+	assert.False(t, isRecordNotFound(errors.New("some error")))
+	assert.False(t, isRecordNotFound(nil))
+}
@@ -5,8 +5,13 @@
 package domain
 
 import (
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
+	"github.com/sbosnick1/openacct/logger"
+)
+
+var (
+	log = logger.New("domain")
 )
 
 const (
@@ -14,7 +19,7 @@ const (
 )
 
 func New(dsn string) (Store, error) {
-	db, err := gorm.Open(mysqlDialect, dsn)
+	db, err := open(dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -23,16 +28,45 @@ func New(dsn string) (Store, error) {
 }
 
 func CreateOrMigrate(dsn string) error {
-	db, err := gorm.Open(mysqlDialect, dsn)
+	db, err := open(dsn)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	err = db.AutoMigrate(&fundImpl{}).Error
+	err = db.AutoMigrate(&fundImpl{}, &accountImpl{}, &idempotentRequestImpl{},
+		&journalEntryImpl{}, &postingImpl{}, &scheduleImpl{}, &approvalRuleImpl{}, &donorImpl{},
+		&receiptImpl{}, &holdingImpl{}, &schemaVersionImpl{}).Error
+	if err == nil {
+		err = migrateFundCurrencies(db)
+	}
+	if err == nil {
+		// entries made before the approval workflow have no status
+		// and already counted toward the balances
+		err = db.Model(&journalEntryImpl{}).Where("entry_status = ?", 0).
+			UpdateColumn("entry_status", Approved).Error
+	}
+	if err == nil {
+		err = db.Save(&schemaVersionImpl{ID: 1, Version: SchemaVersion}).Error
+	}
 	if err != nil {
+		log.WithError(err).Error("Unable to create or migrate the database schema.")
 		return err
 	}
 
+	log.WithField("version", SchemaVersion).Debug("Created or migrated the database schema.")
+
 	return nil
 }
+
+// open opens the database given by dsn. The dsn is adjusted so that the
+// driver parses DATETIME columns into time.Time values.
+func open(dsn string) (*gorm.DB, error) {
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	config.ParseTime = true
+
+	return gorm.Open(mysqlDialect, config.FormatDSN())
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 func makeDsn() string {
@@ -28,11 +29,12 @@ func makeDsn() string {
 	}
 
 	config := &mysql.Config{
-		User:   os.Getenv("OPENACCT_DB_USER"),
-		Passwd: os.Getenv("OPENACCT_DB_PASSWD"),
-		Net:    net,
-		Addr:   host,
-		DBName: database,
+		User:      os.Getenv("OPENACCT_DB_USER"),
+		Passwd:    os.Getenv("OPENACCT_DB_PASSWD"),
+		Net:       net,
+		Addr:      host,
+		DBName:    database,
+		ParseTime: true,
 	}
 
 	return config.FormatDSN()
@@ -109,17 +111,53 @@ func TestCreateOrMigrate(t *testing.T) {
 	require.NoError(err, "gorm.Open() failed.")
 	defer db.Close()
 	assert.True(db.HasTable(&fundImpl{}))
+	assert.True(db.HasTable(&accountImpl{}))
+	assert.True(db.HasTable(&idempotentRequestImpl{}))
+	assert.True(db.HasTable(&journalEntryImpl{}))
+	assert.True(db.HasTable(&postingImpl{}))
+	assert.True(db.HasTable(&scheduleImpl{}))
+	assert.True(db.HasTable(&approvalRuleImpl{}))
+	assert.True(db.HasTable(&donorImpl{}))
+	assert.True(db.HasTable(&receiptImpl{}))
+	assert.True(db.HasTable(&holdingImpl{}))
+	var version schemaVersionImpl
+	require.NoError(db.First(&version, 1).Error, "Unable to read the schema version.")
+	assert.Equal(SchemaVersion, version.Version)
+}
+
+func TestCreateOrMigrateConvertsFundCurrencyValuesToCodes(t *testing.T) {
+	dsn := makeDsn()
+	createEmptyDb(t, dsn)
+	db := openDb(t, dsn)
+	defer db.Close()
+	// funds from before currencies were stored as codes
+	table := db.NewScope(&fundImpl{}).TableName()
+	require.NoError(t, db.Exec("ALTER TABLE "+table+" MODIFY fund_currency INT UNSIGNED").Error,
+		"Unable to change the currency column.")
+	require.NoError(t, db.Exec("INSERT INTO "+table+" (fund_currency, fund_name) VALUES (?, ?), (?, ?)",
+		uint(CAD), "General", 9999, "Unknown").Error, "Unable to insert the funds.")
+
+	err := CreateOrMigrate(dsn)
+
+	require.NoError(t, err, "CreateOrMigrate() failed.")
+	var codes []string
+	require.NoError(t, db.Table(table).Order("id").Pluck("fund_currency", &codes).Error,
+		"Unable to read the currencies.")
+	assert.Equal(t, []string{"CAD", "XXX"}, codes, "Unexpected currencies of the migrated funds.")
+	var fund fundImpl
+	require.NoError(t, db.First(&fund, 1).Error, "Unable to read the migrated fund.")
+	assert.Equal(t, CAD, fund.FundCurrency, "Unexpected currency of the migrated fund.")
 }
 
 func TestNewFundRepositoryGetAllRetrievesAllFunds(t *testing.T) {
 	dsn := makeDsn()
 	createEmptyDb(t, dsn)
-	expected := []fundImpl{{1, CAD, "General"}, {2, USD, "Special"}}
+	expected := []fundImpl{{1, CAD, "General", 1, nil}, {2, USD, "Special", 1, nil}}
 	openAndInsertFunds(t, dsn, expected)
 
 	sut, err := New(dsn)
 	require.NoError(t, err, "Unable to create Store.")
-	actual, err := sut.FundRepository().GetAll()
+	actual, err := sut.FundRepository().GetAll(context.Background())
 
 	require.NoError(t, err, "Unable to get all funds.")
 	require.NotNil(t, actual, "GetAll() returned nil funds list.")
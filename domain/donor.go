@@ -0,0 +1,334 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"encoding/json"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/net/context"
+)
+
+const (
+	donorEntity string = "donor"
+)
+
+// A Donor is a person or organization that gives to the funds. Each of the
+// Addresses of a Donor is a complete postal address, with its lines
+// separated by newlines, and the first is the one to send receipts to. The
+// Email and TaxId of a Donor are empty if they are not known. The Version
+// of a Donor changes every time the Donor is updated.
+type Donor interface {
+	Id() uint
+	Name() string
+	Addresses() []string
+	Email() string
+	TaxId() string
+	Version() uint
+}
+
+type donorImpl struct {
+	ID             uint
+	DonorName      string `sql:"size:255;not null"`
+	DonorAddresses string `sql:"type:text"`
+	DonorEmail     string `sql:"size:255"`
+	DonorTaxID     string `sql:"size:64"`
+	DonorVersion   uint   `sql:"not null;default:1"`
+}
+
+func (d *donorImpl) Id() uint {
+	return d.ID
+}
+
+func (d *donorImpl) Name() string {
+	return d.DonorName
+}
+
+// Addresses gives the addresses of the donor, which are stored as JSON.
+// Only Create and Update store them so they can always be decoded.
+func (d *donorImpl) Addresses() []string {
+	var addresses []string
+	json.Unmarshal([]byte(d.DonorAddresses), &addresses)
+	return addresses
+}
+
+func (d *donorImpl) Email() string {
+	return d.DonorEmail
+}
+
+func (d *donorImpl) TaxId() string {
+	return d.DonorTaxID
+}
+
+func (d *donorImpl) Version() uint {
+	return d.DonorVersion
+}
+
+// A Gift is the part of an approved JournalEntry that credits an income
+// account of a Fund on behalf of a Donor. Its Amount is in the minor units
+// of the currency of the Fund. The Amount of a Gift from a reversal is
+// negative.
+type Gift struct {
+	EntryId uint
+	FundId  uint
+	DonorId uint
+	Date    time.Time
+	Amount  int64
+}
+
+// A GivingTotal is the total of the gifts of a Donor to a Fund in a Year.
+type GivingTotal struct {
+	DonorId uint
+	FundId  uint
+	Year    int
+	Amount  int64
+}
+
+// The DonorRepository is the means of accessing the Donor's in the store
+// and the history of their giving. Create and Update return a
+// *ValidationError if the name is empty or the email is not an email
+// address. Get, Update, GiftsByDonor and TotalsByDonor return a
+// *NotFoundError if there is no donor with the given id and GiftsByFund and
+// TotalsByFund return one if there is no fund with the given id. Update
+// only changes the donor if its version is the given version and otherwise
+// returns a *ConcurrencyError.
+//
+// Only approved entries count toward the giving of a donor. The gifts are
+// in the order of their dates and the totals are in the order of their
+// years, then funds, then donors. The totals are per fund because the
+// funds may have different currencies.
+type DonorRepository interface {
+	GetAll(ctx context.Context) ([]Donor, error)
+	Get(ctx context.Context, id uint) (Donor, error)
+	Create(ctx context.Context, name string, addresses []string, email string, taxId string) (Donor, error)
+	Update(ctx context.Context, id uint, version uint, name string, addresses []string, email string,
+		taxId string) (Donor, error)
+	GiftsByDonor(ctx context.Context, donorId uint) ([]Gift, error)
+	GiftsByFund(ctx context.Context, fundId uint) ([]Gift, error)
+	TotalsByDonor(ctx context.Context, donorId uint) ([]GivingTotal, error)
+	TotalsByFund(ctx context.Context, fundId uint) ([]GivingTotal, error)
+}
+
+type donorRepository struct {
+	db *gorm.DB
+}
+
+func (d *donorRepository) GetAll(ctx context.Context) ([]Donor, error) {
+	var donors []donorImpl
+
+	err := withContext(ctx, d.db).Order("id").Find(&donors).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Donor
+	for i := range donors {
+		ret = append(ret, &donors[i])
+	}
+
+	return ret, nil
+}
+
+func (d *donorRepository) Get(ctx context.Context, id uint) (Donor, error) {
+	var donor donorImpl
+
+	err := withContext(ctx, d.db).First(&donor, id).Error
+	if isRecordNotFound(err) {
+		return nil, &NotFoundError{donorEntity, formatId(id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &donor, nil
+}
+
+func (d *donorRepository) Create(ctx context.Context, name string, addresses []string, email string,
+	taxId string) (Donor, error) {
+	donor, err := newDonor(name, addresses, email, taxId)
+	if err != nil {
+		return nil, err
+	}
+	donor.DonorVersion = 1
+	err = withContext(ctx, d.db).Create(donor).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return donor, nil
+}
+
+func (d *donorRepository) Update(ctx context.Context, id uint, version uint, name string, addresses []string,
+	email string, taxId string) (Donor, error) {
+	donor, err := newDonor(name, addresses, email, taxId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := withContext(ctx, d.db).Model(&donorImpl{}).
+		Where("id = ? AND donor_version = ?", id, version).
+		Updates(map[string]interface{}{
+			"donor_name":      donor.DonorName,
+			"donor_addresses": donor.DonorAddresses,
+			"donor_email":     donor.DonorEmail,
+			"donor_tax_id":    donor.DonorTaxID,
+			"donor_version":   version + 1,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		_, err := d.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, &ConcurrencyError{donorEntity, formatId(id)}
+	}
+
+	return d.Get(ctx, id)
+}
+
+// newDonor validates the fields of a donor and gives the donor that has
+// them, trimmed of surrounding space.
+func newDonor(name string, addresses []string, email string, taxId string) (*donorImpl, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &ValidationError{donorEntity, "name", "must not be empty"}
+	}
+
+	email = strings.TrimSpace(email)
+	if email != "" {
+		address, err := mail.ParseAddress(email)
+		if err != nil || address.Address != email {
+			return nil, &ValidationError{donorEntity, "email", "must be an email address"}
+		}
+	}
+
+	trimmed := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if address = strings.TrimSpace(address); address != "" {
+			trimmed = append(trimmed, address)
+		}
+	}
+
+	encoded, err := json.Marshal(trimmed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &donorImpl{
+		DonorName:      name,
+		DonorAddresses: string(encoded),
+		DonorEmail:     email,
+		DonorTaxID:     strings.TrimSpace(taxId),
+	}, nil
+}
+
+func (d *donorRepository) GiftsByDonor(ctx context.Context, donorId uint) ([]Gift, error) {
+	if _, err := d.Get(ctx, donorId); err != nil {
+		return nil, err
+	}
+
+	return d.gifts(ctx, d.db.NewScope(&postingImpl{}).TableName()+".posting_donor_id = ?", donorId)
+}
+
+func (d *donorRepository) GiftsByFund(ctx context.Context, fundId uint) ([]Gift, error) {
+	if _, err := (&fundRepository{d.db}).Get(ctx, fundId); err != nil {
+		return nil, err
+	}
+
+	return d.gifts(ctx, d.db.NewScope(&journalEntryImpl{}).TableName()+".entry_fund_id = ?", fundId)
+}
+
+func (d *donorRepository) TotalsByDonor(ctx context.Context, donorId uint) ([]GivingTotal, error) {
+	if _, err := d.Get(ctx, donorId); err != nil {
+		return nil, err
+	}
+
+	return d.totals(ctx, d.db.NewScope(&postingImpl{}).TableName()+".posting_donor_id = ?", donorId)
+}
+
+func (d *donorRepository) TotalsByFund(ctx context.Context, fundId uint) ([]GivingTotal, error) {
+	if _, err := (&fundRepository{d.db}).Get(ctx, fundId); err != nil {
+		return nil, err
+	}
+
+	return d.totals(ctx, d.db.NewScope(&journalEntryImpl{}).TableName()+".entry_fund_id = ?", fundId)
+}
+
+// gifts gives the gifts that meet the condition query with args.
+func (d *donorRepository) gifts(ctx context.Context, query string, args ...interface{}) ([]Gift, error) {
+	postings := d.db.NewScope(&postingImpl{}).TableName()
+	entries := d.db.NewScope(&journalEntryImpl{}).TableName()
+
+	rows, err := d.giving(ctx, query, args...).
+		Select(entries + ".id, " + entries + ".entry_fund_id, " + postings + ".posting_donor_id, " +
+			entries + ".entry_date, -" + postings + ".posting_amount").
+		Order(entries + ".entry_date, " + entries + ".id, " + postings + ".id").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var gifts []Gift
+	for rows.Next() {
+		var gift Gift
+		err := rows.Scan(&gift.EntryId, &gift.FundId, &gift.DonorId, &gift.Date, &gift.Amount)
+		if err != nil {
+			return nil, err
+		}
+		gifts = append(gifts, gift)
+	}
+
+	return gifts, rows.Err()
+}
+
+// totals gives the giving totals of the gifts that meet the condition
+// query with args.
+func (d *donorRepository) totals(ctx context.Context, query string, args ...interface{}) ([]GivingTotal, error) {
+	postings := d.db.NewScope(&postingImpl{}).TableName()
+	entries := d.db.NewScope(&journalEntryImpl{}).TableName()
+	year := "YEAR(" + entries + ".entry_date)"
+
+	rows, err := d.giving(ctx, query, args...).
+		Select(postings + ".posting_donor_id, " + entries + ".entry_fund_id, " + year +
+			", SUM(-" + postings + ".posting_amount)").
+		Group(year + ", " + entries + ".entry_fund_id, " + postings + ".posting_donor_id").
+		Order(year + ", " + entries + ".entry_fund_id, " + postings + ".posting_donor_id").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []GivingTotal
+	for rows.Next() {
+		var total GivingTotal
+		err := rows.Scan(&total.DonorId, &total.FundId, &total.Year, &total.Amount)
+		if err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+
+	return totals, rows.Err()
+}
+
+// giving gives the query for the postings of approved entries that are
+// gifts and that meet the condition query with args.
+func (d *donorRepository) giving(ctx context.Context, query string, args ...interface{}) *gorm.DB {
+	postings := d.db.NewScope(&postingImpl{}).TableName()
+	entries := d.db.NewScope(&journalEntryImpl{}).TableName()
+
+	return withContext(ctx, d.db).Table(postings).
+		Joins("JOIN "+entries+" ON "+entries+".id = "+postings+".posting_entry_id").
+		Where(postings+".posting_donor_id <> 0 AND "+entries+".entry_status = ?", Approved).
+		Where(query, args...)
+}
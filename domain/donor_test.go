@@ -0,0 +1,159 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// getGivingDb gets the ledger database with a donations account in the
+// first fund and two donors.
+func getGivingDb(t *testing.T) *gorm.DB {
+	db := getLedgerDb(t)
+	insertAccounts(t, db, []accountImpl{{4, 1, "Donations", Income, 1}})
+	for _, donor := range []donorImpl{{1, "Ada Lovelace", "[]", "", "", 1}, {2, "Alan Turing", "[]", "", "", 1}} {
+		require.NoError(t, db.Create(&donor).Error, "Unable to create a donor.")
+	}
+	return db
+}
+
+// giveOnDate makes a gift of amount from the donor with the given id to
+// the first fund on date. The gift is posted if post is true.
+func giveOnDate(t *testing.T, db *gorm.DB, donorId uint, amount int64, date time.Time, post bool) {
+	sut := journalEntryRepository{db}
+	entry, err := sut.Create(context.Background(), 1, date, "Gift",
+		[]Posting{{1, amount, 0}, {4, -amount, donorId}})
+	require.NoError(t, err, "Unable to create the gift.")
+	if post {
+		_, err = sut.Submit(context.Background(), entry.Id(), 1, clerk)
+		require.NoError(t, err, "Unable to post the gift.")
+	}
+}
+
+func TestDonorRepositoryCreateReturnsNewDonor(t *testing.T) {
+	db := getEmptyDb(t)
+
+	sut := donorRepository{db}
+	actual, err := sut.Create(context.Background(), " Ada Lovelace ",
+		[]string{"12 St James's Square\nLondon", " "}, "ada@example.org", " 123-456-789 ")
+
+	require.NoError(t, err, "Unable to create a new donor.")
+	assert.NotZero(t, actual.Id(), "Id of the returned donor was zero")
+	assert.Equal(t, "Ada Lovelace", actual.Name())
+	assert.Equal(t, []string{"12 St James's Square\nLondon"}, actual.Addresses())
+	assert.Equal(t, "ada@example.org", actual.Email())
+	assert.Equal(t, "123-456-789", actual.TaxId())
+	assert.Equal(t, uint(1), actual.Version())
+}
+
+func TestDonorRepositoryCreateWithInvalidFieldIsValidationError(t *testing.T) {
+	db := getEmptyDb(t)
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{" ", ""},
+		{"Ada Lovelace", "ada"},
+		{"Ada Lovelace", "Ada <ada@example.org>"},
+	}
+
+	sut := donorRepository{db}
+	for _, test := range tests {
+		_, err := sut.Create(context.Background(), test.name, nil, test.email, "")
+
+		assert.IsType(t, &ValidationError{}, err, "Unexpected error for %+v.", test)
+	}
+}
+
+func TestDonorRepositoryUpdateOldVersionIsConcurrencyError(t *testing.T) {
+	db := getGivingDb(t)
+
+	sut := donorRepository{db}
+	_, err := sut.Update(context.Background(), 1, 2, "Ada King", nil, "", "")
+
+	assert.IsType(t, &ConcurrencyError{}, err, "Update() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryCreateWithDonorForExpenseIsValidationError(t *testing.T) {
+	db := getGivingDb(t)
+
+	sut := journalEntryRepository{db}
+	_, err := sut.Create(context.Background(), 1, time.Now(), "Rent", []Posting{{2, 100, 1}, {1, -100, 0}})
+
+	assert.IsType(t, &ValidationError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryCreateWithMissingDonorIsValidationError(t *testing.T) {
+	db := getGivingDb(t)
+
+	sut := journalEntryRepository{db}
+	_, err := sut.Create(context.Background(), 1, time.Now(), "Gift", []Posting{{1, 100, 0}, {4, -100, 3}})
+
+	assert.IsType(t, &ValidationError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestDonorRepositoryGiftsByDonorOnlyHasPostedGifts(t *testing.T) {
+	db := getGivingDb(t)
+	giveOnDate(t, db, 1, 5000, date(2025, time.December, 24), true)
+	giveOnDate(t, db, 1, 7500, date(2026, time.March, 1), false)
+	giveOnDate(t, db, 2, 2000, date(2026, time.April, 1), true)
+
+	sut := donorRepository{db}
+	actual, err := sut.GiftsByDonor(context.Background(), 1)
+
+	require.NoError(t, err, "Unable to get the donor's gifts.")
+	require.Len(t, actual, 1, "Unexpected number of gifts returned from GiftsByDonor().")
+	assert.Equal(t, uint(1), actual[0].FundId)
+	assert.Equal(t, uint(1), actual[0].DonorId)
+	assert.Equal(t, date(2025, time.December, 24), actual[0].Date)
+	assert.Equal(t, int64(5000), actual[0].Amount)
+}
+
+func TestDonorRepositoryTotalsByFundAreByYearAndDonor(t *testing.T) {
+	db := getGivingDb(t)
+	giveOnDate(t, db, 1, 5000, date(2025, time.December, 24), true)
+	giveOnDate(t, db, 1, 7500, date(2026, time.March, 1), true)
+	giveOnDate(t, db, 1, 2500, date(2026, time.June, 1), true)
+	giveOnDate(t, db, 2, 2000, date(2026, time.April, 1), true)
+
+	sut := donorRepository{db}
+	actual, err := sut.TotalsByFund(context.Background(), 1)
+
+	require.NoError(t, err, "Unable to get the fund's giving totals.")
+	assert.Equal(t, []GivingTotal{
+		{DonorId: 1, FundId: 1, Year: 2025, Amount: 5000},
+		{DonorId: 1, FundId: 1, Year: 2026, Amount: 10000},
+		{DonorId: 2, FundId: 1, Year: 2026, Amount: 2000},
+	}, actual)
+}
+
+func TestDonorRepositoryTotalsByDonorNetsReversals(t *testing.T) {
+	db := getGivingDb(t)
+	giveOnDate(t, db, 1, 5000, date(2026, time.March, 1), true)
+	giveOnDate(t, db, 1, 2500, date(2026, time.June, 1), true)
+	_, err := (&journalEntryRepository{db}).Reverse(context.Background(), 2, 2, date(2026, time.June, 2), clerk)
+	require.NoError(t, err, "Unable to reverse the gift.")
+
+	sut := donorRepository{db}
+	actual, err := sut.TotalsByDonor(context.Background(), 1)
+
+	require.NoError(t, err, "Unable to get the donor's giving totals.")
+	assert.Equal(t, []GivingTotal{{DonorId: 1, FundId: 1, Year: 2026, Amount: 5000}}, actual)
+}
+
+func TestDonorRepositoryGiftsByMissingDonorIsNotFoundError(t *testing.T) {
+	db := getEmptyDb(t)
+
+	sut := donorRepository{db}
+	_, err := sut.GiftsByDonor(context.Background(), 1)
+
+	assert.IsType(t, &NotFoundError{}, err, "GiftsByDonor() returned an unexpected type of error")
+}
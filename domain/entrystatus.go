@@ -0,0 +1,35 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+// EntryStatus is where a JournalEntry is in the approval workflow. A Draft
+// entry is submitted and becomes Pending if an ApprovalRule of its fund
+// applies to it and Approved otherwise. A Pending entry is then Approved
+// or Rejected. Only Approved entries count toward the balances of the
+// accounts.
+type EntryStatus uint
+
+const (
+	Draft EntryStatus = iota + 1
+	Pending
+	Approved
+	Rejected
+)
+
+var entryStatusNames = []string{"", "draft", "pending", "approved", "rejected"}
+
+// IsValid reports whether the EntryStatus is one of the defined statuses.
+func (s EntryStatus) IsValid() bool {
+	return s >= Draft && s <= Rejected
+}
+
+// String returns the string representation of the EntryStatus.
+func (s EntryStatus) String() string {
+	if !s.IsValid() {
+		return ""
+	}
+
+	return entryStatusNames[s]
+}
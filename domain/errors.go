@@ -0,0 +1,85 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// A NotFoundError is returned by a repository when the requested entity
+// does not exist in the store.
+type NotFoundError struct {
+	Entity string
+	Id     string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("No %s with id %s was found.", e.Entity, e.Id)
+}
+
+// A DuplicateError is returned by a repository when an entity would have
+// the same value for a unique field as an existing entity.
+type DuplicateError struct {
+	Entity string
+	Field  string
+	Value  string
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("A %s with %s %s already exists.", e.Entity, e.Field, e.Value)
+}
+
+// A ValidationError is returned by a repository when a field of an entity
+// has a value that is not allowed.
+type ValidationError struct {
+	Entity string
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("Invalid %s %s: %s.", e.Entity, e.Field, e.Reason)
+}
+
+// A ConflictError is returned by a repository when an operation is not
+// allowed because of the current state of an entity.
+type ConflictError struct {
+	Entity string
+	Id     string
+	Reason string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("Conflict with %s %s: %s.", e.Entity, e.Id, e.Reason)
+}
+
+// A ConcurrencyError is returned by a repository when an entity has been
+// changed in the store since it was read.
+type ConcurrencyError struct {
+	Entity string
+	Id     string
+}
+
+func (e *ConcurrencyError) Error() string {
+	return fmt.Sprintf("The %s with id %s was changed by someone else.", e.Entity, e.Id)
+}
+
+// A ForbiddenError is returned by a repository when the principal on whose
+// behalf an operation is made is not allowed to make it.
+type ForbiddenError struct {
+	Entity string
+	Id     string
+	Reason string
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("Not allowed for %s %s: %s.", e.Entity, e.Id, e.Reason)
+}
+
+// formatId gives the string form of an entity id for use in errors.
+func formatId(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -4,20 +4,41 @@
 
 package domain
 
-import "github.com/jinzhu/gorm"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/net/context"
+)
+
+const (
+	fundEntity string = "fund"
+)
 
 // A Fund is a named collection of accounts, all of which are
-// demoniated in the same currency.
+// demoniated in the same currency. The Version of a Fund changes
+// every time the Fund is updated.
+//
+// A Fund is never removed from the store. Instead a Fund that is no
+// longer in use is archived. An archived Fund remains available for
+// historical reports but can't be changed until it is unarchived.
 type Fund interface {
 	Id() uint
 	Currency() Currency
 	Name() string
+	Version() uint
+	IsArchived() bool
+	Archived() time.Time
 }
 
 type fundImpl struct {
 	ID           uint
-	FundCurrency Currency
-	FundName     string `sql:"size:255;unique;index`
+	FundCurrency Currency `sql:"type:char(3)"`
+	FundName     string   `sql:"size:255;unique;index"`
+	FundVersion  uint     `sql:"not null;default:1"`
+	FundArchived *time.Time
 }
 
 func (f *fundImpl) Id() uint {
@@ -32,39 +53,298 @@ func (f *fundImpl) Name() string {
 	return f.FundName
 }
 
+func (f *fundImpl) Version() uint {
+	return f.FundVersion
+}
+
+func (f *fundImpl) IsArchived() bool {
+	return f.FundArchived != nil
+}
+
+// Archived returns the time that the fund was archived or the zero time if
+// the fund is not archived.
+func (f *fundImpl) Archived() time.Time {
+	if f.FundArchived == nil {
+		return time.Time{}
+	}
+
+	return *f.FundArchived
+}
+
 // The FundRepository is the means of accessing the Fund's in the store.
+// GetAll does not include archived funds but GetAllIncludingArchived does.
+// Create gives the new fund the accounts of the chart of accounts template
+// with the given id, unless the id is "", within the same transaction that
+// creates the fund. It returns a *ValidationError if there is no such
+// template.
+// Create and Update return a *ValidationError if the name is empty and a
+// *DuplicateError if a fund with the same name already exists. Get, Update,
+// Archive and Unarchive return a *NotFoundError if there is no fund with
+// the given id. Update, Archive and Unarchive only change the fund if its
+// version is the given version and otherwise return a *ConcurrencyError.
+// Update and Archive return a *ConflictError if the fund is archived and
+// Unarchive returns a *ConflictError if it isn't. Amounts are kept in the
+// minor units of the fund's currency so Update also returns a
+// *ConflictError if it would change the currency of a fund that has any
+// accounts, entries or approval rules. Funds are never removed
+// from the store; archiving a fund takes the place of deleting it.
 type FundRepository interface {
-	GetAll() ([]Fund, error)
-	Create(name string, currency Currency) (Fund, error)
+	GetAll(ctx context.Context) ([]Fund, error)
+	GetAllIncludingArchived(ctx context.Context) ([]Fund, error)
+	Get(ctx context.Context, id uint) (Fund, error)
+	Create(ctx context.Context, name string, currency Currency, template string) (Fund, error)
+	Update(ctx context.Context, id uint, version uint, name string, currency Currency) (Fund, error)
+	Archive(ctx context.Context, id uint, version uint) (Fund, error)
+	Unarchive(ctx context.Context, id uint, version uint) (Fund, error)
 }
 
 type fundRepository struct {
 	db *gorm.DB
 }
 
-func (f *fundRepository) GetAll() ([]Fund, error) {
+func (f *fundRepository) GetAll(ctx context.Context) ([]Fund, error) {
+	return f.find(ctx, withContext(ctx, f.db).Where("fund_archived IS NULL"))
+}
+
+func (f *fundRepository) GetAllIncludingArchived(ctx context.Context) ([]Fund, error) {
+	return f.find(ctx, withContext(ctx, f.db))
+}
+
+func (f *fundRepository) find(ctx context.Context, db *gorm.DB) ([]Fund, error) {
 	var funds []fundImpl
 
-	err := f.db.Find(&funds).Error
+	err := db.Find(&funds).Error
 	if err != nil {
 		return nil, err
 	}
 
 	var ret []Fund
-	for _, f := range funds {
-		ret = append(ret, &f)
+	for i := range funds {
+		ret = append(ret, &funds[i])
 	}
 
 	return ret, nil
 }
 
-func (f *fundRepository) Create(name string, currency Currency) (Fund, error) {
-	fund := fundImpl{FundName: name, FundCurrency: currency}
+func (f *fundRepository) Get(ctx context.Context, id uint) (Fund, error) {
+	var fund fundImpl
+
+	err := withContext(ctx, f.db).First(&fund, id).Error
+	if isRecordNotFound(err) {
+		return nil, &NotFoundError{fundEntity, formatId(id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &fund, nil
+}
+
+func (f *fundRepository) Create(ctx context.Context, name string, currency Currency, template string) (Fund, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, &ValidationError{fundEntity, "name", "must not be empty"}
+	}
+
+	if template == "" {
+		return f.create(ctx, name, currency)
+	}
 
-	err := f.db.Create(&fund).Error
+	chart, ok := GetChartTemplate(template)
+	if !ok {
+		return nil, &ValidationError{fundEntity, "template", "must be a known chart of accounts template"}
+	}
+
+	var fund Fund
+	err := (&store{f.db}).InTransaction(ctx, func(tx Store) error {
+		var err error
+		fund, err = (&fundRepository{tx.(*store).db}).create(ctx, name, currency)
+		if err != nil {
+			return err
+		}
+
+		for _, account := range chart.Accounts {
+			_, err = tx.AccountRepository().Create(ctx, fund.Id(), account.Name, account.Type)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fund, nil
+}
+
+// create creates a fund without any accounts.
+func (f *fundRepository) create(ctx context.Context, name string, currency Currency) (Fund, error) {
+	fund := fundImpl{FundName: name, FundCurrency: currency, FundVersion: 1}
+
+	err := withContext(ctx, f.db).Create(&fund).Error
+	if isDuplicateEntry(err) {
+		return nil, &DuplicateError{fundEntity, "name", name}
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	return &fund, nil
 }
+
+func (f *fundRepository) Update(ctx context.Context, id uint, version uint, name string, currency Currency) (Fund, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, &ValidationError{fundEntity, "name", "must not be empty"}
+	}
+
+	var updated Fund
+	err := (&store{f.db}).InTransaction(ctx, func(tx Store) error {
+		repository := &fundRepository{tx.(*store).db}
+
+		err := repository.checkCurrencyChange(ctx, id, version, currency)
+		if err != nil {
+			return err
+		}
+
+		// The version in the WHERE clause ensures that the update only
+		// succeeds if nobody else has changed the fund since it was read.
+		result := withContext(ctx, repository.db).Model(&fundImpl{}).
+			Where("id = ? AND fund_version = ? AND fund_archived IS NULL", id, version).
+			Updates(map[string]interface{}{
+				"fund_name":     name,
+				"fund_currency": currency,
+				"fund_version":  version + 1,
+			})
+		if isDuplicateEntry(result.Error) {
+			return &DuplicateError{fundEntity, "name", name}
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return repository.unchangedReason(ctx, id, version, false)
+		}
+
+		updated, err = repository.Get(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// checkCurrencyChange checks that changing the currency of the given
+// version of the fund with the given id to currency doesn't change the
+// meaning of amounts that are already kept in the fund's currency. It
+// leaves other versions of the fund for the update to reject.
+func (f *fundRepository) checkCurrencyChange(ctx context.Context, id uint, version uint, currency Currency) error {
+	fund, err := f.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if fund.Version() != version || fund.Currency() == currency {
+		return nil
+	}
+
+	uses := []struct {
+		model  interface{}
+		column string
+	}{
+		{&accountImpl{}, "account_fund_id"},
+		{&journalEntryImpl{}, "entry_fund_id"},
+		{&approvalRuleImpl{}, "rule_fund_id"},
+	}
+	for _, use := range uses {
+		var count int
+		err := withContext(ctx, f.db).Model(use.model).Where(use.column+" = ?", id).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return &ConflictError{fundEntity, formatId(id),
+				"the currency can't change once the fund has accounts, entries or approval rules"}
+		}
+	}
+
+	return nil
+}
+
+func (f *fundRepository) Archive(ctx context.Context, id uint, version uint) (Fund, error) {
+	now := time.Now().UTC()
+	return f.setArchived(ctx, id, version, &now, "fund_archived IS NULL")
+}
+
+func (f *fundRepository) Unarchive(ctx context.Context, id uint, version uint) (Fund, error) {
+	return f.setArchived(ctx, id, version, nil, "fund_archived IS NOT NULL")
+}
+
+func (f *fundRepository) setArchived(ctx context.Context, id uint, version uint,
+	archived *time.Time, precondition string) (Fund, error) {
+	result := withContext(ctx, f.db).Model(&fundImpl{}).
+		Where("id = ? AND fund_version = ?", id, version).
+		Where(precondition).
+		Updates(map[string]interface{}{
+			"fund_archived": archived,
+			"fund_version":  version + 1,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, f.unchangedReason(ctx, id, version, archived == nil)
+	}
+
+	return f.Get(ctx, id)
+}
+
+// unchangedReason gives the reason that a change to the fund with the
+// given id and version affected no rows. wantArchived is whether the change
+// requires the fund to be archived.
+func (f *fundRepository) unchangedReason(ctx context.Context, id uint, version uint, wantArchived bool) error {
+	fund, err := f.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if fund.Version() != version {
+		return &ConcurrencyError{fundEntity, formatId(id)}
+	}
+
+	if wantArchived {
+		return &ConflictError{fundEntity, formatId(id), "the fund is not archived"}
+	}
+
+	return &ConflictError{fundEntity, formatId(id), "the fund is archived"}
+}
+
+// migrateFundCurrencies converts the currencies of the funds that were
+// stored as Currency values, which shift whenever a currency is added
+// before others, to their ISO 4217 codes. A value that isn't a Currency
+// becomes XXX as String gives for it.
+func migrateFundCurrencies(db *gorm.DB) error {
+	table := db.NewScope(&fundImpl{}).TableName()
+	var dataType string
+	err := db.Raw("SELECT data_type FROM information_schema.columns "+
+		"WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?",
+		table, "fund_currency").Row().Scan(&dataType)
+	if err != nil || strings.EqualFold(dataType, "char") {
+		return err
+	}
+
+	err = db.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY fund_currency CHAR(3)", table)).Error
+	if err != nil {
+		return err
+	}
+
+	var codes []string
+	var args []interface{}
+	for _, currency := range Currencies() {
+		codes = append(codes, "?")
+		args = append(args, currency.String())
+	}
+	return db.Exec(fmt.Sprintf("UPDATE %s SET fund_currency = COALESCE(ELT(fund_currency + 1, %s), 'XXX') "+
+		"WHERE fund_currency REGEXP '^[0-9]+$'", table, strings.Join(codes, ", ")), args...).Error
+}
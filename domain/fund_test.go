@@ -6,10 +6,12 @@ package domain
 
 import (
 	"testing"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 func insertFunds(t *testing.T, db *gorm.DB, funds []fundImpl) {
@@ -23,11 +25,11 @@ func TestFundRepositoryGetAllRetreivesAllFunds(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
 	db := getEmptyDb(t)
-	expected := []fundImpl{{1, CAD, "General"}, {2, USD, "Special"}}
+	expected := []fundImpl{{1, CAD, "General", 1, nil}, {2, USD, "Special", 1, nil}}
 	insertFunds(t, db, expected)
 
 	sut := fundRepository{db}
-	actual, err := sut.GetAll()
+	actual, err := sut.GetAll(context.Background())
 
 	require.NoError(err, "Unable to get all funds.")
 	require.NotNil(actual, "GetAll() returned nil funds list.")
@@ -37,11 +39,36 @@ func TestFundRepositoryGetAllRetreivesAllFunds(t *testing.T) {
 	}
 }
 
+func TestFundRepositoryGetAllExcludesArchivedFunds(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}, {2, USD, "Special", 2, &archived}})
+
+	sut := fundRepository{db}
+	actual, err := sut.GetAll(context.Background())
+
+	require.NoError(t, err, "Unable to get all funds.")
+	require.Len(t, actual, 1, "Unexpected number of funds returned from GetAll().")
+	assert.Equal(t, "General", actual[0].Name(), "Unexpected fund returned from GetAll().")
+}
+
+func TestFundRepositoryGetAllIncludingArchivedRetrievesAllFunds(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}, {2, USD, "Special", 2, &archived}})
+
+	sut := fundRepository{db}
+	actual, err := sut.GetAllIncludingArchived(context.Background())
+
+	require.NoError(t, err, "Unable to get all funds.")
+	assert.Len(t, actual, 2, "Unexpected number of funds returned from GetAllIncludingArchived().")
+}
+
 func TestFundRepositoryCreateAddsFund(t *testing.T) {
 	db := getEmptyDb(t)
 
 	sut := fundRepository{db}
-	_, err := sut.Create("General", CAD)
+	_, err := sut.Create(context.Background(), "General", CAD, "")
 
 	require.NoError(t, err, "Unable to create new fund")
 	var f fundImpl
@@ -55,7 +82,7 @@ func TestFundRepositoryCreateReturnsNewFund(t *testing.T) {
 	db := getEmptyDb(t)
 
 	sut := fundRepository{db}
-	actual, err := sut.Create("General", CAD)
+	actual, err := sut.Create(context.Background(), "General", CAD, "")
 
 	require.NoError(t, err, "Unable to create new fund")
 	require.NotNil(t, actual, "Returned fund was nil")
@@ -63,3 +90,236 @@ func TestFundRepositoryCreateReturnsNewFund(t *testing.T) {
 	assert.Equal(t, "General", actual.Name())
 	assert.Equal(t, CAD, actual.Currency())
 }
+
+func TestFundRepositoryCreateWithDuplicateNameIsDuplicateError(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}})
+
+	sut := fundRepository{db}
+	_, err := sut.Create(context.Background(), "General", USD, "")
+
+	require.Error(t, err, "Create() with a duplicate name unexpectedly succeeded")
+	duperr, ok := err.(*DuplicateError)
+	require.True(t, ok, "Create() returned an unexpected type of error")
+	assert.Equal(t, "name", duperr.Field, "Unexpected field in the DuplicateError")
+}
+
+func TestFundRepositoryCreateWithEmptyNameIsValidationError(t *testing.T) {
+	db := getEmptyDb(t)
+
+	sut := fundRepository{db}
+	_, err := sut.Create(context.Background(), " ", CAD, "")
+
+	require.Error(t, err, "Create() with an empty name unexpectedly succeeded")
+	validationerr, ok := err.(*ValidationError)
+	require.True(t, ok, "Create() returned an unexpected type of error")
+	assert.Equal(t, "name", validationerr.Field, "Unexpected field in the ValidationError")
+}
+
+func TestFundRepositoryCreateWithTemplateCreatesAccounts(t *testing.T) {
+	db := getEmptyDb(t)
+	template, _ := GetChartTemplate("nonprofit-basic")
+
+	sut := fundRepository{db}
+	actual, err := sut.Create(context.Background(), "General", CAD, "nonprofit-basic")
+
+	require.NoError(t, err, "Unable to create new fund")
+	accounts, err := (&accountRepository{db}).GetByFund(context.Background(), actual.Id())
+	require.NoError(t, err, "Unable to get the accounts of the fund")
+	require.Len(t, accounts, len(template.Accounts), "Unexpected number of accounts")
+	for i, account := range accounts {
+		assert.Equal(t, template.Accounts[i].Name, account.Name(), "Unexpected account name")
+		assert.Equal(t, template.Accounts[i].Type, account.Type(), "Unexpected account type")
+	}
+}
+
+func TestFundRepositoryCreateWithUnknownTemplateIsValidationError(t *testing.T) {
+	sut := fundRepository{&gorm.DB{}}
+	_, err := sut.Create(context.Background(), "General", CAD, "unknown")
+
+	require.Error(t, err, "Create() with an unknown template unexpectedly succeeded")
+	validationerr, ok := err.(*ValidationError)
+	require.True(t, ok, "Create() returned an unexpected type of error")
+	assert.Equal(t, "template", validationerr.Field, "Unexpected field in the ValidationError")
+}
+
+func TestFundRepositoryCreateReturnsFirstVersion(t *testing.T) {
+	db := getEmptyDb(t)
+
+	sut := fundRepository{db}
+	actual, err := sut.Create(context.Background(), "General", CAD, "")
+
+	require.NoError(t, err, "Unable to create new fund")
+	assert.Equal(t, uint(1), actual.Version(), "Unexpected version of a new fund")
+}
+
+func TestFundRepositoryGetReturnsFund(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}, {2, USD, "Special", 1, nil}})
+
+	sut := fundRepository{db}
+	actual, err := sut.Get(context.Background(), 2)
+
+	require.NoError(t, err, "Unable to get fund")
+	assert.Equal(t, fundImpl{2, USD, "Special", 1, nil}, *actual.(*fundImpl), "Unexpected fund")
+}
+
+func TestFundRepositoryGetMissingFundIsNotFoundError(t *testing.T) {
+	db := getEmptyDb(t)
+
+	sut := fundRepository{db}
+	_, err := sut.Get(context.Background(), 1)
+
+	require.Error(t, err, "Get() of a missing fund unexpectedly succeeded")
+	assert.IsType(t, &NotFoundError{}, err, "Get() returned an unexpected type of error")
+}
+
+func TestFundRepositoryUpdateChangesFundAndVersion(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}})
+
+	sut := fundRepository{db}
+	actual, err := sut.Update(context.Background(), 1, 1, "Operating", USD)
+
+	require.NoError(t, err, "Unable to update fund")
+	assert.Equal(t, fundImpl{1, USD, "Operating", 2, nil}, *actual.(*fundImpl), "Unexpected updated fund")
+	var f fundImpl
+	err = db.First(&f, 1).Error
+	require.NoError(t, err, "Unable to query expected fund")
+	assert.Equal(t, fundImpl{1, USD, "Operating", 2, nil}, f, "Unexpected fund in the database")
+}
+
+func TestFundRepositoryUpdateWithStaleVersionIsConcurrencyError(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 2, nil}})
+
+	sut := fundRepository{db}
+	_, err := sut.Update(context.Background(), 1, 1, "Operating", CAD)
+
+	require.Error(t, err, "Update() with a stale version unexpectedly succeeded")
+	assert.IsType(t, &ConcurrencyError{}, err, "Update() returned an unexpected type of error")
+	var f fundImpl
+	err = db.First(&f, 1).Error
+	require.NoError(t, err, "Unable to query expected fund")
+	assert.Equal(t, "General", f.FundName, "Update() with a stale version changed the fund")
+}
+
+func TestFundRepositoryUpdateMissingFundIsNotFoundError(t *testing.T) {
+	db := getEmptyDb(t)
+
+	sut := fundRepository{db}
+	_, err := sut.Update(context.Background(), 1, 1, "Operating", CAD)
+
+	require.Error(t, err, "Update() of a missing fund unexpectedly succeeded")
+	assert.IsType(t, &NotFoundError{}, err, "Update() returned an unexpected type of error")
+}
+
+func TestFundRepositoryUpdateCurrencyOfFundWithAccountsIsConflictError(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}})
+	insertAccounts(t, db, []accountImpl{{1, 1, "Cash", Asset, 1}})
+
+	sut := fundRepository{db}
+	_, err := sut.Update(context.Background(), 1, 1, "General", JPY)
+
+	require.Error(t, err, "Update() of the currency unexpectedly succeeded")
+	assert.IsType(t, &ConflictError{}, err, "Update() returned an unexpected type of error")
+	var f fundImpl
+	err = db.First(&f, 1).Error
+	require.NoError(t, err, "Unable to query expected fund")
+	assert.Equal(t, CAD, f.FundCurrency, "Update() changed the currency")
+}
+
+func TestFundRepositoryUpdateNameOfFundWithAccountsKeepsCurrency(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}})
+	insertAccounts(t, db, []accountImpl{{1, 1, "Cash", Asset, 1}})
+
+	sut := fundRepository{db}
+	actual, err := sut.Update(context.Background(), 1, 1, "Operating", CAD)
+
+	require.NoError(t, err, "Unable to update fund")
+	assert.Equal(t, "Operating", actual.Name(), "Update() did not change the name")
+}
+
+func TestFundRepositoryArchiveArchivesFund(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}})
+
+	sut := fundRepository{db}
+	actual, err := sut.Archive(context.Background(), 1, 1)
+
+	require.NoError(t, err, "Unable to archive fund")
+	assert.True(t, actual.IsArchived(), "Archive() did not archive the fund")
+	assert.False(t, actual.Archived().IsZero(), "Archive() did not set the archived time")
+	assert.Equal(t, uint(2), actual.Version(), "Archive() did not change the version")
+}
+
+func TestFundRepositoryArchiveArchivedFundIsConflictError(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 2, &archived}})
+
+	sut := fundRepository{db}
+	_, err := sut.Archive(context.Background(), 1, 2)
+
+	require.Error(t, err, "Archive() of an archived fund unexpectedly succeeded")
+	assert.IsType(t, &ConflictError{}, err, "Archive() returned an unexpected type of error")
+}
+
+func TestFundRepositoryArchiveWithStaleVersionIsConcurrencyError(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 2, nil}})
+
+	sut := fundRepository{db}
+	_, err := sut.Archive(context.Background(), 1, 1)
+
+	require.Error(t, err, "Archive() with a stale version unexpectedly succeeded")
+	assert.IsType(t, &ConcurrencyError{}, err, "Archive() returned an unexpected type of error")
+}
+
+func TestFundRepositoryUnarchiveUnarchivesFund(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 2, &archived}})
+
+	sut := fundRepository{db}
+	actual, err := sut.Unarchive(context.Background(), 1, 2)
+
+	require.NoError(t, err, "Unable to unarchive fund")
+	assert.False(t, actual.IsArchived(), "Unarchive() did not unarchive the fund")
+	assert.Equal(t, uint(3), actual.Version(), "Unarchive() did not change the version")
+}
+
+func TestFundRepositoryUnarchiveUnarchivedFundIsConflictError(t *testing.T) {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}})
+
+	sut := fundRepository{db}
+	_, err := sut.Unarchive(context.Background(), 1, 1)
+
+	require.Error(t, err, "Unarchive() of an unarchived fund unexpectedly succeeded")
+	assert.IsType(t, &ConflictError{}, err, "Unarchive() returned an unexpected type of error")
+}
+
+func TestFundRepositoryUpdateArchivedFundIsConflictError(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 2, &archived}})
+
+	sut := fundRepository{db}
+	_, err := sut.Update(context.Background(), 1, 2, "Operating", CAD)
+
+	require.Error(t, err, "Update() of an archived fund unexpectedly succeeded")
+	assert.IsType(t, &ConflictError{}, err, "Update() returned an unexpected type of error")
+}
+
+func TestFundRepositoryWithCancelledContextIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	sut := fundRepository{&gorm.DB{}}
+	_, err := sut.Get(ctx, 1)
+
+	assert.Equal(t, context.Canceled, err, "Get() with a cancelled context did not fail")
+}
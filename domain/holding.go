@@ -0,0 +1,248 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/net/context"
+)
+
+const (
+	holdingEntity string = "holding"
+
+	// MaxHoldingPrecision is the largest Precision that a Holding may
+	// have.
+	MaxHoldingPrecision = 9
+)
+
+// holdingUnitPattern matches the unit of a holding: an ISO 4217 code or
+// the symbol of a commodity or security, such as "BRK.B".
+var holdingUnitPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.\-]{0,15}$`)
+
+// A Holding is a quantity of a unit other than the currency of its Fund
+// that the Fund holds, such as troy ounces of gold or shares of a
+// security. The Unit is the ISO 4217 code of a metal or fund currency,
+// such as XAU, or the symbol of a commodity or security. The Quantity is
+// in the smallest part of the unit that the Precision, a number of decimal
+// places, allows: a Quantity of 12345 with a Precision of 3 is 12.345 of
+// the unit. The Version of a Holding changes every time it is updated.
+type Holding interface {
+	Id() uint
+	FundId() uint
+	Name() string
+	Unit() string
+	Precision() int
+	Quantity() int64
+	Version() uint
+}
+
+type holdingImpl struct {
+	ID               uint
+	HoldingFundID    uint   `sql:"not null;unique_index:idx_holding_fund_unit"`
+	HoldingName      string `sql:"size:255"`
+	HoldingUnit      string `sql:"size:16;unique_index:idx_holding_fund_unit"`
+	HoldingPrecision int    `sql:"not null"`
+	HoldingQuantity  int64  `sql:"not null"`
+	HoldingVersion   uint   `sql:"not null;default:1"`
+}
+
+func (h *holdingImpl) Id() uint {
+	return h.ID
+}
+
+func (h *holdingImpl) FundId() uint {
+	return h.HoldingFundID
+}
+
+func (h *holdingImpl) Name() string {
+	return h.HoldingName
+}
+
+func (h *holdingImpl) Unit() string {
+	return h.HoldingUnit
+}
+
+func (h *holdingImpl) Precision() int {
+	return h.HoldingPrecision
+}
+
+func (h *holdingImpl) Quantity() int64 {
+	return h.HoldingQuantity
+}
+
+func (h *holdingImpl) Version() uint {
+	return h.HoldingVersion
+}
+
+// The HoldingRepository is the means of accessing the Holding's in the
+// store. Create and Update return a *ValidationError if the name is empty
+// or the quantity is negative. Create also returns a *ValidationError if
+// the unit isn't 1 to 16 letters, digits, '.' or '-', starting with a
+// letter or digit, or is the currency of the fund or if the precision
+// isn't from 0 to MaxHoldingPrecision. Create returns a *NotFoundError if
+// there is no fund with the given id, a *ConflictError if the fund is
+// archived and a *DuplicateError if the fund already has a holding of the
+// unit. Get, Update and Delete return a *NotFoundError if there is no
+// holding with the given id. Update and Delete only change the holding if
+// its version is the given version and otherwise return a
+// *ConcurrencyError. The unit and precision of a holding can't be changed.
+type HoldingRepository interface {
+	GetAll(ctx context.Context) ([]Holding, error)
+	GetByFund(ctx context.Context, fundId uint) ([]Holding, error)
+	Get(ctx context.Context, id uint) (Holding, error)
+	Create(ctx context.Context, fundId uint, name string, unit string, precision int,
+		quantity int64) (Holding, error)
+	Update(ctx context.Context, id uint, version uint, name string, quantity int64) (Holding, error)
+	Delete(ctx context.Context, id uint, version uint) error
+}
+
+type holdingRepository struct {
+	db *gorm.DB
+}
+
+func (h *holdingRepository) GetAll(ctx context.Context) ([]Holding, error) {
+	return h.find(ctx, withContext(ctx, h.db))
+}
+
+func (h *holdingRepository) GetByFund(ctx context.Context, fundId uint) ([]Holding, error) {
+	return h.find(ctx, withContext(ctx, h.db).Where("holding_fund_id = ?", fundId))
+}
+
+func (h *holdingRepository) find(ctx context.Context, db *gorm.DB) ([]Holding, error) {
+	var holdings []holdingImpl
+
+	err := db.Order("id").Find(&holdings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Holding
+	for i := range holdings {
+		ret = append(ret, &holdings[i])
+	}
+
+	return ret, nil
+}
+
+func (h *holdingRepository) Get(ctx context.Context, id uint) (Holding, error) {
+	var holding holdingImpl
+
+	err := withContext(ctx, h.db).First(&holding, id).Error
+	if isRecordNotFound(err) {
+		return nil, &NotFoundError{holdingEntity, formatId(id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &holding, nil
+}
+
+func (h *holdingRepository) Create(ctx context.Context, fundId uint, name string, unit string, precision int,
+	quantity int64) (Holding, error) {
+	unit = strings.TrimSpace(unit)
+	if err := validateHolding(name, quantity); err != nil {
+		return nil, err
+	}
+	if !holdingUnitPattern.MatchString(unit) {
+		return nil, &ValidationError{holdingEntity, "unit",
+			"must be 1 to 16 letters, digits, '.' or '-' starting with a letter or digit"}
+	}
+	if precision < 0 || precision > MaxHoldingPrecision {
+		return nil, &ValidationError{holdingEntity, "precision",
+			"must be from 0 to " + strconv.Itoa(MaxHoldingPrecision)}
+	}
+
+	fund, err := (&fundRepository{h.db}).Get(ctx, fundId)
+	if err != nil {
+		return nil, err
+	}
+	if fund.IsArchived() {
+		return nil, &ConflictError{fundEntity, formatId(fundId), "the fund is archived"}
+	}
+	if strings.EqualFold(unit, fund.Currency().String()) {
+		return nil, &ValidationError{holdingEntity, "unit", "must not be the currency of the fund"}
+	}
+	holding := holdingImpl{
+		HoldingFundID:    fundId,
+		HoldingName:      name,
+		HoldingUnit:      unit,
+		HoldingPrecision: precision,
+		HoldingQuantity:  quantity,
+		HoldingVersion:   1,
+	}
+
+	err = withContext(ctx, h.db).Create(&holding).Error
+	if isDuplicateEntry(err) {
+		return nil, &DuplicateError{holdingEntity, "unit", unit}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &holding, nil
+}
+
+func (h *holdingRepository) Update(ctx context.Context, id uint, version uint, name string,
+	quantity int64) (Holding, error) {
+	if err := validateHolding(name, quantity); err != nil {
+		return nil, err
+	}
+
+	result := withContext(ctx, h.db).Model(&holdingImpl{}).
+		Where("id = ? AND holding_version = ?", id, version).
+		Updates(map[string]interface{}{
+			"holding_name":     name,
+			"holding_quantity": quantity,
+			"holding_version":  version + 1,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		_, err := h.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, &ConcurrencyError{holdingEntity, formatId(id)}
+	}
+
+	return h.Get(ctx, id)
+}
+
+func (h *holdingRepository) Delete(ctx context.Context, id uint, version uint) error {
+	result := withContext(ctx, h.db).Where("id = ? AND holding_version = ?", id, version).Delete(&holdingImpl{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		_, err := h.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return &ConcurrencyError{holdingEntity, formatId(id)}
+	}
+
+	return nil
+}
+
+// validateHolding validates the attributes of a holding that both Create
+// and Update take.
+func validateHolding(name string, quantity int64) error {
+	if strings.TrimSpace(name) == "" {
+		return &ValidationError{holdingEntity, "name", "must not be empty"}
+	}
+	if quantity < 0 {
+		return &ValidationError{holdingEntity, "quantity", "must not be negative"}
+	}
+
+	return nil
+}
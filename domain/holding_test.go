@@ -0,0 +1,130 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func insertHoldings(t *testing.T, db *gorm.DB, holdings []holdingImpl) {
+	for _, holding := range holdings {
+		err := db.Create(&holding).Error
+		require.NoError(t, err, "Unable to create a holding.")
+	}
+}
+
+func TestHoldingRepositoryCreateReturnsNewHolding(t *testing.T) {
+	db := getLedgerDb(t)
+
+	sut := holdingRepository{db}
+	actual, err := sut.Create(context.Background(), 1, "Gold bullion", " XAU ", 3, 12345)
+
+	require.NoError(t, err, "Unable to create a new holding.")
+	assert.NotZero(t, actual.Id(), "Id of the returned holding was zero")
+	assert.Equal(t, uint(1), actual.FundId())
+	assert.Equal(t, "Gold bullion", actual.Name())
+	assert.Equal(t, "XAU", actual.Unit())
+	assert.Equal(t, 3, actual.Precision())
+	assert.Equal(t, int64(12345), actual.Quantity())
+	assert.Equal(t, uint(1), actual.Version())
+}
+
+func TestHoldingRepositoryCreateWithInvalidAttributesIsValidationError(t *testing.T) {
+	db := getLedgerDb(t)
+	invalid := []struct {
+		name      string
+		unit      string
+		precision int
+		quantity  int64
+	}{
+		{" ", "XAU", 0, 0},
+		{"Gold", "", 0, 0},
+		{"Gold", "troy ounce", 0, 0},
+		{"Gold", ".XAU", 0, 0},
+		{"Gold", "XAU", -1, 0},
+		{"Gold", "XAU", MaxHoldingPrecision + 1, 0},
+		{"Gold", "XAU", 0, -1},
+		{"Canadian dollars", "cad", 2, 0},
+	}
+
+	sut := holdingRepository{db}
+	for _, holding := range invalid {
+		_, err := sut.Create(context.Background(), 1, holding.name, holding.unit, holding.precision, holding.quantity)
+
+		assert.IsType(t, &ValidationError{}, err, "Create() returned an unexpected type of error for %v", holding)
+	}
+}
+
+func TestHoldingRepositoryCreateInArchivedFundIsConflictError(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 2, &archived}})
+
+	sut := holdingRepository{db}
+	_, err := sut.Create(context.Background(), 1, "Gold", "XAU", 0, 0)
+
+	assert.IsType(t, &ConflictError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestHoldingRepositoryCreateWithDuplicateUnitIsDuplicateError(t *testing.T) {
+	db := getLedgerDb(t)
+	insertHoldings(t, db, []holdingImpl{{1, 1, "Gold", "XAU", 3, 1000, 1}})
+
+	sut := holdingRepository{db}
+	_, err := sut.Create(context.Background(), 1, "More gold", "XAU", 0, 0)
+
+	assert.IsType(t, &DuplicateError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestHoldingRepositoryGetByFundRetrievesFundsHoldings(t *testing.T) {
+	db := getLedgerDb(t)
+	insertHoldings(t, db, []holdingImpl{{1, 1, "Gold", "XAU", 3, 1000, 1}, {2, 2, "Gold", "XAU", 3, 2000, 1}})
+
+	sut := holdingRepository{db}
+	actual, err := sut.GetByFund(context.Background(), 2)
+
+	require.NoError(t, err, "Unable to get the fund's holdings.")
+	require.Len(t, actual, 1, "Unexpected number of holdings returned from GetByFund().")
+	assert.Equal(t, uint(2), actual[0].Id())
+}
+
+func TestHoldingRepositoryUpdateChangesNameQuantityAndVersion(t *testing.T) {
+	db := getLedgerDb(t)
+	insertHoldings(t, db, []holdingImpl{{1, 1, "Shares", "VTI", 4, 100000, 1}})
+
+	sut := holdingRepository{db}
+	actual, err := sut.Update(context.Background(), 1, 1, "Index fund shares", 125000)
+
+	require.NoError(t, err, "Unable to update the holding.")
+	assert.Equal(t, "Index fund shares", actual.Name())
+	assert.Equal(t, int64(125000), actual.Quantity())
+	assert.Equal(t, 4, actual.Precision(), "The precision was changed.")
+	assert.Equal(t, uint(2), actual.Version())
+}
+
+func TestHoldingRepositoryUpdateWithStaleVersionIsConcurrencyError(t *testing.T) {
+	db := getLedgerDb(t)
+	insertHoldings(t, db, []holdingImpl{{1, 1, "Shares", "VTI", 4, 100000, 2}})
+
+	sut := holdingRepository{db}
+	_, err := sut.Update(context.Background(), 1, 1, "Shares", 0)
+
+	assert.IsType(t, &ConcurrencyError{}, err, "Update() returned an unexpected type of error")
+}
+
+func TestHoldingRepositoryDeleteMissingHoldingIsNotFoundError(t *testing.T) {
+	db := getLedgerDb(t)
+
+	sut := holdingRepository{db}
+	err := sut.Delete(context.Background(), 1, 1)
+
+	assert.IsType(t, &NotFoundError{}, err, "Delete() returned an unexpected type of error")
+}
@@ -0,0 +1,129 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/net/context"
+)
+
+const (
+	idempotentRequestEntity string = "idempotent request"
+)
+
+// An IdempotentRequest records a request that was made with an idempotency
+// key so that a retry of the request can be given the original response
+// instead of being performed again. The Response is nil until the original
+// request completes. The format of the Response is up to the caller.
+type IdempotentRequest interface {
+	Key() string
+	RequestHash() string
+	Response() []byte
+	Created() time.Time
+}
+
+type idempotentRequestImpl struct {
+	ID                     uint
+	IdempotencyKey         string `sql:"size:255;unique;index"`
+	IdempotencyRequestHash string `sql:"size:255"`
+	IdempotencyResponse    []byte
+	IdempotencyCreated     time.Time `sql:"index"`
+}
+
+func (i *idempotentRequestImpl) Key() string {
+	return i.IdempotencyKey
+}
+
+func (i *idempotentRequestImpl) RequestHash() string {
+	return i.IdempotencyRequestHash
+}
+
+func (i *idempotentRequestImpl) Response() []byte {
+	return i.IdempotencyResponse
+}
+
+func (i *idempotentRequestImpl) Created() time.Time {
+	return i.IdempotencyCreated
+}
+
+// The IdempotencyRepository is the means of accessing the IdempotentRequest's
+// in the store. Reserve records that a request with a key has started and
+// returns a *DuplicateError if the key is already in use. Complete records
+// the response to the request and Release forgets the key so that the
+// request can be tried again. Get, Complete and Release return a
+// *NotFoundError if the key is not in use.
+type IdempotencyRepository interface {
+	Get(ctx context.Context, key string) (IdempotentRequest, error)
+	Reserve(ctx context.Context, key string, requestHash string, created time.Time) error
+	Complete(ctx context.Context, key string, response []byte) error
+	Release(ctx context.Context, key string) error
+	DeleteCreatedBefore(ctx context.Context, created time.Time) error
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func (i *idempotencyRepository) Get(ctx context.Context, key string) (IdempotentRequest, error) {
+	var request idempotentRequestImpl
+
+	err := withContext(ctx, i.db).Where("idempotency_key = ?", key).First(&request).Error
+	if isRecordNotFound(err) {
+		return nil, &NotFoundError{idempotentRequestEntity, key}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+func (i *idempotencyRepository) Reserve(ctx context.Context, key string, requestHash string,
+	created time.Time) error {
+	request := idempotentRequestImpl{
+		IdempotencyKey:         key,
+		IdempotencyRequestHash: requestHash,
+		IdempotencyCreated:     created,
+	}
+
+	err := withContext(ctx, i.db).Create(&request).Error
+	if isDuplicateEntry(err) {
+		return &DuplicateError{idempotentRequestEntity, "key", key}
+	}
+
+	return err
+}
+
+func (i *idempotencyRepository) Complete(ctx context.Context, key string, response []byte) error {
+	result := withContext(ctx, i.db).Model(&idempotentRequestImpl{}).
+		Where("idempotency_key = ?", key).
+		Update("idempotency_response", response)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &NotFoundError{idempotentRequestEntity, key}
+	}
+
+	return nil
+}
+
+func (i *idempotencyRepository) Release(ctx context.Context, key string) error {
+	result := withContext(ctx, i.db).Where("idempotency_key = ?", key).Delete(&idempotentRequestImpl{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &NotFoundError{idempotentRequestEntity, key}
+	}
+
+	return nil
+}
+
+func (i *idempotencyRepository) DeleteCreatedBefore(ctx context.Context, created time.Time) error {
+	return withContext(ctx, i.db).Where("idempotency_created < ?", created).Delete(&idempotentRequestImpl{}).Error
+}
@@ -0,0 +1,95 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestIdempotencyRepositoryReserveAddsRequest(t *testing.T) {
+	created := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+
+	sut := idempotencyRepository{db}
+	err := sut.Reserve(context.Background(), "key1", "hash1", created)
+
+	require.NoError(t, err, "Unable to reserve the key")
+	actual, err := sut.Get(context.Background(), "key1")
+	require.NoError(t, err, "Unable to get the reserved key")
+	assert.Equal(t, "hash1", actual.RequestHash(), "Unexpected request hash")
+	assert.Nil(t, actual.Response(), "Unexpected response for an incomplete request")
+	assert.True(t, created.Equal(actual.Created()), "Unexpected created time")
+}
+
+func TestIdempotencyRepositoryReserveUsedKeyIsDuplicateError(t *testing.T) {
+	created := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	sut := idempotencyRepository{db}
+	require.NoError(t, sut.Reserve(context.Background(), "key1", "hash1", created), "Unable to reserve the key")
+
+	err := sut.Reserve(context.Background(), "key1", "hash2", created)
+
+	require.Error(t, err, "Reserve() of a used key unexpectedly succeeded")
+	assert.IsType(t, &DuplicateError{}, err, "Reserve() returned an unexpected type of error")
+}
+
+func TestIdempotencyRepositoryCompleteStoresResponse(t *testing.T) {
+	created := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	sut := idempotencyRepository{db}
+	require.NoError(t, sut.Reserve(context.Background(), "key1", "hash1", created), "Unable to reserve the key")
+
+	err := sut.Complete(context.Background(), "key1", []byte("response"))
+
+	require.NoError(t, err, "Unable to complete the request")
+	actual, err := sut.Get(context.Background(), "key1")
+	require.NoError(t, err, "Unable to get the completed key")
+	assert.Equal(t, []byte("response"), actual.Response(), "Unexpected response")
+}
+
+func TestIdempotencyRepositoryReleaseForgetsKey(t *testing.T) {
+	created := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	sut := idempotencyRepository{db}
+	require.NoError(t, sut.Reserve(context.Background(), "key1", "hash1", created), "Unable to reserve the key")
+
+	err := sut.Release(context.Background(), "key1")
+
+	require.NoError(t, err, "Unable to release the key")
+	_, err = sut.Get(context.Background(), "key1")
+	assert.IsType(t, &NotFoundError{}, err, "Get() of a released key returned an unexpected error")
+}
+
+func TestIdempotencyRepositoryGetUnusedKeyIsNotFoundError(t *testing.T) {
+	db := getEmptyDb(t)
+
+	sut := idempotencyRepository{db}
+	_, err := sut.Get(context.Background(), "key1")
+
+	require.Error(t, err, "Get() of an unused key unexpectedly succeeded")
+	assert.IsType(t, &NotFoundError{}, err, "Get() returned an unexpected type of error")
+}
+
+func TestIdempotencyRepositoryDeleteCreatedBeforeRemovesOldKeys(t *testing.T) {
+	old := time.Date(2016, time.March, 30, 12, 0, 0, 0, time.UTC)
+	recent := time.Date(2016, time.March, 31, 12, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	sut := idempotencyRepository{db}
+	require.NoError(t, sut.Reserve(context.Background(), "old", "hash1", old), "Unable to reserve the key")
+	require.NoError(t, sut.Reserve(context.Background(), "recent", "hash2", recent), "Unable to reserve the key")
+
+	err := sut.DeleteCreatedBefore(context.Background(), recent)
+
+	require.NoError(t, err, "Unable to delete old keys")
+	_, err = sut.Get(context.Background(), "old")
+	assert.IsType(t, &NotFoundError{}, err, "DeleteCreatedBefore() did not delete the old key")
+	_, err = sut.Get(context.Background(), "recent")
+	assert.NoError(t, err, "DeleteCreatedBefore() unexpectedly deleted the recent key")
+}
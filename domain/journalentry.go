@@ -0,0 +1,804 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/net/context"
+)
+
+const (
+	journalEntryEntity string = "entry"
+)
+
+// A Posting is one line of a JournalEntry. A positive Amount is a debit to
+// the account and a negative Amount is a credit. Amounts are in the minor
+// units (such as cents) of the currency of the account's fund. A posting
+// to an income account may be a gift from the Donor whose id is its
+// DonorId; other postings have a DonorId of zero.
+type Posting struct {
+	AccountId uint
+	Amount    int64
+	DonorId   uint
+}
+
+// A JournalEntry records a transaction within a Fund on a date as postings
+// to the accounts of the Fund that balance: the debits equal the credits.
+// An entry made by a Schedule has the id of that Schedule as its
+// ScheduleId; other entries have a ScheduleId of zero.
+//
+// The Status of an entry is where it is in the approval workflow (see
+// EntryStatus). SubmittedBy and ReviewedBy are the names of the principals
+// that submitted the entry and that approved or rejected it; they are
+// empty until then. The Version of an entry changes every time it changes.
+//
+// An Approved entry is posted and can't be changed or deleted. It is
+// undone by a reversal, an entry with the opposite postings whose
+// ReversesId is the id of the posted entry, and replaced by a correction,
+// an entry whose CorrectsId is the id of the posted entry. ReversedById
+// and CorrectedById link a posted entry to its reversal and correction.
+// Each of these ids is zero if there is no such entry.
+type JournalEntry interface {
+	Id() uint
+	FundId() uint
+	Date() time.Time
+	Description() string
+	Postings() []Posting
+	ScheduleId() uint
+	Status() EntryStatus
+	SubmittedBy() string
+	ReviewedBy() string
+	ReversesId() uint
+	ReversedById() uint
+	CorrectsId() uint
+	CorrectedById() uint
+	Version() uint
+}
+
+type journalEntryImpl struct {
+	ID                 uint
+	EntryFundID        uint        `sql:"not null;index"`
+	EntryDate          time.Time   `sql:"type:date;not null;unique_index:idx_entry_schedule_date"`
+	EntryDescription   string      `sql:"size:255"`
+	EntryScheduleID    *uint       `sql:"unique_index:idx_entry_schedule_date"`
+	EntryStatus        EntryStatus `sql:"not null;index"`
+	EntrySubmittedBy   string      `sql:"size:255"`
+	EntryReviewedBy    string      `sql:"size:255"`
+	EntryReversesID    *uint       `sql:"unique_index"`
+	EntryReversedByID  *uint
+	EntryCorrectsID    *uint `sql:"unique_index"`
+	EntryCorrectedByID *uint
+	EntryVersion       uint          `sql:"not null;default:1"`
+	EntryPostings      []postingImpl `gorm:"-"`
+}
+
+type postingImpl struct {
+	ID               uint
+	PostingEntryID   uint  `sql:"not null;index"`
+	PostingAccountID uint  `sql:"not null;index"`
+	PostingAmount    int64 `sql:"not null"`
+	PostingDonorID   uint  `sql:"not null;default:0;index"`
+}
+
+func (j *journalEntryImpl) Id() uint {
+	return j.ID
+}
+
+func (j *journalEntryImpl) FundId() uint {
+	return j.EntryFundID
+}
+
+func (j *journalEntryImpl) Date() time.Time {
+	return j.EntryDate
+}
+
+func (j *journalEntryImpl) Description() string {
+	return j.EntryDescription
+}
+
+func (j *journalEntryImpl) Postings() []Posting {
+	postings := make([]Posting, 0, len(j.EntryPostings))
+	for _, posting := range j.EntryPostings {
+		postings = append(postings, Posting{posting.PostingAccountID, posting.PostingAmount, posting.PostingDonorID})
+	}
+
+	return postings
+}
+
+func (j *journalEntryImpl) ScheduleId() uint {
+	return idOrZero(j.EntryScheduleID)
+}
+
+func (j *journalEntryImpl) Status() EntryStatus {
+	return j.EntryStatus
+}
+
+func (j *journalEntryImpl) SubmittedBy() string {
+	return j.EntrySubmittedBy
+}
+
+func (j *journalEntryImpl) ReviewedBy() string {
+	return j.EntryReviewedBy
+}
+
+func (j *journalEntryImpl) ReversesId() uint {
+	return idOrZero(j.EntryReversesID)
+}
+
+func (j *journalEntryImpl) ReversedById() uint {
+	return idOrZero(j.EntryReversedByID)
+}
+
+func (j *journalEntryImpl) CorrectsId() uint {
+	return idOrZero(j.EntryCorrectsID)
+}
+
+func (j *journalEntryImpl) CorrectedById() uint {
+	return idOrZero(j.EntryCorrectedByID)
+}
+
+// idOrZero gives the id that id points to or zero if it is nil.
+func idOrZero(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+
+	return *id
+}
+
+func (j *journalEntryImpl) Version() uint {
+	return j.EntryVersion
+}
+
+// The JournalEntryRepository is the means of accessing the JournalEntry's
+// in the store. Create makes a Draft entry. It returns a *ValidationError
+// if the date is zero, the description is empty or the postings are
+// invalid, a *NotFoundError if there is no fund with the given id and a
+// *ConflictError if the fund is archived. Valid postings balance, are to
+// accounts of the fund, number at least two and have no amounts of zero.
+//
+// Submit, Approve and Reject move an entry through the approval workflow
+// on behalf of a principal (see EntryStatus and ApprovalRule). They
+// return a *ConflictError if the entry
+// doesn't have the status they move it from, and Approve and Reject return
+// a *ForbiddenError if the rules of the fund don't allow the principal to
+// review the entry. Submit and Approve also return a *ConflictError if the
+// fund of the entry is archived so that nothing is posted to it.
+//
+// Update and Delete change and delete an entry that has not been posted:
+// Update only changes a Draft entry and Delete only deletes a Draft or
+// Rejected one. They return a *ConflictError for any other entry. Update
+// returns the same errors as Create for invalid values. Deleting a Rejected
+// reversal or correction lets the entry that it undid be reversed or
+// corrected again.
+//
+// Reverse and Correct undo a posted entry on behalf of a principal. They
+// return a *ConflictError if the entry isn't Approved or has already been
+// reversed. Reverse makes the reversal on date and returns it. Correct
+// makes the reversal and the correction, which has the given date,
+// description and postings, and returns the correction. The reversal and
+// the correction are submitted like any other entry so they are Pending
+// until they are reviewed if the rules of the fund apply to them. Reverse
+// and Correct return the same errors as Create if the entries would be
+// invalid.
+//
+// Get, Update, Delete, Submit, Approve, Reject, Reverse and Correct return
+// a *NotFoundError if there is no entry with the given id. The methods
+// that change an entry only change it if its version is the given version
+// and otherwise return a *ConcurrencyError.
+//
+// Balances gives the balances of the accounts of the fund with the given
+// id keyed by the id of the account. Only Approved entries count toward
+// the balances and accounts without any are left out.
+type JournalEntryRepository interface {
+	GetAll(ctx context.Context) ([]JournalEntry, error)
+	GetByFund(ctx context.Context, fundId uint) ([]JournalEntry, error)
+	Get(ctx context.Context, id uint) (JournalEntry, error)
+	Create(ctx context.Context, fundId uint, date time.Time, description string,
+		postings []Posting) (JournalEntry, error)
+	Update(ctx context.Context, id uint, version uint, date time.Time, description string,
+		postings []Posting) (JournalEntry, error)
+	Delete(ctx context.Context, id uint, version uint) error
+	Submit(ctx context.Context, id uint, version uint, submitter Principal) (JournalEntry, error)
+	Approve(ctx context.Context, id uint, version uint, approver Principal) (JournalEntry, error)
+	Reject(ctx context.Context, id uint, version uint, reviewer Principal) (JournalEntry, error)
+	Reverse(ctx context.Context, id uint, version uint, date time.Time, reverser Principal) (JournalEntry, error)
+	Correct(ctx context.Context, id uint, version uint, date time.Time, description string,
+		postings []Posting, corrector Principal) (JournalEntry, error)
+	Balances(ctx context.Context, fundId uint) (map[uint]int64, error)
+}
+
+type journalEntryRepository struct {
+	db *gorm.DB
+}
+
+func (j *journalEntryRepository) GetAll(ctx context.Context) ([]JournalEntry, error) {
+	return j.find(ctx, withContext(ctx, j.db))
+}
+
+func (j *journalEntryRepository) GetByFund(ctx context.Context, fundId uint) ([]JournalEntry, error) {
+	return j.find(ctx, withContext(ctx, j.db).Where("entry_fund_id = ?", fundId))
+}
+
+func (j *journalEntryRepository) find(ctx context.Context, db *gorm.DB) ([]JournalEntry, error) {
+	var entries []journalEntryImpl
+	err := db.Order("entry_date, id").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+
+	postings, err := j.postings(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []JournalEntry
+	for i := range entries {
+		entries[i].EntryPostings = postings[entries[i].ID]
+		ret = append(ret, &entries[i])
+	}
+
+	return ret, nil
+}
+
+func (j *journalEntryRepository) Get(ctx context.Context, id uint) (JournalEntry, error) {
+	var entry journalEntryImpl
+
+	err := withContext(ctx, j.db).First(&entry, id).Error
+	if isRecordNotFound(err) {
+		return nil, &NotFoundError{journalEntryEntity, formatId(id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	postings, err := j.postings(ctx, []uint{id})
+	if err != nil {
+		return nil, err
+	}
+	entry.EntryPostings = postings[id]
+
+	return &entry, nil
+}
+
+// postings gives the postings of the entries with the given ids keyed by
+// the id of their entry.
+func (j *journalEntryRepository) postings(ctx context.Context, ids []uint) (map[uint][]postingImpl, error) {
+	byEntry := make(map[uint][]postingImpl)
+	if len(ids) == 0 {
+		return byEntry, nil
+	}
+
+	var postings []postingImpl
+	err := withContext(ctx, j.db).Where("posting_entry_id IN (?)", ids).Order("id").Find(&postings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, posting := range postings {
+		byEntry[posting.PostingEntryID] = append(byEntry[posting.PostingEntryID], posting)
+	}
+
+	return byEntry, nil
+}
+
+func (j *journalEntryRepository) Create(ctx context.Context, fundId uint, date time.Time,
+	description string, postings []Posting) (JournalEntry, error) {
+	entry := &journalEntryImpl{
+		EntryFundID:      fundId,
+		EntryDate:        toDate(date),
+		EntryDescription: description,
+		EntryStatus:      Draft,
+		EntryVersion:     1,
+	}
+
+	err := (&store{j.db}).InTransaction(ctx, func(tx Store) error {
+		return (&journalEntryRepository{tx.(*store).db}).create(ctx, entry, postings)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// create validates entry and its postings and inserts them. It must be
+// called within a transaction.
+func (j *journalEntryRepository) create(ctx context.Context, entry *journalEntryImpl, postings []Posting) error {
+	if err := j.validate(ctx, entry.EntryFundID, entry.EntryDate, entry.EntryDescription, postings); err != nil {
+		return err
+	}
+
+	err := withContext(ctx, j.db).Create(entry).Error
+	if err != nil {
+		return err
+	}
+
+	return j.insertPostings(ctx, entry, postings)
+}
+
+// validate checks the date, description and postings of an entry of the
+// fund with the given id.
+func (j *journalEntryRepository) validate(ctx context.Context, fundId uint, date time.Time, description string,
+	postings []Posting) error {
+	if date.IsZero() {
+		return &ValidationError{journalEntryEntity, "date", "must be given"}
+	}
+	if strings.TrimSpace(description) == "" {
+		return &ValidationError{journalEntryEntity, "description", "must not be empty"}
+	}
+	if err := validatePostings(journalEntryEntity, postings); err != nil {
+		return err
+	}
+
+	return checkPostingAccounts(ctx, j.db, journalEntryEntity, fundId, postings)
+}
+
+// insertPostings inserts postings as the postings of entry, which must
+// already have been inserted.
+func (j *journalEntryRepository) insertPostings(ctx context.Context, entry *journalEntryImpl, postings []Posting) error {
+	entry.EntryPostings = nil
+	for _, posting := range postings {
+		row := postingImpl{
+			PostingEntryID:   entry.ID,
+			PostingAccountID: posting.AccountId,
+			PostingAmount:    posting.Amount,
+			PostingDonorID:   posting.DonorId,
+		}
+		if err := withContext(ctx, j.db).Create(&row).Error; err != nil {
+			return err
+		}
+		entry.EntryPostings = append(entry.EntryPostings, row)
+	}
+
+	return nil
+}
+
+// validatePostings checks that there are at least two postings, that none
+// of them is zero and that they balance. The errors are *ValidationError's
+// for the postings field of entity.
+func validatePostings(entity string, postings []Posting) error {
+	if len(postings) < 2 {
+		return &ValidationError{entity, "postings", "must have at least two postings"}
+	}
+
+	var balance int64
+	for _, posting := range postings {
+		if posting.Amount == 0 {
+			return &ValidationError{entity, "postings", "must not have a posting of zero"}
+		}
+		balance += posting.Amount
+	}
+	if balance != 0 {
+		return &ValidationError{entity, "postings", "must balance"}
+	}
+
+	return nil
+}
+
+// checkPostingAccounts checks that the fund with the given id exists and
+// is not archived, that every posting is to an account of the fund and
+// that every posting with a donor is to an income account and is from a
+// donor that exists.
+func checkPostingAccounts(ctx context.Context, db *gorm.DB, entity string, fundId uint, postings []Posting) error {
+	err := checkFundNotArchived(ctx, db, fundId)
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[uint]bool)
+	var accountIds []uint
+	for _, posting := range postings {
+		if !ids[posting.AccountId] {
+			ids[posting.AccountId] = true
+			accountIds = append(accountIds, posting.AccountId)
+		}
+	}
+
+	var accounts []accountImpl
+	err = withContext(ctx, db).Where("id IN (?) AND account_fund_id = ?", accountIds, fundId).Find(&accounts).Error
+	if err != nil {
+		return err
+	}
+	if len(accounts) != len(accountIds) {
+		return &ValidationError{entity, "postings", "must only post to accounts of the fund"}
+	}
+
+	return checkPostingDonors(ctx, db, entity, accounts, postings)
+}
+
+// checkPostingDonors checks that every posting with a donor is to one of
+// accounts that is an income account and is from a donor that exists.
+func checkPostingDonors(ctx context.Context, db *gorm.DB, entity string, accounts []accountImpl, postings []Posting) error {
+	types := make(map[uint]AccountType)
+	for _, account := range accounts {
+		types[account.ID] = account.AccountType
+	}
+
+	ids := make(map[uint]bool)
+	var donorIds []uint
+	for _, posting := range postings {
+		if posting.DonorId == 0 {
+			continue
+		}
+		if types[posting.AccountId] != Income {
+			return &ValidationError{entity, "postings", "must only have donors for income accounts"}
+		}
+		if !ids[posting.DonorId] {
+			ids[posting.DonorId] = true
+			donorIds = append(donorIds, posting.DonorId)
+		}
+	}
+	if len(donorIds) == 0 {
+		return nil
+	}
+
+	var count int
+	err := withContext(ctx, db).Model(&donorImpl{}).Where("id IN (?)", donorIds).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count != len(donorIds) {
+		return &ValidationError{entity, "postings", "must only have donors that exist"}
+	}
+
+	return nil
+}
+
+func (j *journalEntryRepository) Submit(ctx context.Context, id uint, version uint,
+	submitter Principal) (JournalEntry, error) {
+	return j.transition(ctx, id, version, Draft, func(tx *journalEntryRepository, entry JournalEntry) (map[string]interface{}, error) {
+		if err := checkFundNotArchived(ctx, tx.db, entry.FundId()); err != nil {
+			return nil, err
+		}
+
+		status, err := tx.submittedStatus(ctx, entry.FundId(), entry.Postings())
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{
+			"entry_status":       status,
+			"entry_submitted_by": submitter.Name,
+		}, nil
+	})
+}
+
+func (j *journalEntryRepository) Approve(ctx context.Context, id uint, version uint,
+	approver Principal) (JournalEntry, error) {
+	return j.review(ctx, id, version, approver, Approved)
+}
+
+func (j *journalEntryRepository) Reject(ctx context.Context, id uint, version uint,
+	reviewer Principal) (JournalEntry, error) {
+	return j.review(ctx, id, version, reviewer, Rejected)
+}
+
+// review moves the pending entry with the given id and version to status
+// on behalf of reviewer.
+func (j *journalEntryRepository) review(ctx context.Context, id uint, version uint, reviewer Principal,
+	status EntryStatus) (JournalEntry, error) {
+	return j.transition(ctx, id, version, Pending, func(tx *journalEntryRepository, entry JournalEntry) (map[string]interface{}, error) {
+		if status == Approved {
+			if err := checkFundNotArchived(ctx, tx.db, entry.FundId()); err != nil {
+				return nil, err
+			}
+		}
+
+		rules, err := (&approvalRuleRepository{tx.db}).applicable(ctx, entry.FundId(), entryAmount(entry.Postings()))
+		if err != nil {
+			return nil, err
+		}
+
+		err = checkReviewer(rules, id, entry.SubmittedBy(), reviewer, status == Approved)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{
+			"entry_status":      status,
+			"entry_reviewed_by": reviewer.Name,
+		}, nil
+	})
+}
+
+// checkFundNotArchived checks that the fund with the given id isn't
+// archived so that entries may be posted to it.
+func checkFundNotArchived(ctx context.Context, db *gorm.DB, fundId uint) error {
+	fund, err := (&fundRepository{db}).Get(ctx, fundId)
+	if err != nil {
+		return err
+	}
+	if fund.IsArchived() {
+		return &ConflictError{fundEntity, formatId(fundId), "the fund is archived"}
+	}
+
+	return nil
+}
+
+// entryChanges gives the column values to change for a transition of
+// entry. It is called with a repository in the transaction.
+type entryChanges func(tx *journalEntryRepository, entry JournalEntry) (map[string]interface{}, error)
+
+// transition changes the entry with the given id and version, which must
+// have the status from, within a transaction.
+func (j *journalEntryRepository) transition(ctx context.Context, id uint, version uint, from EntryStatus,
+	changes entryChanges) (JournalEntry, error) {
+	var updated JournalEntry
+	err := (&store{j.db}).InTransaction(ctx, func(tx Store) error {
+		repository := &journalEntryRepository{tx.(*store).db}
+
+		entry, err := repository.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if entry.Version() != version {
+			return &ConcurrencyError{journalEntryEntity, formatId(id)}
+		}
+		if entry.Status() != from {
+			return statusConflict(entry)
+		}
+
+		values, err := changes(repository, entry)
+		if err != nil {
+			return err
+		}
+
+		values["entry_version"] = version + 1
+		result := repository.db.Model(&journalEntryImpl{}).
+			Where("id = ? AND entry_version = ?", id, version).
+			Updates(values)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &ConcurrencyError{journalEntryEntity, formatId(id)}
+		}
+
+		updated, err = repository.Get(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// submittedStatus gives the status of an entry of the fund with the given
+// id with postings once it is submitted.
+func (j *journalEntryRepository) submittedStatus(ctx context.Context, fundId uint,
+	postings []Posting) (EntryStatus, error) {
+	rules, err := (&approvalRuleRepository{j.db}).applicable(ctx, fundId, entryAmount(postings))
+	if err != nil {
+		return 0, err
+	}
+	if len(rules) > 0 {
+		return Pending, nil
+	}
+
+	return Approved, nil
+}
+
+func (j *journalEntryRepository) Balances(ctx context.Context, fundId uint) (map[uint]int64, error) {
+	postings := j.db.NewScope(&postingImpl{}).TableName()
+	entries := j.db.NewScope(&journalEntryImpl{}).TableName()
+
+	rows, err := withContext(ctx, j.db).Table(postings).
+		Select(postings+".posting_account_id, SUM("+postings+".posting_amount)").
+		Joins("JOIN "+entries+" ON "+entries+".id = "+postings+".posting_entry_id").
+		Where(entries+".entry_fund_id = ? AND "+entries+".entry_status = ?", fundId, Approved).
+		Group(postings + ".posting_account_id").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := make(map[uint]int64)
+	for rows.Next() {
+		var accountId uint
+		var balance int64
+		if err := rows.Scan(&accountId, &balance); err != nil {
+			return nil, err
+		}
+		balances[accountId] = balance
+	}
+
+	return balances, rows.Err()
+}
+
+func (j *journalEntryRepository) Update(ctx context.Context, id uint, version uint, date time.Time,
+	description string, postings []Posting) (JournalEntry, error) {
+	date = toDate(date)
+	return j.transition(ctx, id, version, Draft, func(tx *journalEntryRepository, entry JournalEntry) (map[string]interface{}, error) {
+		if err := tx.validate(ctx, entry.FundId(), date, description, postings); err != nil {
+			return nil, err
+		}
+
+		err := withContext(ctx, tx.db).Where("posting_entry_id = ?", id).Delete(&postingImpl{}).Error
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.insertPostings(ctx, &journalEntryImpl{ID: id}, postings); err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{
+			"entry_date":        date,
+			"entry_description": description,
+		}, nil
+	})
+}
+
+func (j *journalEntryRepository) Delete(ctx context.Context, id uint, version uint) error {
+	return (&store{j.db}).InTransaction(ctx, func(tx Store) error {
+		repository := &journalEntryRepository{tx.(*store).db}
+
+		entry, err := repository.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if entry.Version() != version {
+			return &ConcurrencyError{journalEntryEntity, formatId(id)}
+		}
+		if entry.Status() != Draft && entry.Status() != Rejected {
+			return statusConflict(entry)
+		}
+
+		err = repository.db.Where("posting_entry_id = ?", id).Delete(&postingImpl{}).Error
+		if err != nil {
+			return err
+		}
+		err = repository.unlink(id)
+		if err != nil {
+			return err
+		}
+
+		result := repository.db.Where("id = ? AND entry_version = ?", id, version).Delete(&journalEntryImpl{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &ConcurrencyError{journalEntryEntity, formatId(id)}
+		}
+
+		return nil
+	})
+}
+
+// unlink clears the links to the entry with the given id from the entries
+// that it reverses or corrects.
+func (j *journalEntryRepository) unlink(id uint) error {
+	for _, column := range []string{"entry_reversed_by_id", "entry_corrected_by_id"} {
+		err := j.db.Model(&journalEntryImpl{}).Where(column+" = ?", id).
+			UpdateColumn(column, gorm.Expr("NULL")).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (j *journalEntryRepository) Reverse(ctx context.Context, id uint, version uint, date time.Time,
+	reverser Principal) (JournalEntry, error) {
+	var reversal *journalEntryImpl
+	_, err := j.transition(ctx, id, version, Approved, func(tx *journalEntryRepository, entry JournalEntry) (map[string]interface{}, error) {
+		var err error
+		reversal, err = tx.reverse(ctx, entry, toDate(date), reverser)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"entry_reversed_by_id": reversal.ID}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reversal, nil
+}
+
+func (j *journalEntryRepository) Correct(ctx context.Context, id uint, version uint, date time.Time,
+	description string, postings []Posting, corrector Principal) (JournalEntry, error) {
+	var correction *journalEntryImpl
+	_, err := j.transition(ctx, id, version, Approved, func(tx *journalEntryRepository, entry JournalEntry) (map[string]interface{}, error) {
+		reversal, err := tx.reverse(ctx, entry, toDate(date), corrector)
+		if err != nil {
+			return nil, err
+		}
+
+		status, err := tx.submittedStatus(ctx, entry.FundId(), postings)
+		if err != nil {
+			return nil, err
+		}
+
+		correction = &journalEntryImpl{
+			EntryFundID:      entry.FundId(),
+			EntryDate:        toDate(date),
+			EntryDescription: description,
+			EntryStatus:      status,
+			EntrySubmittedBy: corrector.Name,
+			EntryCorrectsID:  &id,
+			EntryVersion:     1,
+		}
+		err = tx.create(ctx, correction, postings)
+		if isDuplicateEntry(err) {
+			return nil, &ConflictError{journalEntryEntity, formatId(id), "the entry has already been corrected"}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{
+			"entry_reversed_by_id":  reversal.ID,
+			"entry_corrected_by_id": correction.ID,
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return correction, nil
+}
+
+// reverse submits the reversal of the posted entry on date on behalf of
+// reverser. It must be called within a transaction.
+func (j *journalEntryRepository) reverse(ctx context.Context, entry JournalEntry, date time.Time,
+	reverser Principal) (*journalEntryImpl, error) {
+	if entry.ReversedById() != 0 {
+		return nil, &ConflictError{journalEntryEntity, formatId(entry.Id()), "the entry has already been reversed"}
+	}
+
+	var postings []Posting
+	for _, posting := range entry.Postings() {
+		postings = append(postings, Posting{posting.AccountId, -posting.Amount, posting.DonorId})
+	}
+
+	status, err := j.submittedStatus(ctx, entry.FundId(), postings)
+	if err != nil {
+		return nil, err
+	}
+
+	id := entry.Id()
+	reversal := &journalEntryImpl{
+		EntryFundID:      entry.FundId(),
+		EntryDate:        date,
+		EntryDescription: "Reversal of " + entry.Description(),
+		EntryStatus:      status,
+		EntrySubmittedBy: reverser.Name,
+		EntryReversesID:  &id,
+		EntryVersion:     1,
+	}
+	err = j.create(ctx, reversal, postings)
+	if isDuplicateEntry(err) {
+		return nil, &ConflictError{journalEntryEntity, formatId(id), "the entry has already been reversed"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return reversal, nil
+}
+
+// statusConflict gives the error for a change to entry that its status
+// doesn't allow.
+func statusConflict(entry JournalEntry) error {
+	reason := "the entry is " + entry.Status().String()
+	if entry.Status() == Approved {
+		reason = "the entry is posted and can only be reversed or corrected"
+	}
+
+	return &ConflictError{journalEntryEntity, formatId(entry.Id()), reason}
+}
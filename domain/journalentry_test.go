@@ -0,0 +1,463 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// getLedgerDb gets an empty database with a fund that has a cash account
+// (1) and a rent account (2) and another fund with an account (3).
+func getLedgerDb(t *testing.T) *gorm.DB {
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 1, nil}, {2, USD, "Special", 1, nil}})
+	insertAccounts(t, db, []accountImpl{{1, 1, "Cash", Asset, 1},
+		{2, 1, "Rent", Expense, 1}, {3, 2, "Cash", Asset, 1}})
+	return db
+}
+
+var rentPostings = []Posting{{2, 150000, 0}, {1, -150000, 0}}
+
+func TestValidatePostings(t *testing.T) {
+	assert.NoError(t, validatePostings(journalEntryEntity, rentPostings))
+
+	bad := [][]Posting{
+		nil,
+		{{1, 100, 0}},
+		{{1, 100, 0}, {2, -50, 0}},
+		{{1, 100, 0}, {2, -100, 0}, {1, 0, 0}},
+	}
+	for _, postings := range bad {
+		err := validatePostings(journalEntryEntity, postings)
+		assert.IsType(t, &ValidationError{}, err, "Unexpected error for postings %v.", postings)
+	}
+}
+
+func TestJournalEntryRepositoryCreateReturnsNewEntry(t *testing.T) {
+	db := getLedgerDb(t)
+	date := time.Date(2026, time.October, 1, 15, 30, 0, 0, time.UTC)
+
+	sut := journalEntryRepository{db}
+	actual, err := sut.Create(context.Background(), 1, date, "October rent", rentPostings)
+
+	require.NoError(t, err, "Unable to create a new entry.")
+	assert.NotZero(t, actual.Id(), "Id of the returned entry was zero")
+	assert.Equal(t, uint(1), actual.FundId())
+	assert.Equal(t, time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC), actual.Date())
+	assert.Equal(t, rentPostings, actual.Postings())
+	assert.Zero(t, actual.ScheduleId())
+	assert.Equal(t, Draft, actual.Status())
+	assert.Equal(t, uint(1), actual.Version())
+}
+
+func TestJournalEntryRepositoryGetReturnsEntryWithPostings(t *testing.T) {
+	db := getLedgerDb(t)
+	sut := journalEntryRepository{db}
+	created, err := sut.Create(context.Background(), 1, time.Now(), "Rent", rentPostings)
+	require.NoError(t, err, "Unable to create a new entry.")
+
+	actual, err := sut.Get(context.Background(), created.Id())
+
+	require.NoError(t, err, "Unable to get the entry.")
+	assert.Equal(t, "Rent", actual.Description())
+	assert.Equal(t, rentPostings, actual.Postings())
+}
+
+func TestJournalEntryRepositoryGetByFundRetrievesFundsEntries(t *testing.T) {
+	db := getLedgerDb(t)
+	sut := journalEntryRepository{db}
+	_, err := sut.Create(context.Background(), 1, time.Now(), "Rent", rentPostings)
+	require.NoError(t, err, "Unable to create a new entry.")
+
+	actual, err := sut.GetByFund(context.Background(), 1)
+	require.NoError(t, err, "Unable to get the fund's entries.")
+	other, err := sut.GetByFund(context.Background(), 2)
+	require.NoError(t, err, "Unable to get the other fund's entries.")
+
+	require.Len(t, actual, 1, "Unexpected number of entries returned from GetByFund().")
+	assert.Equal(t, rentPostings, actual[0].Postings())
+	assert.Empty(t, other, "Unexpected entries in the other fund.")
+}
+
+func TestJournalEntryRepositoryCreateWithOtherFundsAccountIsValidationError(t *testing.T) {
+	db := getLedgerDb(t)
+
+	sut := journalEntryRepository{db}
+	_, err := sut.Create(context.Background(), 1, time.Now(), "Rent", []Posting{{2, 100, 0}, {3, -100, 0}})
+
+	assert.IsType(t, &ValidationError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryCreateInArchivedFundIsConflictError(t *testing.T) {
+	archived := time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC)
+	db := getEmptyDb(t)
+	insertFunds(t, db, []fundImpl{{1, CAD, "General", 2, &archived}})
+	insertAccounts(t, db, []accountImpl{{1, 1, "Cash", Asset, 1}, {2, 1, "Rent", Expense, 1}})
+
+	sut := journalEntryRepository{db}
+	_, err := sut.Create(context.Background(), 1, time.Now(), "Rent", rentPostings)
+
+	assert.IsType(t, &ConflictError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryGetMissingEntryIsNotFoundError(t *testing.T) {
+	db := getEmptyDb(t)
+
+	sut := journalEntryRepository{db}
+	_, err := sut.Get(context.Background(), 1)
+
+	assert.IsType(t, &NotFoundError{}, err, "Get() returned an unexpected type of error")
+}
+
+// createRentEntry creates a draft rent entry in the ledger database.
+func createRentEntry(t *testing.T, db *gorm.DB) JournalEntry {
+	entry, err := (&journalEntryRepository{db}).Create(context.Background(), 1,
+		date(2026, time.October, 1), "October rent", rentPostings)
+	require.NoError(t, err, "Unable to create the rent entry.")
+	return entry
+}
+
+var (
+	clerk     = Principal{Name: "clerk"}
+	treasurer = Principal{Name: "treasurer", Roles: []string{"treasurer"}}
+)
+
+func TestJournalEntryRepositorySubmitWithoutRulesApprovesEntry(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := createRentEntry(t, db)
+
+	sut := journalEntryRepository{db}
+	actual, err := sut.Submit(context.Background(), entry.Id(), 1, clerk)
+
+	require.NoError(t, err, "Unable to submit the entry.")
+	assert.Equal(t, Approved, actual.Status())
+	assert.Equal(t, "clerk", actual.SubmittedBy())
+	assert.Equal(t, uint(2), actual.Version())
+}
+
+func TestJournalEntryRepositorySubmitOverThresholdIsPending(t *testing.T) {
+	db := getLedgerDb(t)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 100000, "treasurer", false, 1}})
+	entry := createRentEntry(t, db)
+
+	sut := journalEntryRepository{db}
+	actual, err := sut.Submit(context.Background(), entry.Id(), 1, clerk)
+
+	require.NoError(t, err, "Unable to submit the entry.")
+	assert.Equal(t, Pending, actual.Status())
+}
+
+func TestJournalEntryRepositorySubmitUnderThresholdApprovesEntry(t *testing.T) {
+	db := getLedgerDb(t)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 200000, "treasurer", false, 1}})
+	entry := createRentEntry(t, db)
+
+	sut := journalEntryRepository{db}
+	actual, err := sut.Submit(context.Background(), entry.Id(), 1, clerk)
+
+	require.NoError(t, err, "Unable to submit the entry.")
+	assert.Equal(t, Approved, actual.Status())
+}
+
+func TestJournalEntryRepositorySubmitTwiceIsConflictError(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := createRentEntry(t, db)
+	sut := journalEntryRepository{db}
+	_, err := sut.Submit(context.Background(), entry.Id(), 1, clerk)
+	require.NoError(t, err, "Unable to submit the entry.")
+
+	_, err = sut.Submit(context.Background(), entry.Id(), 2, clerk)
+
+	assert.IsType(t, &ConflictError{}, err, "Submit() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositorySubmitOldVersionIsConcurrencyError(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := createRentEntry(t, db)
+
+	sut := journalEntryRepository{db}
+	_, err := sut.Submit(context.Background(), entry.Id(), 7, clerk)
+
+	assert.IsType(t, &ConcurrencyError{}, err, "Submit() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryApproveByRoleHolderApprovesEntry(t *testing.T) {
+	db := getLedgerDb(t)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 0, "treasurer", true, 1}})
+	entry := createRentEntry(t, db)
+	sut := journalEntryRepository{db}
+	_, err := sut.Submit(context.Background(), entry.Id(), 1, clerk)
+	require.NoError(t, err, "Unable to submit the entry.")
+
+	actual, err := sut.Approve(context.Background(), entry.Id(), 2, treasurer)
+
+	require.NoError(t, err, "Unable to approve the entry.")
+	assert.Equal(t, Approved, actual.Status())
+	assert.Equal(t, "treasurer", actual.ReviewedBy())
+	assert.Equal(t, uint(3), actual.Version())
+}
+
+func TestJournalEntryRepositoryApproveWithoutRoleIsForbiddenError(t *testing.T) {
+	db := getLedgerDb(t)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 0, "treasurer", false, 1}})
+	entry := createRentEntry(t, db)
+	sut := journalEntryRepository{db}
+	_, err := sut.Submit(context.Background(), entry.Id(), 1, clerk)
+	require.NoError(t, err, "Unable to submit the entry.")
+
+	_, err = sut.Approve(context.Background(), entry.Id(), 2, Principal{Name: "bookkeeper"})
+
+	assert.IsType(t, &ForbiddenError{}, err, "Approve() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryApproveBySubmitterUnderTwoPersonRuleIsForbiddenError(t *testing.T) {
+	db := getLedgerDb(t)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 0, "treasurer", true, 1}})
+	entry := createRentEntry(t, db)
+	sut := journalEntryRepository{db}
+	_, err := sut.Submit(context.Background(), entry.Id(), 1, treasurer)
+	require.NoError(t, err, "Unable to submit the entry.")
+
+	_, err = sut.Approve(context.Background(), entry.Id(), 2, treasurer)
+
+	assert.IsType(t, &ForbiddenError{}, err, "Approve() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryApproveInArchivedFundIsConflictError(t *testing.T) {
+	db := getLedgerDb(t)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 0, "treasurer", false, 1}})
+	entry := createRentEntry(t, db)
+	sut := journalEntryRepository{db}
+	_, err := sut.Submit(context.Background(), entry.Id(), 1, clerk)
+	require.NoError(t, err, "Unable to submit the entry.")
+	_, err = (&fundRepository{db}).Archive(context.Background(), 1, 1)
+	require.NoError(t, err, "Unable to archive the fund.")
+
+	_, err = sut.Approve(context.Background(), entry.Id(), 2, treasurer)
+
+	assert.IsType(t, &ConflictError{}, err, "Approve() returned an unexpected type of error")
+	rejected, err := sut.Reject(context.Background(), entry.Id(), 2, treasurer)
+	require.NoError(t, err, "Unable to reject the entry.")
+	assert.Equal(t, Rejected, rejected.Status())
+}
+
+func TestJournalEntryRepositoryRejectBySubmitterRejectsEntry(t *testing.T) {
+	db := getLedgerDb(t)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 0, "treasurer", true, 1}})
+	entry := createRentEntry(t, db)
+	sut := journalEntryRepository{db}
+	_, err := sut.Submit(context.Background(), entry.Id(), 1, treasurer)
+	require.NoError(t, err, "Unable to submit the entry.")
+
+	actual, err := sut.Reject(context.Background(), entry.Id(), 2, treasurer)
+
+	require.NoError(t, err, "Unable to reject the entry.")
+	assert.Equal(t, Rejected, actual.Status())
+}
+
+func TestJournalEntryRepositoryApproveDraftIsConflictError(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := createRentEntry(t, db)
+
+	sut := journalEntryRepository{db}
+	_, err := sut.Approve(context.Background(), entry.Id(), 1, treasurer)
+
+	assert.IsType(t, &ConflictError{}, err, "Approve() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryBalancesOnlyCountsApprovedEntries(t *testing.T) {
+	db := getLedgerDb(t)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 100000, "treasurer", false, 1}})
+	sut := journalEntryRepository{db}
+	small, err := sut.Create(context.Background(), 1, date(2026, time.October, 2), "Supplies",
+		[]Posting{{2, 2500, 0}, {1, -2500, 0}})
+	require.NoError(t, err, "Unable to create the small entry.")
+	_, err = sut.Submit(context.Background(), small.Id(), 1, clerk)
+	require.NoError(t, err, "Unable to submit the small entry.")
+	large := createRentEntry(t, db)
+	_, err = sut.Submit(context.Background(), large.Id(), 1, clerk)
+	require.NoError(t, err, "Unable to submit the large entry.")
+	createRentEntry(t, db)
+
+	actual, err := sut.Balances(context.Background(), 1)
+
+	require.NoError(t, err, "Unable to get the balances.")
+	assert.Equal(t, map[uint]int64{1: -2500, 2: 2500}, actual)
+}
+
+// postRentEntry creates and submits a rent entry that is posted because
+// there are no approval rules.
+func postRentEntry(t *testing.T, db *gorm.DB) JournalEntry {
+	entry := createRentEntry(t, db)
+	posted, err := (&journalEntryRepository{db}).Submit(context.Background(), entry.Id(), 1, clerk)
+	require.NoError(t, err, "Unable to post the rent entry.")
+	require.Equal(t, Approved, posted.Status(), "The rent entry was not posted.")
+	return posted
+}
+
+func TestJournalEntryRepositoryUpdateChangesDraftEntry(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := createRentEntry(t, db)
+	postings := []Posting{{2, 160000, 0}, {1, -160000, 0}}
+
+	sut := journalEntryRepository{db}
+	actual, err := sut.Update(context.Background(), entry.Id(), 1, date(2026, time.October, 2), "Rent", postings)
+
+	require.NoError(t, err, "Unable to update the entry.")
+	assert.Equal(t, date(2026, time.October, 2), actual.Date())
+	assert.Equal(t, "Rent", actual.Description())
+	assert.Equal(t, postings, actual.Postings())
+	assert.Equal(t, uint(2), actual.Version())
+}
+
+func TestJournalEntryRepositoryUpdatePostedEntryIsConflictError(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := postRentEntry(t, db)
+
+	sut := journalEntryRepository{db}
+	_, err := sut.Update(context.Background(), entry.Id(), entry.Version(), entry.Date(), "Rent", rentPostings)
+
+	assert.IsType(t, &ConflictError{}, err, "Update() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryDeleteRemovesDraftEntry(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := createRentEntry(t, db)
+
+	sut := journalEntryRepository{db}
+	err := sut.Delete(context.Background(), entry.Id(), 1)
+	require.NoError(t, err, "Unable to delete the entry.")
+	_, err = sut.Get(context.Background(), entry.Id())
+
+	assert.IsType(t, &NotFoundError{}, err, "The deleted entry was still found.")
+}
+
+func TestJournalEntryRepositoryDeletePostedEntryIsConflictError(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := postRentEntry(t, db)
+
+	sut := journalEntryRepository{db}
+	err := sut.Delete(context.Background(), entry.Id(), entry.Version())
+
+	assert.IsType(t, &ConflictError{}, err, "Delete() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryReverseMakesLinkedMirrorEntry(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := postRentEntry(t, db)
+
+	sut := journalEntryRepository{db}
+	actual, err := sut.Reverse(context.Background(), entry.Id(), entry.Version(), date(2026, time.October, 31), clerk)
+	require.NoError(t, err, "Unable to reverse the entry.")
+	original, err := sut.Get(context.Background(), entry.Id())
+	require.NoError(t, err, "Unable to get the reversed entry.")
+	balances, err := sut.Balances(context.Background(), 1)
+	require.NoError(t, err, "Unable to get the balances.")
+
+	assert.Equal(t, date(2026, time.October, 31), actual.Date())
+	assert.Equal(t, []Posting{{2, -150000, 0}, {1, 150000, 0}}, actual.Postings())
+	assert.Equal(t, Approved, actual.Status())
+	assert.Equal(t, entry.Id(), actual.ReversesId())
+	assert.Equal(t, actual.Id(), original.ReversedById())
+	assert.Equal(t, map[uint]int64{1: 0, 2: 0}, balances)
+}
+
+func TestJournalEntryRepositoryReverseTwiceIsConflictError(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := postRentEntry(t, db)
+	sut := journalEntryRepository{db}
+	_, err := sut.Reverse(context.Background(), entry.Id(), entry.Version(), date(2026, time.October, 31), clerk)
+	require.NoError(t, err, "Unable to reverse the entry.")
+
+	_, err = sut.Reverse(context.Background(), entry.Id(), entry.Version()+1, date(2026, time.October, 31), clerk)
+
+	assert.IsType(t, &ConflictError{}, err, "Reverse() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryReverseDraftIsConflictError(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := createRentEntry(t, db)
+
+	sut := journalEntryRepository{db}
+	_, err := sut.Reverse(context.Background(), entry.Id(), 1, date(2026, time.October, 31), clerk)
+
+	assert.IsType(t, &ConflictError{}, err, "Reverse() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryReverseOverThresholdIsPending(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := postRentEntry(t, db)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 100000, "treasurer", true, 1}})
+
+	sut := journalEntryRepository{db}
+	actual, err := sut.Reverse(context.Background(), entry.Id(), entry.Version(), date(2026, time.October, 31), treasurer)
+	require.NoError(t, err, "Unable to reverse the entry.")
+	balances, err := sut.Balances(context.Background(), 1)
+	require.NoError(t, err, "Unable to get the balances.")
+
+	assert.Equal(t, Pending, actual.Status())
+	assert.Equal(t, "treasurer", actual.SubmittedBy())
+	assert.Equal(t, map[uint]int64{1: -150000, 2: 150000}, balances)
+}
+
+func TestJournalEntryRepositoryApproveReversalByReverserUnderTwoPersonRuleIsForbiddenError(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := postRentEntry(t, db)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 0, "treasurer", true, 1}})
+	sut := journalEntryRepository{db}
+	reversal, err := sut.Reverse(context.Background(), entry.Id(), entry.Version(), date(2026, time.October, 31), treasurer)
+	require.NoError(t, err, "Unable to reverse the entry.")
+
+	_, err = sut.Approve(context.Background(), reversal.Id(), reversal.Version(), treasurer)
+
+	assert.IsType(t, &ForbiddenError{}, err, "Approve() returned an unexpected type of error")
+}
+
+func TestJournalEntryRepositoryDeleteRejectedReversalAllowsNewReversal(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := postRentEntry(t, db)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 0, "treasurer", false, 1}})
+	sut := journalEntryRepository{db}
+	reversal, err := sut.Reverse(context.Background(), entry.Id(), entry.Version(), date(2026, time.October, 31), clerk)
+	require.NoError(t, err, "Unable to reverse the entry.")
+	rejected, err := sut.Reject(context.Background(), reversal.Id(), reversal.Version(), treasurer)
+	require.NoError(t, err, "Unable to reject the reversal.")
+	require.NoError(t, sut.Delete(context.Background(), rejected.Id(), rejected.Version()),
+		"Unable to delete the reversal.")
+	original, err := sut.Get(context.Background(), entry.Id())
+	require.NoError(t, err, "Unable to get the entry.")
+
+	_, err = sut.Reverse(context.Background(), original.Id(), original.Version(), date(2026, time.November, 1), clerk)
+
+	assert.NoError(t, err, "Unable to reverse the entry again.")
+	assert.Zero(t, original.ReversedById(), "The deleted reversal is still linked.")
+}
+
+func TestJournalEntryRepositoryCorrectReversesAndReposts(t *testing.T) {
+	db := getLedgerDb(t)
+	entry := postRentEntry(t, db)
+	postings := []Posting{{2, 160000, 0}, {1, -160000, 0}}
+
+	sut := journalEntryRepository{db}
+	actual, err := sut.Correct(context.Background(), entry.Id(), entry.Version(), date(2026, time.October, 2),
+		"October rent", postings, clerk)
+	require.NoError(t, err, "Unable to correct the entry.")
+	original, err := sut.Get(context.Background(), entry.Id())
+	require.NoError(t, err, "Unable to get the corrected entry.")
+	balances, err := sut.Balances(context.Background(), 1)
+	require.NoError(t, err, "Unable to get the balances.")
+
+	assert.Equal(t, postings, actual.Postings())
+	assert.Equal(t, entry.Id(), actual.CorrectsId())
+	assert.Equal(t, actual.Id(), original.CorrectedById())
+	assert.NotZero(t, original.ReversedById(), "The corrected entry was not reversed.")
+	assert.Equal(t, map[uint]int64{1: -160000, 2: 160000}, balances)
+}
@@ -0,0 +1,187 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+//go:generate go run mklocale.go
+
+package domain
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// defaultLocaleTag is the tag of the Locale that DefaultLocale gives.
+	defaultLocaleTag = "en"
+
+	// currencySign stands for the symbol of the currency in the prefix and
+	// suffix of a locale.
+	currencySign = "¤"
+
+	// symbolSpace separates a symbol that ends (or starts) with a letter
+	// from the digits of an amount.
+	symbolSpace = "\u00a0"
+)
+
+// localeInfo is how a locale formats amounts of money. The prefix and
+// suffix are the text before and after the digits, in which currencySign
+// stands for the symbol of the currency. The integer digits are grouped by
+// primaryGroup digits from the right and then by secondaryGroup digits, or
+// are not grouped if primaryGroup is zero. The symbols are those that
+// differ from the symbol of the currency.
+type localeInfo struct {
+	tag            string
+	decimal        string
+	group          string
+	minus          string
+	prefix         string
+	suffix         string
+	primaryGroup   int
+	secondaryGroup int
+	symbols        map[Currency]string
+}
+
+// A Locale formats amounts of money as the readers of a language, and
+// possibly of a region, expect. The formats and symbols of the locales are
+// derived from the Unicode Common Locale Data Repository (CLDR).
+type Locale struct {
+	info *localeInfo
+}
+
+// DefaultLocale gives the Locale for English.
+func DefaultLocale() Locale {
+	return Locale{locales[defaultLocaleTag]}
+}
+
+// LookupLocale gives the Locale for a BCP 47 language tag, such as "fr-CA",
+// and true, or the DefaultLocale and false if there is no Locale for the
+// tag. A tag for a region that has no Locale of its own gives the Locale of
+// its language.
+func LookupLocale(tag string) (Locale, bool) {
+	key := strings.ToLower(strings.Replace(strings.TrimSpace(tag), "_", "-", -1))
+	for key != "" {
+		if info, ok := locales[key]; ok {
+			return Locale{info}, true
+		}
+
+		i := strings.LastIndex(key, "-")
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+
+	return DefaultLocale(), false
+}
+
+// Tag gives the BCP 47 language tag of the Locale.
+func (l Locale) Tag() string {
+	return l.locale().tag
+}
+
+// Format formats amount, in the minor units of currency, with the symbol
+// of currency, such as "$1,234.56" or "1 234,56 €".
+func (l Locale) Format(amount int64, currency Currency) string {
+	info := l.locale()
+	symbol, ok := info.symbols[currency]
+	if !ok {
+		symbol = currency.Symbol()
+	}
+
+	var formatted bytes.Buffer
+	if amount < 0 {
+		formatted.WriteString(info.minus)
+	}
+	prefix := strings.Replace(info.prefix, currencySign, symbol, -1)
+	formatted.WriteString(prefix)
+	if strings.HasSuffix(info.prefix, currencySign) && endsWithLetter(symbol) {
+		formatted.WriteString(symbolSpace)
+	}
+	formatted.WriteString(info.formatNumber(amount, currency))
+	if strings.HasPrefix(info.suffix, currencySign) && startsWithLetter(symbol) {
+		formatted.WriteString(symbolSpace)
+	}
+	formatted.WriteString(strings.Replace(info.suffix, currencySign, symbol, -1))
+
+	return formatted.String()
+}
+
+// FormatNumber formats amount, in the minor units of currency, without a
+// symbol, such as "1,234.56" or "-1 234,56".
+func (l Locale) FormatNumber(amount int64, currency Currency) string {
+	info := l.locale()
+	number := info.formatNumber(amount, currency)
+	if amount < 0 {
+		return info.minus + number
+	}
+
+	return number
+}
+
+// locale gives the localeInfo of the Locale, which is that of the
+// DefaultLocale for the zero Locale.
+func (l Locale) locale() *localeInfo {
+	if l.info == nil {
+		return locales[defaultLocaleTag]
+	}
+
+	return l.info
+}
+
+// formatNumber formats the absolute value of amount, in the minor units of
+// currency, with the decimal separator and digit groups of the locale.
+func (l *localeInfo) formatNumber(amount int64, currency Currency) string {
+	// the absolute value as a uint64 is correct even for the smallest int64
+	magnitude := uint64(amount)
+	if amount < 0 {
+		magnitude = -magnitude
+	}
+
+	digits := []byte(strconv.FormatUint(magnitude, 10))
+	minorUnits := currency.MinorUnits()
+	for len(digits) <= minorUnits {
+		digits = append([]byte{'0'}, digits...)
+	}
+	integer, fraction := digits[:len(digits)-minorUnits], digits[len(digits)-minorUnits:]
+
+	var formatted bytes.Buffer
+	for i, digit := range integer {
+		if i > 0 && l.isGroupBoundary(len(integer)-i) {
+			formatted.WriteString(l.group)
+		}
+		formatted.WriteByte(digit)
+	}
+	if len(fraction) > 0 {
+		formatted.WriteString(l.decimal)
+		formatted.Write(fraction)
+	}
+
+	return formatted.String()
+}
+
+// isGroupBoundary is whether a group separator comes before the digit that
+// has remaining integer digits to its right, counting itself.
+func (l *localeInfo) isGroupBoundary(remaining int) bool {
+	if l.primaryGroup == 0 || remaining < l.primaryGroup {
+		return false
+	}
+	if remaining == l.primaryGroup {
+		return true
+	}
+
+	return l.secondaryGroup > 0 && (remaining-l.primaryGroup)%l.secondaryGroup == 0
+}
+
+func startsWithLetter(text string) bool {
+	r, _ := utf8.DecodeRuneInString(text)
+	return unicode.IsLetter(r)
+}
+
+func endsWithLetter(text string) bool {
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return unicode.IsLetter(r)
+}
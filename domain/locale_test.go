@@ -0,0 +1,111 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type formatCase struct {
+	tag      string
+	amount   int64
+	currency Currency
+	expected string
+}
+
+func TestLocaleFormatGivesLocalizedAmounts(t *testing.T) {
+	cases := []formatCase{
+		{"en", 123456, USD, "$1,234.56"},
+		{"en", 1235, JPY, "¥1,235"},
+		{"en", 123456, CHF, "CHF 1,234.56"},
+		{"en-CA", 123456, CAD, "$1,234.56"},
+		{"en-CA", 123456, USD, "US$1,234.56"},
+		{"en-IN", 1234567800, INR, "₹1,23,45,678.00"},
+		{"fr", 123456, EUR, "1 234,56 €"},
+		{"fr-CA", 123456, USD, "1 234,56 $ US"},
+		{"de", 123456789, EUR, "1.234.567,89 €"},
+		{"de-CH", 123456, CHF, "CHF 1’234.56"},
+		{"ja", 1235, JPY, "￥1,235"},
+		{"en", 1234, BHD, "BHD 1.234"},
+		{"en", 5, USD, "$0.05"},
+		{"en", 0, USD, "$0.00"},
+	}
+
+	for _, c := range cases {
+		sut, ok := LookupLocale(c.tag)
+		require.True(t, ok, "There is no locale for %s.", c.tag)
+
+		assert.Equal(t, c.expected, sut.Format(c.amount, c.currency), "Unexpected format for %s.", c.tag)
+	}
+}
+
+func TestLocaleFormatGivesNegativeAmountsWithMinusFirst(t *testing.T) {
+	cases := []formatCase{
+		{"en", -123456, USD, "-$1,234.56"},
+		{"fr", -5, EUR, "-0,05 €"},
+		{"sv", -123456, SEK, "−1 234,56 kr"},
+	}
+
+	for _, c := range cases {
+		sut, _ := LookupLocale(c.tag)
+
+		assert.Equal(t, c.expected, sut.Format(c.amount, c.currency), "Unexpected format for %s.", c.tag)
+	}
+}
+
+func TestLocaleFormatOfSmallestAmount(t *testing.T) {
+	assert.Equal(t, "-$92,233,720,368,547,758.08", DefaultLocale().Format(math.MinInt64, USD))
+}
+
+func TestLocaleFormatNumberHasNoSymbol(t *testing.T) {
+	sut, _ := LookupLocale("fr")
+
+	assert.Equal(t, "-1 234,56", sut.FormatNumber(-123456, CAD))
+	assert.Equal(t, "1 235", sut.FormatNumber(1235, JPY))
+}
+
+func TestLookupLocaleFallsBackToLanguage(t *testing.T) {
+	for tag, expected := range map[string]string{"fr_ca": "fr-CA", "FR-BE": "fr", "de-CH-1996": "de-CH"} {
+		actual, ok := LookupLocale(tag)
+
+		assert.True(t, ok, "There is no locale for %s.", tag)
+		assert.Equal(t, expected, actual.Tag(), "Unexpected locale for %s.", tag)
+	}
+}
+
+func TestLookupLocaleWithUnknownTagIsDefault(t *testing.T) {
+	for _, tag := range []string{"xx", "", "*"} {
+		actual, ok := LookupLocale(tag)
+
+		assert.False(t, ok, "There is a locale for %q.", tag)
+		assert.Equal(t, "en", actual.Tag(), "Unexpected locale for %q.", tag)
+	}
+}
+
+func TestZeroLocaleIsDefault(t *testing.T) {
+	var sut Locale
+
+	assert.Equal(t, "en", sut.Tag())
+	assert.Equal(t, "$1.00", sut.Format(100, USD))
+}
+
+func TestCurrencySymbolIsCodeWithoutSymbol(t *testing.T) {
+	assert.Equal(t, "€", EUR.Symbol())
+	assert.Equal(t, "US$", USD.Symbol())
+	assert.Equal(t, "AED", AED.Symbol())
+	assert.Equal(t, "XXX", Currency(math.MaxUint16).Symbol())
+}
+
+func TestCurrencyMinorUnits(t *testing.T) {
+	expected := map[Currency]int{CAD: 2, JPY: 0, BHD: 3, XAU: 0, DEM: 2}
+
+	for currency, units := range expected {
+		assert.Equal(t, units, currency.MinorUnits(), "Unexpected minor units of %s.", currency)
+	}
+}
@@ -0,0 +1,413 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+// This file is generated by mklocale.go from cldr-numbers.json.
+// DO NOT EDIT THIS FILE DIRECTLY.
+// To regenerate this file run "go generate"
+
+package domain
+
+// The locales are from version 30 of the CLDR.
+var locales = map[string]*localeInfo{
+	"de": {
+		tag:            "de",
+		decimal:        ",",
+		group:          ".",
+		minus:          "-",
+		prefix:         "",
+		suffix:         "\u00a0¤",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			AUD: "AU$",
+			JPY: "¥",
+			USD: "$",
+		},
+	},
+	"de-at": {
+		tag:            "de-AT",
+		decimal:        ",",
+		group:          "\u00a0",
+		minus:          "-",
+		prefix:         "¤\u00a0",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			AUD: "AU$",
+			JPY: "¥",
+			USD: "$",
+		},
+	},
+	"de-ch": {
+		tag:            "de-CH",
+		decimal:        ".",
+		group:          "’",
+		minus:          "-",
+		prefix:         "¤\u00a0",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			AUD: "AU$",
+			JPY: "¥",
+			USD: "$",
+		},
+	},
+	"en": {
+		tag:            "en",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			JPY: "¥",
+			USD: "$",
+		},
+	},
+	"en-001": {
+		tag:            "en-001",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			JPY: "¥",
+			USD: "US$",
+		},
+	},
+	"en-au": {
+		tag:            "en-AU",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			AUD: "$",
+			JPY: "¥",
+			USD: "USD",
+		},
+	},
+	"en-ca": {
+		tag:            "en-CA",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			CAD: "$",
+			JPY: "¥",
+			USD: "US$",
+		},
+	},
+	"en-gb": {
+		tag:            "en-GB",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			JPY: "¥",
+			USD: "US$",
+		},
+	},
+	"en-in": {
+		tag:            "en-IN",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 2,
+		symbols: map[Currency]string{
+			JPY: "¥",
+			USD: "US$",
+		},
+	},
+	"en-nz": {
+		tag:            "en-NZ",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			JPY: "¥",
+			NZD: "$",
+			USD: "US$",
+		},
+	},
+	"es": {
+		tag:            "es",
+		decimal:        ",",
+		group:          ".",
+		minus:          "-",
+		prefix:         "",
+		suffix:         "\u00a0¤",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			CAD: "CA$",
+			JPY: "JPY",
+			USD: "US$",
+		},
+	},
+	"es-419": {
+		tag:            "es-419",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			CAD: "CA$",
+			JPY: "JPY",
+			USD: "US$",
+		},
+	},
+	"es-mx": {
+		tag:            "es-MX",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			CAD: "CA$",
+			JPY: "JPY",
+			MXN: "$",
+			USD: "USD",
+		},
+	},
+	"es-us": {
+		tag:            "es-US",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			CAD: "CA$",
+			JPY: "JPY",
+			USD: "$",
+		},
+	},
+	"fr": {
+		tag:            "fr",
+		decimal:        ",",
+		group:          "\u00a0",
+		minus:          "-",
+		prefix:         "",
+		suffix:         "\u00a0¤",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			AUD: "$AU",
+			CAD: "$CA",
+			CNY: "CNY",
+			GBP: "£GB",
+			HKD: "$HK",
+			JPY: "JPY",
+			MXN: "$MX",
+			NZD: "$NZ",
+			TWD: "TWD",
+			USD: "$US",
+			XPF: "FCFP",
+		},
+	},
+	"fr-ca": {
+		tag:            "fr-CA",
+		decimal:        ",",
+		group:          "\u00a0",
+		minus:          "-",
+		prefix:         "",
+		suffix:         "\u00a0¤",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			AUD: "$\u00a0AU",
+			CAD: "$",
+			CNY: "CNY",
+			GBP: "£",
+			HKD: "$\u00a0HK",
+			JPY: "¥",
+			MXN: "$MX",
+			NZD: "$\u00a0NZ",
+			TWD: "TWD",
+			USD: "$\u00a0US",
+			XPF: "FCFP",
+		},
+	},
+	"fr-ch": {
+		tag:            "fr-CH",
+		decimal:        ".",
+		group:          "\u00a0",
+		minus:          "-",
+		prefix:         "",
+		suffix:         "\u00a0¤",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			AUD: "$AU",
+			CAD: "$CA",
+			CNY: "CNY",
+			GBP: "£GB",
+			HKD: "$HK",
+			JPY: "JPY",
+			MXN: "$MX",
+			NZD: "$NZ",
+			TWD: "TWD",
+			USD: "$US",
+			XPF: "FCFP",
+		},
+	},
+	"hi": {
+		tag:            "hi",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 2,
+	},
+	"it": {
+		tag:            "it",
+		decimal:        ",",
+		group:          ".",
+		minus:          "-",
+		prefix:         "",
+		suffix:         "\u00a0¤",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			JPY: "JPY",
+			USD: "USD",
+		},
+	},
+	"ja": {
+		tag:            "ja",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			CNY: "元",
+			JPY: "￥",
+			USD: "$",
+		},
+	},
+	"nl": {
+		tag:            "nl",
+		decimal:        ",",
+		group:          ".",
+		minus:          "-",
+		prefix:         "¤\u00a0",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			CAD: "C$",
+			USD: "US$",
+		},
+	},
+	"pt": {
+		tag:            "pt",
+		decimal:        ",",
+		group:          ".",
+		minus:          "-",
+		prefix:         "¤\u00a0",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			JPY: "JP¥",
+			USD: "US$",
+		},
+	},
+	"pt-pt": {
+		tag:            "pt-PT",
+		decimal:        ",",
+		group:          "\u00a0",
+		minus:          "-",
+		prefix:         "",
+		suffix:         "\u00a0¤",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			JPY: "JP¥",
+			USD: "US$",
+		},
+	},
+	"ru": {
+		tag:            "ru",
+		decimal:        ",",
+		group:          "\u00a0",
+		minus:          "-",
+		prefix:         "",
+		suffix:         "\u00a0¤",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			JPY: "¥",
+			RUB: "₽",
+			USD: "$",
+		},
+	},
+	"sv": {
+		tag:            "sv",
+		decimal:        ",",
+		group:          "\u00a0",
+		minus:          "−",
+		prefix:         "",
+		suffix:         "\u00a0¤",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			SEK: "kr",
+			USD: "US$",
+		},
+	},
+	"zh": {
+		tag:            "zh",
+		decimal:        ".",
+		group:          ",",
+		minus:          "-",
+		prefix:         "¤",
+		suffix:         "",
+		primaryGroup:   3,
+		secondaryGroup: 3,
+		symbols: map[Currency]string{
+			CNY: "￥",
+			USD: "US$",
+		},
+	},
+}
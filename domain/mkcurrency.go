@@ -7,38 +7,69 @@
 package main
 
 import (
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
 	"text/template"
+	"time"
 )
 
 var generatedTemplate = `// Copyright Steven Bosnick 2016. All rights reserved.
 // Use of this source code is governed by the GNU General Public License version 3.
 // See the file COPYING for your rights under that license.
 
-// This file is generated by mkcurrency.go from ISO4217-TABL-a1.xml.
+// This file is generated by mkcurrency.go from ISO4217-tabl-a1.xml,
+// ISO4217-tabl-a3.xml and cldr-numbers.json.
 // DO NOT EDIT THIS FILE DIRECTLY.
 // To regenerate this file run "go generate"
 
 package domain
 
+import "time"
+
 const (
-	XXX Currency = iota // The codes assigned for transactions where no currency is involved
-	{{- range $key, $value := .}}{{if (ne $key "XXX") }}
-	{{ $key }}		    // {{ $value.Name }}
+	{{- range $i, $currency := .}}
+	{{- if eq $i 0}}
+	{{ .Code }} Currency = iota // {{ .Name }}
+	{{- else}}
+	{{ .Code }}		    // {{ .Name }}{{if .Withdrawn}} (withdrawn {{ .Withdrawn }}){{end}}
 	{{- end}}
 	{{- end}}
 )
 
-var currencyStringsBlock string = "XXX
-	{{- range $key,$value := .}}
-	{{- if (ne $key "XXX")}}{{$key}}
-	{{- end}}
+var currencyStringsBlock string = "
+	{{- range .}}{{.Code}}
 	{{- end}}"
+
+var currencyInfos = [...]currencyInfo{
+	{{- range .}}
+	{{ .Code }}: {symbol: {{ printf "%q" .Symbol }}, name: {{ printf "%q" .Name }}, number: {{ .Number }}, minorUnits: {{ .MinorUnits }}, kind: {{ .Kind }}},
+	{{- end}}
+}
+
+var currencyWithdrawals = map[Currency]time.Time{
+	{{- range .}}{{if .Withdrawn}}
+	{{ .Code }}: time.Date({{ .Year }}, time.{{ .Month }}, 1, 0, 0, 0, 0, time.UTC),
+	{{- end}}{{end}}
+}
 `
 
+const (
+	currentFile   = "ISO4217-tabl-a1.xml"
+	historicFile  = "ISO4217-tabl-a3.xml"
+	cldrFile      = "cldr-numbers.json"
+	generatedFile = "currencylists.go"
+
+	// withdrawnMinorUnits are the minor units of a withdrawn currency,
+	// which the historic table doesn't give.
+	withdrawnMinorUnits = 2
+)
+
 type CurrencyName struct {
 	Name   string `xml:",chardata"`
 	IsFund bool   `xml:",attr"`
@@ -53,18 +84,79 @@ type CurrencyEntry struct {
 	CcyMnrUnts string
 }
 
+type HistoricCurrencyEntry struct {
+	XMLName   xml.Name `xml:"HstrcCcyNtry"`
+	CtryNm    string
+	CcyNm     CurrencyName
+	Ccy       string
+	CcyNbr    int
+	WthdrwlDt string
+}
+
 type ISO4217 struct {
-	XMLName xml.Name        `xml:"ISO_4217"`
-	Pblshd  string          `xml:",attr"`
-	CcyTbl  []CurrencyEntry `xml:"CcyTbl>CcyNtry"`
+	XMLName     xml.Name                `xml:"ISO_4217"`
+	Pblshd      string                  `xml:",attr"`
+	CcyTbl      []CurrencyEntry         `xml:"CcyTbl>CcyNtry"`
+	HstrcCcyTbl []HistoricCurrencyEntry `xml:"HstrcCcyTbl>HstrcCcyNtry"`
+}
+
+// CLDR is the part of cldr-numbers.json that gives the symbols that the
+// currencies have unless a locale gives another.
+type CLDR struct {
+	Locales struct {
+		Root struct {
+			Symbols map[string]string `json:"symbols"`
+		} `json:"root"`
+	} `json:"locales"`
 }
 
 type currencyInfo struct {
 	Name       string
 	Number     int
 	MinorUnits int
+	Kind       string
+	Withdrawn  time.Time
+}
+
+// generatedCurrency is a currency as the template for the generated file
+// uses it.
+type generatedCurrency struct {
+	Code       string
+	Name       string
+	Symbol     string
+	Number     int
+	MinorUnits int
+	Kind       string
+	Withdrawn  string
+	Year       int
+	Month      time.Month
+}
+
+// unitKinds are the kinds of the codes that are not for a country. The
+// tables give these codes a country name that starts with "ZZ" and a
+// number that identifies the kind of unit, such as "ZZ08_Gold".
+var unitKinds = map[string]string{
+	"ZZ01": "BondUnitCurrency",
+	"ZZ02": "BondUnitCurrency",
+	"ZZ03": "BondUnitCurrency",
+	"ZZ04": "BondUnitCurrency",
+	"ZZ06": "TestingCurrency",
+	"ZZ07": "NoCurrency",
+	"ZZ08": "MetalCurrency",
+	"ZZ09": "MetalCurrency",
+	"ZZ10": "MetalCurrency",
+	"ZZ11": "MetalCurrency",
 }
 
+// withdrawalPattern matches a year, or a year and a month, in the
+// withdrawal date of a historic entry. Some entries give a range of years
+// so the last match is the date of the withdrawal.
+var withdrawalPattern = regexp.MustCompile(`(\d{4})(?:-(\d{2}))?`)
+
+// generatedBlockPattern matches the string block in the previously
+// generated file.
+var generatedBlockPattern = regexp.MustCompile(`currencyStringsBlock string = "([A-Z]*)"`)
+
 func exitIfError(err error) {
 	if err != nil {
 		fmt.Println(err)
@@ -72,8 +164,8 @@ func exitIfError(err error) {
 	}
 }
 
-func readCurrencies() ISO4217 {
-	file, err := os.Open("ISO4217-tabl-a1.xml")
+func readCurrencies(name string) ISO4217 {
+	file, err := os.Open(name)
 	exitIfError(err)
 	defer file.Close()
 
@@ -84,32 +176,178 @@ func readCurrencies() ISO4217 {
 	return currencies
 }
 
-func mapCurrencies(currencies ISO4217) map[string]currencyInfo {
+// readSymbols reads the symbols of the root locale from the CLDR data.
+func readSymbols() map[string]string {
+	file, err := os.Open(cldrFile)
+	exitIfError(err)
+	defer file.Close()
+
+	var cldr CLDR
+	err = json.NewDecoder(file).Decode(&cldr)
+	exitIfError(err)
+
+	return cldr.Locales.Root.Symbols
+}
+
+// readGeneratedCodes reads the codes, in order, of the previously
+// generated file. Currency is an iota, so the codes must keep this order
+// for the values in databases that have not yet been migrated to store
+// currency codes to keep their meaning.
+func readGeneratedCodes() []string {
+	generated, err := ioutil.ReadFile(generatedFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	exitIfError(err)
+
+	match := generatedBlockPattern.FindSubmatch(generated)
+	if match == nil {
+		exitIfError(fmt.Errorf("%s has no currencyStringsBlock", generatedFile))
+	}
+
+	var codes []string
+	for i := 0; i+3 <= len(match[1]); i += 3 {
+		codes = append(codes, string(match[1][i:i+3]))
+	}
+
+	return codes
+}
+
+func parseWithdrawal(value string) (time.Time, error) {
+	matches := withdrawalPattern.FindAllStringSubmatch(value, -1)
+	if matches == nil {
+		return time.Time{}, fmt.Errorf("invalid withdrawal date: %s", value)
+	}
+
+	last := matches[len(matches)-1]
+	year, _ := strconv.Atoi(last[1])
+	month := 1
+	if last[2] != "" {
+		month, _ = strconv.Atoi(last[2])
+	}
+
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+// classify gives the kind of the currency of an entry of either table.
+func classify(country string, name CurrencyName) string {
+	if name.IsFund {
+		return "FundCurrency"
+	}
+	if len(country) >= 4 {
+		if kind, ok := unitKinds[country[:4]]; ok {
+			return kind
+		}
+	}
+
+	return "OrdinaryCurrency"
+}
+
+func mapCurrencies(current ISO4217, historic ISO4217) map[string]currencyInfo {
 	currencyMap := make(map[string]currencyInfo)
-	for _, entry := range currencies.CcyTbl {
-		if entry.Ccy == "" || entry.CcyNm.IsFund {
+	for _, entry := range current.CcyTbl {
+		if entry.Ccy == "" {
 			continue
 		}
 		minorUnits, err := strconv.Atoi(entry.CcyMnrUnts)
 		if err != nil {
 			minorUnits = 0
 		}
-		currencyMap[entry.Ccy] =
-			currencyInfo{entry.CcyNm.Name, entry.CcyNbr, minorUnits}
+		currencyMap[entry.Ccy] = currencyInfo{entry.CcyNm.Name, entry.CcyNbr, minorUnits,
+			classify(entry.CtryNm, entry.CcyNm), time.Time{}}
+	}
+
+	// a currency in the historic table is withdrawn only if no country
+	// still uses it; a currency that several countries withdrew was
+	// withdrawn when the last of them withdrew it
+	withdrawn := make(map[string]currencyInfo)
+	for _, entry := range historic.HstrcCcyTbl {
+		if _, ok := currencyMap[entry.Ccy]; ok || entry.Ccy == "" {
+			continue
+		}
+		date, err := parseWithdrawal(entry.WthdrwlDt)
+		exitIfError(err)
+		if info, ok := withdrawn[entry.Ccy]; ok && info.Withdrawn.After(date) {
+			continue
+		}
+		withdrawn[entry.Ccy] = currencyInfo{entry.CcyNm.Name, entry.CcyNbr, withdrawnMinorUnits,
+			classify(entry.CtryNm, entry.CcyNm), date}
+	}
+	for code, info := range withdrawn {
+		currencyMap[code] = info
 	}
 
 	return currencyMap
 }
 
+// orderCurrencies orders the currencies in currencyMap with XXX first,
+// then the previously generated codes in their order, and then the new
+// codes in alphabetical order. A previously generated code that neither
+// table has any longer is kept so that its value keeps its meaning. A
+// currency's symbol is its symbol in symbols or else its code.
+func orderCurrencies(generated []string, currencyMap map[string]currencyInfo,
+	symbols map[string]string) []generatedCurrency {
+	seen := map[string]bool{"XXX": true}
+	codes := []string{"XXX"}
+	for _, code := range generated {
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+
+	var added []string
+	for code := range currencyMap {
+		if !seen[code] {
+			added = append(added, code)
+		}
+	}
+	sort.Strings(added)
+	codes = append(codes, added...)
+
+	var currencies []generatedCurrency
+	for _, code := range codes {
+		info, ok := currencyMap[code]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s is no longer listed; keeping it\n", code)
+			info.Name = "No longer listed"
+			info.Kind = "OrdinaryCurrency"
+		}
+
+		symbol, ok := symbols[code]
+		if !ok {
+			symbol = code
+		}
+
+		currency := generatedCurrency{
+			Code:       code,
+			Name:       info.Name,
+			Symbol:     symbol,
+			Number:     info.Number,
+			MinorUnits: info.MinorUnits,
+			Kind:       info.Kind,
+		}
+		if !info.Withdrawn.IsZero() {
+			currency.Withdrawn = info.Withdrawn.Format("2006-01")
+			currency.Year = info.Withdrawn.Year()
+			currency.Month = info.Withdrawn.Month()
+		}
+		currencies = append(currencies, currency)
+	}
+
+	return currencies
+}
+
 func main() {
-	currencyMap := mapCurrencies(readCurrencies())
+	currencyMap := mapCurrencies(readCurrencies(currentFile), readCurrencies(historicFile))
+	currencies := orderCurrencies(readGeneratedCodes(), currencyMap, readSymbols())
 
 	t, err := template.New("generator").Parse(generatedTemplate)
 	exitIfError(err)
 
-	out, err := os.Create("currencylists.go")
+	out, err := os.Create(generatedFile)
 	exitIfError(err)
 	defer out.Close()
 
-	t.Execute(out, currencyMap)
+	exitIfError(t.Execute(out, currencies))
 }
@@ -0,0 +1,258 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+// +build ignore
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+var generatedTemplate = `// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+// This file is generated by mklocale.go from cldr-numbers.json.
+// DO NOT EDIT THIS FILE DIRECTLY.
+// To regenerate this file run "go generate"
+
+package domain
+
+// The locales are from version {{ .Version }} of the CLDR.
+var locales = map[string]*localeInfo{
+	{{- range .Locales}}
+	{{ printf "%q" .Key }}: {
+		tag:            {{ printf "%q" .Tag }},
+		decimal:        {{ printf "%q" .Decimal }},
+		group:          {{ printf "%q" .Group }},
+		minus:          {{ printf "%q" .Minus }},
+		prefix:         {{ printf "%q" .Prefix }},
+		suffix:         {{ printf "%q" .Suffix }},
+		primaryGroup:   {{ .PrimaryGroup }},
+		secondaryGroup: {{ .SecondaryGroup }},
+		{{- if .Symbols}}
+		symbols: map[Currency]string{
+			{{- range .Symbols}}
+			{{ .Code }}: {{ printf "%q" .Symbol }},
+			{{- end}}
+		},
+		{{- end}}
+	},
+	{{- end}}
+}
+`
+
+const (
+	cldrFile      = "cldr-numbers.json"
+	generatedFile = "localelists.go"
+	rootLocale    = "root"
+)
+
+// Locale is a locale of cldr-numbers.json. A locale without a Parent has
+// the parent given by removing its last subtag, or else the root locale,
+// and it has the value of its parent for each of its empty fields.
+type Locale struct {
+	Parent         string            `json:"parent"`
+	Decimal        string            `json:"decimal"`
+	Group          string            `json:"group"`
+	Minus          string            `json:"minus"`
+	CurrencyFormat string            `json:"currencyFormat"`
+	Symbols        map[string]string `json:"symbols"`
+}
+
+type CLDR struct {
+	Version string             `json:"version"`
+	Locales map[string]*Locale `json:"locales"`
+}
+
+type generatedSymbol struct {
+	Code   string
+	Symbol string
+}
+
+// generatedLocale is a locale as the template for the generated file uses
+// it. The symbols are those that differ from the symbols of the root
+// locale, which mkcurrency.go generates.
+type generatedLocale struct {
+	Key            string
+	Tag            string
+	Decimal        string
+	Group          string
+	Minus          string
+	Prefix         string
+	Suffix         string
+	PrimaryGroup   int
+	SecondaryGroup int
+	Symbols        []generatedSymbol
+}
+
+func exitIfError(err error) {
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func readCLDR() CLDR {
+	file, err := os.Open(cldrFile)
+	exitIfError(err)
+	defer file.Close()
+
+	var cldr CLDR
+	err = json.NewDecoder(file).Decode(&cldr)
+	exitIfError(err)
+
+	if cldr.Locales[rootLocale] == nil {
+		exitIfError(fmt.Errorf("%s has no %s locale", cldrFile, rootLocale))
+	}
+
+	return cldr
+}
+
+// parent gives the tag of the parent of the locale with tag.
+func parent(cldr CLDR, tag string) string {
+	if locale := cldr.Locales[tag]; locale != nil && locale.Parent != "" {
+		return locale.Parent
+	}
+	if i := strings.LastIndex(tag, "-"); i >= 0 {
+		return tag[:i]
+	}
+
+	return rootLocale
+}
+
+// resolve gives the locale with tag with the values that it inherits from
+// its parents. The symbols of the root locale are not inherited.
+func resolve(cldr CLDR, tag string) Locale {
+	resolved := Locale{Symbols: make(map[string]string)}
+	var chain []*Locale
+	for current := tag; ; current = parent(cldr, current) {
+		if locale := cldr.Locales[current]; locale != nil {
+			chain = append(chain, locale)
+		}
+		if current == rootLocale {
+			break
+		}
+	}
+
+	for i, locale := range chain {
+		if resolved.Decimal == "" {
+			resolved.Decimal = locale.Decimal
+		}
+		if resolved.Group == "" {
+			resolved.Group = locale.Group
+		}
+		if resolved.Minus == "" {
+			resolved.Minus = locale.Minus
+		}
+		if resolved.CurrencyFormat == "" {
+			resolved.CurrencyFormat = locale.CurrencyFormat
+		}
+		if i == len(chain)-1 {
+			break
+		}
+		for code, symbol := range locale.Symbols {
+			if _, ok := resolved.Symbols[code]; !ok {
+				resolved.Symbols[code] = symbol
+			}
+		}
+	}
+
+	return resolved
+}
+
+// parsePattern parses the positive part of a CLDR currency format pattern
+// such as "¤#,##0.00" into the text before and after the number and the
+// sizes of the groups of its integer digits. The fraction digits of the
+// pattern are ignored since those of the currency are used instead.
+func parsePattern(pattern string) (prefix string, suffix string, primary int, secondary int) {
+	if i := strings.Index(pattern, ";"); i >= 0 {
+		pattern = pattern[:i]
+	}
+
+	start := strings.IndexAny(pattern, "#0")
+	end := strings.LastIndexAny(pattern, "#0") + 1
+	if start < 0 {
+		exitIfError(fmt.Errorf("invalid currency format: %s", pattern))
+	}
+	prefix, suffix = pattern[:start], pattern[end:]
+
+	integer := pattern[start:end]
+	if i := strings.Index(integer, "."); i >= 0 {
+		integer = integer[:i]
+	}
+	groups := strings.Split(integer, ",")
+	if len(groups) > 1 {
+		primary = len(groups[len(groups)-1])
+		secondary = primary
+	}
+	if len(groups) > 2 {
+		secondary = len(groups[len(groups)-2])
+	}
+
+	return prefix, suffix, primary, secondary
+}
+
+func generateLocales(cldr CLDR) []generatedLocale {
+	var tags []string
+	for tag := range cldr.Locales {
+		if tag != rootLocale {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+
+	var locales []generatedLocale
+	for _, tag := range tags {
+		locale := resolve(cldr, tag)
+		prefix, suffix, primary, secondary := parsePattern(locale.CurrencyFormat)
+
+		var codes []string
+		for code := range locale.Symbols {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		var symbols []generatedSymbol
+		for _, code := range codes {
+			symbols = append(symbols, generatedSymbol{code, locale.Symbols[code]})
+		}
+
+		locales = append(locales, generatedLocale{
+			Key:            strings.ToLower(tag),
+			Tag:            tag,
+			Decimal:        locale.Decimal,
+			Group:          locale.Group,
+			Minus:          locale.Minus,
+			Prefix:         prefix,
+			Suffix:         suffix,
+			PrimaryGroup:   primary,
+			SecondaryGroup: secondary,
+			Symbols:        symbols,
+		})
+	}
+
+	return locales
+}
+
+func main() {
+	cldr := readCLDR()
+
+	t, err := template.New("generator").Parse(generatedTemplate)
+	exitIfError(err)
+
+	out, err := os.Create(generatedFile)
+	exitIfError(err)
+	defer out.Close()
+
+	exitIfError(t.Execute(out, struct {
+		Version string
+		Locales []generatedLocale
+	}{cldr.Version, generateLocales(cldr)}))
+}
@@ -0,0 +1,433 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/net/context"
+)
+
+const (
+	receiptEntity string = "receipt"
+)
+
+// A Receipt is an annual tax receipt for the gifts of a Donor to a Fund in
+// a tax Year. Receipts are numbered in the order that they are issued and
+// a Number is never used for another Receipt, even once the Receipt is
+// void. The DonorName and DonorAddress are those of the Donor when the
+// Receipt was issued, since that is what the Donor was sent.
+//
+// A Receipt that is void keeps its Number and has a VoidReason. A Receipt
+// that was reissued is void and has the id of the Receipt that replaces it
+// as its ReplacedById; the replacement has the id of the Receipt it
+// replaces as its ReplacesId. The ids are zero when there is no such
+// Receipt. VoidedBy and ReissuedBy are the names of the principals that
+// voided and reissued the Receipt, or empty if it wasn't. The Version of a Receipt changes every time it changes.
+type Receipt interface {
+	Id() uint
+	Number() uint
+	DonorId() uint
+	FundId() uint
+	Year() int
+	Amount() int64
+	IssuedOn() time.Time
+	DonorName() string
+	DonorAddress() string
+	IsVoid() bool
+	VoidReason() string
+	ReplacesId() uint
+	ReplacedById() uint
+	VoidedBy() string
+	ReissuedBy() string
+	Version() uint
+}
+
+type receiptImpl struct {
+	ID                  uint
+	ReceiptNumber       uint      `sql:"not null;unique_index"`
+	ReceiptDonorID      uint      `sql:"not null;index"`
+	ReceiptFundID       uint      `sql:"not null;index"`
+	ReceiptYear         int       `sql:"not null;index"`
+	ReceiptAmount       int64     `sql:"not null"`
+	ReceiptIssuedOn     time.Time `sql:"type:date;not null"`
+	ReceiptDonorName    string    `sql:"size:255"`
+	ReceiptDonorAddress string    `sql:"type:text"`
+	ReceiptVoid         bool      `sql:"not null;default:false"`
+	ReceiptVoidReason   string    `sql:"size:255"`
+	ReceiptReplacesID   *uint     `sql:"unique_index"`
+	ReceiptReplacedByID *uint
+	ReceiptVoidedBy     string `sql:"size:255"`
+	ReceiptReissuedBy   string `sql:"size:255"`
+	ReceiptVersion      uint   `sql:"not null;default:1"`
+}
+
+func (r *receiptImpl) Id() uint {
+	return r.ID
+}
+
+func (r *receiptImpl) Number() uint {
+	return r.ReceiptNumber
+}
+
+func (r *receiptImpl) DonorId() uint {
+	return r.ReceiptDonorID
+}
+
+func (r *receiptImpl) FundId() uint {
+	return r.ReceiptFundID
+}
+
+func (r *receiptImpl) Year() int {
+	return r.ReceiptYear
+}
+
+func (r *receiptImpl) Amount() int64 {
+	return r.ReceiptAmount
+}
+
+func (r *receiptImpl) IssuedOn() time.Time {
+	return r.ReceiptIssuedOn
+}
+
+func (r *receiptImpl) DonorName() string {
+	return r.ReceiptDonorName
+}
+
+func (r *receiptImpl) DonorAddress() string {
+	return r.ReceiptDonorAddress
+}
+
+func (r *receiptImpl) IsVoid() bool {
+	return r.ReceiptVoid
+}
+
+func (r *receiptImpl) VoidReason() string {
+	return r.ReceiptVoidReason
+}
+
+func (r *receiptImpl) ReplacesId() uint {
+	return idOrZero(r.ReceiptReplacesID)
+}
+
+func (r *receiptImpl) ReplacedById() uint {
+	return idOrZero(r.ReceiptReplacedByID)
+}
+
+func (r *receiptImpl) VoidedBy() string {
+	return r.ReceiptVoidedBy
+}
+
+func (r *receiptImpl) ReissuedBy() string {
+	return r.ReceiptReissuedBy
+}
+
+func (r *receiptImpl) Version() uint {
+	return r.ReceiptVersion
+}
+
+// The ReceiptRepository is the means of accessing the Receipt's in the
+// store. Issue issues a receipt, on the date given, for the gifts of each
+// donor to each fund in year that add up to more than zero and that do not
+// already have a receipt that isn't void. It returns the receipts that it
+// issued, which may be none.
+//
+// Void makes a receipt void for reason on behalf of voider and Reissue
+// makes it void, if it isn't already, and issues a replacement on the date
+// given for the gifts as they are now on behalf of reissuer. Get, Void and Reissue return a *NotFoundError if there
+// is no receipt with the given id. Void returns a *ValidationError if the
+// reason is empty and a *ConflictError if the receipt is already void.
+// Reissue returns a *ConflictError if the receipt has already been
+// replaced or if the gifts no longer add up to more than zero. Void and
+// Reissue only change the receipt if its version is the given version and
+// otherwise return a *ConcurrencyError.
+type ReceiptRepository interface {
+	GetAll(ctx context.Context) ([]Receipt, error)
+	GetByYear(ctx context.Context, year int) ([]Receipt, error)
+	Get(ctx context.Context, id uint) (Receipt, error)
+	Issue(ctx context.Context, year int, on time.Time) ([]Receipt, error)
+	Void(ctx context.Context, id uint, version uint, reason string, voider Principal) (Receipt, error)
+	Reissue(ctx context.Context, id uint, version uint, on time.Time, reissuer Principal) (Receipt, error)
+}
+
+type receiptRepository struct {
+	db *gorm.DB
+}
+
+func (r *receiptRepository) GetAll(ctx context.Context) ([]Receipt, error) {
+	return r.find(ctx, withContext(ctx, r.db))
+}
+
+func (r *receiptRepository) GetByYear(ctx context.Context, year int) ([]Receipt, error) {
+	return r.find(ctx, withContext(ctx, r.db).Where("receipt_year = ?", year))
+}
+
+func (r *receiptRepository) find(ctx context.Context, db *gorm.DB) ([]Receipt, error) {
+	var receipts []receiptImpl
+
+	err := db.Order("receipt_number").Find(&receipts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Receipt
+	for i := range receipts {
+		ret = append(ret, &receipts[i])
+	}
+
+	return ret, nil
+}
+
+func (r *receiptRepository) Get(ctx context.Context, id uint) (Receipt, error) {
+	var receipt receiptImpl
+
+	err := withContext(ctx, r.db).First(&receipt, id).Error
+	if isRecordNotFound(err) {
+		return nil, &NotFoundError{receiptEntity, formatId(id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &receipt, nil
+}
+
+func (r *receiptRepository) Issue(ctx context.Context, year int, on time.Time) ([]Receipt, error) {
+	var issued []Receipt
+	err := (&store{r.db}).InTransaction(ctx, func(tx Store) error {
+		issued = nil
+		repository := &receiptRepository{tx.(*store).db}
+
+		totals, err := repository.totals(ctx, year, 0, 0)
+		if err != nil {
+			return err
+		}
+
+		var current []receiptImpl
+		err = repository.db.Where("receipt_year = ? AND receipt_void = ?", year, false).Find(&current).Error
+		if err != nil {
+			return err
+		}
+		receipted := make(map[[2]uint]bool)
+		for _, receipt := range current {
+			receipted[[2]uint{receipt.ReceiptDonorID, receipt.ReceiptFundID}] = true
+		}
+
+		for _, total := range totals {
+			if total.Amount <= 0 || receipted[[2]uint{total.DonorId, total.FundId}] {
+				continue
+			}
+
+			receipt, err := repository.issue(ctx, total, on, nil)
+			if err != nil {
+				return err
+			}
+			issued = append(issued, receipt)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return issued, nil
+}
+
+func (r *receiptRepository) Void(ctx context.Context, id uint, version uint, reason string,
+	voider Principal) (Receipt, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, &ValidationError{receiptEntity, "reason", "must not be empty"}
+	}
+
+	var updated Receipt
+	err := (&store{r.db}).InTransaction(ctx, func(tx Store) error {
+		repository := &receiptRepository{tx.(*store).db}
+
+		receipt, err := repository.getForChange(ctx, id, version)
+		if err != nil {
+			return err
+		}
+		if receipt.IsVoid() {
+			return &ConflictError{receiptEntity, formatId(id), "the receipt is already void"}
+		}
+
+		err = repository.update(ctx, id, version, map[string]interface{}{
+			"receipt_void":        true,
+			"receipt_void_reason": reason,
+			"receipt_voided_by":   voider.Name,
+		})
+		if err != nil {
+			return err
+		}
+
+		updated, err = repository.Get(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (r *receiptRepository) Reissue(ctx context.Context, id uint, version uint, on time.Time,
+	reissuer Principal) (Receipt, error) {
+	var replacement Receipt
+	err := (&store{r.db}).InTransaction(ctx, func(tx Store) error {
+		repository := &receiptRepository{tx.(*store).db}
+
+		receipt, err := repository.getForChange(ctx, id, version)
+		if err != nil {
+			return err
+		}
+		if receipt.ReplacedById() != 0 {
+			return &ConflictError{receiptEntity, formatId(id), "the receipt has already been reissued"}
+		}
+
+		totals, err := repository.totals(ctx, receipt.Year(), receipt.DonorId(), receipt.FundId())
+		if err != nil {
+			return err
+		}
+		if len(totals) == 0 || totals[0].Amount <= 0 {
+			return &ConflictError{receiptEntity, formatId(id), "there are no longer any gifts to receipt"}
+		}
+
+		replacement, err = repository.issue(ctx, totals[0], on, &id)
+		if err != nil {
+			return err
+		}
+
+		values := map[string]interface{}{
+			"receipt_replaced_by_id": replacement.Id(),
+			"receipt_reissued_by":    reissuer.Name,
+		}
+		if !receipt.IsVoid() {
+			values["receipt_void"] = true
+			values["receipt_void_reason"] = "reissued as receipt " + formatId(replacement.Number())
+			values["receipt_voided_by"] = reissuer.Name
+		}
+
+		return repository.update(ctx, id, version, values)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return replacement, nil
+}
+
+// getForChange gets the receipt with the given id if its version is
+// version.
+func (r *receiptRepository) getForChange(ctx context.Context, id uint, version uint) (Receipt, error) {
+	receipt, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Version() != version {
+		return nil, &ConcurrencyError{receiptEntity, formatId(id)}
+	}
+
+	return receipt, nil
+}
+
+// update changes the receipt with the given id and version to have values
+// and the next version.
+func (r *receiptRepository) update(ctx context.Context, id uint, version uint, values map[string]interface{}) error {
+	values["receipt_version"] = version + 1
+	result := withContext(ctx, r.db).Model(&receiptImpl{}).
+		Where("id = ? AND receipt_version = ?", id, version).
+		Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &ConcurrencyError{receiptEntity, formatId(id)}
+	}
+
+	return nil
+}
+
+// totals gives the giving totals for year, limited to the donor and the
+// fund with the given ids unless they are zero. It must be called within a
+// transaction.
+func (r *receiptRepository) totals(ctx context.Context, year int, donorId uint, fundId uint) ([]GivingTotal, error) {
+	donors := &donorRepository{r.db}
+	postings := r.db.NewScope(&postingImpl{}).TableName()
+	entries := r.db.NewScope(&journalEntryImpl{}).TableName()
+
+	query := "YEAR(" + entries + ".entry_date) = ?"
+	args := []interface{}{year}
+	if donorId != 0 {
+		query += " AND " + postings + ".posting_donor_id = ?"
+		args = append(args, donorId)
+	}
+	if fundId != 0 {
+		query += " AND " + entries + ".entry_fund_id = ?"
+		args = append(args, fundId)
+	}
+
+	return donors.totals(ctx, query, args...)
+}
+
+// issue issues the receipt for total on the date given that replaces the
+// receipt with the id replaces, if it isn't nil. The receipt has the next
+// number. It must be called within a transaction.
+func (r *receiptRepository) issue(ctx context.Context, total GivingTotal, on time.Time,
+	replaces *uint) (*receiptImpl, error) {
+	donor, err := (&donorRepository{r.db}).Get(ctx, total.DonorId)
+	if err != nil {
+		return nil, err
+	}
+
+	number, err := r.nextNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var address string
+	if addresses := donor.Addresses(); len(addresses) > 0 {
+		address = addresses[0]
+	}
+
+	receipt := &receiptImpl{
+		ReceiptNumber:       number,
+		ReceiptDonorID:      total.DonorId,
+		ReceiptFundID:       total.FundId,
+		ReceiptYear:         total.Year,
+		ReceiptAmount:       total.Amount,
+		ReceiptIssuedOn:     toDate(on),
+		ReceiptDonorName:    donor.Name(),
+		ReceiptDonorAddress: address,
+		ReceiptReplacesID:   replaces,
+		ReceiptVersion:      1,
+	}
+
+	err = withContext(ctx, r.db).Create(receipt).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+// nextNumber gives the number after the highest number that has been used
+// for a receipt. Receipts are never deleted so no number is reused. The
+// locking read makes concurrent transactions that issue receipts wait for
+// each other, or deadlock and be tried again, instead of using the same
+// number. It must be called within a transaction.
+func (r *receiptRepository) nextNumber(ctx context.Context) (uint, error) {
+	var number uint
+	err := withContext(ctx, r.db).Raw("SELECT COALESCE(MAX(receipt_number), 0) FROM " +
+		r.db.NewScope(&receiptImpl{}).TableName() + " FOR UPDATE").Row().Scan(&number)
+	if err != nil {
+		return 0, err
+	}
+
+	return number + 1, nil
+}
@@ -0,0 +1,137 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+var receiptDate = date(2027, time.February, 15)
+
+// issueReceipts issues the receipts for 2026 in db.
+func issueReceipts(t *testing.T, db *gorm.DB) []Receipt {
+	receipts, err := (&receiptRepository{db}).Issue(context.Background(), 2026, receiptDate)
+	require.NoError(t, err, "Unable to issue the receipts.")
+	return receipts
+}
+
+func TestReceiptRepositoryIssueNumbersReceiptsForPostedGifts(t *testing.T) {
+	db := getGivingDb(t)
+	giveOnDate(t, db, 1, 5000, date(2026, time.March, 1), true)
+	giveOnDate(t, db, 1, 2500, date(2026, time.June, 1), true)
+	giveOnDate(t, db, 2, 2000, date(2026, time.April, 1), true)
+	giveOnDate(t, db, 2, 9000, date(2025, time.April, 1), true)
+	giveOnDate(t, db, 2, 1000, date(2026, time.May, 1), false)
+
+	actual := issueReceipts(t, db)
+
+	require.Len(t, actual, 2, "Unexpected number of receipts issued.")
+	assert.Equal(t, uint(1), actual[0].Number())
+	assert.Equal(t, uint(1), actual[0].DonorId())
+	assert.Equal(t, "Ada Lovelace", actual[0].DonorName())
+	assert.Equal(t, int64(7500), actual[0].Amount())
+	assert.Equal(t, receiptDate, actual[0].IssuedOn())
+	assert.Equal(t, uint(2), actual[1].Number())
+	assert.Equal(t, int64(2000), actual[1].Amount())
+}
+
+func TestReceiptRepositoryIssueTwiceIssuesNoMoreReceipts(t *testing.T) {
+	db := getGivingDb(t)
+	giveOnDate(t, db, 1, 5000, date(2026, time.March, 1), true)
+	issueReceipts(t, db)
+
+	actual := issueReceipts(t, db)
+
+	assert.Empty(t, actual, "Issue() issued a second receipt for the same gifts")
+}
+
+func TestReceiptRepositoryIssueAfterVoidUsesNewNumber(t *testing.T) {
+	db := getGivingDb(t)
+	giveOnDate(t, db, 1, 5000, date(2026, time.March, 1), true)
+	receipt := issueReceipts(t, db)[0]
+	sut := receiptRepository{db}
+	_, err := sut.Void(context.Background(), receipt.Id(), 1, "Sent to the wrong address", treasurer)
+	require.NoError(t, err, "Unable to void the receipt.")
+
+	actual := issueReceipts(t, db)
+
+	require.Len(t, actual, 1, "Unexpected number of receipts issued.")
+	assert.Equal(t, uint(2), actual[0].Number(), "The number of the void receipt was reused.")
+}
+
+func TestReceiptRepositoryVoidRecordsVoider(t *testing.T) {
+	db := getGivingDb(t)
+	giveOnDate(t, db, 1, 5000, date(2026, time.March, 1), true)
+	receipt := issueReceipts(t, db)[0]
+
+	sut := receiptRepository{db}
+	actual, err := sut.Void(context.Background(), receipt.Id(), 1, "Duplicate", clerk)
+
+	require.NoError(t, err, "Unable to void the receipt.")
+	assert.Equal(t, clerk.Name, actual.VoidedBy(), "The voider was not recorded.")
+	assert.Empty(t, actual.ReissuedBy(), "A receipt that was only voided has a reissuer.")
+}
+
+func TestReceiptRepositoryVoidTwiceIsConflictError(t *testing.T) {
+	db := getGivingDb(t)
+	giveOnDate(t, db, 1, 5000, date(2026, time.March, 1), true)
+	receipt := issueReceipts(t, db)[0]
+	sut := receiptRepository{db}
+	voided, err := sut.Void(context.Background(), receipt.Id(), 1, "Duplicate", treasurer)
+	require.NoError(t, err, "Unable to void the receipt.")
+
+	_, err = sut.Void(context.Background(), receipt.Id(), voided.Version(), "Duplicate", treasurer)
+
+	assert.IsType(t, &ConflictError{}, err, "Void() returned an unexpected type of error")
+}
+
+func TestReceiptRepositoryVoidWithoutReasonIsValidationError(t *testing.T) {
+	db := getGivingDb(t)
+
+	sut := receiptRepository{db}
+	_, err := sut.Void(context.Background(), 1, 1, " ", treasurer)
+
+	assert.IsType(t, &ValidationError{}, err, "Void() returned an unexpected type of error")
+}
+
+func TestReceiptRepositoryReissueReplacesReceiptWithCurrentGifts(t *testing.T) {
+	db := getGivingDb(t)
+	giveOnDate(t, db, 1, 5000, date(2026, time.March, 1), true)
+	receipt := issueReceipts(t, db)[0]
+	giveOnDate(t, db, 1, 2500, date(2026, time.December, 31), true)
+
+	sut := receiptRepository{db}
+	actual, err := sut.Reissue(context.Background(), receipt.Id(), 1, date(2027, time.March, 1), treasurer)
+
+	require.NoError(t, err, "Unable to reissue the receipt.")
+	assert.Equal(t, uint(2), actual.Number())
+	assert.Equal(t, int64(7500), actual.Amount())
+	assert.Equal(t, receipt.Id(), actual.ReplacesId())
+	original, err := sut.Get(context.Background(), receipt.Id())
+	require.NoError(t, err, "Unable to get the original receipt.")
+	assert.True(t, original.IsVoid(), "The original receipt is not void.")
+	assert.Equal(t, actual.Id(), original.ReplacedById())
+	assert.Equal(t, treasurer.Name, original.ReissuedBy(), "The reissuer was not recorded.")
+	assert.Equal(t, treasurer.Name, original.VoidedBy(), "The voider was not recorded.")
+}
+
+func TestReceiptRepositoryReissueTwiceIsConflictError(t *testing.T) {
+	db := getGivingDb(t)
+	giveOnDate(t, db, 1, 5000, date(2026, time.March, 1), true)
+	receipt := issueReceipts(t, db)[0]
+	sut := receiptRepository{db}
+	_, err := sut.Reissue(context.Background(), receipt.Id(), 1, receiptDate, treasurer)
+	require.NoError(t, err, "Unable to reissue the receipt.")
+
+	_, err = sut.Reissue(context.Background(), receipt.Id(), 2, receiptDate, treasurer)
+
+	assert.IsType(t, &ConflictError{}, err, "Reissue() returned an unexpected type of error")
+}
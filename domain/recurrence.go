@@ -0,0 +1,566 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Recurrence is a rule for the dates on which something repeats. It is
+// written in a subset of the RRULE syntax of iCalendar (RFC 5545), for
+// example "FREQ=MONTHLY;BYMONTHDAY=1" (monthly on the 1st),
+// "FREQ=WEEKLY;INTERVAL=2" (every two weeks) or
+// "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1" (the last business day of
+// each month).
+//
+// The supported rule parts are FREQ (DAILY, WEEKLY, MONTHLY or YEARLY),
+// INTERVAL, COUNT, UNTIL, BYMONTH, BYMONTHDAY, BYDAY and BYSETPOS. Weeks
+// start on Monday. A BYDAY with an ordinal (such as -1FR) is only allowed
+// in a MONTHLY or YEARLY rule and in a YEARLY rule it and a BYMONTHDAY pick
+// days within the months of the BYMONTH part, which is then required.
+//
+// A Recurrence only deals in dates; the time of day of a date given to its
+// methods is ignored.
+type Recurrence struct {
+	frequency  frequency
+	interval   int
+	count      int
+	until      time.Time
+	byMonth    []time.Month
+	byMonthDay []int
+	byDay      []weekdayNum
+	bySetPos   []int
+}
+
+type frequency int
+
+const (
+	daily frequency = iota + 1
+	weekly
+	monthly
+	yearly
+)
+
+var frequencyNames = []string{"", "DAILY", "WEEKLY", "MONTHLY", "YEARLY"}
+
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// A weekdayNum is a day of the week with an optional ordinal: the nth such
+// day of the month, counting from the end of the month if n is negative.
+type weekdayNum struct {
+	n   int
+	day time.Weekday
+}
+
+// maxEmptyPeriods is the number of consecutive periods without an
+// occurrence after which a recurrence is taken to have ended. It is enough
+// for a daily rule restricted to the 29th of February.
+const maxEmptyPeriods = 4000
+
+// An InvalidRecurrenceError is returned when a rule is not a recurrence
+// rule that is supported.
+type InvalidRecurrenceError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *InvalidRecurrenceError) Error() string {
+	return fmt.Sprintf("Invalid recurrence rule %s: %s.", e.Rule, e.Reason)
+}
+
+// ParseRecurrence parses a recurrence rule. The rule may have an "RRULE:"
+// prefix and its rule parts may be in any order.
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return &InvalidRecurrenceError{rule, fmt.Sprintf(format, args...)}
+	}
+
+	body := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	if body == "" {
+		return nil, invalid("the rule is empty")
+	}
+
+	r := &Recurrence{interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(body, ";") {
+		nameValue := strings.SplitN(part, "=", 2)
+		if len(nameValue) != 2 || nameValue[1] == "" {
+			return nil, invalid("%s is not a NAME=VALUE rule part", part)
+		}
+		name, value := nameValue[0], nameValue[1]
+		if seen[name] {
+			return nil, invalid("%s is given more than once", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			r.frequency = frequency(indexOf(frequencyNames[1:], value) + 1)
+			if r.frequency == 0 {
+				return nil, invalid("FREQ must be DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+		case "INTERVAL":
+			r.interval, err = parseRulePart(value, 1, 1000)
+		case "COUNT":
+			r.count, err = parseRulePart(value, 1, 10000)
+		case "UNTIL":
+			r.until, err = parseUntil(value)
+		case "BYMONTH":
+			err = parseRuleList(value, func(item string) error {
+				month, err := parseRulePart(item, 1, 12)
+				r.byMonth = append(r.byMonth, time.Month(month))
+				return err
+			})
+		case "BYMONTHDAY":
+			err = parseRuleList(value, func(item string) error {
+				day, err := parseRulePart(item, -31, 31)
+				if day == 0 {
+					err = fmt.Errorf("%s is not a day of the month", item)
+				}
+				r.byMonthDay = append(r.byMonthDay, day)
+				return err
+			})
+		case "BYDAY":
+			err = parseRuleList(value, func(item string) error {
+				day, err := parseWeekdayNum(item)
+				r.byDay = append(r.byDay, day)
+				return err
+			})
+		case "BYSETPOS":
+			err = parseRuleList(value, func(item string) error {
+				pos, err := parseRulePart(item, -366, 366)
+				if pos == 0 {
+					err = fmt.Errorf("%s is not a position", item)
+				}
+				r.bySetPos = append(r.bySetPos, pos)
+				return err
+			})
+		default:
+			return nil, invalid("%s is not supported", name)
+		}
+		if err != nil {
+			return nil, invalid("%s %s", name, err.Error())
+		}
+	}
+
+	if reason := r.check(); reason != "" {
+		return nil, invalid("%s", reason)
+	}
+
+	return r, nil
+}
+
+// check gives the reason that the combination of rule parts in r is not
+// supported or "" if it is.
+func (r *Recurrence) check() string {
+	if r.frequency == 0 {
+		return "FREQ is required"
+	}
+	if r.count != 0 && !r.until.IsZero() {
+		return "COUNT and UNTIL can't both be given"
+	}
+	if len(r.bySetPos) > 0 && len(r.byMonth) == 0 && len(r.byMonthDay) == 0 && len(r.byDay) == 0 {
+		return "BYSETPOS requires BYMONTH, BYMONTHDAY or BYDAY"
+	}
+	if r.frequency == weekly && len(r.byMonthDay) > 0 {
+		return "BYMONTHDAY can't be used in a WEEKLY rule"
+	}
+	if r.frequency == yearly && len(r.byMonth) == 0 && (len(r.byMonthDay) > 0 || len(r.byDay) > 0) {
+		return "BYMONTHDAY and BYDAY in a YEARLY rule require BYMONTH"
+	}
+	for _, day := range r.byDay {
+		if day.n != 0 && (r.frequency == daily || r.frequency == weekly) {
+			return "BYDAY can only have an ordinal in a MONTHLY or YEARLY rule"
+		}
+	}
+
+	return ""
+}
+
+func parseRulePart(value string, min int, max int) (int, error) {
+	parsed, err := strconv.Atoi(strings.TrimPrefix(value, "+"))
+	if err != nil || parsed < min || parsed > max {
+		return 0, fmt.Errorf("must be a number from %d to %d", min, max)
+	}
+
+	return parsed, nil
+}
+
+func parseRuleList(value string, parse func(item string) error) error {
+	for _, item := range strings.Split(value, ",") {
+		if err := parse(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseUntil parses the value of an UNTIL rule part, which is a date or a
+// date and time of which only the date is used.
+func parseUntil(value string) (time.Time, error) {
+	if len(value) > 8 && value[8] == 'T' {
+		value = value[:8]
+	}
+
+	until, err := time.Parse("20060102", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be a date such as 20261231")
+	}
+
+	return until, nil
+}
+
+func parseWeekdayNum(value string) (weekdayNum, error) {
+	if len(value) < 2 {
+		return weekdayNum{}, fmt.Errorf("%s is not a day of the week", value)
+	}
+
+	split := len(value) - 2
+	day := indexOf(weekdayNames, value[split:])
+	if day < 0 {
+		return weekdayNum{}, fmt.Errorf("%s is not a day of the week", value)
+	}
+
+	var n int
+	if split > 0 {
+		var err error
+		n, err = parseRulePart(value[:split], -5, 5)
+		if err != nil || n == 0 {
+			return weekdayNum{}, fmt.Errorf("%s has an ordinal that is not from -5 to 5", value)
+		}
+	}
+
+	return weekdayNum{n, time.Weekday(day)}, nil
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// String gives the rule for the recurrence with its rule parts in a fixed
+// order.
+func (r *Recurrence) String() string {
+	parts := []string{"FREQ=" + frequencyNames[r.frequency]}
+	if r.interval != 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.interval))
+	}
+	if r.count != 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.count))
+	}
+	if !r.until.IsZero() {
+		parts = append(parts, "UNTIL="+r.until.Format("20060102"))
+	}
+	if len(r.byMonth) > 0 {
+		var months []string
+		for _, month := range r.byMonth {
+			months = append(months, strconv.Itoa(int(month)))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.byMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.byMonthDay))
+	}
+	if len(r.byDay) > 0 {
+		var days []string
+		for _, day := range r.byDay {
+			prefix := ""
+			if day.n != 0 {
+				prefix = strconv.Itoa(day.n)
+			}
+			days = append(days, prefix+weekdayNames[day.day])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.bySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.bySetPos))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func joinInts(values []int) string {
+	var items []string
+	for _, value := range values {
+		items = append(items, strconv.Itoa(value))
+	}
+
+	return strings.Join(items, ",")
+}
+
+// IsRecurrence validates a string as a recurrence rule.
+func IsRecurrence(value string) bool {
+	_, err := ParseRecurrence(value)
+	return err == nil
+}
+
+// Next gives the first n occurrences after the date after of the
+// recurrence that starts on the date start. There are fewer than n if the
+// recurrence ends first.
+func (r *Recurrence) Next(start time.Time, after time.Time, n int) []time.Time {
+	after = toDate(after)
+	var dates []time.Time
+	if n <= 0 {
+		return dates
+	}
+
+	r.each(start, func(date time.Time) bool {
+		if date.After(after) {
+			dates = append(dates, date)
+		}
+		return len(dates) < n
+	})
+
+	return dates
+}
+
+// Between gives the occurrences of the recurrence that starts on the date
+// start that are after the date after and on or before the date through.
+func (r *Recurrence) Between(start time.Time, after time.Time, through time.Time) []time.Time {
+	after, through = toDate(after), toDate(through)
+	var dates []time.Time
+
+	r.each(start, func(date time.Time) bool {
+		if date.After(through) {
+			return false
+		}
+		if date.After(after) {
+			dates = append(dates, date)
+		}
+		return true
+	})
+
+	return dates
+}
+
+// each calls fn with each occurrence of the recurrence that starts on start
+// in order until fn returns false or the recurrence ends.
+func (r *Recurrence) each(start time.Time, fn func(date time.Time) bool) {
+	start = toDate(start)
+	occurrences := 0
+	empty := 0
+
+	for period := 0; empty < maxEmptyPeriods; period++ {
+		dates := r.setPositions(r.expand(start, period))
+		if len(dates) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+
+		for _, date := range dates {
+			if date.Before(start) {
+				continue
+			}
+			if !r.until.IsZero() && date.After(r.until) {
+				return
+			}
+			if !fn(date) {
+				return
+			}
+			occurrences++
+			if occurrences == r.count {
+				return
+			}
+		}
+	}
+}
+
+// expand gives the candidate dates, in order, of the period of the
+// recurrence that is the given number of intervals after the period
+// containing start.
+func (r *Recurrence) expand(start time.Time, period int) []time.Time {
+	offset := period * r.interval
+
+	switch r.frequency {
+	case daily:
+		date := start.AddDate(0, 0, offset)
+		if r.matchesMonth(date.Month()) && r.matchesMonthDay(date) && r.matchesWeekday(date.Weekday()) {
+			return []time.Time{date}
+		}
+		return nil
+	case weekly:
+		monday := start.AddDate(0, 0, -weekdayOffset(start.Weekday())+7*offset)
+		weekdays := []time.Weekday{start.Weekday()}
+		if len(r.byDay) > 0 {
+			weekdays = nil
+			for _, day := range r.byDay {
+				weekdays = append(weekdays, day.day)
+			}
+		}
+
+		var dates []time.Time
+		for _, weekday := range weekdays {
+			date := monday.AddDate(0, 0, weekdayOffset(weekday))
+			if r.matchesMonth(date.Month()) {
+				dates = append(dates, date)
+			}
+		}
+		return sortDates(dates)
+	case monthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+		if !r.matchesMonth(first.Month()) {
+			return nil
+		}
+		return r.monthDates(first, start.Day())
+	default:
+		months := r.byMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+
+		var dates []time.Time
+		for _, month := range months {
+			first := time.Date(start.Year()+offset, month, 1, 0, 0, 0, 0, time.UTC)
+			dates = append(dates, r.monthDates(first, start.Day())...)
+		}
+		return sortDates(dates)
+	}
+}
+
+// monthDates gives the dates, in order, within the month starting on
+// first that match the BYMONTHDAY and BYDAY rule parts, or the date with
+// the day of the month day if there are no such rule parts.
+func (r *Recurrence) monthDates(first time.Time, day int) []time.Time {
+	last := first.AddDate(0, 1, -1).Day()
+	if len(r.byMonthDay) == 0 && len(r.byDay) == 0 {
+		if day > last {
+			return nil
+		}
+		return []time.Time{first.AddDate(0, 0, day-1)}
+	}
+
+	var dates []time.Time
+	for d := 1; d <= last; d++ {
+		date := first.AddDate(0, 0, d-1)
+		if r.matchesMonthDay(date) && r.matchesWeekdayNum(date, last) {
+			dates = append(dates, date)
+		}
+	}
+
+	return dates
+}
+
+func (r *Recurrence) matchesMonth(month time.Month) bool {
+	if len(r.byMonth) == 0 {
+		return true
+	}
+
+	for _, m := range r.byMonth {
+		if m == month {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Recurrence) matchesMonthDay(date time.Time) bool {
+	if len(r.byMonthDay) == 0 {
+		return true
+	}
+
+	last := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, day := range r.byMonthDay {
+		if day == date.Day() || day == date.Day()-last-1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Recurrence) matchesWeekday(weekday time.Weekday) bool {
+	if len(r.byDay) == 0 {
+		return true
+	}
+
+	for _, day := range r.byDay {
+		if day.day == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesWeekdayNum reports whether date matches the BYDAY rule part
+// taking the ordinals within its month, which has last days, into account.
+func (r *Recurrence) matchesWeekdayNum(date time.Time, last int) bool {
+	if len(r.byDay) == 0 {
+		return true
+	}
+
+	fromStart := (date.Day()-1)/7 + 1
+	fromEnd := -((last-date.Day())/7 + 1)
+	for _, day := range r.byDay {
+		if day.day == date.Weekday() && (day.n == 0 || day.n == fromStart || day.n == fromEnd) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setPositions applies the BYSETPOS rule part to the ordered dates of one
+// period.
+func (r *Recurrence) setPositions(dates []time.Time) []time.Time {
+	if len(r.bySetPos) == 0 {
+		return dates
+	}
+
+	var selected []time.Time
+	for _, pos := range r.bySetPos {
+		index := pos - 1
+		if pos < 0 {
+			index = len(dates) + pos
+		}
+		if index >= 0 && index < len(dates) && !containsDate(selected, dates[index]) {
+			selected = append(selected, dates[index])
+		}
+	}
+
+	return sortDates(selected)
+}
+
+func containsDate(dates []time.Time, date time.Time) bool {
+	for _, d := range dates {
+		if d.Equal(date) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortDates(dates []time.Time) []time.Time {
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// weekdayOffset gives the number of days from Monday to weekday.
+func weekdayOffset(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+// toDate gives the date of t, in t's location, as midnight UTC.
+func toDate(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
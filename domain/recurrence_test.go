@@ -0,0 +1,157 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func parseRecurrence(t *testing.T, rule string) *Recurrence {
+	recurrence, err := ParseRecurrence(rule)
+	require.NoError(t, err, "Unable to parse the recurrence rule %s.", rule)
+	return recurrence
+}
+
+func TestRecurrenceMonthlyOnTheFirst(t *testing.T) {
+	sut := parseRecurrence(t, "FREQ=MONTHLY;BYMONTHDAY=1")
+
+	actual := sut.Next(date(2026, time.October, 15), time.Time{}, 3)
+
+	assert.Equal(t, []time.Time{date(2026, time.November, 1), date(2026, time.December, 1),
+		date(2027, time.January, 1)}, actual)
+}
+
+func TestRecurrenceEveryTwoWeeks(t *testing.T) {
+	sut := parseRecurrence(t, "FREQ=WEEKLY;INTERVAL=2")
+
+	actual := sut.Next(date(2026, time.October, 16), time.Time{}, 3)
+
+	assert.Equal(t, []time.Time{date(2026, time.October, 16), date(2026, time.October, 30),
+		date(2026, time.November, 13)}, actual)
+}
+
+func TestRecurrenceLastBusinessDay(t *testing.T) {
+	sut := parseRecurrence(t, "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1")
+
+	actual := sut.Next(date(2026, time.October, 1), time.Time{}, 3)
+
+	assert.Equal(t, []time.Time{date(2026, time.October, 30), date(2026, time.November, 30),
+		date(2026, time.December, 31)}, actual)
+}
+
+func TestRecurrenceLastDayOfMonth(t *testing.T) {
+	sut := parseRecurrence(t, "FREQ=MONTHLY;BYMONTHDAY=-1")
+
+	actual := sut.Next(date(2028, time.January, 1), time.Time{}, 2)
+
+	assert.Equal(t, []time.Time{date(2028, time.January, 31), date(2028, time.February, 29)}, actual)
+}
+
+func TestRecurrenceMonthlySkipsMonthsWithoutTheStartDay(t *testing.T) {
+	sut := parseRecurrence(t, "FREQ=MONTHLY")
+
+	actual := sut.Next(date(2026, time.January, 31), time.Time{}, 3)
+
+	assert.Equal(t, []time.Time{date(2026, time.January, 31), date(2026, time.March, 31),
+		date(2026, time.May, 31)}, actual)
+}
+
+func TestRecurrenceYearlyWithOrdinalWeekday(t *testing.T) {
+	sut := parseRecurrence(t, "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH")
+
+	actual := sut.Next(date(2026, time.January, 1), time.Time{}, 2)
+
+	assert.Equal(t, []time.Time{date(2026, time.November, 26), date(2027, time.November, 25)}, actual)
+}
+
+func TestRecurrenceDailyOnWeekdays(t *testing.T) {
+	sut := parseRecurrence(t, "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR")
+
+	actual := sut.Next(date(2026, time.October, 16), time.Time{}, 2)
+
+	assert.Equal(t, []time.Time{date(2026, time.October, 16), date(2026, time.October, 19)}, actual)
+}
+
+func TestRecurrenceCountLimitsOccurrences(t *testing.T) {
+	sut := parseRecurrence(t, "FREQ=MONTHLY;COUNT=2")
+
+	actual := sut.Next(date(2026, time.October, 1), time.Time{}, 5)
+
+	assert.Equal(t, []time.Time{date(2026, time.October, 1), date(2026, time.November, 1)}, actual)
+}
+
+func TestRecurrenceUntilLimitsOccurrences(t *testing.T) {
+	sut := parseRecurrence(t, "FREQ=MONTHLY;UNTIL=20261115T000000Z")
+
+	actual := sut.Next(date(2026, time.October, 1), time.Time{}, 5)
+
+	assert.Equal(t, []time.Time{date(2026, time.October, 1), date(2026, time.November, 1)}, actual)
+}
+
+func TestRecurrenceNextIsAfterTheGivenDate(t *testing.T) {
+	sut := parseRecurrence(t, "FREQ=MONTHLY;BYMONTHDAY=1")
+
+	actual := sut.Next(date(2026, time.January, 1), time.Date(2026, time.March, 1, 13, 0, 0, 0, time.UTC), 1)
+
+	assert.Equal(t, []time.Time{date(2026, time.April, 1)}, actual)
+}
+
+func TestRecurrenceBetweenIncludesThroughDate(t *testing.T) {
+	sut := parseRecurrence(t, "FREQ=MONTHLY;BYMONTHDAY=1")
+
+	actual := sut.Between(date(2026, time.January, 1), date(2026, time.January, 1), date(2026, time.March, 1))
+
+	assert.Equal(t, []time.Time{date(2026, time.February, 1), date(2026, time.March, 1)}, actual)
+}
+
+func TestRecurrenceWithNoOccurrencesEnds(t *testing.T) {
+	sut := parseRecurrence(t, "FREQ=MONTHLY;BYMONTH=2;BYMONTHDAY=30")
+
+	actual := sut.Next(date(2026, time.January, 1), time.Time{}, 1)
+
+	assert.Empty(t, actual, "Unexpected occurrences.")
+}
+
+func TestRecurrenceStringIsCanonical(t *testing.T) {
+	sut := parseRecurrence(t, "rrule:bysetpos=-1;byday=mo,tu,we,th,fr;freq=monthly")
+
+	assert.Equal(t, "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", sut.String())
+}
+
+func TestParseRecurrenceWithBadInputIsError(t *testing.T) {
+	badinput := []string{
+		"",
+		"BYMONTHDAY=1",
+		"FREQ=HOURLY",
+		"FREQ=MONTHLY;FREQ=WEEKLY",
+		"FREQ=MONTHLY;INTERVAL=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=YEARLY;BYDAY=MO",
+		"FREQ=MONTHLY;BYSETPOS=1",
+		"FREQ=MONTHLY;COUNT=2;UNTIL=20261231",
+		"FREQ=MONTHLY;UNTIL=tomorrow",
+		"FREQ=MONTHLY;BYHOUR=9",
+		"FREQ",
+	}
+
+	for _, input := range badinput {
+		_, err := ParseRecurrence(input)
+		assert.IsType(t, &InvalidRecurrenceError{}, err, "Unexpected error for %q.", input)
+		assert.False(t, IsRecurrence(input))
+	}
+}
@@ -0,0 +1,388 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/net/context"
+)
+
+const (
+	scheduleEntity string = "schedule"
+)
+
+// SchedulerPrincipal is the name of the principal that submits the entries
+// made by schedules.
+const SchedulerPrincipal = "scheduler"
+
+// A Schedule is a template for a JournalEntry that recurs, such as rent or
+// payroll. Its entries are made in its Fund with its Description and
+// Postings on the dates given by its recurrence Rule (see Recurrence),
+// starting on its Start date. The Scheduler materializes the occurrences
+// of a Schedule as entries once they are due.
+//
+// Through is the date through which the occurrences of a Schedule have
+// been materialized or skipped, or the zero time if there are none. A
+// paused Schedule has no entries made for it and the occurrences that
+// fall while it is paused are skipped when it is resumed. The Version of a
+// Schedule changes every time the Schedule is paused or resumed but not
+// when one of its occurrences is materialized.
+type Schedule interface {
+	Id() uint
+	FundId() uint
+	Description() string
+	Rule() string
+	Start() time.Time
+	Postings() []Posting
+	IsPaused() bool
+	Through() time.Time
+	Version() uint
+}
+
+type scheduleImpl struct {
+	ID                  uint
+	ScheduleFundID      uint       `sql:"not null;index"`
+	ScheduleDescription string     `sql:"size:255"`
+	ScheduleRule        string     `sql:"size:255"`
+	ScheduleStart       time.Time  `sql:"type:date;not null"`
+	SchedulePostings    string     `sql:"type:text"`
+	SchedulePaused      bool       `sql:"not null;default:false"`
+	ScheduleThrough     *time.Time `sql:"type:date"`
+	ScheduleVersion     uint       `sql:"not null;default:1"`
+}
+
+func (s *scheduleImpl) Id() uint {
+	return s.ID
+}
+
+func (s *scheduleImpl) FundId() uint {
+	return s.ScheduleFundID
+}
+
+func (s *scheduleImpl) Description() string {
+	return s.ScheduleDescription
+}
+
+func (s *scheduleImpl) Rule() string {
+	return s.ScheduleRule
+}
+
+func (s *scheduleImpl) Start() time.Time {
+	return s.ScheduleStart
+}
+
+// Postings gives the postings of the schedule, which are stored as JSON.
+// Only Create stores them so they can always be decoded.
+func (s *scheduleImpl) Postings() []Posting {
+	var postings []Posting
+	json.Unmarshal([]byte(s.SchedulePostings), &postings)
+	return postings
+}
+
+func (s *scheduleImpl) IsPaused() bool {
+	return s.SchedulePaused
+}
+
+func (s *scheduleImpl) Through() time.Time {
+	if s.ScheduleThrough == nil {
+		return time.Time{}
+	}
+
+	return *s.ScheduleThrough
+}
+
+func (s *scheduleImpl) Version() uint {
+	return s.ScheduleVersion
+}
+
+// NextOccurrences gives the next n occurrences of schedule that are still
+// to be materialized as of the time now. For a paused schedule they are
+// the occurrences that would be materialized if it were resumed now.
+func NextOccurrences(schedule Schedule, now time.Time, n int) ([]time.Time, error) {
+	recurrence, err := ParseRecurrence(schedule.Rule())
+	if err != nil {
+		return nil, err
+	}
+
+	return recurrence.Next(schedule.Start(), pendingAfter(schedule, now), n), nil
+}
+
+// pendingAfter gives the date after which the occurrences of schedule are
+// still to be materialized as of the time now.
+func pendingAfter(schedule Schedule, now time.Time) time.Time {
+	after := schedule.Through()
+	if schedule.IsPaused() {
+		yesterday := toDate(now).AddDate(0, 0, -1)
+		if yesterday.After(after) {
+			after = yesterday
+		}
+	}
+
+	return after
+}
+
+// The ScheduleRepository is the means of accessing the Schedule's in the
+// store. GetActive gives the schedules that aren't paused.
+//
+// Create returns a *ValidationError if the description is empty, the rule
+// is not a supported Recurrence, the start date is zero or the postings
+// are not valid for a JournalEntry of the fund. It returns a
+// *NotFoundError if there is no fund with the given id and a
+// *ConflictError if the fund is archived.
+//
+// Resume skips the occurrences before the date of on. Pause returns a
+// *ConflictError if the schedule is paused and Resume returns one if it
+// isn't. Get, Delete, Pause, Resume and Materialize return a
+// *NotFoundError if there is no schedule with the given id. Delete, Pause
+// and Resume only change the schedule if its version is the given version
+// and otherwise return a *ConcurrencyError. The entries made by a deleted
+// schedule are kept.
+//
+// Materialize makes the JournalEntry for the occurrence of the schedule on
+// the date of occurrence. It returns a *ValidationError if the schedule
+// doesn't occur on that date and a *ConflictError if the schedule is
+// paused, its fund is archived or the occurrence is on or before its
+// Through date. It returns a *DuplicateError if the entry for the
+// occurrence has already been made. The entry is submitted on behalf of
+// SchedulerPrincipal so it is Approved unless an ApprovalRule of the fund
+// applies to it.
+type ScheduleRepository interface {
+	GetAll(ctx context.Context) ([]Schedule, error)
+	GetActive(ctx context.Context) ([]Schedule, error)
+	Get(ctx context.Context, id uint) (Schedule, error)
+	Create(ctx context.Context, fundId uint, description string, rule string, start time.Time,
+		postings []Posting) (Schedule, error)
+	Delete(ctx context.Context, id uint, version uint) error
+	Pause(ctx context.Context, id uint, version uint) (Schedule, error)
+	Resume(ctx context.Context, id uint, version uint, on time.Time) (Schedule, error)
+	Materialize(ctx context.Context, id uint, occurrence time.Time) (JournalEntry, error)
+}
+
+type scheduleRepository struct {
+	db *gorm.DB
+}
+
+func (s *scheduleRepository) GetAll(ctx context.Context) ([]Schedule, error) {
+	return s.find(ctx, withContext(ctx, s.db))
+}
+
+func (s *scheduleRepository) GetActive(ctx context.Context) ([]Schedule, error) {
+	return s.find(ctx, withContext(ctx, s.db).Where("schedule_paused = ?", false))
+}
+
+func (s *scheduleRepository) find(ctx context.Context, db *gorm.DB) ([]Schedule, error) {
+	var schedules []scheduleImpl
+
+	err := db.Order("id").Find(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Schedule
+	for i := range schedules {
+		ret = append(ret, &schedules[i])
+	}
+
+	return ret, nil
+}
+
+func (s *scheduleRepository) Get(ctx context.Context, id uint) (Schedule, error) {
+	var schedule scheduleImpl
+
+	err := withContext(ctx, s.db).First(&schedule, id).Error
+	if isRecordNotFound(err) {
+		return nil, &NotFoundError{scheduleEntity, formatId(id)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+func (s *scheduleRepository) Create(ctx context.Context, fundId uint, description string, rule string,
+	start time.Time, postings []Posting) (Schedule, error) {
+	if strings.TrimSpace(description) == "" {
+		return nil, &ValidationError{scheduleEntity, "description", "must not be empty"}
+	}
+	recurrence, err := ParseRecurrence(rule)
+	if err != nil {
+		return nil, &ValidationError{scheduleEntity, "rule", err.(*InvalidRecurrenceError).Reason}
+	}
+	if start.IsZero() {
+		return nil, &ValidationError{scheduleEntity, "start", "must be given"}
+	}
+	if err := validatePostings(scheduleEntity, postings); err != nil {
+		return nil, err
+	}
+	if err := checkPostingAccounts(ctx, s.db, scheduleEntity, fundId, postings); err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(postings)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := scheduleImpl{
+		ScheduleFundID:      fundId,
+		ScheduleDescription: description,
+		ScheduleRule:        recurrence.String(),
+		ScheduleStart:       toDate(start),
+		SchedulePostings:    string(encoded),
+		ScheduleVersion:     1,
+	}
+
+	err = withContext(ctx, s.db).Create(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+func (s *scheduleRepository) Delete(ctx context.Context, id uint, version uint) error {
+	result := withContext(ctx, s.db).Where("id = ? AND schedule_version = ?", id, version).Delete(&scheduleImpl{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return s.unchangedReason(ctx, id, version, false)
+	}
+
+	return nil
+}
+
+func (s *scheduleRepository) Pause(ctx context.Context, id uint, version uint) (Schedule, error) {
+	return s.setPaused(ctx, id, version, map[string]interface{}{"schedule_paused": true}, false)
+}
+
+func (s *scheduleRepository) Resume(ctx context.Context, id uint, version uint, on time.Time) (Schedule, error) {
+	schedule, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]interface{}{"schedule_paused": false}
+	through := toDate(on).AddDate(0, 0, -1)
+	if through.After(schedule.Through()) {
+		changes["schedule_through"] = through
+	}
+
+	return s.setPaused(ctx, id, version, changes, true)
+}
+
+// setPaused makes changes to the schedule with the given id and version
+// that pause or resume it. wantPaused is whether the change requires the
+// schedule to be paused.
+func (s *scheduleRepository) setPaused(ctx context.Context, id uint, version uint,
+	changes map[string]interface{}, wantPaused bool) (Schedule, error) {
+	changes["schedule_version"] = version + 1
+	result := withContext(ctx, s.db).Model(&scheduleImpl{}).
+		Where("id = ? AND schedule_version = ? AND schedule_paused = ?", id, version, wantPaused).
+		Updates(changes)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, s.unchangedReason(ctx, id, version, wantPaused)
+	}
+
+	return s.Get(ctx, id)
+}
+
+// unchangedReason gives the reason that a change to the schedule with the
+// given id and version affected no rows. wantPaused is whether the change
+// requires the schedule to be paused.
+func (s *scheduleRepository) unchangedReason(ctx context.Context, id uint, version uint, wantPaused bool) error {
+	schedule, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if schedule.Version() != version {
+		return &ConcurrencyError{scheduleEntity, formatId(id)}
+	}
+
+	if wantPaused {
+		return &ConflictError{scheduleEntity, formatId(id), "the schedule is not paused"}
+	}
+
+	return &ConflictError{scheduleEntity, formatId(id), "the schedule is paused"}
+}
+
+func (s *scheduleRepository) Materialize(ctx context.Context, id uint, occurrence time.Time) (JournalEntry, error) {
+	var entry *journalEntryImpl
+	err := (&store{s.db}).InTransaction(ctx, func(tx Store) error {
+		var err error
+		entry, err = (&scheduleRepository{tx.(*store).db}).materialize(ctx, id, toDate(occurrence))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// materialize makes the entry for the occurrence of the schedule with the
+// given id on date. It must be called within a transaction.
+func (s *scheduleRepository) materialize(ctx context.Context, id uint, date time.Time) (*journalEntryImpl, error) {
+	schedule, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if schedule.IsPaused() {
+		return nil, &ConflictError{scheduleEntity, formatId(id), "the schedule is paused"}
+	}
+	if !date.After(schedule.Through()) {
+		return nil, &ConflictError{scheduleEntity, formatId(id),
+			"the occurrence has already been materialized or skipped"}
+	}
+
+	recurrence, err := ParseRecurrence(schedule.Rule())
+	if err != nil {
+		return nil, err
+	}
+	if len(recurrence.Between(schedule.Start(), date.AddDate(0, 0, -1), date)) == 0 {
+		return nil, &ValidationError{scheduleEntity, "occurrence", "must be a date on which the schedule occurs"}
+	}
+
+	status, err := (&journalEntryRepository{s.db}).submittedStatus(ctx, schedule.FundId(), schedule.Postings())
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &journalEntryImpl{
+		EntryFundID:      schedule.FundId(),
+		EntryDate:        date,
+		EntryDescription: schedule.Description(),
+		EntryScheduleID:  &id,
+		EntryStatus:      status,
+		EntrySubmittedBy: SchedulerPrincipal,
+		EntryVersion:     1,
+	}
+	err = (&journalEntryRepository{s.db}).create(ctx, entry, schedule.Postings())
+	if isDuplicateEntry(err) {
+		return nil, &DuplicateError{journalEntryEntity, "date", date.Format("2006-01-02")}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = withContext(ctx, s.db).Model(&scheduleImpl{}).
+		Where("id = ?", id).
+		Update("schedule_through", date).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,142 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// createRentSchedule creates a schedule for rent in the first fund of a
+// ledger database that is due on the 1st of each month from September
+// 2026.
+func createRentSchedule(t *testing.T, db *gorm.DB) Schedule {
+	sut := scheduleRepository{db}
+	schedule, err := sut.Create(context.Background(), 1, "Rent", "FREQ=MONTHLY;BYMONTHDAY=1",
+		date(2026, time.September, 1), rentPostings)
+	require.NoError(t, err, "Unable to create a schedule.")
+	return schedule
+}
+
+func TestNextOccurrencesStartAfterThrough(t *testing.T) {
+	through := date(2026, time.October, 1)
+	schedule := &scheduleImpl{ScheduleRule: "FREQ=MONTHLY;BYMONTHDAY=1",
+		ScheduleStart: date(2026, time.January, 1), ScheduleThrough: &through}
+
+	actual, err := NextOccurrences(schedule, date(2026, time.December, 15), 2)
+
+	require.NoError(t, err, "NextOccurrences() failed.")
+	assert.Equal(t, []time.Time{date(2026, time.November, 1), date(2026, time.December, 1)}, actual)
+}
+
+func TestNextOccurrencesOfPausedScheduleStartNow(t *testing.T) {
+	through := date(2026, time.October, 1)
+	schedule := &scheduleImpl{ScheduleRule: "FREQ=MONTHLY;BYMONTHDAY=1",
+		ScheduleStart: date(2026, time.January, 1), ScheduleThrough: &through, SchedulePaused: true}
+
+	actual, err := NextOccurrences(schedule, date(2026, time.December, 15), 2)
+
+	require.NoError(t, err, "NextOccurrences() failed.")
+	assert.Equal(t, []time.Time{date(2027, time.January, 1), date(2027, time.February, 1)}, actual)
+}
+
+func TestScheduleRepositoryCreateReturnsNewSchedule(t *testing.T) {
+	db := getLedgerDb(t)
+
+	sut := scheduleRepository{db}
+	actual, err := sut.Create(context.Background(), 1, "Rent", "freq=monthly;bymonthday=1",
+		date(2026, time.September, 1), rentPostings)
+
+	require.NoError(t, err, "Unable to create a schedule.")
+	assert.NotZero(t, actual.Id(), "Id of the returned schedule was zero")
+	assert.Equal(t, "FREQ=MONTHLY;BYMONTHDAY=1", actual.Rule())
+	assert.Equal(t, rentPostings, actual.Postings())
+	assert.False(t, actual.IsPaused())
+	assert.True(t, actual.Through().IsZero())
+	assert.Equal(t, uint(1), actual.Version())
+}
+
+func TestScheduleRepositoryCreateWithBadRuleIsValidationError(t *testing.T) {
+	db := getLedgerDb(t)
+
+	sut := scheduleRepository{db}
+	_, err := sut.Create(context.Background(), 1, "Rent", "FREQ=HOURLY",
+		date(2026, time.September, 1), rentPostings)
+
+	assert.IsType(t, &ValidationError{}, err, "Create() returned an unexpected type of error")
+}
+
+func TestScheduleRepositoryPauseAndResume(t *testing.T) {
+	db := getLedgerDb(t)
+	schedule := createRentSchedule(t, db)
+
+	sut := scheduleRepository{db}
+	paused, err := sut.Pause(context.Background(), schedule.Id(), 1)
+	require.NoError(t, err, "Unable to pause the schedule.")
+	_, err = sut.Pause(context.Background(), schedule.Id(), 2)
+	assert.IsType(t, &ConflictError{}, err, "Pause() of a paused schedule returned an unexpected error")
+	resumed, err := sut.Resume(context.Background(), schedule.Id(), 2, date(2026, time.December, 15))
+	require.NoError(t, err, "Unable to resume the schedule.")
+
+	assert.True(t, paused.IsPaused())
+	assert.False(t, resumed.IsPaused())
+	assert.Equal(t, date(2026, time.December, 14), resumed.Through())
+	assert.Equal(t, uint(3), resumed.Version())
+}
+
+func TestScheduleRepositoryPauseWithStaleVersionIsConcurrencyError(t *testing.T) {
+	db := getLedgerDb(t)
+	schedule := createRentSchedule(t, db)
+
+	sut := scheduleRepository{db}
+	_, err := sut.Pause(context.Background(), schedule.Id(), 2)
+
+	assert.IsType(t, &ConcurrencyError{}, err, "Pause() returned an unexpected type of error")
+}
+
+func TestScheduleRepositoryMaterializeMakesEntry(t *testing.T) {
+	db := getLedgerDb(t)
+	schedule := createRentSchedule(t, db)
+
+	sut := scheduleRepository{db}
+	actual, err := sut.Materialize(context.Background(), schedule.Id(), date(2026, time.October, 1))
+	require.NoError(t, err, "Unable to materialize an occurrence.")
+	updated, err := sut.Get(context.Background(), schedule.Id())
+	require.NoError(t, err, "Unable to get the schedule.")
+
+	assert.Equal(t, date(2026, time.October, 1), actual.Date())
+	assert.Equal(t, "Rent", actual.Description())
+	assert.Equal(t, rentPostings, actual.Postings())
+	assert.Equal(t, schedule.Id(), actual.ScheduleId())
+	assert.Equal(t, date(2026, time.October, 1), updated.Through())
+	assert.Equal(t, uint(1), updated.Version())
+}
+
+func TestScheduleRepositoryMaterializeTwiceIsConflictError(t *testing.T) {
+	db := getLedgerDb(t)
+	schedule := createRentSchedule(t, db)
+
+	sut := scheduleRepository{db}
+	_, err := sut.Materialize(context.Background(), schedule.Id(), date(2026, time.October, 1))
+	require.NoError(t, err, "Unable to materialize an occurrence.")
+	_, err = sut.Materialize(context.Background(), schedule.Id(), date(2026, time.October, 1))
+
+	assert.IsType(t, &ConflictError{}, err, "Materialize() returned an unexpected type of error")
+}
+
+func TestScheduleRepositoryMaterializeOtherDateIsValidationError(t *testing.T) {
+	db := getLedgerDb(t)
+	schedule := createRentSchedule(t, db)
+
+	sut := scheduleRepository{db}
+	_, err := sut.Materialize(context.Background(), schedule.Id(), date(2026, time.October, 2))
+
+	assert.IsType(t, &ValidationError{}, err, "Materialize() returned an unexpected type of error")
+}
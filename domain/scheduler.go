@@ -0,0 +1,110 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// A Scheduler materializes the occurrences of the schedules in a Store as
+// journal entries once they are due. An occurrence is due on its date,
+// according to the Scheduler's clock. Materializing is idempotent so more
+// than one Scheduler may run against the same database. The occurrences
+// of a schedule whose fund is archived wait until the fund is unarchived.
+type Scheduler struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewScheduler creates a Scheduler for the schedules in store that gets
+// the current time from now. A nil now is time.Now.
+func NewScheduler(store Store, now func() time.Time) *Scheduler {
+	if now == nil {
+		now = time.Now
+	}
+
+	return &Scheduler{store, now}
+}
+
+// RunDue materializes the occurrences of the active schedules that are due
+// and returns the number of entries that it made. A failure to materialize
+// an occurrence of one schedule doesn't stop the other schedules; the
+// first such error is returned once they are done and the failed
+// occurrence is tried again by the next run.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	repository := s.store.ScheduleRepository()
+	schedules, err := repository.GetActive(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	today := toDate(s.now())
+	made := 0
+	var firstErr error
+	for _, schedule := range schedules {
+		n, err := s.runSchedule(ctx, repository, schedule, today)
+		made += n
+		if err != nil {
+			log.WithError(err).WithField("schedule", schedule.Id()).Error("Unable to materialize a scheduled entry.")
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return made, firstErr
+}
+
+// runSchedule materializes the occurrences of schedule through today in
+// order, stopping at the first one that fails.
+func (s *Scheduler) runSchedule(ctx context.Context, repository ScheduleRepository, schedule Schedule,
+	today time.Time) (int, error) {
+	recurrence, err := ParseRecurrence(schedule.Rule())
+	if err != nil {
+		return 0, err
+	}
+
+	made := 0
+	for _, occurrence := range recurrence.Between(schedule.Start(), schedule.Through(), today) {
+		_, err := repository.Materialize(ctx, schedule.Id(), occurrence)
+		switch err.(type) {
+		case nil:
+			made++
+		case *DuplicateError:
+			// another Scheduler made the entry first
+		case *ConflictError:
+			log.WithError(err).WithField("schedule", schedule.Id()).Debug("Skipped a scheduled entry.")
+			return made, nil
+		default:
+			return made, err
+		}
+	}
+
+	return made, nil
+}
+
+// Run calls RunDue every interval, starting straight away, until ctx is
+// done. Errors are logged and don't stop Run.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		made, err := s.RunDue(ctx)
+		if err != nil {
+			log.WithError(err).WithField("entries", made).Warn("The scheduler run had errors.")
+		} else if made > 0 {
+			log.WithField("entries", made).Info("Materialized scheduled entries.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestSchedulerRunDueMaterializesDueOccurrencesOnce(t *testing.T) {
+	db := getLedgerDb(t)
+	createRentSchedule(t, db)
+	now := time.Date(2026, time.October, 15, 12, 0, 0, 0, time.UTC)
+
+	sut := NewScheduler(&store{db}, func() time.Time { return now })
+	first, err := sut.RunDue(context.Background())
+	require.NoError(t, err, "The first run failed.")
+	second, err := sut.RunDue(context.Background())
+	require.NoError(t, err, "The second run failed.")
+	entries, err := (&journalEntryRepository{db}).GetByFund(context.Background(), 1)
+	require.NoError(t, err, "Unable to get the fund's entries.")
+
+	assert.Equal(t, 2, first, "Unexpected number of entries made by the first run.")
+	assert.Equal(t, 0, second, "Unexpected number of entries made by the second run.")
+	require.Len(t, entries, 2, "Unexpected number of entries.")
+	assert.Equal(t, date(2026, time.September, 1), entries[0].Date())
+	assert.Equal(t, date(2026, time.October, 1), entries[1].Date())
+}
+
+func TestSchedulerRunDueFollowsTheClock(t *testing.T) {
+	db := getLedgerDb(t)
+	createRentSchedule(t, db)
+	now := time.Date(2026, time.October, 15, 12, 0, 0, 0, time.UTC)
+
+	sut := NewScheduler(&store{db}, func() time.Time { return now })
+	_, err := sut.RunDue(context.Background())
+	require.NoError(t, err, "The first run failed.")
+	now = time.Date(2026, time.November, 1, 0, 30, 0, 0, time.UTC)
+	actual, err := sut.RunDue(context.Background())
+
+	require.NoError(t, err, "The second run failed.")
+	assert.Equal(t, 1, actual, "Unexpected number of entries made once November was due.")
+}
+
+func TestSchedulerRunDueSkipsOccurrencesWhilePaused(t *testing.T) {
+	db := getLedgerDb(t)
+	schedule := createRentSchedule(t, db)
+	repository := &scheduleRepository{db}
+	_, err := repository.Pause(context.Background(), schedule.Id(), 1)
+	require.NoError(t, err, "Unable to pause the schedule.")
+	now := time.Date(2026, time.October, 15, 12, 0, 0, 0, time.UTC)
+
+	sut := NewScheduler(&store{db}, func() time.Time { return now })
+	paused, err := sut.RunDue(context.Background())
+	require.NoError(t, err, "The run while paused failed.")
+	_, err = repository.Resume(context.Background(), schedule.Id(), 2, now)
+	require.NoError(t, err, "Unable to resume the schedule.")
+	now = time.Date(2026, time.November, 2, 0, 0, 0, 0, time.UTC)
+	resumed, err := sut.RunDue(context.Background())
+
+	require.NoError(t, err, "The run after resuming failed.")
+	assert.Equal(t, 0, paused, "Unexpected entries made while paused.")
+	assert.Equal(t, 1, resumed, "Unexpected number of entries made after resuming.")
+}
+
+func TestSchedulerRunDueSubmitsEntriesForApproval(t *testing.T) {
+	db := getLedgerDb(t)
+	insertApprovalRules(t, db, []approvalRuleImpl{{1, 1, 100000, "treasurer", true, 1}})
+	createRentSchedule(t, db)
+	now := time.Date(2026, time.October, 15, 12, 0, 0, 0, time.UTC)
+
+	sut := NewScheduler(&store{db}, func() time.Time { return now })
+	_, err := sut.RunDue(context.Background())
+	require.NoError(t, err, "The run failed.")
+	entries, err := (&journalEntryRepository{db}).GetByFund(context.Background(), 1)
+	require.NoError(t, err, "Unable to get the fund's entries.")
+
+	require.NotEmpty(t, entries, "The run made no entries.")
+	for _, entry := range entries {
+		assert.Equal(t, Pending, entry.Status(), "Unexpected status of a scheduled entry.")
+		assert.Equal(t, SchedulerPrincipal, entry.SubmittedBy(), "Unexpected submitter of a scheduled entry.")
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package domain
+
+import (
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// SchemaVersion is the version of the database schema that this version of
+// the domain expects. CreateOrMigrate records it in the database. It must be
+// increased whenever CreateOrMigrate changes the schema.
+const SchemaVersion uint = 9
+
+const schemaVersionTable = "schema_versions"
+
+// schemaVersionImpl is the single row that records the version of the
+// schema that CreateOrMigrate last created or migrated.
+type schemaVersionImpl struct {
+	ID      uint
+	Version uint
+}
+
+func (schemaVersionImpl) TableName() string {
+	return schemaVersionTable
+}
+
+// A SchemaVersionError is returned when the database schema is not the
+// version that the domain expects. An Actual of zero means that the schema
+// has no recorded version.
+type SchemaVersionError struct {
+	Expected uint
+	Actual   uint
+}
+
+func (e *SchemaVersionError) Error() string {
+	if e.Actual == 0 {
+		return fmt.Sprintf("The database schema has no version but version %d was expected.", e.Expected)
+	}
+	return fmt.Sprintf("The database schema is version %d but version %d was expected.", e.Actual, e.Expected)
+}
+
+// A HealthChecker checks that the database behind a Store can be used.
+// Ping checks that the database can be reached and CheckSchema checks that
+// its schema is SchemaVersion, returning a *SchemaVersionError if it isn't.
+// The Store returned by New is one.
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+	CheckSchema(ctx context.Context) error
+}
+
+func (s *store) Ping(ctx context.Context) error {
+	return s.db.DB().PingContext(ctx)
+}
+
+func (s *store) CheckSchema(ctx context.Context) error {
+	if !s.db.HasTable(schemaVersionTable) {
+		return &SchemaVersionError{Expected: SchemaVersion}
+	}
+
+	var version uint
+	err := s.db.DB().QueryRowContext(ctx,
+		"SELECT version FROM "+schemaVersionTable+" WHERE id = 1").Scan(&version)
+	if err == sql.ErrNoRows {
+		return &SchemaVersionError{Expected: SchemaVersion}
+	}
+	if err != nil {
+		return err
+	}
+
+	if version != SchemaVersion {
+		return &SchemaVersionError{Expected: SchemaVersion, Actual: version}
+	}
+
+	return nil
+}
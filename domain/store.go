@@ -4,13 +4,49 @@
 
 package domain
 
-import "github.com/jinzhu/gorm"
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/net/context"
+)
 
 // A Store is an abstract factory for the repositories that provide
 // persistance for the entities in the domain. It represents the
 // collection of repositories that make up the domain.
+//
+// InTransaction calls fn with a Store whose repositories all work within
+// one database transaction. The transaction is committed if fn returns nil
+// and rolled back otherwise, in which case InTransaction returns the error
+// from fn. If fn panics the transaction is rolled back and the panic carries
+// on. A transaction that fails because of a deadlock or a lock wait timeout
+// is tried again, with a new transaction, so fn may be called more than once
+// and must not have effects outside of tx. A transaction is not committed if
+// ctx is done. Calling InTransaction on a Store that is already in a
+// transaction calls fn with that Store so the work joins the transaction.
+//
+// Each method of the repositories takes the context of the request that it
+// is part of and sends its statements to the database with that context. The
+// database driver stops a statement that is running when the context is done
+// and the method returns the context's error.
 type Store interface {
 	FundRepository() FundRepository
+	AccountRepository() AccountRepository
+	IdempotencyRepository() IdempotencyRepository
+	JournalEntryRepository() JournalEntryRepository
+	ScheduleRepository() ScheduleRepository
+	ApprovalRuleRepository() ApprovalRuleRepository
+	DonorRepository() DonorRepository
+	ReceiptRepository() ReceiptRepository
+	HoldingRepository() HoldingRepository
+	InTransaction(ctx context.Context, fn func(tx Store) error) error
+}
+
+// A DBStatsProvider gives statistics about the usage of the database
+// connection pool behind a Store. The Store returned by New is one.
+type DBStatsProvider interface {
+	DBStats() sql.DBStats
 }
 
 type store struct {
@@ -20,3 +56,113 @@ type store struct {
 func (s *store) FundRepository() FundRepository {
 	return &fundRepository{s.db}
 }
+
+func (s *store) AccountRepository() AccountRepository {
+	return &accountRepository{s.db}
+}
+
+func (s *store) IdempotencyRepository() IdempotencyRepository {
+	return &idempotencyRepository{s.db}
+}
+
+func (s *store) JournalEntryRepository() JournalEntryRepository {
+	return &journalEntryRepository{s.db}
+}
+
+func (s *store) ScheduleRepository() ScheduleRepository {
+	return &scheduleRepository{s.db}
+}
+
+func (s *store) ApprovalRuleRepository() ApprovalRuleRepository {
+	return &approvalRuleRepository{s.db}
+}
+
+func (s *store) DonorRepository() DonorRepository {
+	return &donorRepository{s.db}
+}
+
+func (s *store) ReceiptRepository() ReceiptRepository {
+	return &receiptRepository{s.db}
+}
+
+func (s *store) HoldingRepository() HoldingRepository {
+	return &holdingRepository{s.db}
+}
+
+func (s *store) DBStats() sql.DBStats {
+	return s.db.DB().Stats()
+}
+
+func (s *store) InTransaction(ctx context.Context, fn func(tx Store) error) error {
+	switch s.db.CommonDB().(type) {
+	case *sql.Tx, *contextTx:
+		return fn(s)
+	}
+
+	return retryTransaction(ctx, func() error {
+		return s.transact(ctx, fn)
+	})
+}
+
+// transact calls fn within one transaction. The transaction is begun with
+// ctx so the database driver rolls it back if ctx is done before it is
+// committed.
+func (s *store) transact(ctx context.Context, fn func(tx Store) error) error {
+	sqlTx, err := s.db.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	tx, err := gorm.Open(mysqlDialect, &contextTx{sqlTx, ctx})
+	if err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			sqlTx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(&store{tx})
+	if err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+
+	err = sqlTx.Commit()
+	if err == sql.ErrTxDone && ctx.Err() != nil {
+		// the driver rolled the transaction back when ctx was done
+		return ctx.Err()
+	}
+	return err
+}
+
+// The number of times that a transaction is tried and the time to wait
+// before the first retry. The wait doubles for each later retry.
+const (
+	maxTransactionAttempts = 3
+	transactionRetryDelay  = 10 * time.Millisecond
+)
+
+// retryTransaction calls attempt until it succeeds, fails with an error that
+// retrying won't help or has been called maxTransactionAttempts times.
+func retryTransaction(ctx context.Context, attempt func() error) error {
+	delay := transactionRetryDelay
+	for attempts := 1; ; attempts++ {
+		err := attempt()
+		if !isRetryable(err) || attempts == maxTransactionAttempts {
+			return err
+		}
+
+		log.WithError(err).WithField("attempt", attempts).Warn("Retrying a transaction.")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
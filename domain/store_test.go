@@ -5,10 +5,14 @@
 package domain
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
 )
 
 func TestStoreFundRepositoryFowardsDb(t *testing.T) {
@@ -21,3 +25,201 @@ func TestStoreFundRepositoryFowardsDb(t *testing.T) {
 	realrepo := repo.(*fundRepository)
 	assert.Equal(fakedb, realrepo.db)
 }
+
+func TestStoreIdempotencyRepositoryFowardsDb(t *testing.T) {
+	assert := assert.New(t)
+	fakedb := &gorm.DB{}
+
+	sut := store{fakedb}
+	repo := sut.IdempotencyRepository()
+
+	realrepo := repo.(*idempotencyRepository)
+	assert.Equal(fakedb, realrepo.db)
+}
+
+func TestStoreAccountRepositoryFowardsDb(t *testing.T) {
+	assert := assert.New(t)
+	fakedb := &gorm.DB{}
+
+	sut := store{fakedb}
+	repo := sut.AccountRepository()
+
+	realrepo := repo.(*accountRepository)
+	assert.Equal(fakedb, realrepo.db)
+}
+
+func TestStoreInTransactionCommitsOnSuccess(t *testing.T) {
+	db := getEmptyDb(t)
+	defer db.Close()
+
+	sut := store{db}
+	err := sut.InTransaction(context.Background(), func(tx Store) error {
+		_, err := tx.FundRepository().Create(context.Background(), "General", CAD, "")
+		return err
+	})
+
+	require.NoError(t, err, "InTransaction() failed.")
+	funds, err := sut.FundRepository().GetAll(context.Background())
+	require.NoError(t, err, "Unable to get all funds.")
+	assert.Len(t, funds, 1, "The fund created in the transaction was not committed.")
+}
+
+func TestStoreInTransactionRollsBackOnError(t *testing.T) {
+	db := getEmptyDb(t)
+	defer db.Close()
+	expected := errors.New("failed")
+
+	sut := store{db}
+	err := sut.InTransaction(context.Background(), func(tx Store) error {
+		_, err := tx.FundRepository().Create(context.Background(), "General", CAD, "")
+		require.NoError(t, err, "Unable to create a fund in the transaction.")
+		return expected
+	})
+
+	assert.Equal(t, expected, err, "InTransaction() returned an unexpected error.")
+	funds, err := sut.FundRepository().GetAll(context.Background())
+	require.NoError(t, err, "Unable to get all funds.")
+	assert.Len(t, funds, 0, "The fund created in the transaction was not rolled back.")
+}
+
+func TestStoreInTransactionRollsBackOnPanic(t *testing.T) {
+	db := getEmptyDb(t)
+	defer db.Close()
+
+	sut := store{db}
+	assert.Panics(t, func() {
+		sut.InTransaction(context.Background(), func(tx Store) error {
+			_, err := tx.FundRepository().Create(context.Background(), "General", CAD, "")
+			require.NoError(t, err, "Unable to create a fund in the transaction.")
+			panic("failed")
+		})
+	}, "InTransaction() did not carry on the panic.")
+
+	funds, err := sut.FundRepository().GetAll(context.Background())
+	require.NoError(t, err, "Unable to get all funds.")
+	assert.Len(t, funds, 0, "The fund created in the transaction was not rolled back.")
+}
+
+func TestStoreInTransactionJoinsOuterTransaction(t *testing.T) {
+	db := getEmptyDb(t)
+	defer db.Close()
+	expected := errors.New("failed")
+
+	sut := store{db}
+	err := sut.InTransaction(context.Background(), func(tx Store) error {
+		err := tx.InTransaction(context.Background(), func(inner Store) error {
+			_, err := inner.FundRepository().Create(context.Background(), "General", CAD, "")
+			return err
+		})
+		require.NoError(t, err, "The inner transaction failed.")
+		return expected
+	})
+
+	assert.Equal(t, expected, err, "InTransaction() returned an unexpected error.")
+	funds, err := sut.FundRepository().GetAll(context.Background())
+	require.NoError(t, err, "Unable to get all funds.")
+	assert.Len(t, funds, 0, "The inner transaction was committed separately.")
+}
+
+func TestStoreInTransactionWithCancelledContextDoesNotCommit(t *testing.T) {
+	db := getEmptyDb(t)
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sut := store{db}
+	err := sut.InTransaction(ctx, func(tx Store) error {
+		_, err := tx.FundRepository().Create(ctx, "General", CAD, "")
+		cancel()
+		return err
+	})
+
+	assert.Equal(t, context.Canceled, err, "InTransaction() returned an unexpected error.")
+	funds, err := sut.FundRepository().GetAll(context.Background())
+	require.NoError(t, err, "Unable to get all funds.")
+	assert.Len(t, funds, 0, "The transaction was committed after its context was cancelled.")
+}
+
+func TestRetryTransactionRetriesDeadlocks(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}
+	attempts := 0
+
+	err := retryTransaction(context.Background(), func() error {
+		attempts++
+		if attempts < 2 {
+			return deadlock
+		}
+		return nil
+	})
+
+	assert.NoError(t, err, "retryTransaction() failed.")
+	assert.Equal(t, 2, attempts, "Unexpected number of attempts.")
+}
+
+func TestRetryTransactionGivesUpAfterMaxAttempts(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}
+	attempts := 0
+
+	err := retryTransaction(context.Background(), func() error {
+		attempts++
+		return deadlock
+	})
+
+	assert.Equal(t, deadlock, err, "Unexpected error.")
+	assert.Equal(t, maxTransactionAttempts, attempts, "Unexpected number of attempts.")
+}
+
+func TestRetryTransactionDoesNotRetryOtherErrors(t *testing.T) {
+	expected := errors.New("failed")
+	attempts := 0
+
+	err := retryTransaction(context.Background(), func() error {
+		attempts++
+		return expected
+	})
+
+	assert.Equal(t, expected, err, "Unexpected error.")
+	assert.Equal(t, 1, attempts, "Unexpected number of attempts.")
+}
+
+func TestStorePingSucceeds(t *testing.T) {
+	db := getEmptyDb(t)
+	defer db.Close()
+
+	sut := store{db}
+	err := sut.Ping(context.Background())
+
+	assert.NoError(t, err, "Ping() failed.")
+}
+
+func TestStoreCheckSchemaSucceedsAfterCreateOrMigrate(t *testing.T) {
+	db := getEmptyDb(t)
+	defer db.Close()
+
+	sut := store{db}
+	err := sut.CheckSchema(context.Background())
+
+	assert.NoError(t, err, "CheckSchema() failed.")
+}
+
+func TestStoreCheckSchemaWithOtherVersionIsError(t *testing.T) {
+	db := getEmptyDb(t)
+	defer db.Close()
+	require.NoError(t, db.Save(&schemaVersionImpl{ID: 1, Version: SchemaVersion + 1}).Error,
+		"Unable to change the schema version.")
+
+	sut := store{db}
+	err := sut.CheckSchema(context.Background())
+
+	assert.Equal(t, &SchemaVersionError{SchemaVersion, SchemaVersion + 1}, err, "Unexpected error.")
+}
+
+func TestStoreCheckSchemaWithoutVersionIsError(t *testing.T) {
+	db := getEmptyDb(t)
+	defer db.Close()
+	require.NoError(t, db.DropTable(&schemaVersionImpl{}).Error, "Unable to drop the schema version.")
+
+	sut := store{db}
+	err := sut.CheckSchema(context.Background())
+
+	assert.Equal(t, &SchemaVersionError{Expected: SchemaVersion}, err, "Unexpected error.")
+}
@@ -0,0 +1,134 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+// Package health provides http handlers that report whether a service is
+// healthy, for use by load balancers and orchestrators. A handler runs its
+// checks for each request and responds with 200 OK if they all pass and
+// 503 Service Unavailable otherwise. Either way the JSON body reports the
+// outcome of each check:
+//
+//	{"status": "fail", "checks": [
+//	    {"name": "database", "status": "pass", "duration": "1.2ms"},
+//	    {"name": "schema", "status": "fail", "duration": "0.8ms", "error": "..."}
+//	]}
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// DefaultTimeout is the time that a handler allows each check to take
+	// unless it is given another timeout.
+	DefaultTimeout = 5 * time.Second
+
+	passStatus = "pass"
+	failStatus = "fail"
+)
+
+// A Check is a named check of something that a service depends on. The
+// check fails if Fn returns an error or doesn't return before its context
+// is done.
+type Check struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+type checkResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type report struct {
+	Status string         `json:"status"`
+	Checks []*checkResult `json:"checks"`
+}
+
+type handler struct {
+	timeout time.Duration
+	checks  []Check
+}
+
+// Handler returns an http.Handler that runs the checks, allowing each of
+// them the timeout, and reports their outcome. A Handler without checks
+// always reports that the service is healthy, which shows only that the
+// process is alive. A timeout that isn't positive is DefaultTimeout.
+func Handler(timeout time.Duration, checks ...Check) http.Handler {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &handler{timeout, checks}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	result := report{Status: passStatus, Checks: []*checkResult{}}
+	for _, check := range h.checks {
+		checked := h.run(check)
+		if checked.Status != passStatus {
+			result.Status = failStatus
+		}
+		result.Checks = append(result.Checks, checked)
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if result.Status != passStatus {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// run runs one check. A check that doesn't return within the timeout fails
+// even if it ignores its context.
+func (h *handler) run(check Check) *checkResult {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := &checkResult{
+		Name:     check.Name,
+		Status:   passStatus,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = failStatus
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,91 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func serveHealthRequest(t *testing.T, sut http.Handler, method string) (*httptest.ResponseRecorder, report) {
+	request, err := http.NewRequest(method, "/readyz", nil)
+	require.NoError(t, err, "Unable to create the request.")
+	response := httptest.NewRecorder()
+
+	sut.ServeHTTP(response, request)
+
+	var body report
+	if response.Code != http.StatusMethodNotAllowed {
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body), "The body is not a report.")
+	}
+	return response, body
+}
+
+func passing(ctx context.Context) error {
+	return nil
+}
+
+func TestHandlerWithoutChecksPasses(t *testing.T) {
+	response, body := serveHealthRequest(t, Handler(0), http.MethodGet)
+
+	assert.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	assert.Equal(t, passStatus, body.Status, "Unexpected status.")
+	assert.Empty(t, body.Checks, "Unexpected checks.")
+}
+
+func TestHandlerWithPassingChecksPasses(t *testing.T) {
+	sut := Handler(time.Second, Check{"database", passing}, Check{"schema", passing})
+
+	response, body := serveHealthRequest(t, sut, http.MethodGet)
+
+	assert.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	require.Len(t, body.Checks, 2, "Unexpected number of checks.")
+	assert.Equal(t, "database", body.Checks[0].Name, "Unexpected check name.")
+	assert.Equal(t, passStatus, body.Checks[0].Status, "Unexpected check status.")
+}
+
+func TestHandlerWithFailingCheckFails(t *testing.T) {
+	failing := func(ctx context.Context) error { return errors.New("no database") }
+	sut := Handler(time.Second, Check{"database", failing}, Check{"schema", passing})
+
+	response, body := serveHealthRequest(t, sut, http.MethodGet)
+
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code, "Unexpected status code.")
+	assert.Equal(t, failStatus, body.Status, "Unexpected status.")
+	require.Len(t, body.Checks, 2, "Unexpected number of checks.")
+	assert.Equal(t, failStatus, body.Checks[0].Status, "Unexpected failing check status.")
+	assert.Equal(t, "no database", body.Checks[0].Error, "Unexpected check error.")
+	assert.Equal(t, passStatus, body.Checks[1].Status, "Unexpected passing check status.")
+}
+
+func TestHandlerWithSlowCheckTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := func(ctx context.Context) error {
+		<-release
+		return nil
+	}
+	sut := Handler(10*time.Millisecond, Check{"database", slow})
+
+	response, body := serveHealthRequest(t, sut, http.MethodGet)
+
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code, "Unexpected status code.")
+	require.Len(t, body.Checks, 1, "Unexpected number of checks.")
+	assert.NotEmpty(t, body.Checks[0].Error, "The timeout was not reported.")
+}
+
+func TestHandlerPostIsNotAllowed(t *testing.T) {
+	response, _ := serveHealthRequest(t, Handler(0), http.MethodPost)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code, "Unexpected status code.")
+}
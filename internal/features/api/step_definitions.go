@@ -12,13 +12,13 @@ import (
 	"net/http/httptest"
 	"net/url"
 
-	jsh "github.com/derekdowling/go-json-spec-handler"
-	jsc "github.com/derekdowling/go-json-spec-handler/client"
 	"github.com/go-sql-driver/mysql"
 	. "github.com/gucumber/gucumber"
 	"github.com/gucumber/gucumber/gherkin"
+	"github.com/sbosnick1/openacct/client"
 	"github.com/sbosnick1/openacct/cmd/openacctapi"
 	"github.com/sbosnick1/openacct/domain"
+	"golang.org/x/net/context"
 )
 
 var worldServerKey = "server"
@@ -58,33 +58,33 @@ func getDsn() string {
 	return "/openacct"
 }
 
-func getList(resourceType string) *jsh.Document {
-	doc, resp, err := jsc.List(getBaseURL(), resourceType)
+func getClient() *client.Client {
+	c, err := client.New(getBaseURL())
 	if err != nil {
-		T.Errorf(err.Error())
-		return nil
+		log.Panic(err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		T.Errorf("Unexpected status code in the response: %s", resp.Status)
-		return nil
-	}
+	return c
+}
 
-	if doc == nil {
-		T.Errorf("No jsh document returned.")
+func listFunds() []*client.Fund {
+	funds, err := getClient().Funds().List(context.Background(), client.ListOptions{})
+	if err != nil {
+		T.Errorf(err.Error())
 		return nil
 	}
 
-	if doc.HasErrors() {
-		T.Errorf("The returned jsh document had the following errors %s", doc.Error())
-		return nil
-	}
+	return funds
+}
 
-	return doc
+// identifyNobody authenticates no request; the features only use the
+// requests that don't act on behalf of a principal.
+func identifyNobody(r *http.Request) (domain.Principal, bool) {
+	return domain.Principal{}, false
 }
 
 func openServer() {
-	handler, err := openacctapi.BuildApiHandler(getDsn())
+	handler, err := openacctapi.BuildApiHandler(getDsn(), openacctapi.Identify(identifyNobody))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -144,43 +144,27 @@ func doNothing() {
 }
 
 func checkFundCount(count int) {
-	doc := getList("fund")
-	if doc == nil {
+	funds := listFunds()
+	if funds == nil {
 		return
 	}
 
-	if doc.Data == nil {
-		T.Errorf("The returned jsh document did not have any data.")
-		return
-	}
-
-	if doc.Mode != jsh.ListMode {
-		T.Errorf("The returned jsh document was not in list mode.")
-		return
-	}
-
-	if len(doc.Data) != count {
-		T.Errorf("The returned list had %d entries but %d were expected", len(doc.Data), count)
+	if len(funds) != count {
+		T.Errorf("The returned list had %d entries but %d were expected", len(funds), count)
 		return
 	}
 }
 
-func addFund(fundName string, currency string) {
-	object, jsherr := jsh.NewObject("", "fund",
-		map[string]string{"name": fundName, "currency": currency})
-	if jsherr != nil {
-		T.Errorf(jsherr.Error())
-		return
-	}
-
-	_, resp, err := jsc.Post(getBaseURL(), object)
+func addFund(fundName string, currencyCode string) {
+	currency, err := domain.ParseCurrency(currencyCode)
 	if err != nil {
 		T.Errorf(err.Error())
 		return
 	}
 
-	if resp.StatusCode != http.StatusCreated {
-		T.Errorf("Unexpected status code in the response: %s", resp.Status)
+	_, err = getClient().Funds().Create(context.Background(), fundName, currency)
+	if err != nil {
+		T.Errorf(err.Error())
 		return
 	}
 }
@@ -193,28 +177,15 @@ func addFunds(fundTable gherkin.TabularData) {
 }
 
 func deleteFund(fundName string) {
-	doc := getList("fund")
-	if doc == nil {
-		return
-	}
-
-	if !doc.HasData() {
-		T.Errorf("The list of funds is empty.")
+	funds := listFunds()
+	if funds == nil {
 		return
 	}
 
 	var id string
-
-	for _, object := range doc.Data {
-		attributes := make(map[string]string)
-		jsherr := object.Unmarshal("fund", &attributes)
-		if jsherr != nil {
-			T.Errorf(jsherr.Error())
-			return
-		}
-
-		if attributes["name"] == fundName {
-			id = object.ID
+	for _, fund := range funds {
+		if fund.Name == fundName {
+			id = fund.ID
 		}
 	}
 
@@ -223,67 +194,45 @@ func deleteFund(fundName string) {
 		return
 	}
 
-	resp, err := jsc.Delete(getBaseURL(), "fund", id)
+	// The list doesn't give the version of the fund so get it before
+	// deleting the fund.
+	fund, err := getClient().Funds().Get(context.Background(), id)
 	if err != nil {
 		T.Errorf(err.Error())
 		return
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		T.Errorf("Expected a status code of StatusOK but instead got a StatusCode of %s.",
-			http.StatusText(resp.StatusCode))
+	err = getClient().Funds().Delete(context.Background(), id, fund.Version)
+	if err != nil {
+		T.Errorf(err.Error())
 		return
 	}
 }
 
-func checkForFund(fundName string, currency string) {
-	doc := getList("fund")
-	if doc == nil {
+func checkForFund(fundName string, currencyCode string) {
+	funds := listFunds()
+	if funds == nil {
 		return
 	}
 
-	if !doc.HasData() {
-		T.Errorf("Returned document has no data.")
-		return
-	}
-
-	for _, object := range doc.Data {
-		var attributes = make(map[string]string)
-		jsherr := object.Unmarshal("fund", &attributes)
-		if jsherr != nil {
-			T.Errorf(jsherr.Error())
-			return
-		}
-
-		if attributes["name"] == fundName && attributes["currency"] == currency {
+	for _, fund := range funds {
+		if fund.Name == fundName && fund.Currency.String() == currencyCode {
 			return
 		}
 	}
 
 	T.Errorf("The returned list of funds did not include one with a name %s and currency %s.",
-		fundName, currency)
+		fundName, currencyCode)
 }
 
 func checkForNoFund(fundName string) {
-	doc := getList("fund")
-	if doc == nil {
-		return
-	}
-
-	if !doc.HasData() {
-		T.Errorf("Returned document has no data.")
+	funds := listFunds()
+	if funds == nil {
 		return
 	}
 
-	for _, object := range doc.Data {
-		var attributes = make(map[string]string)
-		jsherr := object.Unmarshal("fund", &attributes)
-		if jsherr != nil {
-			T.Errorf(jsherr.Error())
-			return
-		}
-
-		if attributes["name"] == fundName {
+	for _, fund := range funds {
+		if fund.Name == fundName {
 			T.Errorf("The list of funds unexpectedly included one named %s.", fundName)
 			return
 		}
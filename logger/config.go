@@ -0,0 +1,182 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// The environment variables read by ConfigFromEnv.
+const (
+	LevelsEnv = "OPENACCT_LOG"
+	FormatEnv = "OPENACCT_LOG_FORMAT"
+)
+
+// ConfigFileEnv is the environment variable that names the configuration
+// file, in the form read by ConfigFromFile, of a program that has one.
+const ConfigFileEnv = "OPENACCT_LOG_CONFIG"
+
+// The output formats of the loggers.
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// A Config configures the loggers of all of the packages. Levels gives the
+// level for the logger of each named package; packages that it doesn't
+// name use DefaultLevel. Format is TextFormat or JSONFormat and Out is where
+// the log entries are written (os.Stderr if it is nil).
+type Config struct {
+	DefaultLevel logrus.Level
+	Levels       map[string]logrus.Level
+	Format       string
+	Out          io.Writer
+}
+
+func defaultConfig() Config {
+	return Config{DefaultLevel: logrus.InfoLevel, Format: TextFormat, Out: os.Stderr}
+}
+
+func (c Config) withDefaults() Config {
+	if c.Format == "" {
+		c.Format = TextFormat
+	}
+	if c.Out == nil {
+		c.Out = os.Stderr
+	}
+	return c
+}
+
+func (c Config) level(packageName string) logrus.Level {
+	if level, ok := c.Levels[packageName]; ok {
+		return level
+	}
+	return c.DefaultLevel
+}
+
+func (c Config) formatter() logrus.Formatter {
+	if c.Format == JSONFormat {
+		return &logrus.JSONFormatter{}
+	}
+	return &logrus.TextFormatter{}
+}
+
+// ConfigFromEnv gives the configuration in the OPENACCT_LOG and
+// OPENACCT_LOG_FORMAT environment variables. OPENACCT_LOG is a comma
+// separated list of package=level pairs and, optionally, a level on its own
+// for the other packages, for example "apiservice=debug,domain=warn,error".
+// OPENACCT_LOG_FORMAT is "text" or "json".
+func ConfigFromEnv() (Config, error) {
+	config := defaultConfig()
+
+	levels, err := ParseLevels(os.Getenv(LevelsEnv))
+	if err != nil {
+		return config, err
+	}
+	if level, ok := levels[""]; ok {
+		config.DefaultLevel = level
+		delete(levels, "")
+	}
+	config.Levels = levels
+
+	config.Format, err = parseFormat(os.Getenv(FormatEnv))
+	if err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// ConfigFromFile gives the configuration in a JSON file such as:
+//
+//	{
+//	    "level": "info",
+//	    "levels": {"apiservice": "debug", "domain": "warn"},
+//	    "format": "json"
+//	}
+func ConfigFromFile(path string) (Config, error) {
+	config := defaultConfig()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return config, err
+	}
+	defer file.Close()
+
+	var contents struct {
+		Level  string            `json:"level"`
+		Levels map[string]string `json:"levels"`
+		Format string            `json:"format"`
+	}
+	err = json.NewDecoder(file).Decode(&contents)
+	if err != nil {
+		return config, fmt.Errorf("invalid logging configuration in %s: %v", path, err)
+	}
+
+	if contents.Level != "" {
+		config.DefaultLevel, err = logrus.ParseLevel(contents.Level)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	config.Levels = make(map[string]logrus.Level)
+	for packageName, value := range contents.Levels {
+		config.Levels[packageName], err = logrus.ParseLevel(value)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	config.Format, err = parseFormat(contents.Format)
+	return config, err
+}
+
+// ParseLevels parses a comma separated list of package=level pairs. A level
+// on its own is returned as the level for the package "".
+func ParseLevels(value string) (map[string]logrus.Level, error) {
+	levels := make(map[string]logrus.Level)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		var packageName, levelName string
+		if i := strings.Index(item, "="); i >= 0 {
+			packageName, levelName = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+			if packageName == "" {
+				return nil, fmt.Errorf("missing package name in %q", item)
+			}
+		} else {
+			levelName = item
+		}
+
+		level, err := logrus.ParseLevel(levelName)
+		if err != nil {
+			return nil, err
+		}
+		levels[packageName] = level
+	}
+
+	return levels, nil
+}
+
+func parseFormat(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", TextFormat:
+		return TextFormat, nil
+	case JSONFormat:
+		return JSONFormat, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q", value)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevelsParsesPackagesAndDefault(t *testing.T) {
+	actual, err := ParseLevels("apiservice=debug, domain=warn,error")
+
+	require.NoError(t, err, "ParseLevels() failed.")
+	assert.Equal(t, map[string]logrus.Level{
+		"apiservice": logrus.DebugLevel,
+		"domain":     logrus.WarnLevel,
+		"":           logrus.ErrorLevel,
+	}, actual, "Unexpected levels.")
+}
+
+func TestParseLevelsWithBadInputIsError(t *testing.T) {
+	for _, value := range []string{"apiservice=loud", "=debug", "loud"} {
+		_, err := ParseLevels(value)
+
+		assert.Error(t, err, "ParseLevels(%q) unexpectedly succeeded.", value)
+	}
+}
+
+func TestConfigFromEnvReadsLevelsAndFormat(t *testing.T) {
+	defer os.Setenv(LevelsEnv, os.Getenv(LevelsEnv))
+	defer os.Setenv(FormatEnv, os.Getenv(FormatEnv))
+	os.Setenv(LevelsEnv, "apiservice=debug,warn")
+	os.Setenv(FormatEnv, "json")
+
+	actual, err := ConfigFromEnv()
+
+	require.NoError(t, err, "ConfigFromEnv() failed.")
+	assert.Equal(t, logrus.WarnLevel, actual.DefaultLevel, "Unexpected default level.")
+	assert.Equal(t, logrus.DebugLevel, actual.level("apiservice"), "Unexpected apiservice level.")
+	assert.Equal(t, JSONFormat, actual.Format, "Unexpected format.")
+}
+
+func TestConfigFromEnvWithBadFormatIsError(t *testing.T) {
+	defer os.Setenv(FormatEnv, os.Getenv(FormatEnv))
+	os.Setenv(FormatEnv, "xml")
+
+	_, err := ConfigFromEnv()
+
+	assert.Error(t, err, "ConfigFromEnv() unexpectedly succeeded.")
+}
+
+func TestConfigFromFileReadsJSON(t *testing.T) {
+	file, err := ioutil.TempFile("", "logconfig")
+	require.NoError(t, err, "Unable to create the config file.")
+	defer os.Remove(file.Name())
+	file.WriteString(`{"level": "error", "levels": {"domain": "debug"}, "format": "json"}`)
+	file.Close()
+
+	actual, err := ConfigFromFile(file.Name())
+
+	require.NoError(t, err, "ConfigFromFile() failed.")
+	assert.Equal(t, logrus.ErrorLevel, actual.DefaultLevel, "Unexpected default level.")
+	assert.Equal(t, logrus.DebugLevel, actual.level("domain"), "Unexpected domain level.")
+	assert.Equal(t, JSONFormat, actual.Format, "Unexpected format.")
+}
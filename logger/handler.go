@@ -0,0 +1,64 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// levelChange is the body of a request to change the level of a package.
+type levelChange struct {
+	Package string `json:"package"`
+	Level   string `json:"level"`
+}
+
+// Handler gives an http.Handler for an admin endpoint that reports and
+// changes the levels of the package loggers. A GET responds with the level
+// of each package as a JSON object. A PUT or POST with a JSON body such as
+//
+//	{"package": "apiservice", "level": "debug"}
+//
+// changes the level of a package and then responds as a GET would.
+// The endpoint has no access control of its own so it must only be
+// mounted where administrators alone can reach it.
+func Handler() http.Handler {
+	return http.HandlerFunc(serveLevels)
+}
+
+func serveLevels(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var change levelChange
+		err := json.NewDecoder(r.Body).Decode(&change)
+		if err != nil || change.Package == "" {
+			http.Error(w, "The body must name a package and a level.", http.StatusBadRequest)
+			return
+		}
+
+		level, err := logrus.ParseLevel(change.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		SetLevel(change.Package, level)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body := make(map[string]string)
+	for packageName, level := range Levels() {
+		body[packageName] = level.String()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
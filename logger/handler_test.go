@@ -0,0 +1,63 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveLevelsRequest(t *testing.T, method string, body string) *httptest.ResponseRecorder {
+	request, err := http.NewRequest(method, "/loglevels", strings.NewReader(body))
+	require.NoError(t, err, "Unable to create the request.")
+	response := httptest.NewRecorder()
+
+	Handler().ServeHTTP(response, request)
+	return response
+}
+
+func TestHandlerGetReportsLevels(t *testing.T) {
+	defer useRegistry(t)()
+	SetLevel("domain", logrus.WarnLevel)
+	New("domain")
+
+	response := serveLevelsRequest(t, http.MethodGet, "")
+
+	require.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	var levels map[string]string
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &levels), "Unable to parse levels.")
+	assert.Equal(t, "warning", levels["domain"], "Unexpected level for domain.")
+}
+
+func TestHandlerPutChangesLevel(t *testing.T) {
+	defer useRegistry(t)()
+	sut := New("apiservice")
+
+	response := serveLevelsRequest(t, http.MethodPut, `{"package": "apiservice", "level": "debug"}`)
+
+	assert.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	assert.Equal(t, logrus.DebugLevel, sut.Logger.Level, "The level was not changed.")
+}
+
+func TestHandlerPutWithBadLevelIsBadRequest(t *testing.T) {
+	defer useRegistry(t)()
+
+	response := serveLevelsRequest(t, http.MethodPut, `{"package": "apiservice", "level": "loud"}`)
+
+	assert.Equal(t, http.StatusBadRequest, response.Code, "Unexpected status code.")
+}
+
+func TestHandlerDeleteIsNotAllowed(t *testing.T) {
+	response := serveLevelsRequest(t, http.MethodDelete, "")
+
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code, "Unexpected status code.")
+}
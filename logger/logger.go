@@ -5,19 +5,131 @@
 // Package logger provides the logging configurations for other packages in the project.
 // Each packages should declarae a variable named 'log' which it then uses for logging.
 // For package 'mypackage' this should be declared as follows:
+//
 //	var (
 //	    log = logger.New("mypackage")
 //	)
-// Currently the logger returned is a default logger in all instances, but this will
-// change to allow a more configurable logging.
+//
+// Each package has its own logger whose entries have a "package" field naming the
+// package. The level of each package's logger and the output format of all of the
+// loggers are set by Configure and by the OPENACCT_LOG and OPENACCT_LOG_FORMAT
+// environment variables (see ConfigFromEnv) or a configuration file (see
+// ConfigFromFile). The levels can also be changed while
+// the program is running through the http.Handler returned by Handler.
 package logger
 
 import (
+	"fmt"
+	"os"
+	"sync"
+
 	"github.com/Sirupsen/logrus"
-	"github.com/gogap/logrus_mate"
 )
 
+const packageField = "package"
+
+// registry holds the logger for each package and the configuration that
+// applies to them.
+type registry struct {
+	mutex   sync.Mutex
+	config  Config
+	loggers map[string]*logrus.Logger
+}
+
+var loggers = newRegistry()
+
+func newRegistry() *registry {
+	return &registry{config: defaultConfig(), loggers: make(map[string]*logrus.Logger)}
+}
+
+func init() {
+	config, err := ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logger: ignoring the logging environment: %v\n", err)
+		return
+	}
+
+	loggers.configure(config)
+}
+
 // Returns a logger for the package 'packageName'
-func New(packageName string) *logrus.Logger {
-	return logrus_mate.Logger()
+func New(packageName string) *logrus.Entry {
+	return loggers.logger(packageName).WithField(packageField, packageName)
+}
+
+// Configure applies config to the loggers of all packages, including those
+// that have already been created.
+func Configure(config Config) {
+	loggers.configure(config)
+}
+
+// SetLevel changes the level of the logger for the package 'packageName'.
+func SetLevel(packageName string, level logrus.Level) {
+	loggers.setLevel(packageName, level)
+}
+
+// Levels gives the level of the logger of each package that has one.
+func Levels() map[string]logrus.Level {
+	return loggers.levels()
+}
+
+func (r *registry) logger(packageName string) *logrus.Logger {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	logger, ok := r.loggers[packageName]
+	if !ok {
+		logger = logrus.New()
+		r.apply(packageName, logger)
+		r.loggers[packageName] = logger
+	}
+
+	return logger
+}
+
+func (r *registry) configure(config Config) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.config = config.withDefaults()
+	for packageName, logger := range r.loggers {
+		r.apply(packageName, logger)
+	}
+}
+
+func (r *registry) setLevel(packageName string, level logrus.Level) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	levels := make(map[string]logrus.Level)
+	for name, packageLevel := range r.config.Levels {
+		levels[name] = packageLevel
+	}
+	levels[packageName] = level
+	r.config.Levels = levels
+
+	if logger, ok := r.loggers[packageName]; ok {
+		logger.SetLevel(level)
+	}
+}
+
+func (r *registry) levels() map[string]logrus.Level {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	levels := make(map[string]logrus.Level)
+	for packageName, logger := range r.loggers {
+		levels[packageName] = logger.Level
+	}
+
+	return levels
+}
+
+// apply applies the registry's configuration to the logger for a package.
+// The caller must hold the mutex. The level is set atomically because it is
+// read, without the mutex, by every goroutine that logs.
+func (r *registry) apply(packageName string, logger *logrus.Logger) {
+	logger.Out = r.config.Out
+	logger.Formatter = r.config.formatter()
+	logger.SetLevel(r.config.level(packageName))
 }
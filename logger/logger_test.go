@@ -0,0 +1,72 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useRegistry replaces the registry of package loggers for a test.
+func useRegistry(t *testing.T) func() {
+	previous := loggers
+	loggers = newRegistry()
+	return func() {
+		loggers = previous
+	}
+}
+
+func TestNewTagsEntriesWithPackage(t *testing.T) {
+	defer useRegistry(t)()
+	var out bytes.Buffer
+	Configure(Config{DefaultLevel: logrus.InfoLevel, Format: JSONFormat, Out: &out})
+
+	sut := New("mypackage")
+	sut.Info("hello")
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry), "The entry is not JSON.")
+	assert.Equal(t, "mypackage", entry["package"], "Unexpected package field.")
+	assert.Equal(t, "hello", entry["msg"], "Unexpected message.")
+}
+
+func TestNewUsesLevelForPackage(t *testing.T) {
+	defer useRegistry(t)()
+	Configure(Config{DefaultLevel: logrus.WarnLevel,
+		Levels: map[string]logrus.Level{"apiservice": logrus.DebugLevel}})
+
+	apiservice := New("apiservice")
+	domain := New("domain")
+
+	assert.Equal(t, logrus.DebugLevel, apiservice.Logger.Level, "Unexpected level for apiservice.")
+	assert.Equal(t, logrus.WarnLevel, domain.Logger.Level, "Unexpected level for domain.")
+}
+
+func TestConfigureChangesExistingLoggers(t *testing.T) {
+	defer useRegistry(t)()
+	sut := New("domain")
+
+	Configure(Config{DefaultLevel: logrus.ErrorLevel, Format: JSONFormat})
+
+	assert.Equal(t, logrus.ErrorLevel, sut.Logger.Level, "Unexpected level.")
+	assert.IsType(t, &logrus.JSONFormatter{}, sut.Logger.Formatter, "Unexpected formatter.")
+}
+
+func TestSetLevelChangesLevelOfPackage(t *testing.T) {
+	defer useRegistry(t)()
+	sut := New("domain")
+
+	SetLevel("domain", logrus.DebugLevel)
+	SetLevel("later", logrus.ErrorLevel)
+
+	assert.Equal(t, logrus.DebugLevel, sut.Logger.Level, "Unexpected level for an existing logger.")
+	assert.Equal(t, logrus.ErrorLevel, New("later").Logger.Level, "Unexpected level for a later logger.")
+	assert.Equal(t, logrus.DebugLevel, Levels()["domain"], "Unexpected reported level.")
+}
@@ -0,0 +1,87 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Handler returns an http.Handler that serves the metrics in registry in the
+// Prometheus exposition format.
+func Handler(registry prometheus.Gatherer) http.Handler {
+	exposition := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		exposition.ServeHTTP(w, r)
+	})
+}
+
+// The descriptions of the gauges of a dbStatsCollector.
+var (
+	maxOpenConnectionsDesc = prometheus.NewDesc("openacct_db_max_open_connections",
+		"The maximum number of open connections to the database.", nil, nil)
+	openConnectionsDesc = prometheus.NewDesc("openacct_db_open_connections",
+		"The number of open connections to the database.", nil, nil)
+	inUseConnectionsDesc = prometheus.NewDesc("openacct_db_in_use_connections",
+		"The number of connections to the database that are in use.", nil, nil)
+	idleConnectionsDesc = prometheus.NewDesc("openacct_db_idle_connections",
+		"The number of idle connections to the database.", nil, nil)
+	waitCountDesc = prometheus.NewDesc("openacct_db_wait_count",
+		"The number of times a request waited for a connection to the database.", nil, nil)
+	waitDurationDesc = prometheus.NewDesc("openacct_db_wait_duration_seconds",
+		"The time spent waiting for connections to the database.", nil, nil)
+)
+
+// dbStatsCollector collects gauges for the usage of a database connection
+// pool whose statistics are given by stats.
+type dbStatsCollector struct {
+	stats func() sql.DBStats
+}
+
+// NewDBStatsCollector creates a collector of gauges for the usage of a
+// database connection pool whose statistics are given by stats.
+func NewDBStatsCollector(stats func() sql.DBStats) prometheus.Collector {
+	return &dbStatsCollector{stats}
+}
+
+func (d *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- maxOpenConnectionsDesc
+	ch <- openConnectionsDesc
+	ch <- inUseConnectionsDesc
+	ch <- idleConnectionsDesc
+	ch <- waitCountDesc
+	ch <- waitDurationDesc
+}
+
+func (d *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := d.stats()
+
+	ch <- prometheus.MustNewConstMetric(maxOpenConnectionsDesc, prometheus.GaugeValue,
+		float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(openConnectionsDesc, prometheus.GaugeValue,
+		float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(inUseConnectionsDesc, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(idleConnectionsDesc, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(waitCountDesc, prometheus.GaugeValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(waitDurationDesc, prometheus.GaugeValue, stats.WaitDuration.Seconds())
+}
+
+// RegisterDBStats registers gauges in registry for the usage of a database
+// connection pool whose statistics are given by stats. A registry has the
+// gauges of at most one pool so it returns an error if registry already has
+// them.
+func RegisterDBStats(registry prometheus.Registerer, stats func() sql.DBStats) error {
+	return registry.Register(NewDBStatsCollector(stats))
+}
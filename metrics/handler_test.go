@@ -0,0 +1,70 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveMetricsRequest(t *testing.T, registry prometheus.Gatherer, method string) *httptest.ResponseRecorder {
+	request, err := http.NewRequest(method, "/metrics", nil)
+	require.NoError(t, err, "Unable to create the request.")
+	response := httptest.NewRecorder()
+
+	Handler(registry).ServeHTTP(response, request)
+	return response
+}
+
+func TestHandlerServesTextFormat(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	Register(registry, newRequestsCounter()).(*prometheus.CounterVec).WithLabelValues("GET").Inc()
+
+	response := serveMetricsRequest(t, registry, http.MethodGet)
+
+	assert.Equal(t, http.StatusOK, response.Code, "Unexpected status code.")
+	assert.Contains(t, response.Header().Get("Content-Type"), "text/plain", "Unexpected content type.")
+	assert.Contains(t, response.Body.String(), "requests_total{method=\"GET\"} 1\n", "Missing counter.")
+}
+
+func TestHandlerPostIsNotAllowed(t *testing.T) {
+	response := serveMetricsRequest(t, prometheus.NewRegistry(), http.MethodPost)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code, "Unexpected status code.")
+}
+
+func TestRegisterDBStatsAddsPoolGauges(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	err := RegisterDBStats(registry, func() sql.DBStats {
+		return sql.DBStats{MaxOpenConnections: 10, OpenConnections: 3, InUse: 2, Idle: 1}
+	})
+	require.NoError(t, err, "Unable to register the pool gauges.")
+
+	response := serveMetricsRequest(t, registry, http.MethodGet)
+
+	body := response.Body.String()
+	assert.Contains(t, body, "openacct_db_max_open_connections 10\n", "Missing max open gauge.")
+	assert.Contains(t, body, "openacct_db_open_connections 3\n", "Missing open gauge.")
+	assert.Contains(t, body, "openacct_db_in_use_connections 2\n", "Missing in use gauge.")
+	assert.Contains(t, body, "openacct_db_idle_connections 1\n", "Missing idle gauge.")
+}
+
+func TestRegisterDBStatsTwiceInSameRegistryIsError(t *testing.T) {
+	stats := func() sql.DBStats { return sql.DBStats{} }
+	registry := prometheus.NewRegistry()
+	require.NoError(t, RegisterDBStats(registry, stats), "Unable to register the pool gauges.")
+
+	err := RegisterDBStats(registry, stats)
+
+	assert.Error(t, err, "The pool gauges were registered twice.")
+	assert.NoError(t, RegisterDBStats(prometheus.NewRegistry(), stats),
+		"Unable to register the pool gauges in another registry.")
+}
@@ -0,0 +1,41 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+// Package metrics provides the Prometheus registries that other packages in
+// the project record their metrics in and the handler that exposes them. The
+// metrics themselves are prometheus/client_golang collectors. Packages record
+// their metrics in the Default registry unless they are given another one:
+//
+//	requests := metrics.Register(metrics.Default, prometheus.NewCounterVec(
+//	    prometheus.CounterOpts{Name: "openacct_requests_total", Help: "The number of requests."},
+//	    []string{"method"})).(*prometheus.CounterVec)
+//	requests.WithLabelValues("GET").Inc()
+//
+// Registering a collector that a registry already has gives the existing
+// collector so packages can register their metrics each time they are set up.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Default is the registry that the project's packages record their metrics
+// in unless they are given another one.
+var Default = prometheus.NewRegistry()
+
+// Register registers collector with registry and gives the collector that
+// registry then has: collector itself or, if registry already has a collector
+// of the same metrics, that collector. It panics if collector can't be
+// registered for any other reason.
+func Register(registry prometheus.Registerer, collector prometheus.Collector) prometheus.Collector {
+	err := registry.Register(collector)
+	if existing, ok := err.(prometheus.AlreadyRegisteredError); ok {
+		return existing.ExistingCollector
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	return collector
+}
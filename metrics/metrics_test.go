@@ -0,0 +1,51 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRequestsCounter() *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "requests_total",
+		Help: "The number of requests.",
+	}, []string{"method"})
+}
+
+func TestRegisterGivesRegisteredCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := newRequestsCounter()
+
+	actual := Register(registry, counter)
+
+	assert.Equal(t, counter, actual, "Unexpected collector.")
+}
+
+func TestRegisterWithSameMetricsIsExistingCollector(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	Register(registry, newRequestsCounter()).(*prometheus.CounterVec).WithLabelValues("GET").Inc()
+	Register(registry, newRequestsCounter()).(*prometheus.CounterVec).WithLabelValues("GET").Inc()
+
+	counter := Register(registry, newRequestsCounter()).(*prometheus.CounterVec)
+	assert.Equal(t, float64(2), testutil.ToFloat64(counter.WithLabelValues("GET")), "Unexpected value.")
+}
+
+func TestRegisterWithSameNameAndDifferentLabelsPanics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	Register(registry, newRequestsCounter())
+
+	assert.Panics(t, func() {
+		Register(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "requests_total",
+			Help: "The number of requests.",
+		}, []string{"path"}))
+	}, "Registering inconsistent metrics didn't panic.")
+}
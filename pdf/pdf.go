@@ -0,0 +1,105 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+// Package pdf writes simple PDF documents of text. It supports only what
+// printed forms such as receipts need: pages of lines of text, placed by
+// their position on the page. Positions are in points (1/72 inch) from the
+// bottom left corner of the page.
+//
+// The documents are written by github.com/go-pdf/fpdf in the Go fonts,
+// which are embedded in the documents as Unicode TrueType fonts so text in
+// the Latin, Greek and Cyrillic scripts prints as written.
+package pdf
+
+import (
+	"io"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// The size of a US Letter page in points.
+const (
+	LetterWidth  = 612
+	LetterHeight = 792
+)
+
+// A Font is one of the fonts that a Document uses.
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+// The family of the fonts of a Document and the fpdf style of each Font.
+const fontFamily = "Go"
+
+var fontStyles = []string{"", "B"}
+
+// A Document is a PDF document whose pages all have the same size.
+type Document struct {
+	pdf    *fpdf.Fpdf
+	height float64
+}
+
+// A Page is one page of a Document.
+type Page struct {
+	document *Document
+	number   int
+}
+
+// New creates an empty Document with pages of width and height.
+func New(width float64, height float64) *Document {
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "pt",
+		Size:    fpdf.SizeType{Wd: width, Ht: height},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddUTF8FontFromBytes(fontFamily, fontStyles[Regular], goregular.TTF)
+	pdf.AddUTF8FontFromBytes(fontFamily, fontStyles[Bold], gobold.TTF)
+
+	return &Document{pdf: pdf, height: height}
+}
+
+// AddPage adds an empty page to the end of the document.
+func (d *Document) AddPage() *Page {
+	d.pdf.AddPage()
+	return &Page{document: d, number: d.pdf.PageCount()}
+}
+
+// Text writes text in font at size on the page with the left end of its
+// baseline at x and y.
+func (p *Page) Text(x float64, y float64, font Font, size float64, text string) {
+	pdf := p.document.pdf
+	pdf.SetPage(p.number)
+	pdf.SetFont(fontFamily, fontStyles[font], size)
+	pdf.Text(x, p.document.height-y, text)
+}
+
+// WriteTo writes the document to w in the PDF format. A document without
+// pages is written with one empty page because a PDF must have a page.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if d.pdf.PageCount() == 0 {
+		d.AddPage()
+	}
+
+	out := &countingWriter{w: w}
+	err := d.pdf.Output(out)
+	return out.n, err
+}
+
+// A countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,50 @@
+// Copyright Steven Bosnick 2016. All rights reserved.
+// Use of this source code is governed by the GNU General Public License version 3.
+// See the file COPYING for your rights under that license.
+
+package pdf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeDocument(t *testing.T, sut *Document) string {
+	var buffer bytes.Buffer
+	n, err := sut.WriteTo(&buffer)
+	require.NoError(t, err, "Unable to write the document.")
+	require.Equal(t, int64(buffer.Len()), n, "WriteTo() gave the wrong length.")
+	return buffer.String()
+}
+
+func TestWriteToWritesPdf(t *testing.T) {
+	sut := New(LetterWidth, LetterHeight)
+	sut.AddPage().Text(72, 720, Bold, 16, "Official Receipt")
+	sut.AddPage().Text(72, 720, Regular, 11, "Page two")
+
+	actual := writeDocument(t, sut)
+
+	assert.Regexp(t, `^%PDF-1\.\d\n`, actual)
+	assert.Regexp(t, `%%EOF\n$`, actual)
+	assert.Contains(t, actual, "/Count 2")
+	assert.Contains(t, actual, "/MediaBox [0 0 612.00 792.00]")
+}
+
+func TestWriteToEmbedsUnicodeFonts(t *testing.T) {
+	sut := New(LetterWidth, LetterHeight)
+	sut.AddPage().Text(72, 720, Regular, 11, "Dons reçus: 5 € – Ωмега")
+
+	actual := writeDocument(t, sut)
+
+	assert.Contains(t, actual, "/Subtype /Type0", "The font is not a Unicode font.")
+	assert.Contains(t, actual, "/FontFile2", "The font is not embedded.")
+}
+
+func TestWriteToWithoutPagesWritesEmptyPage(t *testing.T) {
+	actual := writeDocument(t, New(LetterWidth, LetterHeight))
+
+	assert.Contains(t, actual, "/Count 1")
+}